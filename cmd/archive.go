@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"1337b04rd/internal/adapters/db"
+	"1337b04rd/internal/adapters/export"
+	"1337b04rd/internal/app"
+	"1337b04rd/pkg/logger"
+)
+
+// runArchive runs `1337b04rd archive <command>` and returns the exit code.
+func runArchive(args []string) int {
+	if len(args) == 0 || args[0] != "export" {
+		fmt.Fprintln(os.Stderr, "Usage: 1337b04rd archive export --out <dir> [--format html|json|warc] [--full] [--base-url <url>]")
+		return 2
+	}
+	opts, err := parseExportFlags(args[1:], os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	log, logFile := logger.SetupLogger()
+	defer logFile.Close()
+	slog.SetDefault(log)
+
+	database := db.ConnectToDB(log)
+	defer database.Close()
+
+	exporter, err := app.NewArchiveExporter(database, log, opts)
+	if err != nil {
+		log.Error("Failed to prepare archive export", "error", err)
+		return 1
+	}
+	result, err := exporter.Export(context.Background())
+	if err != nil {
+		log.Error("Archive export failed", "error", err, "threads", result.Threads, "images", result.Images)
+		return 1
+	}
+	log.Info("Archive exported", "format", opts.Format, "out", opts.Out, "threads", result.Threads, "images", result.Images, "since", result.Since)
+	return 0
+}
+
+// parseExportFlags reads the options of archive export. Published links
+// default to PUBLIC_URL, where the board itself is served.
+func parseExportFlags(args []string, output io.Writer) (export.Options, error) {
+	opts := export.Options{}
+	fs := flag.NewFlagSet("archive export", flag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.StringVar(&opts.Format, "format", export.FormatHTML, "Export format: html, json or warc")
+	fs.StringVar(&opts.Out, "out", "", "Directory to export to, kept between runs")
+	fs.BoolVar(&opts.Full, "full", false, "Export every archived thread, not only those archived since the last run")
+	fs.StringVar(&opts.BaseURL, "base-url", os.Getenv("PUBLIC_URL"), "URL the export is published at, for WARC records")
+	if err := fs.Parse(args); err != nil {
+		return opts, err
+	}
+	if opts.Out == "" {
+		return opts, errors.New("archive export: --out is required")
+	}
+	switch opts.Format {
+	case export.FormatHTML, export.FormatJSON, export.FormatWARC:
+	default:
+		return opts, fmt.Errorf("archive export: --format %q is not one of html, json or warc", opts.Format)
+	}
+	if opts.BaseURL == "" {
+		opts.BaseURL = "http://localhost:8080"
+	}
+	return opts, nil
+}
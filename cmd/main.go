@@ -1,15 +1,30 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
-	"log"
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"1337b04rd/internal/adapters/db"
 	"1337b04rd/internal/app"
+	"1337b04rd/pkg/health"
+	"1337b04rd/pkg/logger"
+	"1337b04rd/pkg/tracing"
+)
+
+const (
+	// shutdownDelay keeps serving after a stop signal while /readyz fails,
+	// so load balancers stop routing here before connections are refused.
+	shutdownDelay = 5 * time.Second
+	// shutdownTimeout bounds how long in-flight requests may drain.
+	shutdownTimeout = 15 * time.Second
 )
 
 var (
@@ -24,6 +39,9 @@ func initFlags() {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "archive" {
+		os.Exit(runArchive(os.Args[2:]))
+	}
 	initFlags()
 
 	if showHelp {
@@ -31,21 +49,57 @@ func main() {
 		os.Exit(0)
 	}
 
-	// Initialize logger
-	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
-	if logger == nil {
-		panic("logger is nil")
-	}
+	// Initialize logger, configured by LOG_FORMAT, LOG_LEVEL and LOG_FILE
+	log, logFile := logger.SetupLogger()
+	defer logFile.Close()
+	slog.SetDefault(log)
+
+	// Initialize tracing, exported as TRACE_EXPORTER says
+	stopTracing := tracing.SetupTracing(log)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := stopTracing(ctx); err != nil {
+			log.Error("Failed to flush traces", "error", err)
+		}
+	}()
 
-	database := db.ConnectToDB()
+	database := db.ConnectToDB(log)
 	defer database.Close()
 
-	mux := app.NewApp(database, logger)
+	readiness := health.NewChecker(health.DefaultCacheTTL, health.DefaultTimeout)
+	mux := app.NewApp(database, log, readiness)
+
+	log.Info("App running", "url", fmt.Sprintf("http://localhost:%d/", port))
+	log.Info("Minio running", "url", "http://localhost:9001/")
 
-	log.Printf("App running on http://localhost:%d/", port)
-	log.Println("Minio running on http://localhost:9001/")
+	server := &http.Server{Addr: fmt.Sprintf(":%d", port), Handler: mux}
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- server.ListenAndServe() }()
 
-	http.ListenAndServe(fmt.Sprintf(":%d", port), mux)
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case err := <-serveErr:
+		log.Error("Server stopped", "error", err)
+		return
+	case sig := <-stop:
+		log.Info("Shutting down", "signal", sig.String(), "delay", shutdownDelay)
+	}
+
+	readiness.Shutdown()
+	time.Sleep(shutdownDelay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Error("Failed to drain connections", "error", err)
+	}
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		log.Error("Server stopped", "error", err)
+		return
+	}
+	log.Info("Server stopped")
 }
 
 func printHelp() {
@@ -53,9 +107,13 @@ func printHelp() {
 	fmt.Println()
 	fmt.Println("Usage:")
 	fmt.Println("  1337b04rd [--port <N>]")
+	fmt.Println("  1337b04rd archive export --out <dir> [--format html|json|warc] [--full] [--base-url <url>]")
 	fmt.Println("  1337b04rd --help")
 	fmt.Println()
 	fmt.Println("Options:")
 	fmt.Println("  --help       Show this screen.")
 	fmt.Println("  --port N     Port number.")
+	fmt.Println()
+	fmt.Println("archive export renders archived threads into a static site in <dir>.")
+	fmt.Println("Runs are incremental; --full exports every thread again.")
 }
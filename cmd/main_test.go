@@ -2,6 +2,7 @@ package main
 
 import (
 	"flag"
+	"io"
 	"os"
 	"testing"
 )
@@ -59,3 +60,40 @@ func TestMainFlags(t *testing.T) {
 		})
 	}
 }
+
+func TestArchiveExportFlags(t *testing.T) {
+	t.Setenv("PUBLIC_URL", "https://board.example")
+	tests := []struct {
+		name       string
+		args       []string
+		wantFormat string
+		wantFull   bool
+		wantErr    bool
+	}{
+		{"defaults", []string{"--out", "site"}, "html", false, false},
+		{"json and full", []string{"--out", "site", "--format", "json", "--full"}, "json", true, false},
+		{"no output directory", []string{"--format", "warc"}, "", false, true},
+		{"unknown format", []string{"--out", "site", "--format", "pdf"}, "", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := parseExportFlags(tt.args, io.Discard)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected an error, got %+v", opts)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if opts.Format != tt.wantFormat || opts.Full != tt.wantFull || opts.Out != "site" {
+				t.Errorf("Got %+v", opts)
+			}
+			if opts.BaseURL != "https://board.example" {
+				t.Errorf("Expected the base URL from PUBLIC_URL, got %q", opts.BaseURL)
+			}
+		})
+	}
+}
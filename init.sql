@@ -2,16 +2,39 @@ DO $$ BEGIN
     RAISE NOTICE 'Creating tables...';
 END $$;
 
+-- Boards table
+CREATE TABLE IF NOT EXISTS boards (
+    slug VARCHAR(32) PRIMARY KEY,
+    title TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    captcha_difficulty SMALLINT NOT NULL DEFAULT 16 CHECK (captcha_difficulty BETWEEN 0 AND 24),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+INSERT INTO boards (slug, title, description)
+VALUES ('b', 'Random', 'Anything goes across every dimension.')
+ON CONFLICT (slug) DO NOTHING;
+DO $$ BEGIN
+    RAISE NOTICE 'Created boards table.';
+END $$;
+
 -- Posts table
 CREATE TABLE IF NOT EXISTS posts (
     id SERIAL PRIMARY KEY,
     title TEXT NOT NULL,
     content TEXT NOT NULL,
     image_url TEXT,
+    image_source_url TEXT NOT NULL DEFAULT '',
     author_id VARCHAR(255) NOT NULL, 
     author_name VARCHAR(100) NOT NULL, 
+    ip_hash VARCHAR(64) NOT NULL DEFAULT '',
+    board VARCHAR(32) NOT NULL DEFAULT 'b' REFERENCES boards(slug),
+    sticky BOOLEAN NOT NULL DEFAULT FALSE,
+    locked BOOLEAN NOT NULL DEFAULT FALSE,
     created_at TIMESTAMP NOT NULL DEFAULT NOW(),
-    expires_at TIMESTAMP NOT NULL
+    expires_at TIMESTAMP NOT NULL,
+    search_vector TSVECTOR GENERATED ALWAYS AS (
+        to_tsvector('english', coalesce(title, '') || ' ' || coalesce(content, ''))
+    ) STORED
 );
 DO $$ BEGIN
     RAISE NOTICE 'Created posts table.';
@@ -24,10 +47,15 @@ CREATE TABLE IF NOT EXISTS comments (
     title TEXT NOT NULL,
     content TEXT NOT NULL,
     image_url TEXT,
+    image_source_url TEXT NOT NULL DEFAULT '',
     author_id VARCHAR(255) NOT NULL,
     author_name VARCHAR(255) NOT NULL,
+    ip_hash VARCHAR(64) NOT NULL DEFAULT '',
     created_at TIMESTAMP NOT NULL DEFAULT NOW(),
     reply_to_comment_id INT,
+    search_vector TSVECTOR GENERATED ALWAYS AS (
+        to_tsvector('english', coalesce(title, '') || ' ' || coalesce(content, ''))
+    ) STORED,
     FOREIGN KEY (reply_to_comment_id) REFERENCES comments(id) ON DELETE CASCADE 
 );
 DO $$ BEGIN
@@ -40,11 +68,16 @@ CREATE TABLE IF NOT EXISTS archived_posts (
     title TEXT NOT NULL,
     content TEXT NOT NULL,
     image_url TEXT,
+    image_source_url TEXT NOT NULL DEFAULT '',
     author_id VARCHAR(255) NOT NULL,
     author_name VARCHAR(255) NOT NULL,
+    board VARCHAR(32) NOT NULL DEFAULT 'b',
     created_at TIMESTAMP NOT NULL,
     expires_at TIMESTAMP NOT NULL,
-    archived_at TIMESTAMP NOT NULL DEFAULT NOW()
+    archived_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    search_vector TSVECTOR GENERATED ALWAYS AS (
+        to_tsvector('english', coalesce(title, '') || ' ' || coalesce(content, ''))
+    ) STORED
 );
 DO $$ BEGIN
     RAISE NOTICE 'Created archived_posts table.';
@@ -57,12 +90,246 @@ CREATE TABLE IF NOT EXISTS archived_comments (
     title TEXT NOT NULL,
     content TEXT NOT NULL,
     image_url TEXT,
+    image_source_url TEXT NOT NULL DEFAULT '',
     author_id VARCHAR(255) NOT NULL,
     author_name VARCHAR(255) NOT NULL,
     created_at TIMESTAMP NOT NULL,
     reply_to_comment_id INT,
+    search_vector TSVECTOR GENERATED ALWAYS AS (
+        to_tsvector('english', coalesce(title, '') || ' ' || coalesce(content, ''))
+    ) STORED,
     FOREIGN KEY (reply_to_comment_id) REFERENCES archived_comments(id) ON DELETE CASCADE
 );
 DO $$ BEGIN
     RAISE NOTICE 'Created archived_comments table.';
 END $$;
+
+-- Staff accounts
+CREATE TABLE IF NOT EXISTS staff (
+    id SERIAL PRIMARY KEY,
+    username VARCHAR(64) NOT NULL UNIQUE,
+    password_hash TEXT NOT NULL,
+    role VARCHAR(16) NOT NULL CHECK (role IN ('admin', 'mod', 'janitor')),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+DO $$ BEGIN
+    RAISE NOTICE 'Created staff table.';
+END $$;
+
+-- Staff login sessions, keyed by the SHA-256 of the cookie token
+CREATE TABLE IF NOT EXISTS staff_sessions (
+    token_hash CHAR(64) PRIMARY KEY,
+    staff_id INT NOT NULL REFERENCES staff(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL
+);
+DO $$ BEGIN
+    RAISE NOTICE 'Created staff_sessions table.';
+END $$;
+
+-- Reports of posts and comments
+CREATE TABLE IF NOT EXISTS reports (
+    id SERIAL PRIMARY KEY,
+    target_type VARCHAR(16) NOT NULL CHECK (target_type IN ('post', 'comment')),
+    target_id INT NOT NULL,
+    post_id INT NOT NULL,
+    board VARCHAR(32) NOT NULL,
+    category VARCHAR(16) NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    session_id VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    resolved_at TIMESTAMP,
+    resolved_by INT REFERENCES staff(id) ON DELETE SET NULL,
+    resolution VARCHAR(16) CHECK (resolution IN ('dismiss', 'delete', 'ban'))
+);
+CREATE INDEX IF NOT EXISTS reports_open_idx ON reports (target_type, target_id) WHERE resolved_at IS NULL;
+-- A session may hold one open report per post or comment
+CREATE UNIQUE INDEX IF NOT EXISTS reports_dedupe_idx ON reports (session_id, target_type, target_id) WHERE resolved_at IS NULL;
+CREATE INDEX IF NOT EXISTS reports_session_idx ON reports (session_id, created_at);
+DO $$ BEGIN
+    RAISE NOTICE 'Created reports table.';
+END $$;
+
+-- Bans by session, salted IP hash or CIDR range; a NULL board bans
+-- everywhere and a NULL expires_at never expires
+CREATE TABLE IF NOT EXISTS bans (
+    id SERIAL PRIMARY KEY,
+    session_id VARCHAR(255),
+    ip_hash VARCHAR(64),
+    cidr CIDR,
+    board VARCHAR(32) REFERENCES boards(slug) ON DELETE CASCADE,
+    reason TEXT NOT NULL,
+    staff_id INT REFERENCES staff(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP,
+    CHECK (session_id IS NOT NULL OR ip_hash IS NOT NULL OR cidr IS NOT NULL)
+);
+CREATE INDEX IF NOT EXISTS bans_session_idx ON bans (session_id) WHERE session_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS bans_ip_hash_idx ON bans (ip_hash) WHERE ip_hash IS NOT NULL;
+DO $$ BEGIN
+    RAISE NOTICE 'Created bans table.';
+END $$;
+
+-- One appeal per ban
+CREATE TABLE IF NOT EXISTS ban_appeals (
+    id SERIAL PRIMARY KEY,
+    ban_id INT NOT NULL UNIQUE REFERENCES bans(id) ON DELETE CASCADE,
+    message TEXT NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted', 'denied')),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    decided_at TIMESTAMP,
+    decided_by INT REFERENCES staff(id) ON DELETE SET NULL
+);
+DO $$ BEGIN
+    RAISE NOTICE 'Created ban_appeals table.';
+END $$;
+
+-- Append-only log of staff and system actions. Actors are copied rather
+-- than referenced so entries outlive staff accounts.
+CREATE TABLE IF NOT EXISTS mod_actions (
+    id BIGSERIAL PRIMARY KEY,
+    actor_type VARCHAR(16) NOT NULL CHECK (actor_type IN ('staff', 'system')),
+    actor_id INT,
+    actor_name VARCHAR(64) NOT NULL,
+    action VARCHAR(32) NOT NULL,
+    target_type VARCHAR(16) NOT NULL,
+    target_id VARCHAR(64) NOT NULL,
+    board VARCHAR(32),
+    reason TEXT NOT NULL DEFAULT '',
+    before JSONB,
+    after JSONB,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS mod_actions_target_idx ON mod_actions (target_type, target_id);
+CREATE INDEX IF NOT EXISTS mod_actions_actor_idx ON mod_actions (actor_name, id);
+CREATE OR REPLACE FUNCTION mod_actions_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'mod_actions is append-only';
+END $$ LANGUAGE plpgsql;
+DROP TRIGGER IF EXISTS mod_actions_append_only ON mod_actions;
+CREATE TRIGGER mod_actions_append_only BEFORE UPDATE OR DELETE ON mod_actions
+    FOR EACH ROW EXECUTE FUNCTION mod_actions_append_only();
+DO $$ BEGIN
+    RAISE NOTICE 'Created mod_actions table.';
+END $$;
+
+-- Content filters checked against new threads and comments; a NULL board
+-- applies everywhere
+CREATE TABLE IF NOT EXISTS filters (
+    id SERIAL PRIMARY KEY,
+    board VARCHAR(32) REFERENCES boards(slug) ON DELETE CASCADE,
+    kind VARCHAR(16) NOT NULL CHECK (kind IN ('pattern', 'literal', 'links', 'duplicate', 'caps', 'flood')),
+    pattern TEXT NOT NULL DEFAULT '',
+    replacement TEXT NOT NULL DEFAULT '',
+    threshold INT NOT NULL DEFAULT 0 CHECK (threshold >= 0),
+    action VARCHAR(16) NOT NULL CHECK (action IN ('replace', 'reject', 'ban')),
+    ban_hours INT NOT NULL DEFAULT 0 CHECK (ban_hours >= 0),
+    reason TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    dry_run BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+DO $$ BEGIN
+    RAISE NOTICE 'Created filters table.';
+END $$;
+
+-- Columns that came after the first tables. CREATE TABLE IF NOT EXISTS
+-- leaves tables of older databases as they are, so add them here.
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS board VARCHAR(32) NOT NULL DEFAULT 'b' REFERENCES boards(slug);
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS sticky BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS locked BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS ip_hash VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS image_source_url TEXT NOT NULL DEFAULT '';
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS ip_hash VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS image_source_url TEXT NOT NULL DEFAULT '';
+ALTER TABLE archived_posts ADD COLUMN IF NOT EXISTS board VARCHAR(32) NOT NULL DEFAULT 'b';
+ALTER TABLE archived_posts ADD COLUMN IF NOT EXISTS image_source_url TEXT NOT NULL DEFAULT '';
+ALTER TABLE archived_comments ADD COLUMN IF NOT EXISTS image_source_url TEXT NOT NULL DEFAULT '';
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+    to_tsvector('english', coalesce(title, '') || ' ' || coalesce(content, ''))
+) STORED;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+    to_tsvector('english', coalesce(title, '') || ' ' || coalesce(content, ''))
+) STORED;
+ALTER TABLE archived_posts ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+    to_tsvector('english', coalesce(title, '') || ' ' || coalesce(content, ''))
+) STORED;
+ALTER TABLE archived_comments ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+    to_tsvector('english', coalesce(title, '') || ' ' || coalesce(content, ''))
+) STORED;
+DO $$ BEGIN
+    RAISE NOTICE 'Added columns to older tables.';
+END $$;
+
+-- Full-text search indexes
+CREATE INDEX IF NOT EXISTS posts_search_idx ON posts USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS comments_search_idx ON comments USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS archived_posts_search_idx ON archived_posts USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS archived_comments_search_idx ON archived_comments USING GIN (search_vector);
+DO $$ BEGIN
+    RAISE NOTICE 'Created search indexes.';
+END $$;
+
+-- Live update event IDs, shared by every replica
+CREATE SEQUENCE IF NOT EXISTS event_id_seq;
+DO $$ BEGIN
+    RAISE NOTICE 'Created event sequence.';
+END $$;
+
+-- Rate limit token buckets, shared by every replica with RATE_LIMIT_BACKEND=postgres
+CREATE TABLE IF NOT EXISTS rate_limits (
+    key VARCHAR(128) PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    full_at TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS rate_limits_full_idx ON rate_limits (full_at);
+DO $$ BEGIN
+    RAISE NOTICE 'Created rate_limits table.';
+END $$;
+
+-- Used captcha challenges, kept until they expire so none is used twice
+CREATE TABLE IF NOT EXISTS used_challenges (
+    id VARCHAR(64) PRIMARY KEY,
+    expires_at TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS used_challenges_expires_idx ON used_challenges (expires_at);
+DO $$ BEGIN
+    RAISE NOTICE 'Created used_challenges table.';
+END $$;
+
+-- Schema version, checked by /readyz. Bump it with every change to this
+-- file along with db.SchemaVersion.
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INTEGER PRIMARY KEY,
+    applied_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+INSERT INTO schema_migrations (version) VALUES (1) ON CONFLICT (version) DO NOTHING;
+DO $$ BEGIN
+    RAISE NOTICE 'Created schema_migrations table.';
+END $$;
+
+-- Version 2: archived threads and comments keep their live IDs. Those
+-- archived before got IDs of their own, which may collide with live ones,
+-- so they move to negative IDs.
+DO $$ BEGIN
+    IF NOT EXISTS (SELECT 1 FROM schema_migrations WHERE version = 2) THEN
+        ALTER TABLE archived_comments
+            DROP CONSTRAINT IF EXISTS archived_comments_post_id_fkey,
+            DROP CONSTRAINT IF EXISTS archived_comments_reply_to_comment_id_fkey;
+        UPDATE archived_comments SET id = -id, post_id = -post_id, reply_to_comment_id = -reply_to_comment_id;
+        UPDATE archived_posts SET id = -id;
+        ALTER TABLE archived_comments
+            ADD CONSTRAINT archived_comments_post_id_fkey FOREIGN KEY (post_id) REFERENCES archived_posts(id) ON DELETE CASCADE,
+            ADD CONSTRAINT archived_comments_reply_to_comment_id_fkey FOREIGN KEY (reply_to_comment_id) REFERENCES archived_comments(id) ON DELETE CASCADE;
+        INSERT INTO schema_migrations (version) VALUES (2);
+        RAISE NOTICE 'Moved archived threads to their live IDs.';
+    END IF;
+END $$;
+
+-- Version 3: the columns added to older tables above.
+INSERT INTO schema_migrations (version) VALUES (3) ON CONFLICT (version) DO NOTHING;
+
+-- Version 4: image source URLs in the archive.
+INSERT INTO schema_migrations (version) VALUES (4) ON CONFLICT (version) DO NOTHING;
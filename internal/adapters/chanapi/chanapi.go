@@ -0,0 +1,250 @@
+// Package chanapi maps threads to the JSON of the 4chan read API, so
+// imageboard clients and archivers can read the board unchanged.
+package chanapi
+
+import (
+	"fmt"
+	"html"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"1337b04rd/internal/domain"
+)
+
+// ThreadsPerPage is how many threads a page of the catalog holds, as on
+// 4chan.
+const ThreadsPerPage = 15
+
+// ReplyNoOffset is added to comment IDs to number replies. Threads and
+// comments are counted separately here while clients expect every post of
+// a thread to have its own number, so a reply would otherwise share the
+// number of its opening post.
+const ReplyNoOffset = 1_000_000_000
+
+// Post is a post in the 4chan API, an opening post or a reply. Image
+// fields are only set for posts with an image.
+type Post struct {
+	No       int    `json:"no"`
+	Resto    int    `json:"resto"`
+	Sticky   int    `json:"sticky,omitempty"`
+	Closed   int    `json:"closed,omitempty"`
+	Archived int    `json:"archived,omitempty"`
+	Now      string `json:"now"`
+	Time     int64  `json:"time"`
+	Name     string `json:"name"`
+	Sub      string `json:"sub,omitempty"`
+	Com      string `json:"com,omitempty"`
+
+	Tim      int64  `json:"tim,omitempty"`
+	Filename string `json:"filename,omitempty"`
+	Ext      string `json:"ext,omitempty"`
+
+	// Only on opening posts
+	Replies      *int   `json:"replies,omitempty"`
+	Images       *int   `json:"images,omitempty"`
+	LastModified int64  `json:"last_modified,omitempty"`
+	LastReplies  []Post `json:"last_replies,omitempty"`
+}
+
+// Page is one page of catalog.json.
+type Page struct {
+	Page    int    `json:"page"`
+	Threads []Post `json:"threads"`
+}
+
+// ThreadStub is a thread in threads.json.
+type ThreadStub struct {
+	No           int   `json:"no"`
+	LastModified int64 `json:"last_modified"`
+	Replies      int   `json:"replies"`
+}
+
+// StubPage is one page of threads.json.
+type StubPage struct {
+	Page    int          `json:"page"`
+	Threads []ThreadStub `json:"threads"`
+}
+
+// Thread is the body of thread/{no}.json.
+type Thread struct {
+	Posts []Post `json:"posts"`
+}
+
+// Board is a board in boards.json.
+type Board struct {
+	Board       string `json:"board"`
+	Title       string `json:"title"`
+	MetaDesc    string `json:"meta_description"`
+	WsBoard     int    `json:"ws_board"`
+	PerPage     int    `json:"per_page"`
+	Pages       int    `json:"pages"`
+	MaxFilesize int    `json:"max_filesize"`
+}
+
+// Boards is the body of boards.json.
+type Boards struct {
+	Boards []Board `json:"boards"`
+}
+
+// NewBoard maps a board, which lists at most pages pages of threads.
+func NewBoard(b *domain.Board, pages, maxFilesize int) Board {
+	return Board{Board: b.Slug, Title: b.Title, MetaDesc: b.Description, PerPage: ThreadsPerPage, Pages: pages, MaxFilesize: maxFilesize}
+}
+
+// CatalogThread maps a thread of the catalog, whose Comments hold only its
+// latest replies.
+func CatalogThread(p *domain.Post) Post {
+	op := opening(p)
+	replies, images := p.ReplyCount, p.ImageCount
+	op.Replies, op.Images = &replies, &images
+	op.LastModified = lastModified(p).Unix()
+	for i := range p.Comments {
+		op.LastReplies = append(op.LastReplies, Reply(&p.Comments[i]))
+	}
+	return op
+}
+
+// Catalog splits threads, already in bump order, into pages.
+func Catalog(threads []*domain.Post) []Page {
+	pages := []Page{}
+	for i, p := range threads {
+		if i%ThreadsPerPage == 0 {
+			pages = append(pages, Page{Page: len(pages) + 1, Threads: []Post{}})
+		}
+		pages[len(pages)-1].Threads = append(pages[len(pages)-1].Threads, CatalogThread(p))
+	}
+	return pages
+}
+
+// Threads splits threads, already in bump order, into pages of stubs.
+func Threads(threads []*domain.Post) []StubPage {
+	pages := []StubPage{}
+	for i, p := range threads {
+		if i%ThreadsPerPage == 0 {
+			pages = append(pages, StubPage{Page: len(pages) + 1, Threads: []ThreadStub{}})
+		}
+		stub := ThreadStub{No: p.ID, LastModified: lastModified(p).Unix(), Replies: p.ReplyCount}
+		pages[len(pages)-1].Threads = append(pages[len(pages)-1].Threads, stub)
+	}
+	return pages
+}
+
+// FullThread maps a thread with all its replies. Archived threads are
+// marked as such and closed.
+func FullThread(p *domain.Post, archived bool) Thread {
+	op := opening(p)
+	replies, images := len(p.Comments), 0
+	for _, c := range p.Comments {
+		if c.ImageURL != "" {
+			images++
+		}
+	}
+	op.Replies, op.Images = &replies, &images
+	if archived {
+		op.Archived, op.Closed = 1, 1
+	}
+
+	thread := Thread{Posts: []Post{op}}
+	for i := range p.Comments {
+		thread.Posts = append(thread.Posts, Reply(&p.Comments[i]))
+	}
+	return thread
+}
+
+// Reply maps a comment.
+func Reply(c *domain.Comment) Post {
+	post := Post{
+		No:    ReplyNoOffset + c.ID,
+		Resto: c.PostID,
+		Now:   now(c.CreatedAt),
+		Time:  c.CreatedAt.Unix(),
+		Name:  c.AuthorName,
+		Sub:   c.Title,
+		Com:   Comment(c.Content),
+	}
+	setImage(&post, c.ImageURL)
+	return post
+}
+
+func opening(p *domain.Post) Post {
+	post := Post{
+		No:   p.ID,
+		Now:  now(p.CreatedAt),
+		Time: p.CreatedAt.Unix(),
+		Name: p.AuthorName,
+		Sub:  p.Title,
+		Com:  Comment(p.Content),
+	}
+	if p.Sticky {
+		post.Sticky = 1
+	}
+	if p.Locked {
+		post.Closed = 1
+	}
+	setImage(&post, p.ImageURL)
+	return post
+}
+
+// setImage fills the image fields from an uploaded image's URL, such as
+// /images/posts/1714769700000000000-cat.png: tim is the upload time the
+// object name starts with, filename the rest without its extension.
+func setImage(post *Post, imageURL string) {
+	tim, name, ok := ParseImageURL(imageURL)
+	if !ok {
+		return
+	}
+	post.Tim = tim
+	post.Ext = path.Ext(name)
+	post.Filename = strings.TrimSuffix(name, post.Ext)
+}
+
+// ParseImageURL splits the URL of an upload into its time and file name.
+func ParseImageURL(imageURL string) (int64, string, bool) {
+	var object string
+	switch {
+	case strings.HasPrefix(imageURL, "/images/posts/"):
+		object = strings.TrimPrefix(imageURL, "/images/posts/")
+	case strings.HasPrefix(imageURL, "/images/comments/"):
+		object = strings.TrimPrefix(imageURL, "/images/comments/")
+	default:
+		return 0, "", false
+	}
+	prefix, name, ok := strings.Cut(object, "-")
+	if !ok {
+		return 0, "", false
+	}
+	tim, err := strconv.ParseInt(prefix, 10, 64)
+	if err != nil {
+		return 0, "", false
+	}
+	return tim, name, true
+}
+
+// Comment renders plain text as the HTML clients expect in com: escaped,
+// with line breaks as <br> and greentext quoted.
+func Comment(text string) string {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		escaped := html.EscapeString(line)
+		if strings.HasPrefix(line, ">") && !strings.HasPrefix(line, ">>") {
+			escaped = `<span class="quote">` + escaped + `</span>`
+		}
+		lines[i] = escaped
+	}
+	return strings.Join(lines, "<br>")
+}
+
+// now formats a time the way 4chan shows it, such as 05/03/25(Sat)21:35:00.
+func now(t time.Time) string {
+	t = t.UTC()
+	return fmt.Sprintf("%s(%s)%s", t.Format("01/02/06"), t.Format("Mon"), t.Format("15:04:05"))
+}
+
+func lastModified(p *domain.Post) time.Time {
+	if p.LastReplyAt != nil && p.LastReplyAt.After(p.CreatedAt) {
+		return *p.LastReplyAt
+	}
+	return p.CreatedAt
+}
@@ -0,0 +1,83 @@
+package chanapi_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"1337b04rd/internal/adapters/chanapi"
+	"1337b04rd/internal/domain"
+)
+
+func TestFullThread(t *testing.T) {
+	created := time.Date(2025, 5, 3, 21, 35, 0, 0, time.UTC)
+	post := &domain.Post{
+		ID: 7, Title: "Portal gun", Content: "wubba\n>lubba\n>>6 <dub>", AuthorName: "Rick",
+		ImageURL: "/images/posts/1714769700000000000-portal.gun.png", Board: "b", Locked: true, CreatedAt: created,
+		Comments: []domain.Comment{
+			{ID: 3, PostID: 7, Content: "nice", AuthorName: "Morty", ImageURL: "/images/comments/1714769800000000000-x.jpg", CreatedAt: created.Add(time.Minute)},
+			{ID: 4, PostID: 7, Content: "meh", AuthorName: "Summer", CreatedAt: created.Add(2 * time.Minute)},
+		},
+	}
+
+	thread := chanapi.FullThread(post, true)
+	if len(thread.Posts) != 3 {
+		t.Fatalf("got %d posts, want the opening post and 2 replies", len(thread.Posts))
+	}
+	op := thread.Posts[0]
+	if op.No != 7 || op.Resto != 0 || op.Closed != 1 || op.Archived != 1 || *op.Replies != 2 || *op.Images != 1 {
+		t.Errorf("opening post = %+v", op)
+	}
+	if op.Tim != 1714769700000000000 || op.Filename != "portal.gun" || op.Ext != ".png" {
+		t.Errorf("image = %d %q %q", op.Tim, op.Filename, op.Ext)
+	}
+	if op.Now != "05/03/25(Sat)21:35:00" || op.Time != created.Unix() {
+		t.Errorf("time = %q %d", op.Now, op.Time)
+	}
+	if want := `wubba<br><span class="quote">&gt;lubba</span><br>&gt;&gt;6 &lt;dub&gt;`; op.Com != want {
+		t.Errorf("com = %q, want %q", op.Com, want)
+	}
+
+	reply := thread.Posts[1]
+	if reply.No != chanapi.ReplyNoOffset+3 || reply.Resto != 7 || reply.Ext != ".jpg" {
+		t.Errorf("reply = %+v", reply)
+	}
+
+	body, err := json.Marshal(thread.Posts[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]any
+	json.Unmarshal(body, &fields)
+	for _, field := range []string{"tim", "ext", "replies", "sticky"} {
+		if _, ok := fields[field]; ok {
+			t.Errorf("reply without an image has %q: %s", field, body)
+		}
+	}
+}
+
+func TestCatalogPages(t *testing.T) {
+	var threads []*domain.Post
+	for i := 1; i <= chanapi.ThreadsPerPage+1; i++ {
+		threads = append(threads, &domain.Post{ID: i, ReplyCount: i})
+	}
+
+	pages := chanapi.Catalog(threads)
+	if len(pages) != 2 || pages[0].Page != 1 || len(pages[0].Threads) != chanapi.ThreadsPerPage || len(pages[1].Threads) != 1 {
+		t.Fatalf("got %+v", pages)
+	}
+	if last := pages[1].Threads[0]; last.No != chanapi.ThreadsPerPage+1 || *last.Replies != chanapi.ThreadsPerPage+1 {
+		t.Errorf("last thread = %+v", last)
+	}
+	if stubs := chanapi.Threads(nil); stubs == nil || len(stubs) != 0 {
+		t.Errorf("empty board = %#v, want no pages", stubs)
+	}
+}
+
+func TestParseImageURL(t *testing.T) {
+	for _, url := range []string{"", "https://example.com/1-a.png", "/images/posts/a.png", "/images/posts/x-a.png"} {
+		if _, _, ok := chanapi.ParseImageURL(url); ok {
+			t.Errorf("ParseImageURL(%q) parsed", url)
+		}
+	}
+}
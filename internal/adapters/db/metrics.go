@@ -0,0 +1,160 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"runtime"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"1337b04rd/pkg/metrics"
+	"1337b04rd/pkg/tracing"
+)
+
+var tracer = otel.Tracer("1337b04rd/internal/adapters/db")
+
+var (
+	queryDuration = metrics.NewHistogramVec("db_query_duration_seconds", "Database query latencies by the repository method that ran them.", nil, "method")
+	queryErrors   = metrics.NewCounterVec("db_query_errors_total", "Failed database queries by the repository method that ran them.", "method")
+)
+
+// timedConnector hands out connections that time and trace every query.
+// Doing so in the driver covers every repository without touching their
+// queries.
+type timedConnector struct {
+	driver.Connector
+}
+
+func (c timedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &timedConn{Conn: conn}, nil
+}
+
+// timedConn times and traces queries and passes everything else to the driver's
+// connection.
+type timedConn struct {
+	driver.Conn
+}
+
+func (c *timedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (rows driver.Rows, err error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	ctx, done := startQuery(ctx, query)
+	defer func() { done(err) }()
+	return queryer.QueryContext(ctx, query, args)
+}
+
+func (c *timedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (result driver.Result, err error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	ctx, done := startQuery(ctx, query)
+	defer func() { done(err) }()
+	return execer.ExecContext(ctx, query, args)
+}
+
+func (c *timedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return preparer.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+func (c *timedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *timedConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *timedConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *timedConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+func (c *timedConn) CheckNamedValue(v *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(v)
+	}
+	return driver.ErrSkip
+}
+
+// startQuery opens a span for a query, named after the repository method
+// running it, and returns the function that ends it and records its
+// latency.
+func startQuery(ctx context.Context, query string) (context.Context, func(error)) {
+	start := time.Now()
+	method := callerMethod()
+	ctx, span := tracer.Start(ctx, method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemPostgreSQL, semconv.DBQueryText(query)),
+	)
+	return ctx, func(err error) {
+		if errors.Is(err, driver.ErrSkip) {
+			span.End()
+			return
+		}
+		queryDuration.With(method).Observe(time.Since(start).Seconds())
+		if err != nil {
+			queryErrors.With(method).Inc()
+		}
+		tracing.End(span, err)
+	}
+}
+
+// callerMethod names the first function outside this package and the
+// standard library that is running the query, such as
+// "PostRepository.FindByID".
+func callerMethod() string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		frame, more := frames.Next()
+		if strings.HasPrefix(frame.Function, "1337b04rd/") && !strings.HasPrefix(frame.Function, "1337b04rd/internal/adapters/db.") {
+			return MethodName(frame.Function)
+		}
+		if !more {
+			return "other"
+		}
+	}
+}
+
+// MethodName shortens a qualified function name such as
+// "1337b04rd/internal/repository.(*PostRepository).FindByID" to
+// "PostRepository.FindByID". Closures are named after their function.
+func MethodName(function string) string {
+	name := function[strings.LastIndex(function, "/")+1:]
+	name = name[strings.Index(name, ".")+1:]
+	name = strings.NewReplacer("(*", "", ")", "").Replace(name)
+	if i := strings.Index(name, ".func"); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
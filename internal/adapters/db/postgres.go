@@ -2,30 +2,38 @@ package db
 
 import (
 	"database/sql"
-	"log"
+	"log/slog"
+	"os"
 	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
-func ConnectToDB() *sql.DB {
+// DSN is the connection string of the application database. Besides the
+// pool it is used by connections that LISTEN for notifications.
+const DSN = "postgres://postgres:postgres@db:5432/leetdb?sslmode=disable"
+
+func ConnectToDB(logger *slog.Logger) *sql.DB {
 	var db *sql.DB
 	var err error
 
 	for i := 0; i < 5; i++ {
-		db, err = sql.Open("postgres", "postgres://postgres:postgres@db:5432/leetdb?sslmode=disable")
+		var connector *pq.Connector
+		connector, err = pq.NewConnector(DSN)
 		if err == nil {
+			db = sql.OpenDB(timedConnector{connector})
 			err = db.Ping()
 			if err == nil {
 				break
 			}
 		}
-		log.Println("Waiting for database to be ready...Try: ", i)
+		logger.Warn("Waiting for database to be ready", "attempt", i, "error", err)
 		time.Sleep(2 * time.Second)
 	}
 
 	if err != nil {
-		log.Fatal("cannot connect to database:", err)
+		logger.Error("Cannot connect to database", "error", err)
+		os.Exit(1)
 	}
 
 	return db
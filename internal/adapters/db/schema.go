@@ -0,0 +1,24 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// SchemaVersion is the version of init.sql this build expects, recorded in
+// the schema_migrations table.
+const SchemaVersion = 4
+
+// CheckSchema fails unless the database is reachable and at SchemaVersion
+// or later.
+func CheckSchema(ctx context.Context, db *sql.DB) error {
+	var version sql.NullInt64
+	if err := db.QueryRowContext(ctx, `SELECT MAX(version) FROM schema_migrations`).Scan(&version); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if !version.Valid || version.Int64 < SchemaVersion {
+		return fmt.Errorf("schema version %d is behind %d", version.Int64, SchemaVersion)
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package events
+
+import (
+	"context"
+	"sync"
+
+	"1337b04rd/internal/domain"
+)
+
+const (
+	// DefaultHistorySize is the number of recent events kept for clients
+	// resuming with Last-Event-ID.
+	DefaultHistorySize = 256
+	// DefaultBufferSize is the number of undelivered events a subscriber may
+	// lag behind before it is dropped.
+	DefaultBufferSize = 32
+)
+
+// Bus is an in-process publish/subscribe hub for thread events. Used as a
+// ports.EventPublisher it numbers events itself; behind PostgresPublisher it
+// only fans out events numbered by the database, see Deliver.
+type Bus struct {
+	mu          sync.Mutex
+	seq         int64
+	history     []domain.Event
+	historySize int
+	bufferSize  int
+	subscribers map[*Subscription]struct{}
+}
+
+func NewBus(historySize, bufferSize int) *Bus {
+	return &Bus{
+		historySize: historySize,
+		bufferSize:  bufferSize,
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscription receives the events accepted by its filter on C. C is closed
+// when the subscription is closed or when the subscriber fell more than the
+// buffer size behind, in which case Dropped reports true.
+type Subscription struct {
+	C <-chan domain.Event
+
+	ch      chan domain.Event
+	filter  func(domain.Event) bool
+	bus     *Bus
+	dropped bool
+	closed  bool
+}
+
+// Publish assigns the next event ID and fans the event out to subscribers.
+// It never blocks on slow subscribers.
+func (b *Bus) Publish(ctx context.Context, event domain.Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	event.ID = b.seq
+	b.deliver(event)
+	return nil
+}
+
+// Deliver hands an event that already carries its ID to local subscribers.
+func (b *Bus) Deliver(event domain.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.deliver(event)
+}
+
+// deliver records event and hands it to every matching subscriber. The
+// caller must hold b.mu.
+func (b *Bus) deliver(event domain.Event) {
+	if event.ID > b.seq {
+		b.seq = event.ID
+	}
+
+	b.history = append(b.history, event)
+	if len(b.history) > b.historySize {
+		b.history = b.history[len(b.history)-b.historySize:]
+	}
+
+	for sub := range b.subscribers {
+		if !sub.filter(event) {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			sub.dropped = true
+			b.remove(sub)
+		}
+	}
+}
+
+// Subscribe registers filter and returns the retained events newer than
+// lastEventID that match it. Replay and registration happen atomically, so
+// no event is missed or delivered twice.
+func (b *Bus) Subscribe(filter func(domain.Event) bool, lastEventID int64) (*Subscription, []domain.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan domain.Event, b.bufferSize)
+	sub := &Subscription{C: ch, ch: ch, filter: filter, bus: b}
+	b.subscribers[sub] = struct{}{}
+
+	var replay []domain.Event
+	if lastEventID > 0 {
+		for _, event := range b.history {
+			if event.ID > lastEventID && filter(event) {
+				replay = append(replay, event)
+			}
+		}
+	}
+	return sub, replay
+}
+
+// remove unregisters sub and closes its channel. The caller must hold b.mu.
+func (b *Bus) remove(sub *Subscription) {
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	delete(b.subscribers, sub)
+	close(sub.ch)
+}
+
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	s.bus.remove(s)
+}
+
+func (s *Subscription) Dropped() bool {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	return s.dropped
+}
+
+// ForPost matches the events of a single thread: replies and the thread
+// going away.
+func ForPost(postID int) func(domain.Event) bool {
+	return func(e domain.Event) bool {
+		return e.PostID == postID && e.Type != domain.EventThread && e.Type != domain.EventBump
+	}
+}
+
+// ForBoard matches the catalog events of a board: new threads, bumps and
+// threads leaving it.
+func ForBoard(board string) func(domain.Event) bool {
+	return func(e domain.Event) bool {
+		return e.Board == board && e.IsCatalogEvent()
+	}
+}
@@ -0,0 +1,74 @@
+package events_test
+
+import (
+	"context"
+	"testing"
+
+	"1337b04rd/internal/adapters/events"
+	"1337b04rd/internal/domain"
+)
+
+func publish(t *testing.T, bus *events.Bus, postID int) {
+	t.Helper()
+	if err := bus.Publish(context.Background(), domain.NewEvent(domain.EventReply, postID, "b", nil)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBusDeliversMatchingEvents(t *testing.T) {
+	bus := events.NewBus(events.DefaultHistorySize, events.DefaultBufferSize)
+	sub, _ := bus.Subscribe(events.ForPost(1), 0)
+	defer sub.Close()
+
+	publish(t, bus, 2)
+	publish(t, bus, 1)
+
+	event := <-sub.C
+	if event.PostID != 1 || event.ID != 2 {
+		t.Fatalf("got event %d of post %d, want event 2 of post 1", event.ID, event.PostID)
+	}
+	select {
+	case event := <-sub.C:
+		t.Fatalf("unexpected event %+v", event)
+	default:
+	}
+}
+
+func TestBusReplaysAfterLastEventID(t *testing.T) {
+	bus := events.NewBus(3, events.DefaultBufferSize)
+	for i := 0; i < 5; i++ {
+		publish(t, bus, 1)
+	}
+
+	sub, replay := bus.Subscribe(events.ForPost(1), 3)
+	defer sub.Close()
+	if len(replay) != 2 || replay[0].ID != 4 || replay[1].ID != 5 {
+		t.Fatalf("replay = %+v, want events 4 and 5", replay)
+	}
+
+	_, replay = bus.Subscribe(events.ForPost(1), 0)
+	if len(replay) != 0 {
+		t.Fatalf("fresh subscriber replayed %d events", len(replay))
+	}
+}
+
+func TestBusDropsSlowSubscriber(t *testing.T) {
+	bus := events.NewBus(events.DefaultHistorySize, 2)
+	sub, _ := bus.Subscribe(events.ForPost(1), 0)
+
+	for i := 0; i < 3; i++ {
+		publish(t, bus, 1)
+	}
+
+	received := 0
+	for range sub.C {
+		received++
+	}
+	if received != 2 {
+		t.Fatalf("received %d buffered events, want 2", received)
+	}
+	if !sub.Dropped() {
+		t.Fatal("slow subscriber was not marked as dropped")
+	}
+	sub.Close()
+}
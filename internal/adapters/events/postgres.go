@@ -0,0 +1,115 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/lib/pq"
+
+	"1337b04rd/internal/domain"
+	"1337b04rd/internal/ports"
+)
+
+// NotifyChannel is the Postgres channel events travel on between replicas.
+const NotifyChannel = "thread_events"
+
+// notification is the NOTIFY payload. Payloads are capped at 8000 bytes, so
+// posts and comments travel by ID and every replica loads them on receipt.
+type notification struct {
+	domain.Event
+	CommentID int `json:"comment_id,omitempty"`
+}
+
+// PostgresPublisher fans events out to every replica through LISTEN/NOTIFY.
+// Event IDs come from a database sequence, so Last-Event-ID stays valid when
+// a client reconnects to another replica.
+type PostgresPublisher struct {
+	db       *sql.DB
+	listener *pq.Listener
+	bus      *Bus
+	posts    ports.PostRepository
+	comments ports.CommentRepository
+	logger   *slog.Logger
+}
+
+func NewPostgresPublisher(db *sql.DB, dsn string, bus *Bus, posts ports.PostRepository, comments ports.CommentRepository, logger *slog.Logger) (*PostgresPublisher, error) {
+	listener := pq.NewListener(dsn, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			logger.Error("Event listener connection problem", "error", err)
+		}
+	})
+	if err := listener.Listen(NotifyChannel); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("unable to listen on %s: %w", NotifyChannel, err)
+	}
+
+	p := &PostgresPublisher{db: db, listener: listener, bus: bus, posts: posts, comments: comments, logger: logger}
+	go p.run()
+	return p, nil
+}
+
+func (p *PostgresPublisher) Publish(ctx context.Context, event domain.Event) error {
+	n := notification{Event: event}
+	if event.Comment != nil {
+		n.CommentID = event.Comment.ID
+	}
+	n.Comment = nil
+	n.Post = nil
+
+	payload, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("unable to encode event: %w", err)
+	}
+
+	query := `SELECT pg_notify($1, jsonb_set($2::jsonb, '{id}', to_jsonb(nextval('event_id_seq')))::text)`
+	if _, err := p.db.ExecContext(ctx, query, NotifyChannel, string(payload)); err != nil {
+		return fmt.Errorf("unable to publish event: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresPublisher) Close() error {
+	return p.listener.Close()
+}
+
+func (p *PostgresPublisher) run() {
+	for n := range p.listener.Notify {
+		// A nil notification means the connection was re-established and
+		// anything sent meanwhile is lost; clients recover on reconnect.
+		if n == nil {
+			continue
+		}
+
+		var msg notification
+		if err := json.Unmarshal([]byte(n.Extra), &msg); err != nil {
+			p.logger.Error("Invalid event notification", "error", err)
+			continue
+		}
+		p.bus.Deliver(p.hydrate(msg))
+	}
+}
+
+// hydrate loads the post or comment an event refers to. An event whose
+// subject is already gone is still delivered, just without the body.
+func (p *PostgresPublisher) hydrate(msg notification) domain.Event {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	event := msg.Event
+	switch {
+	case msg.CommentID != 0:
+		if comment, err := p.comments.FindByID(ctx, msg.CommentID); err == nil {
+			event.Comment = comment
+		}
+	case event.Type == domain.EventThread:
+		if post, err := p.posts.FindByID(ctx, event.PostID); err == nil {
+			post.Comments = nil
+			event.Post = post
+		}
+	}
+	return event
+}
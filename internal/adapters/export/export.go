@@ -0,0 +1,333 @@
+// Package export renders the archive as a static site, so it can be
+// published on static hosting apart from the board and its database.
+//
+// Every archived thread becomes a page or JSON document next to the images
+// it shows and an index of the whole archive. Runs are incremental: only
+// threads archived since the last run into the same directory are rendered
+// again, while the index is rebuilt every time.
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"1337b04rd/internal/domain"
+)
+
+// Formats the archive can be exported in.
+const (
+	// FormatHTML writes browsable pages with their stylesheet.
+	FormatHTML = "html"
+	// FormatJSON writes a document per thread and one for the index.
+	FormatJSON = "json"
+	// FormatWARC writes the pages of FormatHTML as records of a new WARC
+	// file per run, for web archives.
+	FormatWARC = "warc"
+)
+
+// stateFile keeps, per format, when the newest exported thread was
+// archived.
+const stateFile = ".export-state.json"
+
+// Source lists archived threads and loads them with every reply.
+type Source interface {
+	FindAll(ctx context.Context) ([]*domain.Archive, error)
+	FindByID(ctx context.Context, id int) (*domain.Post, error)
+}
+
+// Images loads an uploaded image from its bucket.
+type Images interface {
+	GetImage(ctx context.Context, bucket, objectName string) ([]byte, string, error)
+}
+
+// Options says what to export where.
+type Options struct {
+	Format string
+	// Out is the directory the export is written to and kept in between
+	// runs.
+	Out string
+	// Full exports every archived thread, not only those archived since the
+	// last run.
+	Full bool
+	// BaseURL is where the export is published. WARC records are filed
+	// under it.
+	BaseURL string
+	// Templates holds the index.html and thread.html pages are rendered
+	// with, and Static the stylesheet and fonts they link to.
+	Templates string
+	Static    string
+}
+
+// Result tells what a run exported.
+type Result struct {
+	Threads int
+	Images  int
+	// Since is when the newest thread of the previous run was archived,
+	// zero for a full export.
+	Since time.Time
+}
+
+// Exporter exports the archive.
+type Exporter struct {
+	source Source
+	images Images
+	opts   Options
+	pages  *template.Template
+	logger *slog.Logger
+}
+
+// New prepares an export, failing on an unknown format or broken templates.
+func New(source Source, images Images, opts Options, logger *slog.Logger) (*Exporter, error) {
+	switch opts.Format {
+	case FormatHTML, FormatJSON, FormatWARC:
+	default:
+		return nil, fmt.Errorf("format %q is not one of html, json or warc", opts.Format)
+	}
+	if opts.Out == "" {
+		return nil, errors.New("no output directory")
+	}
+	opts.BaseURL = strings.TrimSuffix(opts.BaseURL, "/")
+
+	pages, err := template.New("export").Funcs(template.FuncMap{"image": imageLink}).ParseFiles(
+		filepath.Join(opts.Templates, "index.html"),
+		filepath.Join(opts.Templates, "thread.html"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse export templates: %w", err)
+	}
+	return &Exporter{source: source, images: images, opts: opts, pages: pages, logger: logger}, nil
+}
+
+// Export writes the threads archived since the last run, their images and
+// a fresh index. The next run only starts after the last thread written,
+// so a failed run is simply repeated.
+func (e *Exporter) Export(ctx context.Context) (Result, error) {
+	state, err := loadState(e.opts.Out)
+	if err != nil {
+		return Result{}, err
+	}
+	var result Result
+	if !e.opts.Full {
+		result.Since = state[e.opts.Format]
+	}
+
+	threads, err := e.source.FindAll(ctx)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to list archived threads: %w", err)
+	}
+	// Threads come most recently archived first; export the oldest first so
+	// an interrupted run never skips any
+	var fresh []*domain.Archive
+	for i := len(threads) - 1; i >= 0; i-- {
+		if threads[i].ArchivedAt.After(result.Since) {
+			fresh = append(fresh, threads[i])
+		}
+	}
+
+	w, err := e.open()
+	if err != nil {
+		return Result{}, err
+	}
+	newest := state[e.opts.Format]
+	if e.opts.Full {
+		newest = time.Time{}
+	}
+	written := make(map[string]bool)
+	for _, thread := range fresh {
+		post, err := e.source.FindByID(ctx, thread.ID)
+		if err != nil {
+			w.close()
+			return result, fmt.Errorf("failed to load archived thread %d: %w", thread.ID, err)
+		}
+		n, err := e.exportImages(ctx, w, post, written)
+		result.Images += n
+		if err == nil {
+			err = w.thread(thread, post)
+		}
+		if err != nil {
+			w.close()
+			return result, fmt.Errorf("failed to export archived thread %d: %w", thread.ID, err)
+		}
+		result.Threads++
+		if thread.ArchivedAt.After(newest) {
+			newest = thread.ArchivedAt
+		}
+	}
+
+	if err := w.index(threads); err != nil {
+		w.close()
+		return result, fmt.Errorf("failed to export archive index: %w", err)
+	}
+	if err := w.close(); err != nil {
+		return result, err
+	}
+	state[e.opts.Format] = newest
+	return result, saveState(e.opts.Out, state)
+}
+
+// exportImages writes the images of a thread that are not exported yet. A
+// missing image is reported and left out rather than failing the thread.
+func (e *Exporter) exportImages(ctx context.Context, w writer, post *domain.Post, written map[string]bool) (int, error) {
+	urls := []string{post.ImageURL}
+	for _, c := range post.Comments {
+		urls = append(urls, c.ImageURL)
+	}
+
+	n := 0
+	for _, url := range urls {
+		file, bucket, object, ok := localImage(url)
+		if !ok || written[file] || w.has(file) {
+			continue
+		}
+		written[file] = true
+		data, contentType, err := e.images.GetImage(ctx, bucket, object)
+		if err != nil {
+			e.logger.Warn("Leaving out missing image", "post_id", post.ID, "image_url", url, "error", err)
+			continue
+		}
+		if err := w.image(file, contentType, data); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// writer writes one format.
+type writer interface {
+	// has tells whether an earlier run already wrote the file.
+	has(file string) bool
+	image(file, contentType string, data []byte) error
+	thread(thread *domain.Archive, post *domain.Post) error
+	index(threads []*domain.Archive) error
+	close() error
+}
+
+func (e *Exporter) open() (writer, error) {
+	if err := os.MkdirAll(e.opts.Out, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	switch e.opts.Format {
+	case FormatJSON:
+		return &jsonWriter{out: e.opts.Out}, nil
+	case FormatWARC:
+		return openWARC(e)
+	}
+	err := walkStatic(e.opts.Static, func(file string, data []byte) error {
+		return writeFile(e.opts.Out, file, data)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &htmlWriter{e: e}, nil
+}
+
+// threadFile is where a thread is exported, relative to the output
+// directory.
+func threadFile(id int, ext string) string {
+	return fmt.Sprintf("threads/%d.%s", id, ext)
+}
+
+// localImage maps the URL of an upload, such as /images/posts/1-cat.png,
+// to the file it is exported to and the object it is stored as. URLs of
+// anything else, and object names that would leave their directory, are
+// not exported.
+func localImage(url string) (file, bucket, object string, ok bool) {
+	rest, found := strings.CutPrefix(url, "/images/")
+	if !found {
+		return "", "", "", false
+	}
+	bucket, object, found = strings.Cut(rest, "/")
+	if !found || (bucket != "posts" && bucket != "comments") || object == "" || object == ".." || strings.ContainsAny(object, `/\`) {
+		return "", "", "", false
+	}
+	return path.Join("images", bucket, object), bucket, object, true
+}
+
+// imageLink points a page root directories below the output directory at
+// an exported image. Other URLs are kept as they are.
+func imageLink(root, url string) string {
+	if file, _, _, ok := localImage(url); ok {
+		return root + file
+	}
+	return url
+}
+
+// writeFile writes a file of the export, creating its directory.
+func writeFile(out, file string, data []byte) error {
+	name := filepath.Join(out, filepath.FromSlash(file))
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", file, err)
+	}
+	if err := os.WriteFile(name, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", file, err)
+	}
+	return nil
+}
+
+func exists(out, file string) bool {
+	_, err := os.Stat(filepath.Join(out, filepath.FromSlash(file)))
+	return err == nil
+}
+
+// staticFiles are what exported pages need of the board's static files.
+var staticFiles = []string{"style/catalog.css", "fonts"}
+
+// walkStatic hands the stylesheet and fonts of the board to fn, named as
+// pages link to them. Pages still work without them, so a board without
+// static files is no error.
+func walkStatic(static string, fn func(file string, data []byte) error) error {
+	if static == "" {
+		return nil
+	}
+	root := os.DirFS(static)
+	for _, name := range staticFiles {
+		err := fs.WalkDir(root, name, func(file string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			data, err := fs.ReadFile(root, file)
+			if err != nil {
+				return err
+			}
+			return fn(path.Join("static", file), data)
+		})
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to copy static files: %w", err)
+		}
+	}
+	return nil
+}
+
+func loadState(out string) (map[string]time.Time, error) {
+	state := make(map[string]time.Time)
+	data, err := os.ReadFile(filepath.Join(out, stateFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	}
+	if err == nil {
+		err = json.Unmarshal(data, &state)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read export state: %w", err)
+	}
+	return state, nil
+}
+
+func saveState(out string, state map[string]time.Time) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode export state: %w", err)
+	}
+	return writeFile(out, stateFile, append(data, '\n'))
+}
@@ -0,0 +1,178 @@
+package export_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"1337b04rd/internal/adapters/export"
+	"1337b04rd/internal/domain"
+)
+
+const templates = "../../frontend/templates/export"
+
+// archive holds archived threads in memory.
+type archive struct {
+	threads  []*domain.Post
+	archived map[int]time.Time
+}
+
+func (a *archive) add(post *domain.Post, at time.Time) {
+	a.threads = append([]*domain.Post{post}, a.threads...)
+	a.archived[post.ID] = at
+}
+
+func (a *archive) FindAll(ctx context.Context) ([]*domain.Archive, error) {
+	var list []*domain.Archive
+	for _, p := range a.threads {
+		list = append(list, &domain.Archive{ID: p.ID, Title: p.Title, Content: p.Content, ImageURL: p.ImageURL, Board: p.Board, CreatedAt: p.CreatedAt, ArchivedAt: a.archived[p.ID], ReplyCount: len(p.Comments)})
+	}
+	return list, nil
+}
+
+func (a *archive) FindByID(ctx context.Context, id int) (*domain.Post, error) {
+	for _, p := range a.threads {
+		if p.ID == id {
+			return p, nil
+		}
+	}
+	return nil, domain.ErrPostNotFound
+}
+
+// images serves every object but missing.png, counting downloads.
+type images struct {
+	fetched []string
+}
+
+func (s *images) GetImage(ctx context.Context, bucket, object string) ([]byte, string, error) {
+	if object == "missing.png" {
+		return nil, "", errors.New("no such object")
+	}
+	s.fetched = append(s.fetched, bucket+"/"+object)
+	return []byte("image " + object), "image/png", nil
+}
+
+func newArchive() *archive {
+	a := &archive{archived: make(map[int]time.Time)}
+	created := time.Date(2025, 5, 3, 21, 35, 0, 0, time.UTC)
+	a.add(&domain.Post{ID: 1, Title: "Portal gun", Content: "<script>alert(1)</script>", Board: "b", ImageURL: "/images/posts/1-gun.png", CreatedAt: created, Comments: []domain.Comment{
+		{ID: 10, PostID: 1, Content: "nice", AuthorName: "Morty", ImageURL: "/images/comments/2-morty.png", CreatedAt: created},
+		{ID: 11, PostID: 1, Content: "gone", ImageURL: "/images/comments/missing.png", CreatedAt: created},
+		{ID: 12, PostID: 1, Content: "sneaky", ImageURL: "/images/comments/..", CreatedAt: created},
+	}}, created.Add(time.Hour))
+	return a
+}
+
+func TestExportHTMLIsIncremental(t *testing.T) {
+	out := t.TempDir()
+	a, store := newArchive(), &images{}
+	exporter, err := export.New(a, store, export.Options{Format: export.FormatHTML, Out: out, Templates: templates}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := exporter.Export(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Threads != 1 || result.Images != 2 {
+		t.Errorf("first run = %+v, want 1 thread and 2 images", result)
+	}
+	page := read(t, out, "threads/1.html")
+	if strings.Contains(page, "<script>") || !strings.Contains(page, `src="../images/posts/1-gun.png"`) || !strings.Contains(page, `href="../static/style/catalog.css"`) {
+		t.Errorf("thread page:\n%s", page)
+	}
+	if got := read(t, out, "images/comments/2-morty.png"); got != "image 2-morty.png" {
+		t.Errorf("image = %q", got)
+	}
+
+	// Only the thread archived since is exported on the next run
+	a.add(&domain.Post{ID: 2, Title: "Pickle", Board: "b", ImageURL: "/images/posts/1-gun.png"}, time.Date(2025, 5, 4, 0, 0, 0, 0, time.UTC))
+	result, err = exporter.Export(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Threads != 1 || result.Images != 0 || len(store.fetched) != 2 {
+		t.Errorf("second run = %+v after fetching %v, want only the new thread", result, store.fetched)
+	}
+	index := read(t, out, "index.html")
+	if !strings.Contains(index, `href="threads/1.html"`) || !strings.Contains(index, `href="threads/2.html"`) {
+		t.Errorf("index does not link both threads:\n%s", index)
+	}
+}
+
+func TestExportJSON(t *testing.T) {
+	out := t.TempDir()
+	exporter, err := export.New(newArchive(), &images{}, export.Options{Format: export.FormatJSON, Out: out, Templates: templates}, slog.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := exporter.Export(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var thread struct {
+		ImageURL   string           `json:"image_url"`
+		ArchivedAt time.Time        `json:"archived_at"`
+		Comments   []domain.Comment `json:"comments"`
+	}
+	if err := json.Unmarshal([]byte(read(t, out, "threads/1.json")), &thread); err != nil {
+		t.Fatal(err)
+	}
+	if thread.ImageURL != "images/posts/1-gun.png" || thread.Comments[0].ImageURL != "images/comments/2-morty.png" || thread.ArchivedAt.IsZero() {
+		t.Errorf("thread = %+v", thread)
+	}
+
+	var index []struct{ Path string }
+	if err := json.Unmarshal([]byte(read(t, out, "index.json")), &index); err != nil || len(index) != 1 || index[0].Path != "threads/1.json" {
+		t.Errorf("index = %+v, %v", index, err)
+	}
+}
+
+func TestExportWARC(t *testing.T) {
+	out := t.TempDir()
+	exporter, err := export.New(newArchive(), &images{}, export.Options{Format: export.FormatWARC, Out: out, BaseURL: "https://archive.example/", Templates: templates}, slog.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := exporter.Export(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(out, "archive-*.warc"))
+	if len(files) != 1 {
+		t.Fatalf("got WARC files %v", files)
+	}
+	data, _ := os.ReadFile(files[0])
+	if !bytes.HasPrefix(data, []byte("WARC/1.1\r\nWARC-Type: warcinfo\r\n")) {
+		t.Errorf("WARC file starts with %q", data[:40])
+	}
+	for _, uri := range []string{"https://archive.example/threads/1.html", "https://archive.example/index.html", "https://archive.example/images/posts/1-gun.png"} {
+		if !bytes.Contains(data, []byte("WARC-Target-URI: "+uri+"\r\n")) {
+			t.Errorf("no record of %s", uri)
+		}
+	}
+}
+
+func TestNewRejectsUnknownFormat(t *testing.T) {
+	if _, err := export.New(newArchive(), &images{}, export.Options{Format: "pdf", Out: t.TempDir(), Templates: templates}, slog.Default()); err == nil {
+		t.Error("pdf export was accepted")
+	}
+}
+
+func read(t *testing.T, out, file string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(out, file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
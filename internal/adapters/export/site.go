@@ -0,0 +1,139 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"1337b04rd/internal/domain"
+)
+
+// renderThread renders the page of a thread, which lives one directory
+// below the index.
+func (e *Exporter) renderThread(thread *domain.Archive, post *domain.Post) ([]byte, error) {
+	return e.render("thread.html", map[string]interface{}{
+		"Root":     "../",
+		"Title":    post.Title,
+		"Post":     post,
+		"Comments": post.Comments,
+		"Archived": thread.ArchivedAt,
+	})
+}
+
+// renderIndex renders the index of every archived thread.
+func (e *Exporter) renderIndex(threads []*domain.Archive) ([]byte, error) {
+	return e.render("index.html", map[string]interface{}{
+		"Root":    "",
+		"Title":   "1337b04rd archive",
+		"Threads": threads,
+	})
+}
+
+func (e *Exporter) render(name string, data map[string]interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := e.pages.ExecuteTemplate(&buf, name, data); err != nil {
+		return nil, fmt.Errorf("failed to render %s: %w", name, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// htmlWriter writes a browsable site: index.html, threads/{id}.html and
+// the images and static files they link to.
+type htmlWriter struct {
+	e *Exporter
+}
+
+func (w *htmlWriter) has(file string) bool { return exists(w.e.opts.Out, file) }
+
+func (w *htmlWriter) image(file, contentType string, data []byte) error {
+	return writeFile(w.e.opts.Out, file, data)
+}
+
+func (w *htmlWriter) thread(thread *domain.Archive, post *domain.Post) error {
+	page, err := w.e.renderThread(thread, post)
+	if err != nil {
+		return err
+	}
+	return writeFile(w.e.opts.Out, threadFile(thread.ID, "html"), page)
+}
+
+func (w *htmlWriter) index(threads []*domain.Archive) error {
+	page, err := w.e.renderIndex(threads)
+	if err != nil {
+		return err
+	}
+	return writeFile(w.e.opts.Out, "index.html", page)
+}
+
+func (w *htmlWriter) close() error { return nil }
+
+// jsonThread is threads/{id}.json, a thread as the API returns it with its
+// image URLs pointing into the export.
+type jsonThread struct {
+	*domain.Post
+	ArchivedAt time.Time `json:"archived_at"`
+}
+
+// jsonIndexEntry is a thread in index.json. Path is its document, relative
+// to the index.
+type jsonIndexEntry struct {
+	ID         int       `json:"id"`
+	Title      string    `json:"title"`
+	AuthorName string    `json:"author_name"`
+	Board      string    `json:"board"`
+	ReplyCount int       `json:"reply_count"`
+	CreatedAt  time.Time `json:"created_at"`
+	ArchivedAt time.Time `json:"archived_at"`
+	Path       string    `json:"path"`
+}
+
+// jsonWriter writes index.json, threads/{id}.json and the images they
+// point at.
+type jsonWriter struct {
+	out string
+}
+
+func (w *jsonWriter) has(file string) bool { return exists(w.out, file) }
+
+func (w *jsonWriter) image(file, contentType string, data []byte) error {
+	return writeFile(w.out, file, data)
+}
+
+func (w *jsonWriter) thread(thread *domain.Archive, post *domain.Post) error {
+	relinked := *post
+	relinked.ImageURL = imageLink("", post.ImageURL)
+	relinked.Comments = make([]domain.Comment, len(post.Comments))
+	for i, c := range post.Comments {
+		c.ImageURL = imageLink("", c.ImageURL)
+		relinked.Comments[i] = c
+	}
+	return w.write(threadFile(thread.ID, "json"), jsonThread{Post: &relinked, ArchivedAt: thread.ArchivedAt})
+}
+
+func (w *jsonWriter) index(threads []*domain.Archive) error {
+	entries := make([]jsonIndexEntry, 0, len(threads))
+	for _, t := range threads {
+		entries = append(entries, jsonIndexEntry{
+			ID:         t.ID,
+			Title:      t.Title,
+			AuthorName: t.AuthorName,
+			Board:      t.Board,
+			ReplyCount: t.ReplyCount,
+			CreatedAt:  t.CreatedAt,
+			ArchivedAt: t.ArchivedAt,
+			Path:       threadFile(t.ID, "json"),
+		})
+	}
+	return w.write("index.json", entries)
+}
+
+func (w *jsonWriter) write(file string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", file, err)
+	}
+	return writeFile(w.out, file, append(data, '\n'))
+}
+
+func (w *jsonWriter) close() error { return nil }
@@ -0,0 +1,140 @@
+package export
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"fmt"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"1337b04rd/internal/domain"
+)
+
+// warcWriter writes the pages of the HTML export, with their images and
+// static files, as response records of a new WARC 1.1 file. Every run
+// gets a file of its own, named after when it started, so earlier ones are
+// never rewritten.
+type warcWriter struct {
+	e    *Exporter
+	file *os.File
+	buf  *bufio.Writer
+	now  time.Time
+}
+
+func openWARC(e *Exporter) (*warcWriter, error) {
+	now := time.Now().UTC()
+	name := filepath.Join(e.opts.Out, "archive-"+now.Format("20060102T150405Z")+".warc")
+	file, err := os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create WARC file: %w", err)
+	}
+	w := &warcWriter{e: e, file: file, buf: bufio.NewWriter(file), now: now}
+
+	info := fmt.Sprintf("software: 1337b04rd\r\nformat: WARC File Format 1.1\r\nisPartOf: %s\r\n", e.opts.BaseURL+"/")
+	err = w.record("warcinfo", "", "application/warc-fields", []byte(info), "")
+	if err == nil {
+		err = walkStatic(e.opts.Static, func(file string, data []byte) error {
+			return w.response(file, contentTypeOf(file), data)
+		})
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// has is always false: images of earlier runs are in earlier files, and
+// every thread is only exported once.
+func (w *warcWriter) has(file string) bool { return false }
+
+func (w *warcWriter) image(file, contentType string, data []byte) error {
+	return w.response(file, contentType, data)
+}
+
+func (w *warcWriter) thread(thread *domain.Archive, post *domain.Post) error {
+	page, err := w.e.renderThread(thread, post)
+	if err != nil {
+		return err
+	}
+	return w.response(threadFile(thread.ID, "html"), "text/html; charset=utf-8", page)
+}
+
+func (w *warcWriter) index(threads []*domain.Archive) error {
+	page, err := w.e.renderIndex(threads)
+	if err != nil {
+		return err
+	}
+	return w.response("index.html", "text/html; charset=utf-8", page)
+}
+
+func (w *warcWriter) close() error {
+	err := w.buf.Flush()
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write WARC file: %w", err)
+	}
+	return nil
+}
+
+// response records body as the response to a GET of file under the base
+// URL.
+func (w *warcWriter) response(file, contentType string, body []byte) error {
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	block := fmt.Appendf(nil, "HTTP/1.1 200 OK\r\nContent-Type: %s\r\nContent-Length: %d\r\n\r\n", contentType, len(body))
+	block = append(block, body...)
+	return w.record("response", w.e.opts.BaseURL+"/"+file, "application/http; msgtype=response", block, digest(body))
+}
+
+// record writes a WARC record. payloadDigest is left out when empty.
+func (w *warcWriter) record(kind, targetURI, contentType string, block []byte, payloadDigest string) error {
+	id, err := recordID()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w.buf, "WARC/1.1\r\nWARC-Type: %s\r\nWARC-Record-ID: <urn:uuid:%s>\r\nWARC-Date: %s\r\n", kind, id, w.now.Format(time.RFC3339))
+	if targetURI != "" {
+		fmt.Fprintf(w.buf, "WARC-Target-URI: %s\r\n", targetURI)
+	}
+	if payloadDigest != "" {
+		fmt.Fprintf(w.buf, "WARC-Payload-Digest: %s\r\n", payloadDigest)
+	}
+	fmt.Fprintf(w.buf, "WARC-Block-Digest: %s\r\nContent-Type: %s\r\nContent-Length: %d\r\n\r\n", digest(block), contentType, len(block))
+	w.buf.Write(block)
+	_, err = w.buf.WriteString("\r\n\r\n")
+	return err
+}
+
+// digest is the SHA-1 digest WARC tools expect, in base 32.
+func digest(data []byte) string {
+	sum := sha1.Sum(data)
+	return "sha1:" + base32.StdEncoding.EncodeToString(sum[:])
+}
+
+// recordID makes a random UUID for a record.
+func recordID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("failed to generate WARC record ID: %w", err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// contentTypeOf guesses the type of a static file from its extension.
+func contentTypeOf(file string) string {
+	if t := mime.TypeByExtension(path.Ext(file)); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
@@ -1,15 +1,25 @@
 package externalapi
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"math/rand"
 	"net/http"
+	"net/url"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 
 	"1337b04rd/internal/domain"
+	"1337b04rd/pkg/tracing"
 )
 
+var tracer = otel.Tracer("1337b04rd/internal/adapters/externalapi")
+
 type RickAndMortyClient struct {
 	baseURL string
 }
@@ -28,12 +38,19 @@ func NewRickAndMortyClient() *RickAndMortyClient {
 }
 
 // FetchAllCharacters fetches all characters from the Rick and Morty API
-func (c *RickAndMortyClient) FetchAllCharacters() ([]domain.Character, error) {
+func (c *RickAndMortyClient) FetchAllCharacters(ctx context.Context) (_ []domain.Character, err error) {
+	ctx, span := c.startSpan(ctx, "RickAndMortyClient.FetchAllCharacters")
+	defer func() { tracing.End(span, err) }()
+
 	var allCharacters []domain.Character
 	nextURL := fmt.Sprintf("%s/character", c.baseURL)
 
 	for nextURL != "" {
-		resp, err := http.Get(nextURL)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, nextURL, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch characters: %w", err)
+		}
+		resp, err := http.DefaultClient.Do(req)
 		if err != nil {
 			return nil, fmt.Errorf("failed to fetch characters: %w", err)
 		}
@@ -52,13 +69,21 @@ func (c *RickAndMortyClient) FetchAllCharacters() ([]domain.Character, error) {
 		allCharacters = append(allCharacters, apiResp.Results...)
 		nextURL = apiResp.Info.Next
 	}
+	span.SetAttributes(attribute.Int("characters", len(allCharacters)))
 
 	return allCharacters, nil
 }
 
-func (c *RickAndMortyClient) FetchRandomCharacter() (*domain.Character, error) {
+func (c *RickAndMortyClient) FetchRandomCharacter(ctx context.Context) (_ *domain.Character, err error) {
+	ctx, span := c.startSpan(ctx, "RickAndMortyClient.FetchRandomCharacter")
+	defer func() { tracing.End(span, err) }()
+
 	randomID := rand.Intn(826) + 1
-	resp, err := http.Get(fmt.Sprintf("%s%d", c.baseURL, randomID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/character/%d", c.baseURL, randomID), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch character: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch character: %w", err)
 	}
@@ -71,3 +96,32 @@ func (c *RickAndMortyClient) FetchRandomCharacter() (*domain.Character, error) {
 
 	return &char, nil
 }
+
+// Ping fails unless the API answers its index.
+func (c *RickAndMortyClient) Ping(ctx context.Context) (err error) {
+	ctx, span := c.startSpan(ctx, "RickAndMortyClient.Ping")
+	defer func() { tracing.End(span, err) }()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach avatar API: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("avatar API answered %s", resp.Status)
+	}
+	return nil
+}
+
+// startSpan opens a client span for a call to the API.
+func (c *RickAndMortyClient) startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	var host string
+	if u, err := url.Parse(c.baseURL); err == nil {
+		host = u.Hostname()
+	}
+	return tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(semconv.ServerAddress(host)))
+}
@@ -0,0 +1,205 @@
+// Package feed writes syndication feeds of threads in Atom and RSS 2.0.
+package feed
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+// Feed is a feed in neither format; WriteAtom and WriteRSS encode it.
+type Feed struct {
+	// ID never changes for the same feed, wherever the board is hosted.
+	ID       string
+	Title    string
+	Subtitle string
+	// Link is the page the feed follows and SelfLink the feed itself, both
+	// absolute.
+	Link     string
+	SelfLink string
+	Updated  time.Time
+	Entries  []Entry
+}
+
+// Entry is one post in a feed.
+type Entry struct {
+	// ID is the GUID of the post, the same in every feed it appears in.
+	ID     string
+	Title  string
+	Link   string
+	Author string
+	// Content is HTML, escaped when written.
+	Content   string
+	Published time.Time
+	Updated   time.Time
+	Enclosure *Enclosure
+}
+
+// Enclosure is an image attached to an entry. Length is zero when unknown.
+type Enclosure struct {
+	URL    string
+	Type   string
+	Length int64
+}
+
+// Media types of the two formats.
+const (
+	AtomContentType = "application/atom+xml; charset=utf-8"
+	RSSContentType  = "application/rss+xml; charset=utf-8"
+)
+
+type atomFeed struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID       string      `xml:"id"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	Updated  string      `xml:"updated"`
+	Links    []atomLink  `xml:"link"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel    string `xml:"rel,attr,omitempty"`
+	Type   string `xml:"type,attr,omitempty"`
+	Href   string `xml:"href,attr"`
+	Length int64  `xml:"length,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID        string     `xml:"id"`
+	Title     string     `xml:"title"`
+	Updated   string     `xml:"updated"`
+	Published string     `xml:"published"`
+	Author    atomPerson `xml:"author"`
+	Links     []atomLink `xml:"link"`
+	Content   atomText   `xml:"content"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// WriteAtom writes f as an Atom 1.0 document.
+func WriteAtom(w io.Writer, f *Feed) error {
+	doc := atomFeed{
+		ID:       f.ID,
+		Title:    f.Title,
+		Subtitle: f.Subtitle,
+		Updated:  f.Updated.UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Rel: "alternate", Type: "text/html", Href: f.Link},
+			{Rel: "self", Type: "application/atom+xml", Href: f.SelfLink},
+		},
+	}
+	for _, e := range f.Entries {
+		entry := atomEntry{
+			ID:        e.ID,
+			Title:     e.Title,
+			Updated:   e.Updated.UTC().Format(time.RFC3339),
+			Published: e.Published.UTC().Format(time.RFC3339),
+			Author:    atomPerson{Name: e.Author},
+			Links:     []atomLink{{Rel: "alternate", Type: "text/html", Href: e.Link}},
+			Content:   atomText{Type: "html", Body: e.Content},
+		}
+		if e.Enclosure != nil {
+			entry.Links = append(entry.Links, atomLink{Rel: "enclosure", Type: e.Enclosure.Type, Href: e.Enclosure.URL, Length: e.Enclosure.Length})
+		}
+		doc.Entries = append(doc.Entries, entry)
+	}
+	return encode(w, doc)
+}
+
+type rssDocument struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr"`
+	DCNS    string     `xml:"xmlns:dc,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Self          rssSelf   `xml:"atom:link"`
+	Items         []rssItem `xml:"item"`
+}
+
+// rssSelf is the atom:link RSS feeds use to point at themselves.
+type rssSelf struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssItem struct {
+	Title       string        `xml:"title"`
+	Link        string        `xml:"link"`
+	Description string        `xml:"description"`
+	Creator     string        `xml:"dc:creator"`
+	GUID        rssGUID       `xml:"guid"`
+	PubDate     string        `xml:"pubDate"`
+	Enclosure   *rssEnclosure `xml:"enclosure"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Length int64  `xml:"length,attr"`
+}
+
+// WriteRSS writes f as an RSS 2.0 document. RSS has no updated date per
+// item, so items carry when they were published.
+func WriteRSS(w io.Writer, f *Feed) error {
+	doc := rssDocument{
+		Version: "2.0",
+		AtomNS:  "http://www.w3.org/2005/Atom",
+		DCNS:    "http://purl.org/dc/elements/1.1/",
+		Channel: rssChannel{
+			Title:         f.Title,
+			Link:          f.Link,
+			Description:   f.Subtitle,
+			LastBuildDate: f.Updated.UTC().Format(time.RFC1123Z),
+			Self:          rssSelf{Href: f.SelfLink, Rel: "self", Type: "application/rss+xml"},
+		},
+	}
+	for _, e := range f.Entries {
+		item := rssItem{
+			Title:       e.Title,
+			Link:        e.Link,
+			Description: e.Content,
+			Creator:     e.Author,
+			GUID:        rssGUID{Value: e.ID},
+			PubDate:     e.Published.UTC().Format(time.RFC1123Z),
+		}
+		if e.Enclosure != nil {
+			item.Enclosure = &rssEnclosure{URL: e.Enclosure.URL, Type: e.Enclosure.Type, Length: e.Enclosure.Length}
+		}
+		doc.Channel.Items = append(doc.Channel.Items, item)
+	}
+	return encode(w, doc)
+}
+
+func encode(w io.Writer, doc any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,114 @@
+// Solves the proof-of-work captcha of every form marked with
+// data-captcha-board before it is sent: it fetches a challenge for the
+// board, looks for a nonce such that SHA-256(token + ":" + nonce) starts
+// with enough zero bits and fills in captcha_token and captcha_nonce.
+// SHA-256 is done by hand as crypto.subtle needs HTTPS and is slow for
+// many tiny hashes.
+(function () {
+  var K = [
+    0x428a2f98, 0x71374491, 0xb5c0fbcf, 0xe9b5dba5, 0x3956c25b, 0x59f111f1, 0x923f82a4, 0xab1c5ed5,
+    0xd807aa98, 0x12835b01, 0x243185be, 0x550c7dc3, 0x72be5d74, 0x80deb1fe, 0x9bdc06a7, 0xc19bf174,
+    0xe49b69c1, 0xefbe4786, 0x0fc19dc6, 0x240ca1cc, 0x2de92c6f, 0x4a7484aa, 0x5cb0a9dc, 0x76f988da,
+    0x983e5152, 0xa831c66d, 0xb00327c8, 0xbf597fc7, 0xc6e00bf3, 0xd5a79147, 0x06ca6351, 0x14292967,
+    0x27b70a85, 0x2e1b2138, 0x4d2c6dfc, 0x53380d13, 0x650a7354, 0x766a0abb, 0x81c2c92e, 0x92722c85,
+    0xa2bfe8a1, 0xa81a664b, 0xc24b8b70, 0xc76c51a3, 0xd192e819, 0xd6990624, 0xf40e3585, 0x106aa070,
+    0x19a4c116, 0x1e376c08, 0x2748774c, 0x34b0bcb5, 0x391c0cb3, 0x4ed8aa4a, 0x5b9cca4f, 0x682e6ff3,
+    0x748f82ee, 0x78a5636f, 0x84c87814, 0x8cc70208, 0x90befffa, 0xa4506ceb, 0xbef9a3f7, 0xc67178f2
+  ];
+  var W = new Uint32Array(64);
+
+  function ror(x, n) {
+    return (x >>> n) | (x << (32 - n));
+  }
+
+  // sha256 returns the digest of bytes as eight 32-bit words.
+  function sha256(bytes) {
+    var len = bytes.length;
+    var padded = new Uint8Array(((len + 9 + 63) >> 6) << 6);
+    padded.set(bytes);
+    padded[len] = 0x80;
+    var view = new DataView(padded.buffer);
+    view.setUint32(padded.length - 8, Math.floor(len / 0x20000000));
+    view.setUint32(padded.length - 4, (len << 3) >>> 0);
+
+    var H = [0x6a09e667, 0xbb67ae85, 0x3c6ef372, 0xa54ff53a, 0x510e527f, 0x9b05688c, 0x1f83d9ab, 0x5be0cd19];
+    for (var off = 0; off < padded.length; off += 64) {
+      var t;
+      for (t = 0; t < 16; t++) W[t] = view.getUint32(off + t * 4);
+      for (t = 16; t < 64; t++) {
+        var x = W[t - 15], y = W[t - 2];
+        W[t] = W[t - 16] + (ror(x, 7) ^ ror(x, 18) ^ (x >>> 3)) + W[t - 7] + (ror(y, 17) ^ ror(y, 19) ^ (y >>> 10));
+      }
+
+      var a = H[0], b = H[1], c = H[2], d = H[3], e = H[4], f = H[5], g = H[6], h = H[7];
+      for (t = 0; t < 64; t++) {
+        var t1 = (h + (ror(e, 6) ^ ror(e, 11) ^ ror(e, 25)) + ((e & f) ^ (~e & g)) + K[t] + W[t]) | 0;
+        var t2 = ((ror(a, 2) ^ ror(a, 13) ^ ror(a, 22)) + ((a & b) ^ (a & c) ^ (b & c))) | 0;
+        h = g; g = f; f = e; e = (d + t1) | 0;
+        d = c; c = b; b = a; a = (t1 + t2) | 0;
+      }
+      H[0] = (H[0] + a) | 0; H[1] = (H[1] + b) | 0; H[2] = (H[2] + c) | 0; H[3] = (H[3] + d) | 0;
+      H[4] = (H[4] + e) | 0; H[5] = (H[5] + f) | 0; H[6] = (H[6] + g) | 0; H[7] = (H[7] + h) | 0;
+    }
+    return H;
+  }
+
+  function zeroBits(words) {
+    var n = 0;
+    for (var i = 0; i < words.length; i++) {
+      if (words[i] !== 0) return n + Math.clz32(words[i]);
+      n += 32;
+    }
+    return n;
+  }
+
+  // solve tries nonces in batches so the page stays responsive.
+  function solve(challenge, done) {
+    var encoder = new TextEncoder();
+    var prefix = challenge.token + ":";
+    var nonce = 0;
+    (function batch() {
+      for (var end = nonce + 5000; nonce < end; nonce++) {
+        if (zeroBits(sha256(encoder.encode(prefix + nonce))) >= challenge.difficulty) {
+          done(String(nonce));
+          return;
+        }
+      }
+      setTimeout(batch, 0);
+    })();
+  }
+
+  window.solveCaptcha = solve;
+
+  document.querySelectorAll("form[data-captcha-board]").forEach(function (form) {
+    form.addEventListener("submit", function (e) {
+      e.preventDefault();
+      var button = form.querySelector("[type=submit]");
+      var label = button ? button.textContent : "";
+      if (button) {
+        button.disabled = true;
+        button.textContent = "Solving captcha…";
+      }
+
+      fetch("/api/v1/boards/" + encodeURIComponent(form.dataset.captchaBoard) + "/challenge")
+        .then(function (res) {
+          if (!res.ok) throw new Error("challenge: " + res.status);
+          return res.json();
+        })
+        .then(function (challenge) {
+          solve(challenge, function (nonce) {
+            form.elements.captcha_token.value = challenge.token;
+            form.elements.captcha_nonce.value = nonce;
+            form.submit();
+          });
+        })
+        .catch(function () {
+          if (button) {
+            button.disabled = false;
+            button.textContent = label;
+          }
+          alert("Could not load the captcha, please try again.");
+        });
+    });
+  });
+})();
@@ -0,0 +1,16 @@
+// Package openapi embeds the OpenAPI document of the /api/v1 JSON API and the
+// HTML page that renders it.
+package openapi
+
+import (
+	_ "embed"
+)
+
+//go:embed openapi.json
+var Spec []byte
+
+// DocsPage is a template; its script needs the response's CSP nonce as
+// .CSPNonce.
+//
+//go:embed docs.html
+var DocsPage string
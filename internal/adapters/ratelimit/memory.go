@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"1337b04rd/internal/domain"
+)
+
+// sweepInterval is how often full buckets are dropped from memory.
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	fullAt time.Time
+}
+
+// MemoryLimiter keeps the buckets in process. Each replica counts on its
+// own, so behind a load balancer use PostgresLimiter.
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{buckets: make(map[string]*bucket), lastSweep: time.Now()}
+}
+
+func (l *MemoryLimiter) Allow(ctx context.Context, key string, rule domain.RateLimitRule) (bool, time.Duration, error) {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) > sweepInterval {
+		l.sweep(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(rule.Burst), last: now}
+		l.buckets[key] = b
+	}
+
+	left, wait, allowed := rule.Take(b.tokens, b.last, now)
+	if !allowed {
+		return false, wait, nil
+	}
+	b.tokens, b.last, b.fullAt = left, now, rule.FullAt(left, now)
+	return true, 0, nil
+}
+
+// sweep drops the buckets that have refilled; a fresh one is the same.
+func (l *MemoryLimiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		if now.After(b.fullAt) {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
@@ -0,0 +1,89 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"1337b04rd/internal/domain"
+)
+
+// pruneInterval is how often refilled buckets are deleted.
+const pruneInterval = 10 * time.Minute
+
+// PostgresLimiter keeps the buckets in the rate_limits table, so every
+// replica counts against the same budget. A bucket row is locked while a
+// token is taken.
+type PostgresLimiter struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewPostgresLimiter(db *sql.DB, logger *slog.Logger) *PostgresLimiter {
+	l := &PostgresLimiter{db: db, logger: logger}
+	go l.run()
+	return l
+}
+
+func (l *PostgresLimiter) Allow(ctx context.Context, key string, rule domain.RateLimitRule) (bool, time.Duration, error) {
+	tx, err := l.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, 0, fmt.Errorf("unable to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO rate_limits (key, tokens, updated_at, full_at)
+		VALUES ($1, $2, $3, $3)
+		ON CONFLICT (key) DO NOTHING
+	`, key, rule.Burst, now)
+	if err != nil {
+		return false, 0, fmt.Errorf("unable to create rate limit bucket: %w", err)
+	}
+
+	var tokens float64
+	var last time.Time
+	err = tx.QueryRowContext(ctx, `
+		SELECT tokens, updated_at FROM rate_limits WHERE key = $1 FOR UPDATE
+	`, key).Scan(&tokens, &last)
+	if err != nil {
+		return false, 0, fmt.Errorf("unable to fetch rate limit bucket: %w", err)
+	}
+
+	left, wait, allowed := rule.Take(tokens, last, now)
+	if !allowed {
+		return false, wait, nil
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE rate_limits SET tokens = $2, updated_at = $3, full_at = $4 WHERE key = $1
+	`, key, left, now, rule.FullAt(left, now))
+	if err != nil {
+		return false, 0, fmt.Errorf("unable to update rate limit bucket: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return false, 0, fmt.Errorf("unable to commit transaction: %w", err)
+	}
+	return true, 0, nil
+}
+
+// Prune deletes the buckets that have refilled; a missing bucket is full.
+func (l *PostgresLimiter) Prune(ctx context.Context) error {
+	if _, err := l.db.ExecContext(ctx, `DELETE FROM rate_limits WHERE full_at < now()`); err != nil {
+		return fmt.Errorf("unable to prune rate limits: %w", err)
+	}
+	return nil
+}
+
+func (l *PostgresLimiter) run() {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := l.Prune(context.Background()); err != nil {
+			l.logger.Error("Failed to prune rate limits", "error", err)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package ratelimit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"1337b04rd/internal/adapters/ratelimit"
+	"1337b04rd/internal/domain"
+)
+
+func TestMemoryLimiterBurstAndRefill(t *testing.T) {
+	ctx := context.Background()
+	limiter := ratelimit.NewMemoryLimiter()
+	rule := domain.RateLimitRule{Name: domain.RateLimitReply, Burst: 2, Interval: 50 * time.Millisecond}
+
+	for i := 0; i < rule.Burst; i++ {
+		if ok, _, err := limiter.Allow(ctx, "reply:session:a", rule); err != nil || !ok {
+			t.Fatalf("request %d within the burst: ok = %v, err = %v", i+1, ok, err)
+		}
+	}
+
+	ok, wait, err := limiter.Allow(ctx, "reply:session:a", rule)
+	if err != nil || ok {
+		t.Fatalf("request over the burst: ok = %v, err = %v; want it refused", ok, err)
+	}
+	if wait <= 0 || wait > rule.Interval {
+		t.Fatalf("wait = %v, want up to one interval", wait)
+	}
+
+	if ok, _, _ := limiter.Allow(ctx, "reply:session:b", rule); !ok {
+		t.Fatal("another key shared the exhausted bucket")
+	}
+
+	time.Sleep(wait)
+	if ok, _, _ := limiter.Allow(ctx, "reply:session:a", rule); !ok {
+		t.Fatal("bucket did not refill after the wait")
+	}
+}
+
+func TestParseRateLimitRule(t *testing.T) {
+	rule, err := domain.ParseRateLimitRule(domain.RateLimitReply, "3/10s")
+	if err != nil || rule.Burst != 3 || rule.Interval != 10*time.Second {
+		t.Fatalf("ParseRateLimitRule(3/10s) = %+v, %v", rule, err)
+	}
+	if rule, err := domain.ParseRateLimitRule(domain.RateLimitUpload, "off"); err != nil || rule.Enabled() {
+		t.Fatalf("ParseRateLimitRule(off) = %+v, %v; want a disabled rule", rule, err)
+	}
+	for _, spec := range []string{"3", "x/10s", "3/soon", "3/-1s"} {
+		if _, err := domain.ParseRateLimitRule(domain.RateLimitReply, spec); err == nil {
+			t.Errorf("ParseRateLimitRule(%q) accepted an invalid rule", spec)
+		}
+	}
+}
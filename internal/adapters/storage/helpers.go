@@ -2,37 +2,255 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"mime"
+	"net"
 	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
 	"time"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"1337b04rd/internal/domain"
+	"1337b04rd/pkg/tracing"
+)
+
+// Fetcher defaults.
+const (
+	DefaultFetchMaxBytes     = 10 << 20
+	DefaultFetchMaxRedirects = 3
+	DefaultFetchTimeout      = 10 * time.Second
 )
 
-func DownloadFile(ctx context.Context, url string) ([]byte, string, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+// ImageContentTypes are the types image fetchers accept; each is one Go
+// can sniff, so the body is checked as well as the header.
+var ImageContentTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp"}
+
+// ImageExtension returns the file extension for one of ImageContentTypes.
+func ImageExtension(contentType string) string {
+	switch contentType {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/png":
+		return ".png"
+	case "image/gif":
+		return ".gif"
+	case "image/webp":
+		return ".webp"
+	}
+	return ""
+}
+
+// FetchOptions configures a Fetcher. Zero values take the defaults.
+type FetchOptions struct {
+	// AllowedHosts limits fetching to these hosts; "*.example.org" also
+	// matches subdomains. Empty allows any host with a public address.
+	AllowedHosts []string
+	// ContentTypes lists the media types accepted, by header and by content.
+	// Empty accepts anything.
+	ContentTypes []string
+	MaxBytes     int64
+	MaxRedirects int
+	Timeout      time.Duration
+	// AllowAddress decides which addresses may be dialled, PublicAddress
+	// unless set.
+	AllowAddress func(ip net.IP) bool
+}
+
+// Fetcher downloads files from URLs posters or other services hand us
+// without letting them reach our own network: every address is checked
+// when it is dialled, after DNS and after each redirect, and hosts, sizes
+// and content types are limited.
+type Fetcher struct {
+	opts   FetchOptions
+	client *http.Client
+}
+
+func NewFetcher(opts FetchOptions) *Fetcher {
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = DefaultFetchMaxBytes
+	}
+	if opts.MaxRedirects <= 0 {
+		opts.MaxRedirects = DefaultFetchMaxRedirects
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultFetchTimeout
+	}
+	if opts.AllowAddress == nil {
+		opts.AllowAddress = PublicAddress
+	}
+
+	f := &Fetcher{opts: opts}
+	dialer := &net.Dialer{
+		Timeout: opts.Timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !opts.AllowAddress(ip) {
+				return fmt.Errorf("%w: %s", domain.ErrFetchBlocked, host)
+			}
+			return nil
+		},
+	}
+	f.client = &http.Client{
+		Timeout: opts.Timeout,
+		Transport: &http.Transport{
+			// A proxy would dial for us, past the address check.
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   opts.Timeout,
+			ResponseHeaderTimeout: opts.Timeout,
+			MaxIdleConns:          10,
+			IdleConnTimeout:       30 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > opts.MaxRedirects {
+				return fmt.Errorf("%w: more than %d redirects", domain.ErrFetchFailed, opts.MaxRedirects)
+			}
+			return f.checkURL(req.URL)
+		},
+	}
+	return f
+}
+
+// Fetch downloads rawURL and returns its content and media type.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) ([]byte, string, error) {
+	u, err := url.Parse(rawURL)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to create request: %w", err)
+		return nil, "", fmt.Errorf("%w: invalid URL", domain.ErrFetchFailed)
+	}
+	ctx, span := tracer.Start(ctx, "Fetcher.Fetch",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.ServerAddress(u.Hostname())),
+	)
+	data, contentType, err := f.fetch(ctx, u)
+	tracing.End(span, err)
+	return data, contentType, err
+}
+
+func (f *Fetcher) fetch(ctx context.Context, u *url.URL) ([]byte, string, error) {
+	if err := f.checkURL(u); err != nil {
+		return nil, "", err
 	}
 
-	client := &http.Client{
-		Timeout: 10 * time.Second,
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", domain.ErrFetchFailed, err)
 	}
-	resp, err := client.Do(req)
+	resp, err := f.client.Do(req)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to download file: %w", err)
+		for _, known := range []error{domain.ErrFetchBlocked, domain.ErrFetchFailed} {
+			if errors.Is(err, known) {
+				return nil, "", fmt.Errorf("%w: %s", known, u.Host)
+			}
+		}
+		return nil, "", fmt.Errorf("%w: %v", domain.ErrFetchFailed, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, "", fmt.Errorf("bad status downloading file: %d", resp.StatusCode)
+		return nil, "", fmt.Errorf("%w: status %d", domain.ErrFetchFailed, resp.StatusCode)
+	}
+	if resp.ContentLength > f.opts.MaxBytes {
+		return nil, "", fmt.Errorf("%w: %d bytes", domain.ErrFetchTooLarge, resp.ContentLength)
+	}
+	declared, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if !f.acceptsType(declared) {
+		return nil, "", fmt.Errorf("%w: %q", domain.ErrFetchContentType, declared)
 	}
 
-	contentType := resp.Header.Get("Content-Type")
-
-	data, err := io.ReadAll(resp.Body)
+	data, err := io.ReadAll(io.LimitReader(resp.Body, f.opts.MaxBytes+1))
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to read response body: %w", err)
+		return nil, "", fmt.Errorf("%w: %v", domain.ErrFetchFailed, err)
+	}
+	if int64(len(data)) > f.opts.MaxBytes {
+		return nil, "", fmt.Errorf("%w: over %d bytes", domain.ErrFetchTooLarge, f.opts.MaxBytes)
+	}
+
+	if len(f.opts.ContentTypes) == 0 {
+		return data, declared, nil
+	}
+	sniffed, _, _ := mime.ParseMediaType(http.DetectContentType(data))
+	if !f.acceptsType(sniffed) {
+		return nil, "", fmt.Errorf("%w: content is %q", domain.ErrFetchContentType, sniffed)
+	}
+	return data, sniffed, nil
+}
+
+// checkURL allows http and https URLs on allowed hosts.
+func (f *Fetcher) checkURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%w: scheme %q", domain.ErrFetchBlocked, u.Scheme)
+	}
+	if u.User != nil {
+		return fmt.Errorf("%w: credentials in URL", domain.ErrFetchBlocked)
 	}
+	host := strings.ToLower(u.Hostname())
+	if host == "" {
+		return fmt.Errorf("%w: invalid URL", domain.ErrFetchFailed)
+	}
+	if len(f.opts.AllowedHosts) == 0 {
+		return nil
+	}
+	for _, allowed := range f.opts.AllowedHosts {
+		allowed = strings.ToLower(allowed)
+		if host == allowed || (strings.HasPrefix(allowed, "*.") && strings.HasSuffix(host, allowed[1:])) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: host %s", domain.ErrFetchBlocked, host)
+}
+
+func (f *Fetcher) acceptsType(mediaType string) bool {
+	if len(f.opts.ContentTypes) == 0 {
+		return true
+	}
+	for _, accepted := range f.opts.ContentTypes {
+		if mediaType == accepted {
+			return true
+		}
+	}
+	return false
+}
 
-	return data, contentType, nil
+// PublicAddress reports whether ip is routable on the internet: not
+// loopback, private, link-local (where cloud metadata lives), multicast,
+// unspecified, or in the shared and reserved ranges.
+func PublicAddress(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+	for _, reserved := range reservedNets {
+		if reserved.Contains(ip) {
+			return false
+		}
+	}
+	return true
 }
+
+// reservedNets are ranges net.IP has no predicate for.
+var reservedNets = func() []*net.IPNet {
+	var nets []*net.IPNet
+	for _, cidr := range []string{
+		"0.0.0.0/8",     // this network
+		"100.64.0.0/10", // carrier-grade NAT
+		"192.0.0.0/24",  // IETF protocol assignments
+		"198.18.0.0/15", // benchmarking
+		"240.0.0.0/4",   // reserved, and broadcast
+		"64:ff9b::/96",  // NAT64, which may map to private IPv4
+		"2001:db8::/32", // documentation
+		"fec0::/10",     // deprecated site-local
+	} {
+		_, n, _ := net.ParseCIDR(cidr)
+		nets = append(nets, n)
+	}
+	return nets
+}()
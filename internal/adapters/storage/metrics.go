@@ -0,0 +1,31 @@
+package storage
+
+import (
+	"context"
+	"io"
+
+	"github.com/minio/minio-go/v7"
+
+	"1337b04rd/pkg/metrics"
+	"1337b04rd/pkg/tracing"
+)
+
+var (
+	uploadBytes   = metrics.NewCounterVec("storage_upload_bytes_total", "Bytes written to object storage by bucket.", "bucket")
+	downloadBytes = metrics.NewCounterVec("storage_download_bytes_total", "Bytes read from object storage by bucket.", "bucket")
+	storageErrors = metrics.NewCounterVec("storage_errors_total", "Failed object storage operations by bucket and operation.", "bucket", "operation")
+)
+
+// put stores an object and counts the bytes written or the failure.
+func (m *MinioClient) put(ctx context.Context, bucket, objectName string, r io.Reader, size int64, opts minio.PutObjectOptions) (err error) {
+	ctx, span := startSpan(ctx, "MinioClient.PutObject", bucket, objectName)
+	defer func() { tracing.End(span, err) }()
+
+	info, err := m.client.PutObject(ctx, bucket, objectName, r, size, opts)
+	if err != nil {
+		storageErrors.With(bucket, "upload").Inc()
+		return err
+	}
+	uploadBytes.With(bucket).Add(float64(info.Size))
+	return nil
+}
@@ -6,11 +6,15 @@ import (
 	"fmt"
 	"mime/multipart"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
+
+	"1337b04rd/internal/domain"
+	"1337b04rd/pkg/tracing"
 )
 
 type MinioClient struct {
@@ -19,8 +23,16 @@ type MinioClient struct {
 	postBucket    string
 	commentBucket string
 	sessionBucket string // Add session bucket
+	avatars       *Fetcher
+	remote        *Fetcher
 }
 
+// avatarHosts are where the avatars of new sessions come from.
+var avatarHosts = []string{"rickandmortyapi.com"}
+
+// MaxImageBytes caps images posted by URL, as the form limit caps uploads.
+const MaxImageBytes = 10 << 20
+
 func NewMinioClient(endpoint, accessKey, secretKey string, useSSL bool) (*MinioClient, error) {
 	client, err := minio.New(endpoint, &minio.Options{
 		Creds:  credentials.NewStaticV4(accessKey, secretKey, ""),
@@ -56,22 +68,21 @@ func NewMinioClient(endpoint, accessKey, secretKey string, useSSL bool) (*MinioC
 		postBucket:    "posts",
 		commentBucket: "comments",
 		sessionBucket: "sessions", // Set session bucket
+		avatars:       NewFetcher(FetchOptions{AllowedHosts: avatarHosts, ContentTypes: ImageContentTypes}),
+		remote:        NewFetcher(FetchOptions{ContentTypes: ImageContentTypes, MaxBytes: MaxImageBytes}),
 	}, nil
 }
 
 // UploadAvatarFromURL uploads an avatar image from a URL to the avatars bucket
 func (m *MinioClient) UploadAvatarFromURL(ctx context.Context, imageUrl string) (string, error) {
-	data, contentType, err := DownloadFile(ctx, imageUrl)
+	data, contentType, err := m.avatars.Fetch(ctx, imageUrl)
 	if err != nil {
 		return "", fmt.Errorf("failed to download file: %w", err)
 	}
-	if !strings.HasPrefix(contentType, "image/") {
-		return "", fmt.Errorf("invalid content type: %s", contentType)
-	}
 
 	objectName := fmt.Sprintf("%d-avatar", time.Now().UnixNano())
 
-	_, err = m.client.PutObject(ctx, m.avatarBucket, objectName, bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{
+	err = m.put(ctx, m.avatarBucket, objectName, bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{
 		ContentType: contentType,
 	})
 	if err != nil {
@@ -85,7 +96,7 @@ func (m *MinioClient) UploadAvatarFromURL(ctx context.Context, imageUrl string)
 func (m *MinioClient) UploadCommentImage(ctx context.Context, fileBytes []byte, filename, contentType string) (string, error) {
 	objectName := fmt.Sprintf("%d-%s", time.Now().UnixNano(), filename)
 
-	_, err := m.client.PutObject(ctx, m.commentBucket, objectName, bytes.NewReader(fileBytes), int64(len(fileBytes)), minio.PutObjectOptions{
+	err := m.put(ctx, m.commentBucket, objectName, bytes.NewReader(fileBytes), int64(len(fileBytes)), minio.PutObjectOptions{
 		ContentType: contentType,
 	})
 	if err != nil {
@@ -95,12 +106,33 @@ func (m *MinioClient) UploadCommentImage(ctx context.Context, fileBytes []byte,
 	return fmt.Sprintf("/images/comments/%s", objectName), nil
 }
 
+// FetchImage downloads an image a poster linked to, from any public host,
+// and returns it with its sniffed content type.
+func (m *MinioClient) FetchImage(ctx context.Context, imageURL string) ([]byte, string, error) {
+	return m.remote.Fetch(ctx, imageURL)
+}
+
+// UploadPostImage uploads a post image already in memory, such as one
+// fetched by URL.
+func (m *MinioClient) UploadPostImage(ctx context.Context, fileBytes []byte, filename, contentType string) (string, error) {
+	objectName := fmt.Sprintf("%d-%s", time.Now().UnixNano(), filename)
+
+	err := m.put(ctx, m.postBucket, objectName, bytes.NewReader(fileBytes), int64(len(fileBytes)), minio.PutObjectOptions{
+		ContentType: contentType,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload image: %w", err)
+	}
+
+	return fmt.Sprintf("/images/posts/%s", objectName), nil
+}
+
 func (m *MinioClient) UploadImage(ctx context.Context, file multipart.File, filename, contentType string) (string, error) {
 	// Generate a unique object name using timestamp and filename
 	objectName := fmt.Sprintf("%d-%s", time.Now().UnixNano(), filename)
 
 	// Upload the image to the 'posts' bucket
-	_, err := m.client.PutObject(ctx, m.postBucket, objectName, file, -1, minio.PutObjectOptions{
+	err := m.put(ctx, m.postBucket, objectName, file, -1, minio.PutObjectOptions{
 		ContentType: contentType,
 	})
 	if err != nil {
@@ -115,63 +147,159 @@ func (m *MinioClient) UploadImage(ctx context.Context, file multipart.File, file
 // SaveSession saves session data to the sessions bucket
 func (m *MinioClient) SaveSession(ctx context.Context, sessionID string, sessionData []byte) error {
 	objectName := sessionID
-	_, err := m.client.PutObject(ctx, m.sessionBucket, objectName, bytes.NewReader(sessionData), int64(len(sessionData)), minio.PutObjectOptions{})
+	err := m.put(ctx, m.sessionBucket, objectName, bytes.NewReader(sessionData), int64(len(sessionData)), minio.PutObjectOptions{})
 	if err != nil {
 		return fmt.Errorf("failed to upload session: %w", err)
 	}
 	return nil
 }
 
+// Ping fails unless every bucket the board writes to can be reached.
+func (m *MinioClient) Ping(ctx context.Context) error {
+	for _, bucket := range []string{m.avatarBucket, m.postBucket, m.commentBucket, m.sessionBucket} {
+		exists, err := m.client.BucketExists(ctx, bucket)
+		if err != nil {
+			return fmt.Errorf("failed to reach bucket %s: %w", bucket, err)
+		}
+		if !exists {
+			return fmt.Errorf("bucket %s does not exist", bucket)
+		}
+	}
+	return nil
+}
+
 func (m *MinioClient) Client() *minio.Client {
 	return m.client
 }
 
-func (m *MinioClient) GetImage(ctx context.Context, bucket, objectName string) ([]byte, string, error) {
+func (m *MinioClient) GetImage(ctx context.Context, bucket, objectName string) (_ []byte, _ string, err error) {
+	ctx, span := startSpan(ctx, "MinioClient.GetObject", bucket, objectName)
+	defer func() { tracing.End(span, err) }()
+
 	obj, err := m.client.GetObject(ctx, bucket, objectName, minio.GetObjectOptions{})
 	if err != nil {
+		storageErrors.With(bucket, "download").Inc()
 		return nil, "", fmt.Errorf("failed to get object: %w", err)
 	}
 	defer obj.Close()
 
 	stat, err := obj.Stat()
 	if err != nil {
+		storageErrors.With(bucket, "download").Inc()
 		return nil, "", fmt.Errorf("failed to stat object: %w", err)
 	}
 
 	buf := new(bytes.Buffer)
 	if _, err := buf.ReadFrom(obj); err != nil {
+		storageErrors.With(bucket, "download").Inc()
 		return nil, "", fmt.Errorf("failed to read object data: %w", err)
 	}
+	downloadBytes.With(bucket).Add(float64(buf.Len()))
 
 	return buf.Bytes(), stat.ContentType, nil
 }
 
-func ServePostImageHandler(storage *MinioClient) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		filename := r.PathValue("filename") // Go 1.21+
-		data, contentType, err := storage.GetImage(r.Context(), "posts", filename)
-		if err != nil {
-			http.Error(w, "Image not found", http.StatusNotFound)
-			return
-		}
+// DeleteImage removes the object behind an image URL handed out by
+// UploadImage or UploadCommentImage.
+func (m *MinioClient) DeleteImage(ctx context.Context, imageURL string) error {
+	var bucket, objectName string
+	switch {
+	case strings.HasPrefix(imageURL, "/images/posts/"):
+		bucket, objectName = m.postBucket, strings.TrimPrefix(imageURL, "/images/posts/")
+	case strings.HasPrefix(imageURL, "/images/comments/"):
+		bucket, objectName = m.commentBucket, strings.TrimPrefix(imageURL, "/images/comments/")
+	default:
+		return fmt.Errorf("not a stored image: %s", imageURL)
+	}
 
-		w.Header().Set("Content-Type", contentType)
-		w.WriteHeader(http.StatusOK)
-		w.Write(data)
+	ctx, span := startSpan(ctx, "MinioClient.RemoveObject", bucket, objectName)
+	err := m.client.RemoveObject(ctx, bucket, objectName, minio.RemoveObjectOptions{})
+	tracing.End(span, err)
+	if err != nil {
+		storageErrors.With(bucket, "delete").Inc()
+		return fmt.Errorf("failed to delete image: %w", err)
 	}
+	return nil
+}
+
+func ServePostImageHandler(storage *MinioClient) http.HandlerFunc {
+	return serveImage(storage, "posts")
 }
 
 func ServeCommentImageHandler(storage *MinioClient) http.HandlerFunc {
+	return serveImage(storage, "comments")
+}
+
+// imageTypes are the content types uploads are served as; anything else is
+// sent as a download.
+var imageTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+	"image/avif": true,
+}
+
+// serveImage serves an upload so browsers never take it for anything but
+// an image.
+func serveImage(storage *MinioClient, bucket string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		filename := r.PathValue("filename")
-		data, contentType, err := storage.GetImage(r.Context(), "comments", filename)
+		data, contentType, err := storage.GetImage(r.Context(), bucket, filename)
 		if err != nil {
 			http.Error(w, "Image not found", http.StatusNotFound)
 			return
 		}
+		WriteImage(w, data, contentType, filename)
+	}
+}
 
-		w.Header().Set("Content-Type", contentType)
-		w.WriteHeader(http.StatusOK)
-		w.Write(data)
+// WriteImage writes an upload with no sniffing, a sandbox without scripts
+// and a harmless name. Anything but an image is sent as a download.
+func WriteImage(w http.ResponseWriter, data []byte, contentType, filename string) {
+	disposition := "inline"
+	if !imageTypes[strings.ToLower(contentType)] {
+		contentType, disposition = "application/octet-stream", "attachment"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("%s; filename=%q", disposition, safeFilename(filename)))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; sandbox")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// FindImage finds the post or comment image uploaded at tim, the UnixNano
+// time its object name starts with, and returns its bucket and name.
+func (m *MinioClient) FindImage(ctx context.Context, tim int64) (string, string, error) {
+	prefix := strconv.FormatInt(tim, 10) + "-"
+	for _, bucket := range []string{m.postBucket, m.commentBucket} {
+		for object := range m.client.ListObjects(ctx, bucket, minio.ListObjectsOptions{Prefix: prefix, MaxKeys: 1}) {
+			if object.Err != nil {
+				storageErrors.With(bucket, "list").Inc()
+				return "", "", fmt.Errorf("failed to list images: %w", object.Err)
+			}
+			return bucket, object.Key, nil
+		}
+	}
+	return "", "", fmt.Errorf("no image uploaded at %d: %w", tim, domain.ErrImageNotFound)
+}
+
+// safeFilename keeps letters, digits, dots, dashes and underscores of name,
+// enough to be quoted in a header as is.
+func safeFilename(name string) string {
+	safe := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, name)
+	if len(safe) > 100 {
+		safe = safe[len(safe)-100:]
+	}
+	if strings.Trim(safe, "._") == "" {
+		return "image"
 	}
+	return safe
 }
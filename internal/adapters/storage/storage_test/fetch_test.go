@@ -0,0 +1,110 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"1337b04rd/internal/adapters/storage"
+	"1337b04rd/internal/domain"
+)
+
+// pngHeader is enough of a PNG for content sniffing.
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+// loopbackOnly stands in for PublicAddress so tests can reach httptest
+// servers while everything else stays blocked.
+func loopbackOnly(ip net.IP) bool { return ip.IsLoopback() }
+
+func TestFetch(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/image.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(pngHeader)
+	})
+	mux.HandleFunc("/disguised.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("<html><script>alert(1)</script></html>"))
+	})
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html></html>"))
+	})
+	mux.HandleFunc("/large.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(append(pngHeader, make([]byte, 1024)...))
+	})
+	mux.HandleFunc("/metadata", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", http.StatusFound)
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	mux.HandleFunc("/missing", http.NotFound)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	images := storage.NewFetcher(storage.FetchOptions{
+		ContentTypes: storage.ImageContentTypes,
+		MaxBytes:     512,
+		AllowAddress: loopbackOnly,
+	})
+
+	tests := []struct {
+		name    string
+		fetcher *storage.Fetcher
+		url     string
+		wantErr error
+	}{
+		{"image", images, srv.URL + "/image.png", nil},
+		{"loopback blocked by default", storage.NewFetcher(storage.FetchOptions{}), srv.URL + "/image.png", domain.ErrFetchBlocked},
+		{"redirect to metadata address", images, srv.URL + "/metadata", domain.ErrFetchBlocked},
+		{"host not allowed", storage.NewFetcher(storage.FetchOptions{AllowedHosts: []string{"*.example.org"}, AllowAddress: loopbackOnly}), srv.URL + "/image.png", domain.ErrFetchBlocked},
+		{"scheme not allowed", images, "file:///etc/passwd", domain.ErrFetchBlocked},
+		{"credentials in URL", images, "http://user:pass@" + srv.Listener.Addr().String() + "/image.png", domain.ErrFetchBlocked},
+		{"too large", images, srv.URL + "/large.png", domain.ErrFetchTooLarge},
+		{"declared type", images, srv.URL + "/page", domain.ErrFetchContentType},
+		{"sniffed type", images, srv.URL + "/disguised.png", domain.ErrFetchContentType},
+		{"redirect loop", images, srv.URL + "/loop", domain.ErrFetchFailed},
+		{"not found", images, srv.URL + "/missing", domain.ErrFetchFailed},
+	}
+	for _, tt := range tests {
+		data, contentType, err := tt.fetcher.Fetch(context.Background(), tt.url)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr == nil && (contentType != "image/png" || len(data) != len(pngHeader)) {
+			t.Errorf("%s: got %d bytes of %q, want the PNG", tt.name, len(data), contentType)
+		}
+	}
+}
+
+func TestPublicAddress(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"10.0.0.1", false},
+		{"172.16.5.4", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"::1", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"::ffff:127.0.0.1", false},
+	}
+	for _, tt := range tests {
+		if got := storage.PublicAddress(net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("PublicAddress(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package storage
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("1337b04rd/internal/adapters/storage")
+
+// startSpan opens a client span for an operation on one object.
+func startSpan(ctx context.Context, name, bucket, objectName string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("storage.bucket", bucket),
+			attribute.String("storage.object", objectName),
+		),
+	)
+}
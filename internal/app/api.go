@@ -0,0 +1,79 @@
+package app
+
+import (
+	"net/http"
+
+	"1337b04rd/internal/handler"
+	"1337b04rd/pkg/middleware"
+)
+
+// Route is a single JSON API endpoint. Every Route must be described in the
+// embedded OpenAPI document, which is enforced by the contract test.
+type Route struct {
+	Method  string
+	Pattern string
+	Handler http.HandlerFunc
+}
+
+// APIRoutes lists the versioned JSON API.
+func APIRoutes(api *handler.APIHandler, searchHandler *handler.SearchHandler) []Route {
+	return []Route{
+		// Boards and threads
+		{"GET", "/api/v1/boards", api.ListBoards},
+		{"GET", "/api/v1/boards/{board}", api.GetBoard},
+		{"GET", "/api/v1/boards/{board}/challenge", api.GetChallenge},
+		{"GET", "/api/v1/boards/{board}/threads", api.ListThreads},
+		{"POST", "/api/v1/boards/{board}/threads", api.CreateThread},
+		{"GET", "/api/v1/threads/{id}", api.GetThread},
+
+		// Comments
+		{"GET", "/api/v1/threads/{id}/comments", api.ListComments},
+		{"POST", "/api/v1/threads/{id}/comments", api.CreateComment},
+		{"GET", "/api/v1/comments/{id}", api.GetComment},
+		{"GET", "/api/v1/comments/{id}/replies", api.ListReplies},
+
+		// Archive
+		{"GET", "/api/v1/archive", api.ListArchive},
+		{"GET", "/api/v1/archive/{id}", api.GetArchivedThread},
+
+		// Sessions
+		{"POST", "/api/v1/sessions", api.CreateSession},
+		{"GET", "/api/v1/sessions/me", api.GetCurrentSession},
+		{"PUT", "/api/v1/sessions/me", api.UpdateCurrentSession},
+		{"DELETE", "/api/v1/sessions/me", api.DeleteCurrentSession},
+
+		// Search
+		{"GET", "/api/v1/search", searchHandler.Search},
+
+		// Specification
+		{"GET", "/api/v1/openapi.json", handler.ServeOpenAPISpec},
+	}
+}
+
+// NewAPIRouter registers the versioned JSON API. It is mounted under
+// /api/v1/ and answers unknown routes with a JSON error envelope instead of
+// falling through to the HTML pages. guards wraps routes, by pattern, in
+// the checks they need such as bans and rate limits.
+func NewAPIRouter(api *handler.APIHandler, searchHandler *handler.SearchHandler, guards map[string]func(http.Handler) http.Handler) http.Handler {
+	return middleware.LoadSessionMiddleware()(newAPIMux(api, searchHandler, guards))
+}
+
+// newAPIMux routes the API without the session middleware, for callers
+// that also need to resolve its patterns.
+func newAPIMux(api *handler.APIHandler, searchHandler *handler.SearchHandler, guards map[string]func(http.Handler) http.Handler) *http.ServeMux {
+	mux := http.NewServeMux()
+
+	for _, route := range APIRoutes(api, searchHandler) {
+		pattern := route.Method + " " + route.Pattern
+		var h http.Handler = route.Handler
+		if guard := guards[pattern]; guard != nil {
+			h = guard(h)
+		}
+		mux.Handle(pattern, h)
+	}
+
+	mux.HandleFunc("GET /api/v1/docs", handler.ServeAPIDocs)
+	mux.HandleFunc("/api/v1/", api.NotFound)
+
+	return mux
+}
@@ -1,26 +1,32 @@
 package app
 
 import (
+	"context"
+	"crypto/rand"
 	"database/sql"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"os"
+	"strings"
 
+	dbadapter "1337b04rd/internal/adapters/db"
+	"1337b04rd/internal/adapters/events"
 	"1337b04rd/internal/adapters/storage"
+	"1337b04rd/internal/domain"
 	"1337b04rd/internal/handler"
+	"1337b04rd/internal/ports"
 	"1337b04rd/internal/repository"
 	"1337b04rd/internal/service"
+	"1337b04rd/pkg/health"
 	"1337b04rd/pkg/middleware"
+	"1337b04rd/pkg/tracing"
 )
 
-func NewApp(db *sql.DB, logger *slog.Logger) http.Handler {
+// NewApp wires the board and registers its dependencies with readiness.
+func NewApp(db *sql.DB, logger *slog.Logger, readiness *health.Checker) http.Handler {
 	// Initialize Minio storage
-	s3Storage, err := storage.NewMinioClient(
-		"minio:9000", // endpoint
-		"minioadmin", // access key
-		"minioadmin", // secret key
-		false,        // useSSL
-	)
+	s3Storage, err := newStorage()
 	if err != nil {
 		panic(fmt.Sprintf("failed to initialize Minio client: %v", err))
 	}
@@ -29,39 +35,108 @@ func NewApp(db *sql.DB, logger *slog.Logger) http.Handler {
 	commentRepo := repository.NewCommentRepository(db)
 	postRepo := repository.NewPostRepository(db)
 	archiveRepo := repository.NewArchiveRepository(db)
+	searchRepo := repository.NewSearchRepository(db)
+	boardRepo := repository.NewBoardRepository(db)
+	staffRepo := repository.NewStaffRepository(db)
+	reportRepo := repository.NewReportRepository(db)
+	banRepo := repository.NewBanRepository(db)
+	modActionRepo := repository.NewModActionRepository(db)
+	challengeRepo := repository.NewChallengeRepository(db)
+	filterRepo := repository.NewFilterRepository(db)
+
+	// Live thread updates. With EVENT_BACKEND=postgres events fan out to
+	// every replica through LISTEN/NOTIFY instead of staying in process.
+	eventBus := events.NewBus(events.DefaultHistorySize, events.DefaultBufferSize)
+	var eventPublisher ports.EventPublisher = eventBus
+	if os.Getenv("EVENT_BACKEND") == "postgres" {
+		pgPublisher, err := events.NewPostgresPublisher(db, dbadapter.DSN, eventBus, postRepo, commentRepo, logger)
+		if err != nil {
+			panic(fmt.Sprintf("failed to initialize event publisher: %v", err))
+		}
+		eventPublisher = pgPublisher
+	}
 
 	// Initialize services
-	commentService := service.NewCommentService(commentRepo, postRepo)
-	sessionService := service.NewSessionService(s3Storage, postRepo, commentRepo)
-	postService := service.NewPostService(postRepo)
-	archiveService := service.NewArchiveService(archiveRepo, postRepo)
+	banService := service.NewBanService(banRepo, postRepo, commentRepo, modActionRepo)
+	filterService := service.NewFilterService(filterRepo, banService, modActionRepo)
+	commentService := service.NewCommentService(commentRepo, postRepo, eventPublisher, filterService)
+	sessionService := service.NewSessionService(s3Storage, postRepo, commentRepo, logger)
+	postService := service.NewPostService(postRepo, eventPublisher, modActionRepo, filterService)
+	archiveService := service.NewArchiveService(archiveRepo, postRepo, eventPublisher, modActionRepo)
+	searchService := service.NewSearchService(searchRepo)
+	boardService := service.NewBoardService(boardRepo)
+	staffService := service.NewStaffService(staffRepo, modActionRepo)
+	moderationService := service.NewModerationService(postRepo, commentRepo, boardRepo, s3Storage, eventPublisher, modActionRepo)
+	reportService := service.NewReportService(reportRepo, postRepo, commentRepo, moderationService, banService, modActionRepo)
+	auditService := service.NewAuditService(modActionRepo)
+	captchaService := service.NewCaptchaService(captchaSecret(logger), boardRepo, postRepo, challengeRepo)
+
+	// The first admin comes from the environment; further accounts are
+	// created in the staff panel.
+	if username, password := os.Getenv("STAFF_ADMIN_USERNAME"), os.Getenv("STAFF_ADMIN_PASSWORD"); username != "" && password != "" {
+		if err := staffService.EnsureAdmin(context.Background(), username, password); err != nil {
+			panic(fmt.Sprintf("failed to create admin account: %v", err))
+		}
+	}
 
 	// Initialize handlers
-	postHandler := handler.NewPostHandler(postService, s3Storage, logger)
-	commentHandler := handler.NewCommentHandler(commentService, s3Storage, logger)
+	postHandler := handler.NewPostHandler(postService, captchaService, s3Storage, logger)
+	commentHandler := handler.NewCommentHandler(commentService, captchaService, s3Storage, logger)
 	sessionHandler := handler.NewSessionHandler(sessionService, logger)
 	archiveHandler := handler.NewArchiveHandler(archiveService, logger)
+	searchHandler := handler.NewSearchHandler(searchService, logger)
+	eventsHandler := handler.NewEventsHandler(eventBus, postService, logger)
+	wsHandler := handler.NewWebSocketHandler(eventBus, allowedOrigins(), handler.DefaultMaxConnsPerSession, handler.DefaultMaxConnsPerIP, logger)
+	modHandler := handler.NewModHandler(staffService, moderationService, banService, reportService, auditService, filterService, logger)
+	reportHandler := handler.NewReportHandler(reportService, logger)
+	banHandler := handler.NewBanHandler(banService, postService, logger)
+	feedHandler := handler.NewFeedHandler(postService, archiveService, os.Getenv("PUBLIC_URL"), logger)
+	chanHandler := handler.NewChanHandler(boardService, postService, archiveService, s3Storage, logger)
+	apiHandler := handler.NewAPIHandler(boardService, postService, commentService, archiveService, sessionService, captchaService, logger)
 
 	templateHandler := handler.NewTemplateHandler(logger)
+	rejectBanned := banHandler.RejectBanned(templateHandler.RenderBannedPage(banHandler))
+
+	// Posting guards: rate limits first, as they are the cheapest check
+	rateLimitHandler := handler.NewRateLimitHandler(newRateLimiter(db, logger), rateLimitRules(), logger)
+	newThreadGuard := chain(rateLimitHandler.Limit(domain.RateLimitNewThread), rateLimitHandler.LimitUploads(domain.RateLimitUpload), rejectBanned)
+	replyGuard := chain(rateLimitHandler.Limit(domain.RateLimitReply), rateLimitHandler.LimitUploads(domain.RateLimitUpload), rejectBanned)
+	newSessionGuard := rateLimitHandler.Limit(domain.RateLimitSession)
 
 	// Setup routes
 	mux := http.NewServeMux()
 	fs := http.FileServer(http.Dir("internal/adapters/frontend/static"))
 	mux.Handle("/static/", http.StripPrefix("/static/", fs))
 
-	s := NewCleanupService(postService)
+	s := NewCleanupService(postService, captchaService, auditService, logger)
 	s.StartCleanupTask()
 
 	// Post routes
 	wrappedPostCreateHandler := middleware.InjectSessionMiddleware()(http.HandlerFunc(postHandler.CreatePost))
-	mux.Handle("/", templateHandler.RenderHomePage(sessionHandler))
-	mux.Handle("POST /posts/create", middleware.InjectSessionMiddleware()(wrappedPostCreateHandler))
+	chanMux := newChanMux(chanHandler)
+	homePage := rateLimitHandler.LimitNewSessions(domain.RateLimitSession)(templateHandler.RenderHomePage(sessionHandler))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		// The 4chan-compatible API lives under board slugs, which only this
+		// catch-all route sees
+		if _, pattern := chanMux.Handler(r); pattern != "" {
+			chanMux.ServeHTTP(w, r)
+			return
+		}
+		homePage.ServeHTTP(w, r)
+	})
+	mux.Handle("POST /posts/create", middleware.InjectSessionMiddleware()(newThreadGuard(wrappedPostCreateHandler)))
 	mux.HandleFunc("GET /posts/{id}", func(w http.ResponseWriter, r *http.Request) {
+		// A wildcard cannot share its segment, so thread feeds arrive here
+		if strings.HasSuffix(r.PathValue("id"), ".atom") {
+			feedHandler.ThreadAtom(w, r)
+			return
+		}
 		middleware.InjectSessionMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			templateHandler.RenderPostPage(postHandler, commentHandler).ServeHTTP(w, r)
 		})).ServeHTTP(w, r)
 	})
 
+	mux.HandleFunc("GET /posts/{id}/events", eventsHandler.StreamPostEvents)
 	mux.HandleFunc("GET /posts/create", func(w http.ResponseWriter, r *http.Request) {
 		middleware.InjectSessionMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			templateHandler.RenderCreatePostPage().ServeHTTP(w, r)
@@ -78,15 +153,13 @@ func NewApp(db *sql.DB, logger *slog.Logger) http.Handler {
 	mux.HandleFunc("GET /images/comments/{filename}", storage.ServeCommentImageHandler(s3Storage))
 
 	// Comment routes
-	wrappedCommentCreateHandler := middleware.InjectSessionMiddleware()(http.HandlerFunc(commentHandler.CreateComment))
+	wrappedCommentCreateHandler := middleware.InjectSessionMiddleware()(replyGuard(http.HandlerFunc(commentHandler.CreateComment)))
 	mux.Handle("POST /comments/create", wrappedCommentCreateHandler)
 	mux.HandleFunc("GET /comments/post/{id}", commentHandler.GetCommentsOfPost)
 	mux.HandleFunc("GET /comments/replies/{id}", commentHandler.GetRepliesToComment)
 
 	// archive routes
-	wrappedArchiveHandler := middleware.InjectSessionMiddleware()(http.HandlerFunc(archiveHandler.ArchivePost))
-	mux.Handle("POST /archive/{id}", wrappedArchiveHandler)
-	mux.HandleFunc("POST /archive-expired-posts", archiveHandler.ArchiveExpired)
+	RegisterArchiveRoutes(mux, archiveHandler, modHandler)
 	mux.HandleFunc("GET /archive", func(w http.ResponseWriter, r *http.Request) {
 		middleware.InjectSessionMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			templateHandler.RenderArchivePage(archiveHandler).ServeHTTP(w, r)
@@ -99,12 +172,116 @@ func NewApp(db *sql.DB, logger *slog.Logger) http.Handler {
 		})).ServeHTTP(w, r)
 	})
 
+	// Feeds
+	mux.HandleFunc("GET /posts.rss", feedHandler.PostsRSS)
+	mux.HandleFunc("GET /posts.atom", feedHandler.PostsAtom)
+	mux.HandleFunc("GET /archive.atom", feedHandler.ArchiveAtom)
+
+	// Search routes
+	mux.Handle("GET /search", templateHandler.RenderSearchPage(searchHandler))
+
+	// Public, anonymised moderation log
+	mux.Handle("GET /log", templateHandler.RenderPublicLogPage(modHandler))
+
+	// JSON API
+	apiMux := newAPIMux(apiHandler, searchHandler, map[string]func(http.Handler) http.Handler{
+		"POST /api/v1/boards/{board}/threads": chain(rateLimitHandler.Limit(domain.RateLimitNewThread), rejectBanned),
+		"POST /api/v1/threads/{id}/comments":  chain(rateLimitHandler.Limit(domain.RateLimitReply), rejectBanned),
+		"POST /api/v1/sessions":               newSessionGuard,
+	})
+	mux.Handle("/api/v1/", middleware.LoadSessionMiddleware()(apiMux))
+
+	// Probes: /healthz while the process serves, /readyz while its
+	// dependencies do too
+	registerHealthChecks(readiness, db, s3Storage)
+	mux.Handle("GET /healthz", health.LiveHandler())
+	mux.Handle("GET /readyz", readiness.ReadyHandler())
+
+	// Prometheus metrics
+	registerThreadGauges(postRepo, archiveRepo, logger)
+	mux.Handle("GET /metrics", metricsHandler(logger))
+
+	// Reports
+	mux.Handle("POST /reports", middleware.InjectSessionMiddleware()(http.HandlerFunc(reportHandler.CreateReport)))
+
+	// Bans
+	mux.Handle("GET /banned", middleware.LoadSessionMiddleware()(templateHandler.RenderBannedPage(banHandler)))
+	mux.Handle("POST /banned/appeal", middleware.LoadSessionMiddleware()(http.HandlerFunc(banHandler.SubmitAppeal)))
+
+	// Staff panel
+	registerModRoutes(mux, modHandler, templateHandler)
+
+	// WebSocket gateway
+	mux.Handle("GET /ws", middleware.LoadSessionMiddleware()(wsHandler))
+
 	// Session routes
-	mux.HandleFunc("POST /sessions/create", sessionHandler.CreateSession)
+	mux.Handle("POST /sessions/create", newSessionGuard(http.HandlerFunc(sessionHandler.CreateSession)))
 	mux.HandleFunc("GET /sessions/{id}", sessionHandler.GetSession)
 	mux.HandleFunc("DELETE /sessions/{id}", sessionHandler.DeleteSession)
-	mux.HandleFunc("GET /sessions", sessionHandler.GetAllSession)
+	mux.Handle("GET /sessions", modHandler.RequireStaff(domain.PermViewSessions)(http.HandlerFunc(sessionHandler.GetAllSession)))
 	mux.HandleFunc("PUT /sessions/{id}", sessionHandler.UpdateSession)
 
-	return mux
+	// Every form and API write needs a CSRF token or, for API clients, the
+	// X-Requested-With header
+	csrfHandler := handler.NewCSRFHandler(csrfSecret(logger), logger)
+	route := routeOf(apiMux, mux, chanMux)
+	observe := chain(tracing.Middleware(route), middleware.AccessLog(logger, route), httpMetrics.Instrument(route))
+	return observe(secure(middleware.ClientIPMiddleware(ipHashSalt(logger), os.Getenv("TRUST_PROXY") == "true")(csrfHandler.Protect(mux))))
+}
+
+// newStorage connects to the Minio instance uploads are kept in.
+func newStorage() (*storage.MinioClient, error) {
+	return storage.NewMinioClient(
+		"minio:9000", // endpoint
+		"minioadmin", // access key
+		"minioadmin", // secret key
+		false,        // useSSL
+	)
+}
+
+// ipHashSalt returns the IP_HASH_SALT secret that poster addresses are hashed
+// with. Without one a random salt is used, so IP bans do not survive a
+// restart.
+func ipHashSalt(logger *slog.Logger) []byte {
+	return envSecret(logger, "IP_HASH_SALT", "IP bans will not survive a restart")
+}
+
+// captchaSecret returns the CAPTCHA_SECRET captcha challenges are signed
+// with. Without one a random secret is used, so challenges only verify on
+// the replica that issued them and not across a restart.
+func captchaSecret(logger *slog.Logger) []byte {
+	return envSecret(logger, "CAPTCHA_SECRET", "captchas will not verify across replicas or restarts")
+}
+
+// csrfSecret returns the CSRF_SECRET form tokens are signed with. Without
+// one a random secret is used, so forms only submit to the replica that
+// rendered them and not across a restart.
+func csrfSecret(logger *slog.Logger) []byte {
+	return envSecret(logger, "CSRF_SECRET", "forms will not submit across replicas or restarts")
+}
+
+// envSecret reads the secret in the named variable, or makes up a random
+// one and warns what that costs.
+func envSecret(logger *slog.Logger, name, without string) []byte {
+	if secret := os.Getenv(name); secret != "" {
+		return []byte(secret)
+	}
+	logger.Warn(name + " is not set; " + without)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic(fmt.Sprintf("failed to generate %s: %v", name, err))
+	}
+	return secret
+}
+
+// allowedOrigins lists the cross-site origins, besides this host, that may
+// open WebSocket connections, from the comma-separated ALLOWED_ORIGINS.
+func allowedOrigins() []string {
+	var origins []string
+	for _, origin := range strings.Split(os.Getenv("ALLOWED_ORIGINS"), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	return origins
 }
@@ -0,0 +1,59 @@
+package app_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"1337b04rd/internal/app"
+	"1337b04rd/internal/domain"
+	"1337b04rd/internal/handler"
+	"1337b04rd/internal/ports"
+	"1337b04rd/internal/service"
+)
+
+// loggedInStaff finds a moderator behind every staff session.
+type loggedInStaff struct {
+	ports.StaffRepository
+}
+
+func (loggedInStaff) FindBySession(ctx context.Context, tokenHash string) (*domain.Staff, error) {
+	return &domain.Staff{ID: 1, Username: "mod", Role: domain.RoleMod}, nil
+}
+
+func TestArchiveRoutesNeedOnlyStaffLogin(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	staff := service.NewStaffService(loggedInStaff{}, nil)
+	mux := http.NewServeMux()
+	app.RegisterArchiveRoutes(mux,
+		handler.NewArchiveHandler(nil, logger),
+		handler.NewModHandler(staff, nil, nil, nil, nil, nil, logger))
+
+	tests := []struct {
+		name    string
+		cookies []*http.Cookie
+		want    int
+	}{
+		{"no login", nil, http.StatusUnauthorized},
+		{"poster session only", []*http.Cookie{{Name: "session_id", Value: "s1"}, {Name: "session_name", Value: "Rick"}}, http.StatusUnauthorized},
+		// An invalid ID is refused by the handler, so the request got past
+		// the middleware without a poster session
+		{"staff login", []*http.Cookie{{Name: "staff_session", Value: "token"}}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/archive/abc", nil)
+			for _, c := range tt.cookies {
+				req.AddCookie(c)
+			}
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("got %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,345 @@
+package app_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"1337b04rd/internal/adapters/openapi"
+	"1337b04rd/internal/app"
+	"1337b04rd/internal/domain"
+	"1337b04rd/internal/handler"
+)
+
+var httpMethods = []string{"get", "post", "put", "patch", "delete"}
+
+type spec struct {
+	Paths      map[string]map[string]operation `json:"paths"`
+	Components struct {
+		Schemas map[string]map[string]interface{} `json:"schemas"`
+	} `json:"components"`
+}
+
+type operation struct {
+	Responses map[string]struct {
+		Content map[string]struct {
+			Schema map[string]interface{} `json:"schema"`
+		} `json:"content"`
+	} `json:"responses"`
+}
+
+func loadSpec(t *testing.T) *spec {
+	t.Helper()
+	var s spec
+	if err := json.Unmarshal(openapi.Spec, &s); err != nil {
+		t.Fatalf("openapi.json is not valid JSON: %v", err)
+	}
+	return &s
+}
+
+func newRouter() http.Handler {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	api := handler.NewAPIHandler(nil, nil, nil, nil, nil, nil, logger)
+	search := handler.NewSearchHandler(nil, logger)
+	return app.NewAPIRouter(api, search, nil)
+}
+
+func TestSpecCoversEveryRoute(t *testing.T) {
+	s := loadSpec(t)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	routes := app.APIRoutes(handler.NewAPIHandler(nil, nil, nil, nil, nil, nil, logger), handler.NewSearchHandler(nil, logger))
+
+	registered := map[string]bool{}
+	for _, r := range routes {
+		registered[r.Method+" "+r.Pattern] = true
+	}
+
+	documented := map[string]bool{}
+	for path, item := range s.Paths {
+		for _, m := range httpMethods {
+			if _, ok := item[m]; ok {
+				documented[strings.ToUpper(m)+" "+path] = true
+			}
+		}
+	}
+
+	for route := range registered {
+		if !documented[route] {
+			t.Errorf("route %s is not documented in openapi.json", route)
+		}
+	}
+	for route := range documented {
+		if !registered[route] {
+			t.Errorf("openapi.json documents %s which is not routed", route)
+		}
+	}
+}
+
+// TestSchemasMatchGoTypes encodes fully populated values and checks that
+// every field is described by the schema and every schema property exists.
+func TestSchemasMatchGoTypes(t *testing.T) {
+	s := loadSpec(t)
+
+	now := time.Now()
+	replyTo := 1
+	commentID := 2
+	comment := domain.Comment{
+		ID: 2, PostID: 1, Title: "t", Content: "c", AuthorID: "a", AuthorName: "n",
+		ImageURL: "/images/comments/x", ReplyToCommentID: &replyTo, CreatedAt: now,
+	}
+	post := domain.Post{
+		ID: 1, Title: "t", Content: "c", AuthorID: "a", AuthorName: "n", ImageURL: "/images/posts/x",
+		Board: "b", Comments: []domain.Comment{comment}, CreatedAt: now, ExpiresAt: now,
+		ReplyCount: 1, LastReplyAt: &now,
+	}
+	archive := domain.Archive{
+		ID: 1, Title: "t", Content: "c", AuthorID: "a", AuthorName: "n", ImageURL: "/images/posts/x",
+		Board: "b", Comments: []domain.Comment{comment}, CreatedAt: now, ExpiredAt: now, ArchivedAt: now,
+		ReplyCount: 1, LastReplyAt: &now,
+	}
+
+	samples := map[string]interface{}{
+		"Board":   domain.Board{Slug: "b", Title: "Random", Description: "d", CreatedAt: now},
+		"Post":    post,
+		"Archive": archive,
+		"Comment": comment,
+		"Session": domain.Session{ID: "1", Name: "Rick", AvatarURL: "u", CreatedAt: now, ExpiresAt: now},
+		"SearchResult": domain.SearchResult{
+			Kind: "comment", Archived: true, PostID: 1, CommentID: &commentID, Board: "b", Title: "t",
+			Snippet: "<mark>t</mark>", ImageURL: "u", CreatedAt: now, Rank: 0.5,
+		},
+		"SearchPage":  domain.SearchPage{Results: []domain.SearchResult{}, Total: 1, Page: 1, PerPage: 20},
+		"ThreadPage":  handler.Page{Data: []domain.Post{post}, NextCursor: "MQ"},
+		"CommentPage": handler.Page{Data: []domain.Comment{comment}, NextCursor: "MQ"},
+		"ArchivePage": handler.Page{Data: []domain.Archive{archive}, NextCursor: "MQ"},
+	}
+
+	for name, sample := range samples {
+		t.Run(name, func(t *testing.T) {
+			schema, ok := s.Components.Schemas[name]
+			if !ok {
+				t.Fatalf("schema %s missing from openapi.json", name)
+			}
+			doc := encode(t, sample)
+			if err := validate(s, schema, doc, name); err != nil {
+				t.Fatal(err)
+			}
+
+			props, _ := resolve(s, schema)["properties"].(map[string]interface{})
+			var missing []string
+			for prop := range props {
+				if _, ok := doc.(map[string]interface{})[prop]; !ok {
+					missing = append(missing, prop)
+				}
+			}
+			sort.Strings(missing)
+			if len(missing) > 0 {
+				t.Fatalf("schema %s documents properties the type does not produce: %v", name, missing)
+			}
+		})
+	}
+}
+
+// TestResponsesMatchSpec drives the router through the paths that do not
+// need storage and checks status codes and bodies against the document.
+func TestResponsesMatchSpec(t *testing.T) {
+	s := loadSpec(t)
+	router := newRouter()
+
+	tests := []struct {
+		method, target, path string
+		wantStatus           int
+	}{
+		{"GET", "/api/v1/threads/abc", "/api/v1/threads/{id}", http.StatusBadRequest},
+		{"GET", "/api/v1/threads/abc/comments", "/api/v1/threads/{id}/comments", http.StatusBadRequest},
+		{"GET", "/api/v1/comments/abc", "/api/v1/comments/{id}", http.StatusBadRequest},
+		{"GET", "/api/v1/archive?cursor=%21", "/api/v1/archive", http.StatusBadRequest},
+		{"POST", "/api/v1/boards/b/threads", "/api/v1/boards/{board}/threads", http.StatusUnauthorized},
+		{"POST", "/api/v1/threads/1/comments", "/api/v1/threads/{id}/comments", http.StatusUnauthorized},
+		{"GET", "/api/v1/sessions/me", "/api/v1/sessions/me", http.StatusUnauthorized},
+		{"DELETE", "/api/v1/sessions/me", "/api/v1/sessions/me", http.StatusUnauthorized},
+		{"POST", "/api/v1/sessions", "/api/v1/sessions", http.StatusBadRequest},
+		{"GET", "/api/v1/search", "/api/v1/search", http.StatusBadRequest},
+		{"GET", "/api/v1/search?q=rick&sort=loudest", "/api/v1/search", http.StatusBadRequest},
+		{"GET", "/api/v1/openapi.json", "/api/v1/openapi.json", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.target, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader("not json"))
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rr.Code, tt.wantStatus, rr.Body.String())
+			}
+
+			op, ok := s.Paths[tt.path][strings.ToLower(tt.method)]
+			if !ok {
+				t.Fatalf("%s %s not documented", tt.method, tt.path)
+			}
+			resp, ok := op.Responses[fmt.Sprint(rr.Code)]
+			if !ok {
+				t.Fatalf("status %d of %s %s not documented", rr.Code, tt.method, tt.path)
+			}
+			media, ok := resp.Content["application/json"]
+			if !ok {
+				return
+			}
+
+			var doc interface{}
+			if err := json.Unmarshal(rr.Body.Bytes(), &doc); err != nil {
+				t.Fatalf("response is not JSON: %v", err)
+			}
+			if err := validate(s, media.Schema, doc, "response"); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+
+	t.Run("unknown route", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/nope", nil))
+		if rr.Code != http.StatusNotFound {
+			t.Fatalf("status = %d, want 404", rr.Code)
+		}
+		var doc interface{}
+		json.Unmarshal(rr.Body.Bytes(), &doc)
+		if err := validate(s, s.Components.Schemas["Error"], doc, "response"); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func encode(t *testing.T, v interface{}) interface{} {
+	t.Helper()
+	raw, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func resolve(s *spec, schema map[string]interface{}) map[string]interface{} {
+	for {
+		ref, ok := schema["$ref"].(string)
+		if !ok {
+			return schema
+		}
+		schema = s.Components.Schemas[strings.TrimPrefix(ref, "#/components/schemas/")]
+	}
+}
+
+// validate checks doc against the subset of JSON Schema used by
+// openapi.json: $ref, type (single or list), enum, properties, required,
+// additionalProperties: false and items.
+func validate(s *spec, schema map[string]interface{}, doc interface{}, path string) error {
+	schema = resolve(s, schema)
+	if schema == nil {
+		return fmt.Errorf("%s: unresolved schema", path)
+	}
+
+	if typ, ok := schema["type"]; ok {
+		var types []string
+		switch typ := typ.(type) {
+		case string:
+			types = []string{typ}
+		case []interface{}:
+			for _, t := range typ {
+				types = append(types, t.(string))
+			}
+		}
+		matched := false
+		for _, typ := range types {
+			if jsonType(doc, typ) {
+				matched = true
+			}
+		}
+		if !matched {
+			return fmt.Errorf("%s: %v is not of type %v", path, doc, types)
+		}
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			if e == doc {
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: %v not in enum %v", path, doc, enum)
+		}
+	}
+
+	switch doc := doc.(type) {
+	case map[string]interface{}:
+		props, _ := schema["properties"].(map[string]interface{})
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, name := range required {
+				if _, ok := doc[name.(string)]; !ok {
+					return fmt.Errorf("%s: missing required property %q", path, name)
+				}
+			}
+		}
+		for name, value := range doc {
+			prop, ok := props[name].(map[string]interface{})
+			if !ok {
+				if extra, ok := schema["additionalProperties"].(bool); ok && !extra {
+					return fmt.Errorf("%s: undocumented property %q", path, name)
+				}
+				continue
+			}
+			if err := validate(s, prop, value, path+"."+name); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range doc {
+				if err := validate(s, items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func jsonType(doc interface{}, typ string) bool {
+	switch typ {
+	case "null":
+		return doc == nil
+	case "object":
+		_, ok := doc.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := doc.([]interface{})
+		return ok
+	case "string":
+		_, ok := doc.(string)
+		return ok
+	case "boolean":
+		_, ok := doc.(bool)
+		return ok
+	case "number":
+		_, ok := doc.(float64)
+		return ok
+	case "integer":
+		n, ok := doc.(float64)
+		return ok && n == math.Trunc(n)
+	}
+	return false
+}
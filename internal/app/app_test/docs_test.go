@@ -0,0 +1,63 @@
+package app_test
+
+import (
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"1337b04rd/pkg/middleware"
+)
+
+var (
+	scriptTag   = regexp.MustCompile(`<script\b[^>]*>`)
+	scriptNonce = regexp.MustCompile(`nonce="([^"]*)"`)
+	scriptSrc   = regexp.MustCompile(`src="([^"]*)"`)
+)
+
+// TestAPIDocsScriptsAllowedByCSP checks that every script of the docs page
+// runs under the policy it is served with.
+func TestAPIDocsScriptsAllowedByCSP(t *testing.T) {
+	router := middleware.SecurityHeaders(middleware.SecurityConfig{})(newRouter())
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/docs", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got %d", rec.Code)
+	}
+
+	var sources []string
+	for _, directive := range strings.Split(rec.Header().Get("Content-Security-Policy"), ";") {
+		if fields := strings.Fields(directive); len(fields) > 0 && fields[0] == "script-src" {
+			sources = fields[1:]
+		}
+	}
+	if len(sources) == 0 {
+		t.Fatal("no script-src in the policy")
+	}
+	allowed := func(source string) bool {
+		for _, s := range sources {
+			if s == source {
+				return true
+			}
+		}
+		return false
+	}
+
+	scripts := scriptTag.FindAllString(rec.Body.String(), -1)
+	if len(scripts) == 0 {
+		t.Fatal("docs page has no script")
+	}
+	for _, tag := range scripts {
+		// Browsers decode attribute values, so the nonce's "+" may be
+		// written as "&#43;".
+		if m := scriptNonce.FindStringSubmatch(tag); m != nil && m[1] != "" && allowed("'nonce-"+html.UnescapeString(m[1])+"'") {
+			continue
+		}
+		if m := scriptSrc.FindStringSubmatch(tag); m != nil && strings.HasPrefix(m[1], "/") && allowed("'self'") {
+			continue
+		}
+		t.Errorf("%s is blocked by script-src %s", tag, strings.Join(sources, " "))
+	}
+}
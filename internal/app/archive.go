@@ -0,0 +1,18 @@
+package app
+
+import (
+	"net/http"
+
+	"1337b04rd/internal/domain"
+	"1337b04rd/internal/handler"
+)
+
+// RegisterArchiveRoutes adds the staff's archive maintenance to mux. It
+// acts on threads, not on the staff member's poster session, so a staff
+// login is all it asks for.
+func RegisterArchiveRoutes(mux *http.ServeMux, archiveHandler *handler.ArchiveHandler, modHandler *handler.ModHandler) {
+	requireMaintenance := modHandler.RequireStaff(domain.PermMaintenance)
+	mux.Handle("POST /archive/{id}", requireMaintenance(http.HandlerFunc(archiveHandler.ArchivePost)))
+	mux.Handle("POST /archive/{id}/restore", requireMaintenance(http.HandlerFunc(archiveHandler.RestorePost)))
+	mux.Handle("POST /archive-expired-posts", requireMaintenance(http.HandlerFunc(archiveHandler.ArchiveExpired)))
+}
@@ -0,0 +1,23 @@
+package app
+
+import (
+	"net/http"
+
+	"1337b04rd/internal/handler"
+)
+
+// newChanMux routes the read-only 4chan-compatible API. Its paths start
+// with a board slug, so they would clash with the board's own routes in
+// one mux; the home route hands them over instead. A board named like one
+// of those routes, such as "posts", is shadowed by it.
+func newChanMux(chanHandler *handler.ChanHandler) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /boards.json", chanHandler.Boards)
+	mux.HandleFunc("GET /{board}/catalog.json", chanHandler.Catalog)
+	mux.HandleFunc("GET /{board}/threads.json", chanHandler.Threads)
+	mux.HandleFunc("GET /{board}/archive.json", chanHandler.Archive)
+	// A wildcard cannot share its segment, so Thread strips the .json
+	mux.HandleFunc("GET /{board}/thread/{id}", chanHandler.Thread)
+	mux.HandleFunc("GET /{board}/{file}", chanHandler.Image)
+	return mux
+}
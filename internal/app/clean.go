@@ -2,19 +2,26 @@ package app
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"time"
 
+	"1337b04rd/internal/domain"
 	"1337b04rd/internal/service"
 )
 
 type CleanupService struct {
-	postService service.PostService
+	postService    service.PostService
+	captchaService *service.CaptchaService
+	auditService   *service.AuditService
+	logger         *slog.Logger
 }
 
-func NewCleanupService(postService service.PostService) *CleanupService {
+func NewCleanupService(postService service.PostService, captchaService *service.CaptchaService, auditService *service.AuditService, logger *slog.Logger) *CleanupService {
 	return &CleanupService{
-		postService: postService,
+		postService:    postService,
+		captchaService: captchaService,
+		auditService:   auditService,
+		logger:         logger,
 	}
 }
 
@@ -26,7 +33,13 @@ func (cs *CleanupService) StartCleanupTask() {
 		for {
 			<-ticker.C
 			if err := cs.postService.DeleteExpiredPost(context.Background()); err != nil {
-				log.Println("Error deleting expired posts:", err)
+				cs.logger.Error("Failed to delete expired posts", "error", err)
+				// Expired threads are logged one by one; a failed run is
+				// logged so staff can tell why threads outlived their time.
+				cs.auditService.Record(context.Background(), domain.NewModAction(domain.ActionCleanupRun, domain.TargetJob, "cleanup", "", err.Error(), nil, nil))
+			}
+			if _, err := cs.captchaService.Prune(context.Background()); err != nil {
+				cs.logger.Error("Failed to prune used captchas", "error", err)
 			}
 		}
 	}()
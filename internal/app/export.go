@@ -0,0 +1,33 @@
+package app
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	"1337b04rd/internal/adapters/export"
+	"1337b04rd/internal/repository"
+)
+
+// Where the export finds its templates and the board's static files,
+// relative to the working directory like the board's own.
+const (
+	exportTemplates = "internal/adapters/frontend/templates/export"
+	exportStatic    = "internal/adapters/frontend/static"
+)
+
+// NewArchiveExporter wires an export of the archive for the archive export
+// command.
+func NewArchiveExporter(db *sql.DB, logger *slog.Logger, opts export.Options) (*export.Exporter, error) {
+	s3Storage, err := newStorage()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize Minio client: %w", err)
+	}
+	if opts.Templates == "" {
+		opts.Templates = exportTemplates
+	}
+	if opts.Static == "" {
+		opts.Static = exportStatic
+	}
+	return export.New(repository.NewArchiveRepository(db), s3Storage, opts, logger)
+}
@@ -0,0 +1,24 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+
+	dbadapter "1337b04rd/internal/adapters/db"
+	"1337b04rd/internal/adapters/externalapi"
+	"1337b04rd/internal/adapters/storage"
+	"1337b04rd/pkg/health"
+)
+
+// registerHealthChecks adds the dependencies /readyz reports on. The
+// avatar API is optional: while it is down only new sessions fail, and
+// every replica shares the outage, so taking them out of rotation would
+// not help.
+func registerHealthChecks(readiness *health.Checker, db *sql.DB, store *storage.MinioClient) {
+	readiness.Register("database", db.PingContext)
+	readiness.Register("schema", func(ctx context.Context) error {
+		return dbadapter.CheckSchema(ctx, db)
+	})
+	readiness.Register("storage", store.Ping)
+	readiness.RegisterOptional("avatars", externalapi.NewRickAndMortyClient().Ping)
+}
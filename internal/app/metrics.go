@@ -0,0 +1,80 @@
+package app
+
+import (
+	"context"
+	"crypto/subtle"
+	"log/slog"
+	"math"
+	"net/http"
+	"os"
+	"time"
+
+	"1337b04rd/pkg/metrics"
+)
+
+// metricsScrapeTimeout bounds the queries behind the thread gauges.
+const metricsScrapeTimeout = 2 * time.Second
+
+var httpMetrics = metrics.NewHTTPMetrics(metrics.Default)
+
+// threadCounter is a repository that can count its threads.
+type threadCounter interface {
+	Count(ctx context.Context) (int, error)
+}
+
+// registerThreadGauges counts live and archived threads on every scrape.
+func registerThreadGauges(live, archived threadCounter, logger *slog.Logger) {
+	gauge := func(name, help string, threads threadCounter) {
+		metrics.NewGaugeFunc(name, help, func() float64 {
+			ctx, cancel := context.WithTimeout(context.Background(), metricsScrapeTimeout)
+			defer cancel()
+			n, err := threads.Count(ctx)
+			if err != nil {
+				logger.Error("Failed to count threads for metrics", "metric", name, "error", err)
+				return math.NaN()
+			}
+			return float64(n)
+		})
+	}
+	gauge("board_live_threads", "Threads on the boards.", live)
+	gauge("board_archived_threads", "Threads in the archive.", archived)
+}
+
+// routeOf names requests after the pattern they match, trying the muxes in
+// order so the API's own routes win over its mount point. The catch-all "/"
+// only names requests no later mux matches, as it hands some on to them.
+func routeOf(muxes ...*http.ServeMux) func(*http.Request) string {
+	return func(r *http.Request) string {
+		catchAll := ""
+		for _, mux := range muxes {
+			switch _, pattern := mux.Handler(r); pattern {
+			case "":
+			case "/":
+				catchAll = pattern
+			default:
+				return pattern
+			}
+		}
+		return catchAll
+	}
+}
+
+// metricsHandler serves the metrics to Prometheus. With METRICS_TOKEN set
+// scrapers must send it as a bearer token; without it the metrics are
+// public, which only suits a board behind a private network.
+func metricsHandler(logger *slog.Logger) http.Handler {
+	token := os.Getenv("METRICS_TOKEN")
+	if token == "" {
+		logger.Warn("METRICS_TOKEN is not set; /metrics is public")
+		return metrics.Default.Handler()
+	}
+	want := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		metrics.Default.Handler().ServeHTTP(w, r)
+	})
+}
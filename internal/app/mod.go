@@ -0,0 +1,50 @@
+package app
+
+import (
+	"net/http"
+
+	"1337b04rd/internal/domain"
+	"1337b04rd/internal/handler"
+)
+
+// registerModRoutes mounts the staff panel under /mod. Every page and action
+// requires a staff login holding the listed permission.
+func registerModRoutes(mux *http.ServeMux, mod *handler.ModHandler, templates *handler.TemplateHandler) {
+	require := func(perm domain.Permission, h http.Handler) http.Handler {
+		return mod.RequireStaff(perm)(h)
+	}
+
+	mux.Handle("GET /mod/login", templates.RenderModLoginPage())
+	mux.HandleFunc("POST /mod/login", mod.Login)
+	mux.HandleFunc("POST /mod/logout", mod.Logout)
+
+	mux.Handle("GET /mod", require(domain.PermViewQueue, templates.RenderModPage(mod)))
+	mux.Handle("GET /mod/threads/{id}", require(domain.PermViewQueue, templates.RenderModThreadPage(mod)))
+	mux.Handle("GET /mod/reports", require(domain.PermViewQueue, templates.RenderModReportsPage(mod)))
+	mux.Handle("POST /mod/reports/resolve", require(domain.PermViewQueue, http.HandlerFunc(mod.ResolveReport)))
+	mux.Handle("GET /mod/log", require(domain.PermViewQueue, templates.RenderModLogPage(mod)))
+
+	mux.Handle("POST /mod/threads/{id}/delete", require(domain.PermDeletePost, http.HandlerFunc(mod.DeleteThread)))
+	mux.Handle("POST /mod/threads/{id}/delete-file", require(domain.PermDeleteFile, http.HandlerFunc(mod.DeleteThreadFile)))
+	mux.Handle("POST /mod/threads/{id}/sticky", require(domain.PermSticky, http.HandlerFunc(mod.StickyThread)))
+	mux.Handle("POST /mod/threads/{id}/lock", require(domain.PermLock, http.HandlerFunc(mod.LockThread)))
+	mux.Handle("POST /mod/threads/{id}/move", require(domain.PermMove, http.HandlerFunc(mod.MoveThread)))
+	mux.Handle("POST /mod/comments/{id}/delete", require(domain.PermDeletePost, http.HandlerFunc(mod.DeleteComment)))
+	mux.Handle("POST /mod/comments/{id}/delete-file", require(domain.PermDeleteFile, http.HandlerFunc(mod.DeleteCommentFile)))
+
+	mux.Handle("POST /mod/threads/{id}/ban", require(domain.PermBan, http.HandlerFunc(mod.BanThreadAuthor)))
+	mux.Handle("POST /mod/comments/{id}/ban", require(domain.PermBan, http.HandlerFunc(mod.BanCommentAuthor)))
+	mux.Handle("GET /mod/bans", require(domain.PermBan, templates.RenderModBansPage(mod)))
+	mux.Handle("POST /mod/bans", require(domain.PermBan, http.HandlerFunc(mod.CreateRangeBan)))
+	mux.Handle("POST /mod/bans/{id}/lift", require(domain.PermBan, http.HandlerFunc(mod.LiftBan)))
+	mux.Handle("GET /mod/appeals", require(domain.PermBan, templates.RenderModAppealsPage(mod)))
+	mux.Handle("POST /mod/appeals/{id}", require(domain.PermBan, http.HandlerFunc(mod.DecideAppeal)))
+
+	mux.Handle("GET /mod/filters", require(domain.PermFilters, templates.RenderModFiltersPage(mod)))
+	mux.Handle("POST /mod/filters", require(domain.PermFilters, http.HandlerFunc(mod.CreateFilter)))
+	mux.Handle("POST /mod/filters/{id}", require(domain.PermFilters, http.HandlerFunc(mod.SetFilter)))
+	mux.Handle("POST /mod/filters/{id}/delete", require(domain.PermFilters, http.HandlerFunc(mod.DeleteFilter)))
+
+	mux.Handle("GET /mod/staff", require(domain.PermManageStaff, templates.RenderModStaffPage(mod)))
+	mux.Handle("POST /mod/staff", require(domain.PermManageStaff, http.HandlerFunc(mod.CreateStaff)))
+}
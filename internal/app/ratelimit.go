@@ -0,0 +1,49 @@
+package app
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+
+	"1337b04rd/internal/adapters/ratelimit"
+	"1337b04rd/internal/domain"
+	"1337b04rd/internal/ports"
+)
+
+// newRateLimiter keeps the buckets in memory or, with
+// RATE_LIMIT_BACKEND=postgres, in the database shared by every replica.
+func newRateLimiter(db *sql.DB, logger *slog.Logger) ports.RateLimiter {
+	if os.Getenv("RATE_LIMIT_BACKEND") == "postgres" {
+		return ratelimit.NewPostgresLimiter(db, logger)
+	}
+	return ratelimit.NewMemoryLimiter()
+}
+
+// rateLimitRules starts from the defaults and applies any RATE_LIMIT_<NAME>
+// override, such as RATE_LIMIT_REPLY=3/10s or RATE_LIMIT_UPLOAD=off.
+func rateLimitRules() map[string]domain.RateLimitRule {
+	rules := make(map[string]domain.RateLimitRule, len(domain.DefaultRateLimitRules))
+	for name, rule := range domain.DefaultRateLimitRules {
+		if spec := os.Getenv("RATE_LIMIT_" + strings.ToUpper(name)); spec != "" {
+			var err error
+			if rule, err = domain.ParseRateLimitRule(name, spec); err != nil {
+				panic(fmt.Sprintf("failed to read rate limits: %v", err))
+			}
+		}
+		rules[name] = rule
+	}
+	return rules
+}
+
+// chain applies guards in order, the first one outermost.
+func chain(guards ...func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		for i := len(guards) - 1; i >= 0; i-- {
+			h = guards[i](h)
+		}
+		return h
+	}
+}
@@ -0,0 +1,59 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"1337b04rd/pkg/middleware"
+)
+
+// securityConfig allows the fonts and scripts the templates load from CDNs.
+// CSP_REPORT_ONLY=true only reports violations, HSTS_MAX_AGE (such as 8760h)
+// turns on Strict-Transport-Security and MEDIA_ORIGIN allows images from the
+// media origin.
+func securityConfig(media *url.URL) middleware.SecurityConfig {
+	cfg := middleware.SecurityConfig{
+		ScriptSources: []string{"https://cdn.jsdelivr.net"},
+		StyleSources:  []string{"https://fonts.googleapis.com"},
+		FontSources:   []string{"https://fonts.gstatic.com"},
+		ReportOnly:    os.Getenv("CSP_REPORT_ONLY") == "true",
+	}
+	if maxAge := os.Getenv("HSTS_MAX_AGE"); maxAge != "" {
+		d, err := time.ParseDuration(maxAge)
+		if err != nil {
+			panic(fmt.Sprintf("failed to read HSTS_MAX_AGE: %v", err))
+		}
+		cfg.HSTSMaxAge = d
+	}
+	if media != nil {
+		cfg.ImageSources = []string{media.Scheme + "://" + media.Host}
+	}
+	return cfg
+}
+
+// mediaOrigin returns the MEDIA_ORIGIN uploads are served from, such as
+// https://media.example.org, or nil to serve them from the board's origin.
+func mediaOrigin() *url.URL {
+	origin := os.Getenv("MEDIA_ORIGIN")
+	if origin == "" {
+		return nil
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		panic(fmt.Sprintf("failed to read MEDIA_ORIGIN: %q is not an origin", origin))
+	}
+	return u
+}
+
+// secure wraps the whole app in the security headers and, with a media
+// origin, keeps uploads and pages apart.
+func secure(h http.Handler) http.Handler {
+	media := mediaOrigin()
+	if media != nil {
+		h = middleware.MediaOrigin(media, "/images/")(h)
+	}
+	return middleware.SecurityHeaders(securityConfig(media))(h)
+}
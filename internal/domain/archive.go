@@ -5,16 +5,22 @@ import (
 )
 
 type Archive struct {
-	ID         int       `json:"id"`
-	Title      string    `json:"title"`
-	Content    string    `json:"content"`
-	AuthorID   string    `json:"author_id"`
-	AuthorName string    `json:"author_name"`
-	ImageURL   string    `json:"image_url"`
-	Comments   []Comment `json:"comments"`
-	CreatedAt  time.Time `json:"created_at"`
-	ExpiredAt  time.Time `json:"expires_at"`
-	ArchivedAt time.Time `json:"archived_at"`
+	ID         int    `json:"id"`
+	Title      string `json:"title"`
+	Content    string `json:"content"`
+	AuthorID   string `json:"-"`
+	AuthorName string `json:"author_name"`
+	ImageURL   string `json:"image_url"`
+	// ImageSourceURL is where an image posted by URL was fetched from.
+	ImageSourceURL string    `json:"image_source_url,omitempty"`
+	Board          string    `json:"board"`
+	Comments       []Comment `json:"comments"`
+	CreatedAt      time.Time `json:"created_at"`
+	ExpiredAt      time.Time `json:"expires_at"`
+	ArchivedAt     time.Time `json:"archived_at"`
+
+	ReplyCount  int        `json:"reply_count"`
+	LastReplyAt *time.Time `json:"last_reply_at,omitempty"`
 }
 
 func NewArchive(title, content string, authorID string, image_url string, createdAt time.Time, expiredAt time.Time, comments []Comment) (*Archive, error) {
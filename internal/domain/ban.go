@@ -0,0 +1,107 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// MaxAppealLength caps the text of a ban appeal.
+const MaxAppealLength = 2000
+
+// Ban keeps a poster from creating threads and comments. It matches by
+// session, by salted IP hash or by CIDR range; any one of them is enough.
+// An empty Board bans from every board and a nil ExpiresAt never expires.
+type Ban struct {
+	ID        int        `json:"id"`
+	SessionID string     `json:"-"`
+	IPHash    string     `json:"-"`
+	CIDR      string     `json:"cidr,omitempty"`
+	Board     string     `json:"board,omitempty"`
+	Reason    string     `json:"reason"`
+	StaffID   int        `json:"staff_id"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// Appeal is filled in for the banned poster's own page.
+	Appeal *BanAppeal `json:"appeal,omitempty"`
+}
+
+// NewBan bans for duration, or permanently when duration is zero.
+func NewBan(sessionID, ipHash, cidr, board, reason string, staffID int, duration time.Duration) (*Ban, error) {
+	if sessionID == "" && ipHash == "" && cidr == "" {
+		return nil, ErrInvalidBanTarget
+	}
+	if strings.TrimSpace(reason) == "" {
+		return nil, ErrEmptyBanReason
+	}
+	if duration < 0 {
+		return nil, ErrInvalidParam
+	}
+
+	now := time.Now()
+	ban := &Ban{
+		SessionID: sessionID,
+		IPHash:    ipHash,
+		CIDR:      cidr,
+		Board:     board,
+		Reason:    strings.TrimSpace(reason),
+		StaffID:   staffID,
+		CreatedAt: now,
+	}
+	if duration > 0 {
+		expires := now.Add(duration)
+		ban.ExpiresAt = &expires
+	}
+	return ban, nil
+}
+
+func (b *Ban) Permanent() bool {
+	return b.ExpiresAt == nil
+}
+
+// Global reports whether the ban applies to every board.
+func (b *Ban) Global() bool {
+	return b.Board == ""
+}
+
+type AppealStatus string
+
+const (
+	AppealPending  AppealStatus = "pending"
+	AppealAccepted AppealStatus = "accepted"
+	AppealDenied   AppealStatus = "denied"
+)
+
+func (s AppealStatus) Valid() bool {
+	return s == AppealPending || s == AppealAccepted || s == AppealDenied
+}
+
+// BanAppeal is the single appeal a banned poster may submit per ban.
+type BanAppeal struct {
+	ID        int          `json:"id"`
+	BanID     int          `json:"ban_id"`
+	Message   string       `json:"message"`
+	Status    AppealStatus `json:"status"`
+	CreatedAt time.Time    `json:"created_at"`
+	DecidedAt *time.Time   `json:"decided_at,omitempty"`
+	DecidedBy *int         `json:"decided_by,omitempty"`
+
+	// Ban is filled in for the staff queue.
+	Ban *Ban `json:"ban,omitempty"`
+}
+
+func NewBanAppeal(banID int, message string) (*BanAppeal, error) {
+	message = strings.TrimSpace(message)
+	if message == "" {
+		return nil, ErrEmptyAppeal
+	}
+	if len(message) > MaxAppealLength {
+		return nil, ErrAppealTooLong
+	}
+	return &BanAppeal{
+		BanID:     banID,
+		Message:   message,
+		Status:    AppealPending,
+		CreatedAt: time.Now(),
+	}, nil
+}
@@ -0,0 +1,18 @@
+package domain
+
+import (
+	"time"
+)
+
+// DefaultBoard is the board threads are posted to when none is given.
+const DefaultBoard = "b"
+
+// Board is a topic threads are posted to. CaptchaDifficulty is the proof of
+// work posting asks for, in leading zero bits; 0 switches the captcha off.
+type Board struct {
+	Slug              string    `json:"slug"`
+	Title             string    `json:"title"`
+	Description       string    `json:"description"`
+	CaptchaDifficulty int       `json:"captcha_difficulty"`
+	CreatedAt         time.Time `json:"created_at"`
+}
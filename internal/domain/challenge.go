@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"math/bits"
+	"strconv"
+	"time"
+)
+
+const (
+	// DefaultCaptchaDifficulty is the number of leading zero bits a
+	// solution needs on a board that does not set its own.
+	DefaultCaptchaDifficulty = 16
+	// MaxCaptchaDifficulty caps the difficulty however busy a board gets.
+	MaxCaptchaDifficulty = 24
+	// ChallengeTTL is how long a challenge can be solved and used.
+	ChallengeTTL = 10 * time.Minute
+)
+
+// Posting surges raise the difficulty by a bit each time the posts a board
+// got within CaptchaSurgeWindow double past CaptchaSurgeThreshold.
+const (
+	CaptchaSurgeWindow    = time.Minute
+	CaptchaSurgeThreshold = 10
+)
+
+// Challenge is a hashcash-style proof of work. The client looks for a nonce
+// such that SHA-256(Token + ":" + nonce) starts with Difficulty zero bits.
+// Token is signed by the server and carries everything else.
+type Challenge struct {
+	ID         string    `json:"id"`
+	Board      string    `json:"board"`
+	Difficulty int       `json:"difficulty"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Token      string    `json:"token"`
+}
+
+// Solved reports whether nonce solves the challenge.
+func (c *Challenge) Solved(nonce string) bool {
+	sum := sha256.Sum256([]byte(c.Token + ":" + nonce))
+	return LeadingZeroBits(sum[:]) >= c.Difficulty
+}
+
+// Solve finds a nonce for the challenge by brute force, the way the
+// browser does.
+func (c *Challenge) Solve() string {
+	for n := 0; ; n++ {
+		nonce := strconv.Itoa(n)
+		if c.Solved(nonce) {
+			return nonce
+		}
+	}
+}
+
+func LeadingZeroBits(sum []byte) int {
+	n := 0
+	for _, b := range sum {
+		if b != 0 {
+			return n + bits.LeadingZeros8(b)
+		}
+		n += 8
+	}
+	return n
+}
@@ -9,11 +9,20 @@ type Comment struct {
 	PostID           int       `json:"post_id"`
 	Title            string    `json:"title"`
 	Content          string    `json:"content"`
-	AuthorID         string    `json:"author_id"`
 	AuthorName       string    `json:"author_name"`
 	ImageURL         string    `json:"image_url"`
 	ReplyToCommentID *int      `json:"reply_to_comment_id,omitempty"`
 	CreatedAt        time.Time `json:"created_at"`
+
+	// ImageSourceURL is where an image posted by URL was fetched from.
+	ImageSourceURL string `json:"image_source_url,omitempty"`
+
+	// IPHash is the salted hash of the poster's address, kept for bans.
+	IPHash string `json:"-"`
+
+	// AuthorID is the poster's session ID, which authenticates them, so it
+	// never leaves the server.
+	AuthorID string `json:"-"`
 }
 
 type ArchivedComment struct {
@@ -21,7 +30,7 @@ type ArchivedComment struct {
 	PostID           int       `json:"post_id"`
 	Title            string    `json:"title"`
 	Content          string    `json:"content"`
-	AuthorID         string    `json:"author_id"`
+	AuthorID         string    `json:"-"`
 	AuthorName       string    `json:"author_name"`
 	ImageURL         string    `json:"image_url"`
 	CreatedAt        time.Time `json:"created_at"`
@@ -27,18 +27,109 @@ var (
 	ErrInvalidSessionID = errors.New("invalid session ID")
 )
 
+// thread state errors
+var (
+	ErrThreadLocked = errors.New("thread is locked")
+)
+
+// staff errors
+var (
+	ErrStaffNotFound       = errors.New("staff member not found")
+	ErrStaffExists         = errors.New("staff username already taken")
+	ErrInvalidCredentials  = errors.New("invalid username or password")
+	ErrInvalidStaffRole    = errors.New("invalid staff role")
+	ErrWeakPassword        = errors.New("password is too short")
+	ErrInvalidStaffSession = errors.New("staff login required")
+)
+
+// ban errors
+var (
+	ErrBanned           = errors.New("you are banned from posting")
+	ErrBanCheckFailed   = errors.New("unable to check bans, try again later")
+	ErrBanNotFound      = errors.New("ban not found")
+	ErrInvalidBanTarget = errors.New("a ban needs a session, an IP or a CIDR range")
+	ErrEmptyBanReason   = errors.New("ban reason cannot be empty")
+	ErrEmptyAppeal      = errors.New("appeal cannot be empty")
+	ErrAppealTooLong    = errors.New("appeal is too long")
+	ErrAppealExists     = errors.New("this ban has already been appealed")
+	ErrAppealNotFound   = errors.New("appeal not found")
+)
+
+// report errors
+var (
+	ErrInvalidReportTarget   = errors.New("invalid report target")
+	ErrInvalidReportCategory = errors.New("invalid report category")
+	ErrInvalidResolution     = errors.New("invalid report resolution")
+	ErrReportNoteTooLong     = errors.New("report note is too long")
+	ErrAlreadyReported       = errors.New("you already reported this")
+	ErrReportRateLimited     = errors.New("too many reports, try again later")
+)
+
+// captcha errors
+var (
+	ErrCaptchaRequired = errors.New("solve the captcha before posting")
+	ErrCaptchaInvalid  = errors.New("captcha solution is invalid")
+	ErrCaptchaExpired  = errors.New("captcha has expired, solve a new one")
+	ErrCaptchaUsed     = errors.New("captcha was already used")
+)
+
+// request forgery errors
+var (
+	ErrInvalidCSRFToken = errors.New("invalid or missing CSRF token, reload the page and try again")
+)
+
+// rate limit errors
+var (
+	ErrRateLimited      = errors.New("too many requests, slow down")
+	ErrInvalidRateLimit = errors.New("invalid rate limit rule")
+)
+
+// board errors
+var (
+	ErrBoardNotFound = errors.New("board not found")
+)
+
+// live update errors
+var (
+	ErrInvalidTopic          = errors.New("invalid subscription topic")
+	ErrTooManySubscriptions  = errors.New("too many subscriptions")
+	ErrTooManyConnections    = errors.New("too many connections for this session")
+	ErrOriginNotAllowed      = errors.New("origin not allowed")
+	ErrSubscriptionOverflown = errors.New("subscriber fell behind, resubscribe with last_event_id")
+)
+
+// search errors
+var (
+	ErrEmptySearchQuery  = errors.New("search query cannot be empty")
+	ErrInvalidSearchSort = errors.New("invalid search sort")
+	ErrInvalidDateRange  = errors.New("invalid date range")
+)
+
 // s3 errors
 var (
 	ErrStorageUploadFailed = errors.New("failed to upload")
 	ErrStorageDeleteFailed = errors.New("failed to delete from storage")
+	ErrImageNotFound       = errors.New("image not found")
 	ErrDatabaseError       = errors.New("database operation failed")
 )
 
+// remote fetch errors
+var (
+	ErrFetchFailed      = errors.New("failed to fetch the remote file")
+	ErrFetchBlocked     = errors.New("remote address is not allowed")
+	ErrFetchTooLarge    = errors.New("remote file is too large")
+	ErrFetchContentType = errors.New("remote file has an unsupported content type")
+	ErrImageAndImageURL = errors.New("attach an image or give an image URL, not both")
+)
+
 // validation errors
 var (
 	ErrMissingID       = errors.New("missing ID")
 	ErrInvalidIDFormat = errors.New("invalid ID format")
 	ErrUnauthorized    = errors.New("unauthorized action")
+	ErrInvalidCursor   = errors.New("invalid cursor")
+	ErrInvalidParam    = errors.New("invalid parameter")
+	ErrInvalidBody     = errors.New("invalid request body")
 )
 
 const (
@@ -169,3 +260,12 @@ const (
 	StatusNetworkAuthenticationRequired = 511 // RFC 6585, 6
 
 )
+
+// content filter errors
+var (
+	ErrContentRejected      = errors.New("post rejected by the content filter")
+	ErrFilterNotFound       = errors.New("filter not found")
+	ErrInvalidFilter        = errors.New("invalid filter")
+	ErrInvalidFilterPattern = errors.New("filter pattern is not a valid regular expression")
+	ErrEmptyFilterReason    = errors.New("filter reason cannot be empty")
+)
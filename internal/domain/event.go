@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"time"
+)
+
+type EventType string
+
+const (
+	EventThread   EventType = "thread"
+	EventReply    EventType = "reply"
+	EventBump     EventType = "bump"
+	EventDeleted  EventType = "deleted"
+	EventArchived EventType = "archived"
+)
+
+// Event describes a change to a thread that live readers are notified about.
+// ID is assigned by the publisher and increases monotonically, so clients can
+// resume with the last ID they saw. Thread events carry the new Post, reply
+// events the new Comment.
+type Event struct {
+	ID        int64     `json:"id"`
+	Type      EventType `json:"type"`
+	PostID    int       `json:"post_id"`
+	Board     string    `json:"board,omitempty"`
+	Post      *Post     `json:"post,omitempty"`
+	Comment   *Comment  `json:"comment,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func NewEvent(eventType EventType, postID int, board string, comment *Comment) Event {
+	return Event{
+		Type:      eventType,
+		PostID:    postID,
+		Board:     board,
+		Comment:   comment,
+		CreatedAt: time.Now(),
+	}
+}
+
+// NewThreadEvent announces post to catalog readers. The thread is sent
+// without its comments, which a new thread does not have anyway.
+func NewThreadEvent(post *Post) Event {
+	summary := *post
+	summary.Comments = nil
+	event := NewEvent(EventThread, post.ID, post.Board, nil)
+	event.Post = &summary
+	return event
+}
+
+// IsCatalogEvent reports whether e changes a board catalog, as opposed to
+// only the contents of a thread.
+func (e Event) IsCatalogEvent() bool {
+	switch e.Type {
+	case EventThread, EventBump, EventDeleted, EventArchived:
+		return true
+	}
+	return false
+}
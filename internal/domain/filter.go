@@ -0,0 +1,237 @@
+package domain
+
+import (
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// FilterReloadInterval is how long a replica trusts its cached filters
+// before reading them again, so edits made elsewhere take effect.
+const FilterReloadInterval = 30 * time.Second
+
+// Duplicate detection looks this far back and ignores short texts, which
+// are too often repeated in good faith ("this", "bump").
+const (
+	DuplicateWindow    = time.Hour
+	MinDuplicateLength = 20
+)
+
+// MinCapsLetters is how many letters a text needs before the caps filter
+// looks at it.
+const MinCapsLetters = 20
+
+type FilterKind string
+
+const (
+	// FilterPattern matches a regular expression.
+	FilterPattern FilterKind = "pattern"
+	// FilterLiteral matches a word or phrase, ignoring case.
+	FilterLiteral FilterKind = "literal"
+	// FilterLinks matches texts with more than Threshold links.
+	FilterLinks FilterKind = "links"
+	// FilterDuplicate matches texts already posted Threshold times within
+	// DuplicateWindow.
+	FilterDuplicate FilterKind = "duplicate"
+	// FilterCaps matches texts of which more than Threshold percent of the
+	// letters are capitals.
+	FilterCaps FilterKind = "caps"
+	// FilterFlood matches texts repeating one character, or one line, more
+	// than Threshold times in a row.
+	FilterFlood FilterKind = "flood"
+)
+
+// FilterKinds lists the kinds in the order they are offered.
+var FilterKinds = []FilterKind{FilterPattern, FilterLiteral, FilterLinks, FilterDuplicate, FilterCaps, FilterFlood}
+
+func (k FilterKind) Valid() bool {
+	for _, valid := range FilterKinds {
+		if k == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches reports whether the kind looks for a pattern, as opposed to a
+// heuristic with a threshold.
+func (k FilterKind) Matches() bool {
+	return k == FilterPattern || k == FilterLiteral
+}
+
+type FilterAction string
+
+const (
+	FilterReplace FilterAction = "replace"
+	FilterReject  FilterAction = "reject"
+	FilterBan     FilterAction = "ban"
+)
+
+// FilterActions lists the actions in the order they are offered.
+var FilterActions = []FilterAction{FilterReplace, FilterReject, FilterBan}
+
+func (a FilterAction) Valid() bool {
+	return a == FilterReplace || a == FilterReject || a == FilterBan
+}
+
+// Filter is a content rule checked against new threads and comments. An
+// empty Board applies it everywhere. In dry run a match is only logged.
+type Filter struct {
+	ID          int          `json:"id"`
+	Board       string       `json:"board,omitempty"`
+	Kind        FilterKind   `json:"kind"`
+	Pattern     string       `json:"pattern,omitempty"`
+	Replacement string       `json:"replacement,omitempty"`
+	Threshold   int          `json:"threshold,omitempty"`
+	Action      FilterAction `json:"action"`
+	BanHours    int          `json:"ban_hours,omitempty"`
+	Reason      string       `json:"reason"`
+	Enabled     bool         `json:"enabled"`
+	DryRun      bool         `json:"dry_run"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+// NewFilter validates a rule. Only pattern and literal rules can replace,
+// and only heuristics take a threshold. A ban of zero hours is permanent.
+func NewFilter(board string, kind FilterKind, pattern, replacement string, threshold int, action FilterAction, banHours int, reason string, dryRun bool) (*Filter, error) {
+	if !kind.Valid() || !action.Valid() {
+		return nil, ErrInvalidFilter
+	}
+	if strings.TrimSpace(reason) == "" {
+		return nil, ErrEmptyFilterReason
+	}
+	if banHours < 0 || threshold < 0 {
+		return nil, ErrInvalidFilter
+	}
+
+	f := &Filter{
+		Board:     strings.TrimSpace(board),
+		Kind:      kind,
+		Action:    action,
+		Reason:    strings.TrimSpace(reason),
+		Enabled:   true,
+		DryRun:    dryRun,
+		CreatedAt: time.Now(),
+	}
+	f.UpdatedAt = f.CreatedAt
+	if action == FilterBan {
+		f.BanHours = banHours
+	}
+
+	if kind.Matches() {
+		if pattern == "" {
+			return nil, ErrInvalidFilter
+		}
+		f.Pattern, f.Replacement = pattern, replacement
+		if _, err := f.Compile(); err != nil {
+			return nil, err
+		}
+		return f, nil
+	}
+
+	if action == FilterReplace {
+		return nil, ErrInvalidFilter
+	}
+	if kind == FilterCaps && threshold > 100 {
+		return nil, ErrInvalidFilter
+	}
+	f.Threshold = threshold
+	return f, nil
+}
+
+// AppliesTo reports whether the rule covers board.
+func (f *Filter) AppliesTo(board string) bool {
+	return f.Board == "" || f.Board == board
+}
+
+// Compile returns the expression of a pattern or literal rule, nil for
+// heuristics. Literals match whole words, ignoring case.
+func (f *Filter) Compile() (*regexp.Regexp, error) {
+	switch f.Kind {
+	case FilterPattern:
+		re, err := regexp.Compile(f.Pattern)
+		if err != nil {
+			return nil, ErrInvalidFilterPattern
+		}
+		return re, nil
+	case FilterLiteral:
+		return regexp.MustCompile(`(?i)\b` + regexp.QuoteMeta(f.Pattern) + `\b`), nil
+	}
+	return nil, nil
+}
+
+// linkPattern finds the links CountLinks counts.
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+`)
+
+func CountLinks(text string) int {
+	return len(linkPattern.FindAllStringIndex(text, -1))
+}
+
+// CapsPercent returns the share of capitals among the letters of text, or
+// zero when it has fewer than MinCapsLetters letters.
+func CapsPercent(text string) int {
+	var letters, upper int
+	for _, r := range text {
+		if unicode.IsLetter(r) {
+			letters++
+			if unicode.IsUpper(r) {
+				upper++
+			}
+		}
+	}
+	if letters < MinCapsLetters {
+		return 0
+	}
+	return upper * 100 / letters
+}
+
+// LongestRun returns the most times one character, or one non-blank line,
+// repeats in a row in text. Whitespace runs do not count.
+func LongestRun(text string) int {
+	longest, run := 0, 0
+	var last rune
+	for _, r := range text {
+		if r == last && !unicode.IsSpace(r) {
+			run++
+		} else {
+			run = 1
+		}
+		last = r
+		longest = max(longest, run)
+	}
+
+	run = 0
+	var lastLine string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if line == lastLine {
+			run++
+		} else {
+			run = 1
+		}
+		lastLine = line
+		longest = max(longest, run)
+	}
+	return longest
+}
+
+// NormalizeForDuplicates folds case and whitespace so trivial edits do not
+// hide a repost.
+func NormalizeForDuplicates(text string) string {
+	return strings.Join(strings.Fields(strings.ToLower(text)), " ")
+}
+
+// Submission is a new thread or comment on its way through the filters,
+// which may rewrite its title and content.
+type Submission struct {
+	Board     string
+	SessionID string
+	IPHash    string
+	Title     string
+	Content   string
+}
@@ -0,0 +1,215 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+type ActorType string
+
+const (
+	ActorStaff  ActorType = "staff"
+	ActorSystem ActorType = "system"
+)
+
+// SystemActorName names the scheduler and other unattended jobs in the log.
+const SystemActorName = "system"
+
+type ModActionType string
+
+const (
+	ActionDeleteThread   ModActionType = "delete_thread"
+	ActionDeleteComment  ModActionType = "delete_comment"
+	ActionDeleteFile     ModActionType = "delete_file"
+	ActionSticky         ModActionType = "sticky"
+	ActionLock           ModActionType = "lock"
+	ActionMove           ModActionType = "move"
+	ActionArchive        ModActionType = "archive"
+	ActionRestore        ModActionType = "restore"
+	ActionExpire         ModActionType = "expire"
+	ActionBan            ModActionType = "ban"
+	ActionLiftBan        ModActionType = "lift_ban"
+	ActionDecideAppeal   ModActionType = "decide_appeal"
+	ActionDismissReports ModActionType = "dismiss_reports"
+	ActionCreateStaff    ModActionType = "create_staff"
+	ActionCleanupRun     ModActionType = "cleanup_run"
+	ActionCreateFilter   ModActionType = "create_filter"
+	ActionEditFilter     ModActionType = "edit_filter"
+	ActionDeleteFilter   ModActionType = "delete_filter"
+	ActionFilterMatch    ModActionType = "filter_match"
+)
+
+// Targets of actions that are not posts or comments.
+const (
+	TargetBan    = "ban"
+	TargetRange  = "range"
+	TargetAppeal = "appeal"
+	TargetStaff  = "staff"
+	TargetJob    = "job"
+	TargetFilter = "filter"
+)
+
+// ModAction is one entry of the append-only moderation log. Before and After
+// are JSON snapshots of what changed; either may be empty.
+type ModAction struct {
+	ID         int64           `json:"id"`
+	ActorType  ActorType       `json:"actor_type"`
+	ActorID    int             `json:"actor_id,omitempty"`
+	ActorName  string          `json:"actor_name"`
+	Action     ModActionType   `json:"action"`
+	TargetType string          `json:"target_type"`
+	TargetID   string          `json:"target_id"`
+	Board      string          `json:"board,omitempty"`
+	Reason     string          `json:"reason,omitempty"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// NewModAction builds a log entry; the actor is filled in by whoever records
+// it. before and after are marshalled to JSON unless nil.
+func NewModAction(action ModActionType, targetType string, targetID interface{}, board, reason string, before, after interface{}) *ModAction {
+	return &ModAction{
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   fmt.Sprint(targetID),
+		Board:      board,
+		Reason:     reason,
+		Before:     snapshot(before),
+		After:      snapshot(after),
+		CreatedAt:  time.Now(),
+	}
+}
+
+func snapshot(v interface{}) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// PostSnapshot is what the log keeps of a thread: enough to tell what was
+// removed, without the poster's session.
+func PostSnapshot(p *Post) map[string]interface{} {
+	return map[string]interface{}{
+		"id":          p.ID,
+		"title":       p.Title,
+		"content":     p.Content,
+		"image_url":   p.ImageURL,
+		"board":       p.Board,
+		"author_name": p.AuthorName,
+		"sticky":      p.Sticky,
+		"locked":      p.Locked,
+		"created_at":  p.CreatedAt,
+	}
+}
+
+// CommentSnapshot is what the log keeps of a comment.
+func CommentSnapshot(c *Comment) map[string]interface{} {
+	return map[string]interface{}{
+		"id":          c.ID,
+		"post_id":     c.PostID,
+		"title":       c.Title,
+		"content":     c.Content,
+		"image_url":   c.ImageURL,
+		"author_name": c.AuthorName,
+		"created_at":  c.CreatedAt,
+	}
+}
+
+// ModActionPageSize is the most log entries returned at once.
+const ModActionPageSize = 100
+
+// ModActionFilter narrows the staff log. Zero fields match everything;
+// BeforeID pages backwards.
+type ModActionFilter struct {
+	Actor      string
+	Action     ModActionType
+	TargetType string
+	TargetID   string
+	Board      string
+	PublicOnly bool
+	BeforeID   int64
+	Limit      int
+}
+
+// publicActions are shown, anonymised, in the public log.
+var publicActions = map[ModActionType]bool{
+	ActionDeleteThread:  true,
+	ActionDeleteComment: true,
+	ActionDeleteFile:    true,
+	ActionSticky:        true,
+	ActionLock:          true,
+	ActionMove:          true,
+	ActionArchive:       true,
+	ActionRestore:       true,
+	ActionBan:           true,
+}
+
+// PublicActionTypes lists the actions shown in the public log.
+func PublicActionTypes() []ModActionType {
+	types := make([]ModActionType, 0, len(publicActions))
+	for action := range publicActions {
+		types = append(types, action)
+	}
+	return types
+}
+
+// Public reports whether the action shows in the public log: public action
+// types on threads and comments, so range bans stay private.
+func (a *ModAction) Public() bool {
+	return publicActions[a.Action] && (a.TargetType == string(ReportTargetPost) || a.TargetType == string(ReportTargetComment))
+}
+
+// PublicSummary describes the action without naming the moderator or the
+// poster, as in "a moderator deleted >>123 for spam".
+func (a *ModAction) PublicSummary() string {
+	actor := "a moderator"
+	if a.ActorType == ActorSystem {
+		actor = "the system"
+	}
+
+	ref := ">>" + a.TargetID
+	var what string
+	switch a.Action {
+	case ActionDeleteThread, ActionDeleteComment:
+		what = "deleted " + ref
+	case ActionDeleteFile:
+		what = "removed the file of " + ref
+	case ActionSticky:
+		what = toggled(a.After, "sticky", "stickied ", "unstickied ") + ref
+	case ActionLock:
+		what = toggled(a.After, "locked", "locked ", "unlocked ") + ref
+	case ActionMove:
+		what = "moved " + ref + " to /" + a.Board + "/"
+	case ActionArchive:
+		what = "archived " + ref
+	case ActionRestore:
+		what = "restored " + ref + " from the archive"
+	case ActionBan:
+		what = "banned the author of " + ref
+	default:
+		what = string(a.Action) + " " + ref
+	}
+
+	if a.Reason != "" {
+		return actor + " " + what + " for " + a.Reason
+	}
+	return actor + " " + what
+}
+
+// toggled picks the verb for a flag change from the After snapshot.
+func toggled(after json.RawMessage, key, on, off string) string {
+	var state map[string]interface{}
+	if json.Unmarshal(after, &state) == nil {
+		if set, _ := state[key].(bool); set {
+			return on
+		}
+	}
+	return off
+}
@@ -8,14 +8,35 @@ type Post struct {
 	ID         int       `json:"id"`
 	Title      string    `json:"title"`
 	Content    string    `json:"content"`
-	AuthorID   string    `json:"author_id"`
 	AuthorName string    `json:"author_name"`
 	ImageURL   string    `json:"image_url"`
+	Board      string    `json:"board"`
+	Sticky     bool      `json:"sticky"`
+	Locked     bool      `json:"locked"`
 	Comments   []Comment `json:"comments"`
 	CreatedAt  time.Time `json:"created_at"`
 	ExpiresAt  time.Time `json:"expires_at"`
+
+	// ImageSourceURL is where an image posted by URL was fetched from.
+	ImageSourceURL string `json:"image_source_url,omitempty"`
+
+	// IPHash is the salted hash of the poster's address, kept for bans.
+	IPHash string `json:"-"`
+
+	// AuthorID is the poster's session ID, which authenticates them, so it
+	// never leaves the server.
+	AuthorID string `json:"-"`
+
+	// ReplyCount, ImageCount and LastReplyAt are aggregated by the catalog
+	// query; Comments then only holds the latest replies.
+	ReplyCount  int        `json:"reply_count"`
+	ImageCount  int        `json:"image_count"`
+	LastReplyAt *time.Time `json:"last_reply_at,omitempty"`
 }
 
+// DefaultPostTTL is how long a new thread lives before replies extend it.
+const DefaultPostTTL = 10 * time.Minute
+
 func NewPost(title, content string, authorID string, comments []Comment) (*Post, error) {
 	if len(content) == 0 {
 		return nil, ErrEmptyContent
@@ -27,15 +48,14 @@ func NewPost(title, content string, authorID string, comments []Comment) (*Post,
 
 	now := time.Now()
 
-	ttl := 10 * time.Minute // Default is 10 minutes
-
 	return &Post{
 		Title:     title,
 		Content:   content,
 		AuthorID:  authorID,
 		ImageURL:  "",
+		Board:     DefaultBoard,
 		Comments:  comments,
 		CreatedAt: now,
-		ExpiresAt: now.Add(ttl),
+		ExpiresAt: now.Add(DefaultPostTTL),
 	}, nil
 }
@@ -0,0 +1,81 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Names of the rate limit rules. Each is configurable through
+// RATE_LIMIT_<NAME>.
+const (
+	RateLimitNewThread = "new_thread"
+	RateLimitReply     = "reply"
+	RateLimitSession   = "session"
+	RateLimitUpload    = "upload"
+)
+
+// RateLimitRule is a token bucket: Burst requests may be made at once and a
+// token comes back every Interval. A rule with no Burst is switched off.
+type RateLimitRule struct {
+	Name     string
+	Burst    int
+	Interval time.Duration
+}
+
+// DefaultRateLimitRules apply per session and per IP hash.
+var DefaultRateLimitRules = map[string]RateLimitRule{
+	RateLimitNewThread: {Name: RateLimitNewThread, Burst: 1, Interval: time.Minute},
+	RateLimitReply:     {Name: RateLimitReply, Burst: 3, Interval: 10 * time.Second},
+	RateLimitSession:   {Name: RateLimitSession, Burst: 3, Interval: time.Minute},
+	RateLimitUpload:    {Name: RateLimitUpload, Burst: 5, Interval: 30 * time.Second},
+}
+
+// ParseRateLimitRule reads a rule written as "burst/interval", as in "3/10s".
+// "off" switches the rule off.
+func ParseRateLimitRule(name, spec string) (RateLimitRule, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "off" {
+		return RateLimitRule{Name: name}, nil
+	}
+
+	burst, interval, ok := strings.Cut(spec, "/")
+	if !ok {
+		return RateLimitRule{}, fmt.Errorf("%s %q: %w", name, spec, ErrInvalidRateLimit)
+	}
+	n, err := strconv.Atoi(burst)
+	if err != nil || n < 0 {
+		return RateLimitRule{}, fmt.Errorf("%s %q: %w", name, spec, ErrInvalidRateLimit)
+	}
+	d, err := time.ParseDuration(interval)
+	if err != nil || d <= 0 {
+		return RateLimitRule{}, fmt.Errorf("%s %q: %w", name, spec, ErrInvalidRateLimit)
+	}
+	return RateLimitRule{Name: name, Burst: n, Interval: d}, nil
+}
+
+func (r RateLimitRule) Enabled() bool {
+	return r.Burst > 0
+}
+
+// Take spends a token from a bucket that held tokens at last. It returns the
+// tokens left or, when the bucket is empty, how long until the next token.
+// A bucket never seen before holds Burst tokens.
+func (r RateLimitRule) Take(tokens float64, last, now time.Time) (left float64, wait time.Duration, ok bool) {
+	if elapsed := now.Sub(last); elapsed > 0 {
+		tokens += float64(elapsed) / float64(r.Interval)
+	}
+	tokens = math.Min(tokens, float64(r.Burst))
+	if tokens < 1 {
+		return tokens, time.Duration((1 - tokens) * float64(r.Interval)), false
+	}
+	return tokens - 1, 0, true
+}
+
+// FullAt is when a bucket holding tokens at now is full again, after which
+// it need not be kept.
+func (r RateLimitRule) FullAt(tokens float64, now time.Time) time.Time {
+	return now.Add(time.Duration((float64(r.Burst) - tokens) * float64(r.Interval)))
+}
@@ -0,0 +1,114 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// Reports a session may file within ReportRateWindow.
+const (
+	ReportRateLimit  = 5
+	ReportRateWindow = 10 * time.Minute
+)
+
+// MaxReportNoteLength caps the optional note of a report.
+const MaxReportNoteLength = 500
+
+type ReportTarget string
+
+const (
+	ReportTargetPost    ReportTarget = "post"
+	ReportTargetComment ReportTarget = "comment"
+)
+
+func (t ReportTarget) Valid() bool {
+	return t == ReportTargetPost || t == ReportTargetComment
+}
+
+type ReportCategory string
+
+const (
+	ReportSpam     ReportCategory = "spam"
+	ReportIllegal  ReportCategory = "illegal"
+	ReportOffTopic ReportCategory = "off-topic"
+)
+
+// ReportCategories lists the categories in the order they are offered.
+var ReportCategories = []ReportCategory{ReportSpam, ReportIllegal, ReportOffTopic}
+
+func (c ReportCategory) Valid() bool {
+	for _, valid := range ReportCategories {
+		if c == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// ReportResolution is what a moderator did about a reported post.
+type ReportResolution string
+
+const (
+	ResolutionDismiss ReportResolution = "dismiss"
+	ResolutionDelete  ReportResolution = "delete"
+	ResolutionBan     ReportResolution = "ban"
+)
+
+func (r ReportResolution) Valid() bool {
+	return r == ResolutionDismiss || r == ResolutionDelete || r == ResolutionBan
+}
+
+type Report struct {
+	ID         int              `json:"id"`
+	TargetType ReportTarget     `json:"target_type"`
+	TargetID   int              `json:"target_id"`
+	PostID     int              `json:"post_id"`
+	Board      string           `json:"board"`
+	Category   ReportCategory   `json:"category"`
+	Note       string           `json:"note"`
+	SessionID  string           `json:"-"`
+	CreatedAt  time.Time        `json:"created_at"`
+	ResolvedAt *time.Time       `json:"resolved_at,omitempty"`
+	Resolution ReportResolution `json:"resolution,omitempty"`
+
+	// ResolvedBy is the username of the staff member who resolved it.
+	ResolvedBy string `json:"-"`
+}
+
+func NewReport(targetType ReportTarget, targetID int, category ReportCategory, note, sessionID string) (*Report, error) {
+	if !targetType.Valid() || targetID <= 0 {
+		return nil, ErrInvalidReportTarget
+	}
+	if !category.Valid() {
+		return nil, ErrInvalidReportCategory
+	}
+	note = strings.TrimSpace(note)
+	if len(note) > MaxReportNoteLength {
+		return nil, ErrReportNoteTooLong
+	}
+	if sessionID == "" {
+		return nil, ErrInvalidSession
+	}
+	return &Report{
+		TargetType: targetType,
+		TargetID:   targetID,
+		Category:   category,
+		Note:       note,
+		SessionID:  sessionID,
+		CreatedAt:  time.Now(),
+	}, nil
+}
+
+// ReportGroup gathers the open reports of one post or comment for the
+// moderator queue.
+type ReportGroup struct {
+	TargetType      ReportTarget     `json:"target_type"`
+	TargetID        int              `json:"target_id"`
+	PostID          int              `json:"post_id"`
+	Board           string           `json:"board"`
+	Count           int              `json:"count"`
+	Categories      []ReportCategory `json:"categories"`
+	Notes           []string         `json:"notes"`
+	FirstReportedAt time.Time        `json:"first_reported_at"`
+	LastReportedAt  time.Time        `json:"last_reported_at"`
+}
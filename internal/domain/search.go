@@ -0,0 +1,79 @@
+package domain
+
+import (
+	"time"
+)
+
+type SearchSort string
+
+const (
+	SortByRelevance SearchSort = "relevance"
+	SortByDate      SearchSort = "date"
+)
+
+type SearchScope string
+
+const (
+	ScopeAll     SearchScope = "all"
+	ScopeLive    SearchScope = "live"
+	ScopeArchive SearchScope = "archive"
+)
+
+const (
+	DefaultSearchPerPage = 20
+	MaxSearchPerPage     = 100
+)
+
+// Highlighted terms in raw snippets are wrapped in these control characters
+// so the text can be escaped before they are turned into markup. They are
+// stripped from posts before highlighting, so every one in a snippet is
+// markup.
+const (
+	HighlightStart = "\x01"
+	HighlightStop  = "\x02"
+)
+
+type SearchQuery struct {
+	Text     string
+	Board    string
+	From     *time.Time
+	To       *time.Time
+	HasImage bool
+	Scope    SearchScope
+	Sort     SearchSort
+	Page     int
+	PerPage  int
+}
+
+type SearchResult struct {
+	Kind      string    `json:"kind"` // "post" or "comment"
+	Archived  bool      `json:"archived"`
+	PostID    int       `json:"post_id"`
+	CommentID *int      `json:"comment_id,omitempty"`
+	Board     string    `json:"board"`
+	Title     string    `json:"title"`
+	Snippet   string    `json:"snippet"` // HTML-escaped, matches wrapped in <mark>
+	ImageURL  string    `json:"image_url"`
+	CreatedAt time.Time `json:"created_at"`
+	Rank      float64   `json:"rank"`
+}
+
+type SearchPage struct {
+	Results []SearchResult `json:"results"`
+	Total   int            `json:"total"`
+	Page    int            `json:"page"`
+	PerPage int            `json:"per_page"`
+}
+
+func NewSearchQuery(text string) (*SearchQuery, error) {
+	if len(text) == 0 {
+		return nil, ErrEmptySearchQuery
+	}
+	return &SearchQuery{
+		Text:    text,
+		Scope:   ScopeAll,
+		Sort:    SortByRelevance,
+		Page:    1,
+		PerPage: DefaultSearchPerPage,
+	}, nil
+}
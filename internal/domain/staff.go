@@ -0,0 +1,84 @@
+package domain
+
+import (
+	"time"
+)
+
+type StaffRole string
+
+const (
+	RoleAdmin   StaffRole = "admin"
+	RoleMod     StaffRole = "mod"
+	RoleJanitor StaffRole = "janitor"
+)
+
+// StaffSessionTTL is how long a staff login stays valid.
+const StaffSessionTTL = 12 * time.Hour
+
+// MinStaffPasswordLength is the shortest password a staff account may have.
+const MinStaffPasswordLength = 10
+
+type Permission string
+
+const (
+	PermViewQueue    Permission = "view_queue"
+	PermDeletePost   Permission = "delete_post"
+	PermDeleteFile   Permission = "delete_file"
+	PermSticky       Permission = "sticky"
+	PermLock         Permission = "lock"
+	PermMove         Permission = "move"
+	PermBan          Permission = "ban"
+	PermViewSessions Permission = "view_sessions"
+	PermMaintenance  Permission = "maintenance"
+	PermManageStaff  Permission = "manage_staff"
+	PermFilters      Permission = "filters"
+)
+
+// rolePermissions grants janitors cleanup, mods thread management and
+// admins everything.
+var rolePermissions = map[StaffRole][]Permission{
+	RoleJanitor: {PermViewQueue, PermDeletePost, PermDeleteFile},
+	RoleMod:     {PermViewQueue, PermDeletePost, PermDeleteFile, PermSticky, PermLock, PermMove, PermBan, PermViewSessions, PermMaintenance, PermFilters},
+	RoleAdmin:   {PermViewQueue, PermDeletePost, PermDeleteFile, PermSticky, PermLock, PermMove, PermBan, PermViewSessions, PermMaintenance, PermFilters, PermManageStaff},
+}
+
+func (r StaffRole) Valid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+func (r StaffRole) Can(p Permission) bool {
+	for _, granted := range rolePermissions[r] {
+		if granted == p {
+			return true
+		}
+	}
+	return false
+}
+
+type Staff struct {
+	ID           int       `json:"id"`
+	Username     string    `json:"username"`
+	PasswordHash string    `json:"-"`
+	Role         StaffRole `json:"role"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (s *Staff) Can(p Permission) bool {
+	return s.Role.Can(p)
+}
+
+func NewStaff(username, passwordHash string, role StaffRole) (*Staff, error) {
+	if len(username) == 0 {
+		return nil, ErrEmptyUserName
+	}
+	if !role.Valid() {
+		return nil, ErrInvalidStaffRole
+	}
+	return &Staff{
+		Username:     username,
+		PasswordHash: passwordHash,
+		Role:         role,
+		CreatedAt:    time.Now(),
+	}, nil
+}
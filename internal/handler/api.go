@@ -0,0 +1,520 @@
+package handler
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"1337b04rd/internal/adapters/openapi"
+	"1337b04rd/internal/domain"
+	"1337b04rd/internal/service"
+	"1337b04rd/pkg/middleware"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+	maxJSONBodyBytes = 1 << 20
+)
+
+// API clients send the captcha solution in headers, as the body is the
+// thread or comment itself.
+const (
+	captchaTokenHeader = "X-Captcha-Token"
+	captchaNonceHeader = "X-Captcha-Nonce"
+)
+
+// APIHandler serves the versioned JSON API under /api/v1. Every error goes
+// through writeError so clients always get the same envelope.
+type APIHandler struct {
+	boardService   *service.BoardService
+	postService    service.PostService
+	commentService *service.CommentService
+	archiveService *service.ArchiveService
+	sessionService *service.SessionService
+	captchaService *service.CaptchaService
+	logger         *slog.Logger
+}
+
+func NewAPIHandler(
+	boardService *service.BoardService,
+	postService service.PostService,
+	commentService *service.CommentService,
+	archiveService *service.ArchiveService,
+	sessionService *service.SessionService,
+	captchaService *service.CaptchaService,
+	logger *slog.Logger,
+) *APIHandler {
+	return &APIHandler{
+		boardService:   boardService,
+		postService:    postService,
+		commentService: commentService,
+		archiveService: archiveService,
+		sessionService: sessionService,
+		captchaService: captchaService,
+		logger:         logger,
+	}
+}
+
+// Page is the body of every paginated list. NextCursor is empty on the last
+// page and is passed back as ?cursor= to fetch the next one.
+type Page struct {
+	Data       interface{} `json:"data"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
+
+func encodeCursor(id int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(id)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, domain.ErrInvalidCursor
+	}
+	id, err := strconv.Atoi(string(raw))
+	if err != nil || id <= 0 {
+		return 0, domain.ErrInvalidCursor
+	}
+	return id, nil
+}
+
+// pageParams reads ?cursor= and ?limit= from the request.
+func pageParams(r *http.Request) (cursor, limit int, err error) {
+	cursor, err = decodeCursor(r.URL.Query().Get("cursor"))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	limit = defaultPageLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			return 0, 0, fmt.Errorf("%w: limit %q", domain.ErrInvalidParam, v)
+		}
+		if limit > maxPageLimit {
+			limit = maxPageLimit
+		}
+	}
+	return cursor, limit, nil
+}
+
+// pathID parses the integer path wildcard name.
+func pathID(r *http.Request, name string) (int, error) {
+	v := r.PathValue(name)
+	if v == "" {
+		return 0, domain.ErrMissingID
+	}
+	id, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", domain.ErrInvalidIDFormat, v)
+	}
+	return id, nil
+}
+
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	r.Body = http.MaxBytesReader(w, r.Body, maxJSONBodyBytes)
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrInvalidBody, err)
+	}
+	return nil
+}
+
+func apiSession(r *http.Request) (*domain.Session, error) {
+	session, err := middleware.GetSession(r.Context())
+	if err != nil {
+		return nil, domain.ErrInvalidSession
+	}
+	return session, nil
+}
+
+func (h *APIHandler) fail(w http.ResponseWriter, r *http.Request, msg string, err error) {
+	requestLog(r, h.logger).Error(msg, "error", err)
+	writeError(w, err)
+}
+
+// NotFound answers every unknown /api/v1 route.
+func (h *APIHandler) NotFound(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusNotFound, errorEnvelope{Error: APIError{
+		Code:    "route_not_found",
+		Message: fmt.Sprintf("no route for %s %s", r.Method, r.URL.Path),
+	}})
+}
+
+// boards
+
+func (h *APIHandler) ListBoards(w http.ResponseWriter, r *http.Request) {
+	boards, err := h.boardService.GetAllBoards(r.Context())
+	if err != nil {
+		h.fail(w, r, "Failed to fetch boards", err)
+		return
+	}
+	if boards == nil {
+		boards = []*domain.Board{}
+	}
+	writeJSON(w, http.StatusOK, boards)
+}
+
+func (h *APIHandler) GetBoard(w http.ResponseWriter, r *http.Request) {
+	board, err := h.boardService.GetBoard(r.Context(), r.PathValue("board"))
+	if err != nil {
+		h.fail(w, r, "Failed to fetch board", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, board)
+}
+
+// GetChallenge issues a captcha challenge for posting on the board.
+func (h *APIHandler) GetChallenge(w http.ResponseWriter, r *http.Request) {
+	challenge, err := h.captchaService.Issue(r.Context(), r.PathValue("board"))
+	if err != nil {
+		h.fail(w, r, "Failed to issue challenge", err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, challenge)
+}
+
+// threads
+
+func (h *APIHandler) ListThreads(w http.ResponseWriter, r *http.Request) {
+	board, err := h.boardService.GetBoard(r.Context(), r.PathValue("board"))
+	if err != nil {
+		h.fail(w, r, "Failed to fetch board", err)
+		return
+	}
+	cursor, limit, err := pageParams(r)
+	if err != nil {
+		h.fail(w, r, "Invalid pagination", err)
+		return
+	}
+
+	posts, err := h.postService.ListPosts(r.Context(), board.Slug, cursor, limit+1)
+	if err != nil {
+		h.fail(w, r, "Failed to fetch threads", err)
+		return
+	}
+
+	page := Page{Data: posts}
+	if len(posts) > limit {
+		page.Data = posts[:limit]
+		page.NextCursor = encodeCursor(posts[limit-1].ID)
+	} else if posts == nil {
+		page.Data = []*domain.Post{}
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (h *APIHandler) CreateThread(w http.ResponseWriter, r *http.Request) {
+	session, err := apiSession(r)
+	if err != nil {
+		h.fail(w, r, "Unauthorized", err)
+		return
+	}
+	board, err := h.boardService.GetBoard(r.Context(), r.PathValue("board"))
+	if err != nil {
+		h.fail(w, r, "Failed to fetch board", err)
+		return
+	}
+
+	var req struct {
+		Title   string `json:"title"`
+		Content string `json:"content"`
+	}
+	if err := decodeJSON(w, r, &req); err != nil {
+		h.fail(w, r, "Invalid request body", err)
+		return
+	}
+
+	post, err := domain.NewPost(req.Title, req.Content, session.ID, nil)
+	if err != nil {
+		h.fail(w, r, "Invalid thread", err)
+		return
+	}
+
+	// Verifying uses the challenge up, so only valid threads get this far.
+	if err := h.captchaService.VerifyThread(r.Context(), board.Slug, r.Header.Get(captchaTokenHeader), r.Header.Get(captchaNonceHeader)); err != nil {
+		h.fail(w, r, "Captcha check failed", err)
+		return
+	}
+	post.AuthorName = session.Name
+	post.Board = board.Slug
+
+	if err := h.postService.CreatePost(r.Context(), post); err != nil {
+		h.fail(w, r, "Failed to create thread", err)
+		return
+	}
+
+	requestLog(r, h.logger).Info("Successfully created thread")
+	writeJSON(w, http.StatusCreated, post)
+}
+
+func (h *APIHandler) GetThread(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		h.fail(w, r, "Invalid thread ID", err)
+		return
+	}
+	post, err := h.postService.GetPost(r.Context(), id)
+	if err != nil {
+		h.fail(w, r, "Failed to fetch thread", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, post)
+}
+
+// comments
+
+func (h *APIHandler) ListComments(w http.ResponseWriter, r *http.Request) {
+	postID, err := pathID(r, "id")
+	if err != nil {
+		h.fail(w, r, "Invalid thread ID", err)
+		return
+	}
+	cursor, limit, err := pageParams(r)
+	if err != nil {
+		h.fail(w, r, "Invalid pagination", err)
+		return
+	}
+
+	comments, err := h.commentService.ListCommentsOfPost(r.Context(), postID, cursor, limit+1)
+	if err != nil {
+		h.fail(w, r, "Failed to fetch comments", err)
+		return
+	}
+
+	page := Page{Data: comments}
+	if len(comments) > limit {
+		page.Data = comments[:limit]
+		page.NextCursor = encodeCursor(comments[limit-1].ID)
+	} else if comments == nil {
+		page.Data = []*domain.Comment{}
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (h *APIHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
+	session, err := apiSession(r)
+	if err != nil {
+		h.fail(w, r, "Unauthorized", err)
+		return
+	}
+	postID, err := pathID(r, "id")
+	if err != nil {
+		h.fail(w, r, "Invalid thread ID", err)
+		return
+	}
+
+	var req struct {
+		Title     string `json:"title"`
+		Content   string `json:"content"`
+		ReplyToID *int   `json:"reply_to_id"`
+	}
+	if err := decodeJSON(w, r, &req); err != nil {
+		h.fail(w, r, "Invalid request body", err)
+		return
+	}
+	if _, err := domain.NewComment(postID, session.ID, session.Name, req.Title, req.Content, "", req.ReplyToID); err != nil {
+		h.fail(w, r, "Invalid comment", err)
+		return
+	}
+
+	// Verifying uses the challenge up, so only valid comments get this far.
+	if err := h.captchaService.VerifyReply(r.Context(), postID, r.Header.Get(captchaTokenHeader), r.Header.Get(captchaNonceHeader)); err != nil {
+		h.fail(w, r, "Captcha check failed", err)
+		return
+	}
+
+	comment, err := h.commentService.CreateComment(
+		r.Context(),
+		postID,
+		session.ID,
+		session.Name,
+		req.Title,
+		req.Content,
+		"",
+		"",
+		req.ReplyToID,
+	)
+	if err != nil {
+		h.fail(w, r, "Failed to create comment", err)
+		return
+	}
+
+	requestLog(r, h.logger).Info("Successfully created comment")
+	writeJSON(w, http.StatusCreated, comment)
+}
+
+func (h *APIHandler) GetComment(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		h.fail(w, r, "Invalid comment ID", err)
+		return
+	}
+	comment, err := h.commentService.GetComment(r.Context(), id)
+	if err != nil {
+		h.fail(w, r, "Failed to fetch comment", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, comment)
+}
+
+func (h *APIHandler) ListReplies(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		h.fail(w, r, "Invalid comment ID", err)
+		return
+	}
+	if _, err := h.commentService.GetComment(r.Context(), id); err != nil {
+		h.fail(w, r, "Failed to fetch comment", err)
+		return
+	}
+	replies, err := h.commentService.GetRepliesToComment(r.Context(), id)
+	if err != nil {
+		h.fail(w, r, "Failed to fetch replies", err)
+		return
+	}
+	if replies == nil {
+		replies = []*domain.Comment{}
+	}
+	writeJSON(w, http.StatusOK, replies)
+}
+
+// archive
+
+func (h *APIHandler) ListArchive(w http.ResponseWriter, r *http.Request) {
+	cursor, limit, err := pageParams(r)
+	if err != nil {
+		h.fail(w, r, "Invalid pagination", err)
+		return
+	}
+
+	posts, err := h.archiveService.ListArchivedPosts(r.Context(), r.URL.Query().Get("board"), cursor, limit+1)
+	if err != nil {
+		h.fail(w, r, "Failed to fetch archived threads", err)
+		return
+	}
+
+	page := Page{Data: posts}
+	if len(posts) > limit {
+		page.Data = posts[:limit]
+		page.NextCursor = encodeCursor(posts[limit-1].ID)
+	} else if posts == nil {
+		page.Data = []*domain.Archive{}
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (h *APIHandler) GetArchivedThread(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		h.fail(w, r, "Invalid thread ID", err)
+		return
+	}
+	post, err := h.archiveService.GetArchivedPostByID(r.Context(), id)
+	if err != nil {
+		h.fail(w, r, "Failed to fetch archived thread", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, post)
+}
+
+// sessions
+
+func (h *APIHandler) CreateSession(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := decodeJSON(w, r, &req); err != nil {
+		h.fail(w, r, "Invalid request body", err)
+		return
+	}
+
+	session, err := h.sessionService.CreateSession(r.Context(), req.Name)
+	if err != nil {
+		h.fail(w, r, "Failed to create session", err)
+		return
+	}
+
+	setSessionCookies(w, session)
+	requestLog(r, h.logger).Info("Successfully created session")
+	writeJSON(w, http.StatusCreated, session)
+}
+
+func (h *APIHandler) GetCurrentSession(w http.ResponseWriter, r *http.Request) {
+	session, err := apiSession(r)
+	if err != nil {
+		h.fail(w, r, "Unauthorized", err)
+		return
+	}
+	if stored, err := h.sessionService.GetSession(r.Context(), session.ID); err == nil {
+		session = stored
+	}
+	writeJSON(w, http.StatusOK, session)
+}
+
+func (h *APIHandler) UpdateCurrentSession(w http.ResponseWriter, r *http.Request) {
+	current, err := apiSession(r)
+	if err != nil {
+		h.fail(w, r, "Unauthorized", err)
+		return
+	}
+
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := decodeJSON(w, r, &req); err != nil {
+		h.fail(w, r, "Invalid request body", err)
+		return
+	}
+	if req.Name == "" {
+		h.fail(w, r, "Invalid session name", domain.ErrEmptyUserName)
+		return
+	}
+
+	session, err := h.sessionService.UpdateSession(current.ID, req.Name)
+	if err != nil {
+		h.fail(w, r, "Failed to update session", err)
+		return
+	}
+
+	setSessionCookies(w, session)
+	requestLog(r, h.logger).Info("Successfully updated session")
+	writeJSON(w, http.StatusOK, session)
+}
+
+func (h *APIHandler) DeleteCurrentSession(w http.ResponseWriter, r *http.Request) {
+	if _, err := apiSession(r); err != nil {
+		h.fail(w, r, "Unauthorized", err)
+		return
+	}
+	if err := h.sessionService.DeleteSession(w, r); err != nil {
+		h.fail(w, r, "Failed to delete session", err)
+		return
+	}
+	requestLog(r, h.logger).Info("Successfully deleted session")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ServeOpenAPISpec serves the OpenAPI document describing this API.
+func ServeOpenAPISpec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openapi.Spec)
+}
+
+// apiDocs renders the OpenAPI document in the browser.
+var apiDocs = template.Must(template.New("docs").Parse(openapi.DocsPage))
+
+// ServeAPIDocs serves the bundled HTML page that renders the OpenAPI document.
+func ServeAPIDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	apiDocs.Execute(w, map[string]interface{}{"CSPNonce": middleware.GetCSPNonce(r.Context())})
+}
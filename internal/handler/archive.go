@@ -8,7 +8,6 @@ import (
 	"strings"
 
 	"1337b04rd/internal/service"
-	"1337b04rd/pkg/middleware"
 )
 
 type ArchiveHandler struct {
@@ -21,60 +20,76 @@ func NewArchiveHandler(service *service.ArchiveService, logger *slog.Logger) *Ar
 }
 
 func (h *ArchiveHandler) ArchivePost(w http.ResponseWriter, r *http.Request) {
-	session, err := middleware.GetSession(r.Context())
-	if err != nil {
-		h.logger.Error("Unauthorized: session not found", "method", r.Method)
-		http.Error(w, "Unauthorized: session not found", http.StatusUnauthorized)
-		return
-	}
-	sessionID := session.ID
-
 	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
 	if len(parts) < 2 {
-		h.logger.Error("Invalid URL format", "method", r.Method)
+		requestLog(r, h.logger).Error("Invalid URL format")
 		http.Error(w, "Invalid URL format", http.StatusBadRequest)
 		return
 	}
 	postID, err := strconv.Atoi(parts[len(parts)-1])
 	if err != nil {
-		h.logger.Error("Invalid post ID", "method", r.Method)
+		requestLog(r, h.logger).Error("Invalid post ID")
 		http.Error(w, "Invalid post ID", http.StatusBadRequest)
 		return
 	}
 
 	err = h.service.ArchivePostByID(r.Context(), postID)
 	if err != nil {
-		h.logger.Error("Failed to archive post", "error", err, "method", r.Method)
+		requestLog(r, h.logger).Error("Failed to archive post", "error", err)
 		http.Error(w, "Failed to archive post: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	h.logger.Info("Successfully archived post", "method", r.Method)
-	json.NewEncoder(w).Encode(map[string]string{"message": "Post archived successfully by session " + sessionID})
+	requestLog(r, h.logger).Info("Successfully archived post")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Post archived successfully"})
 }
 
 func (h *ArchiveHandler) ArchiveExpired(w http.ResponseWriter, r *http.Request) {
 	err := h.service.ArchiveExpiredPosts(r.Context())
 	if err != nil {
-		h.logger.Error("Failed to archive expired posts", "error", err, "method", r.Method)
+		requestLog(r, h.logger).Error("Failed to archive expired posts", "error", err)
 		http.Error(w, "Failed to archive expired posts: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	h.logger.Info("Successfully archived post", "method", r.Method)
+	requestLog(r, h.logger).Info("Successfully archived post")
 	json.NewEncoder(w).Encode(map[string]string{"message": "Expired posts archived"})
 }
 
 func (h *ArchiveHandler) GetAllArchivedPosts(w http.ResponseWriter, r *http.Request) {
 	posts, err := h.service.GetAllArchivedPosts(r.Context())
 	if err != nil {
-		h.logger.Error("Failed to fetch archived posts", "error", err, "method", r.Method)
+		requestLog(r, h.logger).Error("Failed to fetch archived posts", "error", err)
 		http.Error(w, "Failed to fetch archived posts: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	h.logger.Info("Successfully got all archived post", "method", r.Method)
+	requestLog(r, h.logger).Info("Successfully got all archived post")
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(posts)
 }
+
+// RestorePost moves an archived thread back onto the board under the ID it
+// had before archiving, which the response carries. Threads archived before
+// the archive kept IDs come back under a new one.
+func (h *ArchiveHandler) RestorePost(w http.ResponseWriter, r *http.Request) {
+	archivedID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	post, err := h.service.RestorePost(r.Context(), archivedID)
+	if err != nil {
+		status, _ := ErrorStatus(err)
+		if status >= http.StatusInternalServerError {
+			requestLog(r, h.logger).Error("Failed to restore archived post", "archived_id", archivedID, "error", err)
+		}
+		http.Error(w, "Failed to restore post: "+err.Error(), status)
+		return
+	}
+
+	requestLog(r, h.logger).Info("Restored archived post", "archived_id", archivedID, "post_id", post.ID)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"message": "Post restored", "post_id": post.ID})
+}
@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"1337b04rd/internal/domain"
+	"1337b04rd/internal/service"
+	"1337b04rd/pkg/middleware"
+)
+
+type BanHandler struct {
+	banService  *service.BanService
+	postService service.PostService
+	logger      *slog.Logger
+}
+
+func NewBanHandler(banService *service.BanService, postService service.PostService, logger *slog.Logger) *BanHandler {
+	return &BanHandler{banService: banService, postService: postService, logger: logger}
+}
+
+// RejectBanned stops banned posters before a thread or comment is created.
+// API clients get an error envelope; everyone else gets banPage with a 403.
+// While bans cannot be checked nobody may post, and posters get a 503.
+func (h *BanHandler) RejectBanned(banPage http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var sessionID string
+			if session, err := middleware.GetSession(r.Context()); err == nil {
+				sessionID = session.ID
+			}
+			client := middleware.GetClientIP(r.Context())
+
+			ban, err := h.banService.CheckBan(r.Context(), sessionID, client.Hash, ipString(client), h.targetBoard(r))
+			if err != nil {
+				// Without the check banned posters would get through, so
+				// posting waits until it works again.
+				requestLog(r, h.logger).Error("Failed to check bans", "path", r.URL.Path, "error", err)
+				if strings.HasPrefix(r.URL.Path, "/api/") {
+					writeError(w, domain.ErrBanCheckFailed)
+					return
+				}
+				http.Error(w, domain.ErrBanCheckFailed.Error(), http.StatusServiceUnavailable)
+				return
+			}
+			if ban == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			requestLog(r, h.logger).Warn("Rejected banned poster", "ban", ban.ID, "path", r.URL.Path)
+			if strings.HasPrefix(r.URL.Path, "/api/") {
+				writeError(w, fmt.Errorf("%w: %s", domain.ErrBanned, describeBan(ban)))
+				return
+			}
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(http.StatusForbidden)
+			banPage.ServeHTTP(w, r)
+		})
+	}
+}
+
+// targetBoard names the board a post is about to land on: the {board} path
+// value for new threads, or the board of the thread being replied to.
+func (h *BanHandler) targetBoard(r *http.Request) string {
+	if board := r.PathValue("board"); board != "" {
+		return board
+	}
+
+	threadID := r.PathValue("id")
+	if threadID == "" {
+		threadID = r.FormValue("post_id")
+	}
+	if id, err := strconv.Atoi(threadID); err == nil {
+		if post, err := h.postService.GetPost(r.Context(), id); err == nil {
+			return post.Board
+		}
+	}
+
+	if board := r.FormValue("board"); board != "" {
+		return board
+	}
+	return domain.DefaultBoard
+}
+
+func (h *BanHandler) SubmitAppeal(w http.ResponseWriter, r *http.Request) {
+	banID, err := strconv.Atoi(r.FormValue("ban_id"))
+	if err != nil {
+		requestLog(r, h.logger).Error("Invalid ban ID")
+		http.Error(w, "Invalid ban ID", http.StatusBadRequest)
+		return
+	}
+
+	var sessionID string
+	if session, err := middleware.GetSession(r.Context()); err == nil {
+		sessionID = session.ID
+	}
+	client := middleware.GetClientIP(r.Context())
+
+	if _, err := h.banService.Appeal(r.Context(), banID, sessionID, client.Hash, ipString(client), r.FormValue("message")); err != nil {
+		requestLog(r, h.logger).Error("Failed to submit appeal", "ban", banID, "error", err)
+		status, _ := ErrorStatus(err)
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	requestLog(r, h.logger).Info("Submitted ban appeal", "ban", banID)
+	http.Redirect(w, r, "/banned?appealed=1", http.StatusSeeOther)
+}
+
+// describeBan tells the poster why and until when they are banned.
+func describeBan(ban *domain.Ban) string {
+	until := "permanently"
+	if ban.ExpiresAt != nil {
+		until = "until " + ban.ExpiresAt.UTC().Format("2006-01-02 15:04 MST")
+	}
+	scope := "on every board"
+	if !ban.Global() {
+		scope = "on /" + ban.Board + "/"
+	}
+	return fmt.Sprintf("%s, %s %s", ban.Reason, scope, until)
+}
+
+func ipString(client *middleware.ClientIP) string {
+	if client.IP == nil {
+		return ""
+	}
+	return client.IP.String()
+}
@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"1337b04rd/internal/adapters/chanapi"
+	"1337b04rd/internal/adapters/storage"
+	"1337b04rd/internal/domain"
+	"1337b04rd/internal/service"
+)
+
+// chanMaxPages caps how many catalog pages boards.json announces.
+const chanMaxPages = 10
+
+// chanMaxFilesize is the largest upload, as boards.json reports it.
+const chanMaxFilesize = 10 << 20
+
+// ChanHandler serves the board read-only in the shape of the 4chan JSON
+// API, so existing imageboard clients and archivers work against it.
+type ChanHandler struct {
+	boardService   *service.BoardService
+	postService    service.PostService
+	archiveService service.ArchiveReader
+	storage        *storage.MinioClient
+	logger         *slog.Logger
+}
+
+func NewChanHandler(boardService *service.BoardService, postService service.PostService, archiveService service.ArchiveReader, storage *storage.MinioClient, logger *slog.Logger) *ChanHandler {
+	return &ChanHandler{boardService: boardService, postService: postService, archiveService: archiveService, storage: storage, logger: logger}
+}
+
+// Boards serves boards.json.
+func (h *ChanHandler) Boards(w http.ResponseWriter, r *http.Request) {
+	boards, err := h.boardService.GetAllBoards(r.Context())
+	if err != nil {
+		h.fail(w, r, "Failed to list boards", err)
+		return
+	}
+	body := chanapi.Boards{Boards: []chanapi.Board{}}
+	for _, b := range boards {
+		body.Boards = append(body.Boards, chanapi.NewBoard(b, chanMaxPages, chanMaxFilesize))
+	}
+	h.write(w, body)
+}
+
+// Catalog serves {board}/catalog.json: every live thread of the board in
+// bump order with its latest replies.
+func (h *ChanHandler) Catalog(w http.ResponseWriter, r *http.Request) {
+	threads, err := h.threads(r)
+	if err != nil {
+		h.fail(w, r, "Failed to list threads for catalog", err)
+		return
+	}
+	h.write(w, chanapi.Catalog(threads))
+}
+
+// Threads serves {board}/threads.json, the catalog without the posts.
+func (h *ChanHandler) Threads(w http.ResponseWriter, r *http.Request) {
+	threads, err := h.threads(r)
+	if err != nil {
+		h.fail(w, r, "Failed to list threads", err)
+		return
+	}
+	h.write(w, chanapi.Threads(threads))
+}
+
+// Thread serves {board}/thread/{no}.json, falling back to the archive, by
+// the same number, for threads that have expired.
+func (h *ChanHandler) Thread(w http.ResponseWriter, r *http.Request) {
+	board := r.PathValue("board")
+	id, ok := strings.CutSuffix(r.PathValue("id"), ".json")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	postID, err := strconv.Atoi(id)
+	if err != nil {
+		http.Error(w, domain.ErrInvalidIDFormat.Error(), http.StatusBadRequest)
+		return
+	}
+
+	archived := false
+	post, err := h.postService.GetPost(r.Context(), postID)
+	if err != nil && errors.Is(err, domain.ErrPostNotFound) {
+		archived = true
+		post, err = h.archiveService.GetArchivedPostByID(r.Context(), postID)
+	}
+	if err == nil && post.Board != board {
+		err = domain.ErrPostNotFound
+	}
+	if err != nil {
+		h.fail(w, r, "Failed to load thread", err)
+		return
+	}
+	h.write(w, chanapi.FullThread(post, archived))
+}
+
+// Archive serves {board}/archive.json, the numbers of the board's archived
+// threads, oldest first. Threads keep their number in the archive, so each
+// is served by Thread under it.
+func (h *ChanHandler) Archive(w http.ResponseWriter, r *http.Request) {
+	board := r.PathValue("board")
+	if _, err := h.boardService.GetBoard(r.Context(), board); err != nil {
+		h.fail(w, r, "Failed to load board", err)
+		return
+	}
+	archived, err := h.archiveService.ListArchivedPosts(r.Context(), board, 0, math.MaxInt32)
+	if err != nil {
+		h.fail(w, r, "Failed to list archived threads", err)
+		return
+	}
+	nos := make([]int, 0, len(archived))
+	for i := len(archived) - 1; i >= 0; i-- {
+		nos = append(nos, archived[i].ID)
+	}
+	h.write(w, nos)
+}
+
+// Image serves {board}/{tim}{ext} and the thumbnail {board}/{tim}s.jpg.
+// Thumbnails are not kept, so the original stands in for them.
+func (h *ChanHandler) Image(w http.ResponseWriter, r *http.Request) {
+	file := r.PathValue("file")
+	name := strings.TrimSuffix(file, path.Ext(file))
+	if thumb, ok := strings.CutSuffix(file, "s.jpg"); ok {
+		name = thumb
+	}
+	tim, err := strconv.ParseInt(name, 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	bucket, object, err := h.storage.FindImage(r.Context(), tim)
+	if err != nil {
+		h.fail(w, r, "Failed to find image", err)
+		return
+	}
+	data, contentType, err := h.storage.GetImage(r.Context(), bucket, object)
+	if err != nil {
+		http.Error(w, "Image not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Cache-Control", "public, max-age=86400, immutable")
+	storage.WriteImage(w, data, contentType, object)
+}
+
+// threads lists the live threads of the board in the path, in bump order.
+func (h *ChanHandler) threads(r *http.Request) ([]*domain.Post, error) {
+	board := r.PathValue("board")
+	if _, err := h.boardService.GetBoard(r.Context(), board); err != nil {
+		return nil, err
+	}
+	posts, err := h.postService.GetAllPosts(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	threads := posts[:0]
+	for _, post := range posts {
+		if post.Board == board {
+			threads = append(threads, post)
+		}
+	}
+	return threads, nil
+}
+
+// write sends body as JSON. Clients poll these endpoints, so they may
+// cache it briefly.
+func (h *ChanHandler) write(w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Cache-Control", "public, max-age=10")
+	writeJSON(w, http.StatusOK, body)
+}
+
+func (h *ChanHandler) fail(w http.ResponseWriter, r *http.Request, message string, err error) {
+	status, _ := ErrorStatus(err)
+	if status >= http.StatusInternalServerError {
+		requestLog(r, h.logger).Error(message, "error", err)
+	}
+	http.Error(w, err.Error(), status)
+}
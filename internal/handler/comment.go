@@ -12,34 +12,43 @@ import (
 
 	"1337b04rd/internal/adapters/storage"
 	"1337b04rd/internal/ports"
+	"1337b04rd/internal/service"
 	"1337b04rd/pkg/middleware"
 )
 
 type CommentHandler struct {
 	commentService ports.CommentService
+	captchaService *service.CaptchaService
 	storage        *storage.MinioClient
 	logger         *slog.Logger
 }
 
-func NewCommentHandler(commentService ports.CommentService, storage *storage.MinioClient, logger *slog.Logger) *CommentHandler {
-	return &CommentHandler{commentService: commentService, storage: storage, logger: logger}
+func NewCommentHandler(commentService ports.CommentService, captchaService *service.CaptchaService, storage *storage.MinioClient, logger *slog.Logger) *CommentHandler {
+	return &CommentHandler{commentService: commentService, captchaService: captchaService, storage: storage, logger: logger}
 }
 
 func (h *CommentHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
 	err := r.ParseMultipartForm(10 << 20)
 	if err != nil {
-		h.logger.Error("Could not parse multipart form", "method", r.Method)
+		requestLog(r, h.logger).Error("Could not parse multipart form")
 		http.Error(w, "Could not parse multipart form", http.StatusBadRequest)
 		return
 	}
 
 	postID, err := strconv.Atoi(r.FormValue("post_id"))
 	if err != nil {
-		h.logger.Error("Invalid post_id", "method", r.Method)
+		requestLog(r, h.logger).Error("Invalid post_id")
 		http.Error(w, "Invalid post_id", http.StatusBadRequest)
 		return
 	}
 
+	if err := h.captchaService.VerifyReply(r.Context(), postID, r.FormValue("captcha_token"), r.FormValue("captcha_nonce")); err != nil {
+		requestLog(r, h.logger).Warn("Captcha check failed", "post_id", postID, "error", err)
+		status, _ := ErrorStatus(err)
+		http.Error(w, err.Error(), status)
+		return
+	}
+
 	title := r.FormValue("title")
 	content := r.FormValue("content")
 	replyToStr := r.FormValue("reply_to_id")
@@ -53,12 +62,39 @@ func (h *CommentHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
 
 	session, err := middleware.GetSession(r.Context())
 	if err != nil {
-		h.logger.Error("Unauthorized", "method", r.Method)
+		requestLog(r, h.logger).Error("Unauthorized")
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	var imageURL string
+	// An image may also be linked; it is fetched and stored like an upload.
+	remote, err := fetchFormImage(r, h.storage)
+	if err != nil {
+		requestLog(r, h.logger).Warn("Failed to fetch image by URL", "post_id", postID, "error", err)
+		status, _ := ErrorStatus(err)
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	// The image is stored before the comment, which may still be refused;
+	// it is deleted again unless the comment is saved.
+	var imageURL, imageSourceURL string
+	saved := false
+	defer func() {
+		if imageURL != "" && !saved {
+			discardImage(r, h.storage, h.logger, imageURL)
+		}
+	}()
+	if remote != nil {
+		imageURL, err = h.storage.UploadCommentImage(r.Context(), remote.data, remote.filename(), remote.contentType)
+		if err != nil {
+			requestLog(r, h.logger).Error("Error uploading image", "error", err)
+			http.Error(w, fmt.Sprintf("Error uploading image: %s", err), http.StatusInternalServerError)
+			return
+		}
+		imageSourceURL = remote.sourceURL
+	}
+
 	file, handler, err := r.FormFile("image")
 	if err == nil {
 		defer file.Close()
@@ -66,7 +102,7 @@ func (h *CommentHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
 		buf := new(bytes.Buffer)
 		_, err := io.Copy(buf, file)
 		if err != nil {
-			h.logger.Error("Failed to read image", "method", r.Method)
+			requestLog(r, h.logger).Error("Failed to read image")
 			http.Error(w, "Failed to read image", http.StatusInternalServerError)
 			return
 		}
@@ -78,7 +114,7 @@ func (h *CommentHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
 			handler.Header.Get("Content-Type"),
 		)
 		if err != nil {
-			h.logger.Error("Error uploading image", "error", err, "method", r.Method)
+			requestLog(r, h.logger).Error("Error uploading image", "error", err)
 			http.Error(w, fmt.Sprintf("Error uploading image: %s", err), http.StatusInternalServerError)
 			return
 		}
@@ -92,16 +128,18 @@ func (h *CommentHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
 		title,
 		content,
 		imageURL,
+		imageSourceURL,
 		replyToID,
 	)
-	fmt.Println(comment)
 	if err != nil {
-		h.logger.Error("error", "error", err.Error(), "method", r.Method)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		requestLog(r, h.logger).Warn("Failed to create comment", "post_id", postID, "error", err)
+		status, _ := ErrorStatus(err)
+		http.Error(w, err.Error(), status)
 		return
 	}
+	saved = true
 
-	h.logger.Info("Successfully created comment", "method", r.Method)
+	requestLog(r, h.logger).Info("Successfully created comment", "post_id", postID, "comment_id", comment.ID)
 	http.Redirect(w, r, fmt.Sprintf("/posts/%d", postID), http.StatusSeeOther)
 }
 
@@ -109,19 +147,19 @@ func (h *CommentHandler) GetComment(w http.ResponseWriter, r *http.Request) {
 	idStr := strings.TrimPrefix(r.URL.Path, "/comments/")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		h.logger.Error("Invalid comment ID", "method", r.Method)
+		requestLog(r, h.logger).Error("Invalid comment ID")
 		http.Error(w, "Invalid comment ID", http.StatusBadRequest)
 		return
 	}
 
 	comment, err := h.commentService.GetComment(r.Context(), id)
 	if err != nil {
-		h.logger.Error("Comment not found", "method", r.Method)
+		requestLog(r, h.logger).Error("Comment not found")
 		http.Error(w, "Comment not found", http.StatusNotFound)
 		return
 	}
 
-	h.logger.Info("Successfully got comment by id", "method", r.Method)
+	requestLog(r, h.logger).Info("Successfully got comment by id")
 	writeJSON(w, http.StatusOK, comment)
 }
 
@@ -129,18 +167,18 @@ func (h *CommentHandler) GetCommentsOfPost(w http.ResponseWriter, r *http.Reques
 	idStr := strings.TrimPrefix(r.URL.Path, "/comments/post/")
 	postID, err := strconv.Atoi(idStr)
 	if err != nil {
-		h.logger.Error("Invalid post ID", "method", r.Method)
+		requestLog(r, h.logger).Error("Invalid post ID")
 		http.Error(w, "Invalid post ID", http.StatusBadRequest)
 		return
 	}
 
 	comments, err := h.commentService.GetAllCommentsOfPost(r.Context(), postID)
 	if err != nil {
-		h.logger.Error("Failed to fetch comments", "method", r.Method)
+		requestLog(r, h.logger).Error("Failed to fetch comments")
 		http.Error(w, "Failed to fetch comments", http.StatusInternalServerError)
 		return
 	}
-	h.logger.Info("Successfully got comments of post", "method", r.Method)
+	requestLog(r, h.logger).Info("Successfully got comments of post")
 	writeJSON(w, http.StatusOK, comments)
 }
 
@@ -153,7 +191,7 @@ func writeJSON(w http.ResponseWriter, code int, data interface{}) {
 func (h *CommentHandler) GetRepliesToComment(w http.ResponseWriter, r *http.Request) {
 	pathParts := strings.Split(r.URL.Path, "/")
 	if len(pathParts) < 4 {
-		h.logger.Error("Invalid URL", "method", r.Method)
+		requestLog(r, h.logger).Error("Invalid URL")
 		http.Error(w, "Invalid URL", http.StatusBadRequest)
 		return
 	}
@@ -161,19 +199,19 @@ func (h *CommentHandler) GetRepliesToComment(w http.ResponseWriter, r *http.Requ
 	idStr := pathParts[3]
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		h.logger.Error("Invalid comment ID", "method", r.Method)
+		requestLog(r, h.logger).Error("Invalid comment ID")
 		http.Error(w, "Invalid comment ID", http.StatusBadRequest)
 		return
 	}
 
 	replies, err := h.commentService.GetRepliesToComment(r.Context(), id)
 	if err != nil {
-		h.logger.Error("Failed to fetch replies", "method", r.Method)
+		requestLog(r, h.logger).Error("Failed to fetch replies")
 		http.Error(w, "Failed to fetch replies", http.StatusInternalServerError)
 		return
 	}
 
-	h.logger.Info("Successfully got replies to comment", "method", r.Method)
+	requestLog(r, h.logger).Info("Successfully got replies to comment")
 
 	writeJSON(w, http.StatusOK, replies)
 }
@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"1337b04rd/internal/domain"
+)
+
+const (
+	// csrfCookie holds the random key tokens are derived from, so visitors
+	// without a session, such as staff logging in, are covered too.
+	csrfCookie = "csrf_key"
+	// CSRFField is the hidden form field templates put the token in.
+	CSRFField = "csrf_token"
+	// CSRFHeader carries the token for scripts.
+	CSRFHeader = "X-CSRF-Token"
+	// APIClientHeader exempts a request from the token. Browsers do not send
+	// custom headers cross-site without a CORS preflight, which is never
+	// granted, so only same-origin scripts and API clients can set it.
+	APIClientHeader = "X-Requested-With"
+)
+
+type csrfTokenKey struct{}
+
+// CSRFHandler guards every state-changing request with a synchronizer token
+// derived from the CSRF key cookie and the poster and staff session cookies,
+// so a token is only good for the browser and sessions it was rendered for.
+type CSRFHandler struct {
+	secret []byte
+	logger *slog.Logger
+}
+
+func NewCSRFHandler(secret []byte, logger *slog.Logger) *CSRFHandler {
+	return &CSRFHandler{secret: secret, logger: logger}
+}
+
+// Protect issues the CSRF key cookie where missing, stores the request's
+// token for templates, and rejects POST, PUT, PATCH and DELETE requests
+// that carry neither a valid token nor APIClientHeader.
+func (h *CSRFHandler) Protect(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := ""
+		if cookie, err := r.Cookie(csrfCookie); err == nil && validCSRFKey(cookie.Value) {
+			key = cookie.Value
+		} else {
+			var err error
+			if key, err = newCSRFKey(); err != nil {
+				requestLog(r, h.logger).Error("Failed to generate CSRF key", "error", err)
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			http.SetCookie(w, &http.Cookie{
+				Name:     csrfCookie,
+				Value:    key,
+				Path:     "/",
+				HttpOnly: true,
+				Secure:   r.TLS != nil,
+				SameSite: http.SameSiteLaxMode,
+			})
+		}
+
+		token := h.token(r, key)
+		r = r.WithContext(context.WithValue(r.Context(), csrfTokenKey{}, token))
+
+		if safeMethod(r.Method) || r.Header.Get(APIClientHeader) != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		sent := r.Header.Get(CSRFHeader)
+		if sent == "" {
+			if err := r.ParseMultipartForm(maxUploadFormMemory); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+				requestLog(r, h.logger).Warn("Failed to parse form for CSRF token", "error", err, "path", r.URL.Path)
+			}
+			sent = r.PostFormValue(CSRFField)
+		}
+		if !hmac.Equal([]byte(sent), []byte(token)) {
+			requestLog(r, h.logger).Warn("Rejected request without a valid CSRF token", "path", r.URL.Path)
+			if strings.HasPrefix(r.URL.Path, "/api/") {
+				writeError(w, domain.ErrInvalidCSRFToken)
+				return
+			}
+			http.Error(w, domain.ErrInvalidCSRFToken.Error(), http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// token signs the key together with the session cookies the request has.
+func (h *CSRFHandler) token(r *http.Request, key string) string {
+	mac := hmac.New(sha256.New, h.secret)
+	mac.Write([]byte(key))
+	for _, name := range []string{"session_id", staffCookie} {
+		mac.Write([]byte{0})
+		if cookie, err := r.Cookie(name); err == nil {
+			mac.Write([]byte(cookie.Value))
+		}
+	}
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// CSRFToken returns the token forms of this request must send back, empty
+// for requests that did not pass through Protect.
+func CSRFToken(ctx context.Context) string {
+	token, _ := ctx.Value(csrfTokenKey{}).(string)
+	return token
+}
+
+func safeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+func newCSRFKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(key), nil
+}
+
+func validCSRFKey(key string) bool {
+	if len(key) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(key)
+	return err == nil
+}
@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"1337b04rd/internal/domain"
+)
+
+// APIError is the body of every error returned by the JSON API:
+//
+//	{"error": {"code": "post_not_found", "message": "post not found"}}
+type APIError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type errorEnvelope struct {
+	Error APIError `json:"error"`
+}
+
+type errorMapping struct {
+	err    error
+	status int
+	code   string
+}
+
+// errorMappings translates domain errors into HTTP statuses. The first match
+// (by errors.Is) wins; anything unmapped is an internal error.
+var errorMappings = []errorMapping{
+	// not found
+	{domain.ErrPostNotFound, http.StatusNotFound, "post_not_found"},
+	{domain.ErrImageNotFound, http.StatusNotFound, "image_not_found"},
+	{domain.ErrCommentNptFound, http.StatusNotFound, "comment_not_found"},
+	{domain.ErrBoardNotFound, http.StatusNotFound, "board_not_found"},
+	{domain.ErrStaffNotFound, http.StatusNotFound, "staff_not_found"},
+	{domain.ErrBanNotFound, http.StatusNotFound, "ban_not_found"},
+	{domain.ErrAppealNotFound, http.StatusNotFound, "appeal_not_found"},
+	{domain.ErrFilterNotFound, http.StatusNotFound, "filter_not_found"},
+	{domain.ErrUserNotFound, http.StatusNotFound, "user_not_found"},
+	{domain.ErrAvatarNotFound, http.StatusNotFound, "avatar_not_found"},
+
+	// state conflicts
+	{domain.ErrPostAlreadyDeleted, http.StatusGone, "post_deleted"},
+	{domain.ErrPostExpired, http.StatusGone, "post_expired"},
+	{domain.ErrThreadLocked, http.StatusLocked, "thread_locked"},
+	{domain.ErrStaffExists, http.StatusConflict, "staff_exists"},
+	{domain.ErrAppealExists, http.StatusConflict, "appeal_exists"},
+	{domain.ErrAlreadyReported, http.StatusConflict, "already_reported"},
+
+	// validation
+	{domain.ErrEmptyContent, http.StatusUnprocessableEntity, "empty_content"},
+	{domain.ErrTitleEmpty, http.StatusUnprocessableEntity, "empty_title"},
+	{domain.ErrEmptyComment, http.StatusUnprocessableEntity, "empty_comment"},
+	{domain.ErrEmptyUserName, http.StatusUnprocessableEntity, "empty_user_name"},
+	{domain.ErrEmptyAvatar, http.StatusUnprocessableEntity, "empty_avatar"},
+	{domain.ErrInvalidStaffRole, http.StatusUnprocessableEntity, "invalid_staff_role"},
+	{domain.ErrWeakPassword, http.StatusUnprocessableEntity, "weak_password"},
+	{domain.ErrInvalidBanTarget, http.StatusUnprocessableEntity, "invalid_ban_target"},
+	{domain.ErrEmptyBanReason, http.StatusUnprocessableEntity, "empty_ban_reason"},
+	{domain.ErrEmptyAppeal, http.StatusUnprocessableEntity, "empty_appeal"},
+	{domain.ErrAppealTooLong, http.StatusUnprocessableEntity, "appeal_too_long"},
+	{domain.ErrInvalidReportTarget, http.StatusUnprocessableEntity, "invalid_report_target"},
+	{domain.ErrInvalidReportCategory, http.StatusUnprocessableEntity, "invalid_report_category"},
+	{domain.ErrInvalidResolution, http.StatusUnprocessableEntity, "invalid_resolution"},
+	{domain.ErrReportNoteTooLong, http.StatusUnprocessableEntity, "report_note_too_long"},
+	{domain.ErrContentRejected, http.StatusUnprocessableEntity, "content_rejected"},
+	{domain.ErrInvalidFilter, http.StatusUnprocessableEntity, "invalid_filter"},
+	{domain.ErrInvalidFilterPattern, http.StatusUnprocessableEntity, "invalid_filter_pattern"},
+	{domain.ErrEmptyFilterReason, http.StatusUnprocessableEntity, "empty_filter_reason"},
+	{domain.ErrFetchFailed, http.StatusUnprocessableEntity, "fetch_failed"},
+	{domain.ErrFetchBlocked, http.StatusUnprocessableEntity, "fetch_blocked"},
+	{domain.ErrFetchTooLarge, http.StatusRequestEntityTooLarge, "fetch_too_large"},
+	{domain.ErrFetchContentType, http.StatusUnsupportedMediaType, "unsupported_media_type"},
+	{domain.ErrEmptySearchQuery, http.StatusBadRequest, "empty_search_query"},
+	{domain.ErrInvalidSearchSort, http.StatusBadRequest, "invalid_search_sort"},
+	{domain.ErrInvalidDateRange, http.StatusBadRequest, "invalid_date_range"},
+	{domain.ErrMissingID, http.StatusBadRequest, "missing_id"},
+	{domain.ErrInvalidIDFormat, http.StatusBadRequest, "invalid_id"},
+	{domain.ErrInvalidCursor, http.StatusBadRequest, "invalid_cursor"},
+	{domain.ErrInvalidParam, http.StatusBadRequest, "invalid_parameter"},
+	{domain.ErrInvalidBody, http.StatusBadRequest, "invalid_body"},
+	{domain.ErrInvalidTopic, http.StatusBadRequest, "invalid_topic"},
+	{domain.ErrImageAndImageURL, http.StatusBadRequest, "image_and_image_url"},
+
+	// rate limits
+	{domain.ErrReportRateLimited, http.StatusTooManyRequests, "report_rate_limited"},
+	{domain.ErrRateLimited, http.StatusTooManyRequests, "rate_limited"},
+
+	// live updates
+	{domain.ErrTooManySubscriptions, http.StatusTooManyRequests, "too_many_subscriptions"},
+	{domain.ErrTooManyConnections, http.StatusTooManyRequests, "too_many_connections"},
+	{domain.ErrOriginNotAllowed, http.StatusForbidden, "origin_not_allowed"},
+	{domain.ErrSubscriptionOverflown, http.StatusServiceUnavailable, "subscription_dropped"},
+
+	// sessions
+	{domain.ErrInvalidSession, http.StatusUnauthorized, "invalid_session"},
+	{domain.ErrInvalidSessionID, http.StatusUnauthorized, "invalid_session"},
+	{domain.ErrUnauthorized, http.StatusForbidden, "forbidden"},
+	{domain.ErrBanned, http.StatusForbidden, "banned"},
+	{domain.ErrBanCheckFailed, http.StatusServiceUnavailable, "ban_check_failed"},
+	{domain.ErrInvalidCSRFToken, http.StatusForbidden, "csrf_failed"},
+	{domain.ErrInvalidCredentials, http.StatusUnauthorized, "invalid_credentials"},
+	{domain.ErrInvalidStaffSession, http.StatusUnauthorized, "staff_login_required"},
+
+	// captcha
+	{domain.ErrCaptchaRequired, http.StatusForbidden, "captcha_required"},
+	{domain.ErrCaptchaInvalid, http.StatusForbidden, "captcha_invalid"},
+	{domain.ErrCaptchaExpired, http.StatusForbidden, "captcha_expired"},
+	{domain.ErrCaptchaUsed, http.StatusForbidden, "captcha_used"},
+
+	// dependencies
+	{domain.ErrStorageUploadFailed, http.StatusBadGateway, "storage_error"},
+	{domain.ErrStorageDeleteFailed, http.StatusBadGateway, "storage_error"},
+	{domain.ErrDatabaseError, http.StatusInternalServerError, "database_error"},
+}
+
+// ErrorStatus translates err into an HTTP status and a machine-readable code.
+func ErrorStatus(err error) (int, string) {
+	for _, m := range errorMappings {
+		if errors.Is(err, m.err) {
+			return m.status, m.code
+		}
+	}
+	return http.StatusInternalServerError, "internal_error"
+}
+
+// writeError writes err as an error envelope. Messages of server errors are
+// replaced by the status text so internals do not leak to clients.
+func writeError(w http.ResponseWriter, err error) {
+	status, code := ErrorStatus(err)
+	message := err.Error()
+	if status >= http.StatusInternalServerError {
+		message = http.StatusText(status)
+	}
+	writeJSON(w, status, errorEnvelope{Error: APIError{Code: code, Message: message}})
+}
@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"1337b04rd/internal/adapters/events"
+	"1337b04rd/internal/domain"
+	"1337b04rd/internal/service"
+)
+
+const (
+	// heartbeatInterval keeps idle streams alive through proxies.
+	heartbeatInterval = 15 * time.Second
+	// eventWriteTimeout bounds how long a single frame may take to reach a
+	// client before the connection is given up on.
+	eventWriteTimeout = 10 * time.Second
+	// reconnectDelay is the retry hint sent to EventSource clients.
+	reconnectDelay = 3 * time.Second
+)
+
+type EventsHandler struct {
+	bus         *events.Bus
+	postService service.PostService
+	logger      *slog.Logger
+}
+
+func NewEventsHandler(bus *events.Bus, postService service.PostService, logger *slog.Logger) *EventsHandler {
+	return &EventsHandler{bus: bus, postService: postService, logger: logger}
+}
+
+// StreamPostEvents streams the events of one thread as Server-Sent Events.
+// Clients resume with the Last-Event-ID header, or the last_event_id query
+// parameter for clients that cannot set headers.
+func (h *EventsHandler) StreamPostEvents(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		requestLog(r, h.logger).Error("Invalid post ID")
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	lastEventID, err := parseLastEventID(r)
+	if err != nil {
+		requestLog(r, h.logger).Error("Invalid Last-Event-ID")
+		http.Error(w, "Invalid Last-Event-ID", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.postService.GetPost(r.Context(), postID); err != nil {
+		requestLog(r, h.logger).Error("Post not found", "post_id", postID)
+		http.Error(w, "Post not found", http.StatusNotFound)
+		return
+	}
+
+	sub, replay := h.bus.Subscribe(events.ForPost(postID), lastEventID)
+	defer sub.Close()
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if err := h.write(w, rc, fmt.Sprintf("retry: %d\n\n", reconnectDelay.Milliseconds())); err != nil {
+		return
+	}
+
+	for _, event := range replay {
+		if done, err := h.send(w, rc, event); err != nil || done {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if err := h.write(w, rc, ": ping\n\n"); err != nil {
+				return
+			}
+		case event, ok := <-sub.C:
+			if !ok {
+				if sub.Dropped() {
+					requestLog(r, h.logger).Warn("Dropped slow event subscriber", "post_id", postID)
+				}
+				return
+			}
+			if done, err := h.send(w, rc, event); err != nil || done {
+				return
+			}
+		}
+	}
+}
+
+// send writes event as a frame and reports whether the thread is gone, in
+// which case the stream ends.
+func (h *EventsHandler) send(w http.ResponseWriter, rc *http.ResponseController, event domain.Event) (bool, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return false, err
+	}
+	frame := fmt.Sprintf("id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	if err := h.write(w, rc, frame); err != nil {
+		return false, err
+	}
+	return event.Type == domain.EventDeleted || event.Type == domain.EventArchived, nil
+}
+
+func (h *EventsHandler) write(w http.ResponseWriter, rc *http.ResponseController, frame string) error {
+	_ = rc.SetWriteDeadline(time.Now().Add(eventWriteTimeout))
+	if _, err := fmt.Fprint(w, frame); err != nil {
+		return err
+	}
+	return rc.Flush()
+}
+
+func parseLastEventID(r *http.Request) (int64, error) {
+	raw := r.Header.Get("Last-Event-ID")
+	if raw == "" {
+		raw = r.URL.Query().Get("last_event_id")
+	}
+	if raw == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(raw, 10, 64)
+}
@@ -0,0 +1,236 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"1337b04rd/internal/adapters/feed"
+	"1337b04rd/internal/domain"
+	"1337b04rd/internal/service"
+)
+
+// feedLimit is how many threads the board and archive feeds list.
+const feedLimit = 50
+
+// entryContent renders a post as the HTML of a feed entry. Posts are plain
+// text, so every line is escaped and only the markup here is HTML.
+var entryContent = template.Must(template.New("entry").Parse(
+	`{{if .Image}}<p><a href="{{.Image}}"><img src="{{.Image}}" alt="{{.Title}}"></a></p>{{end}}` +
+		`{{range .Paragraphs}}<p>{{range $i, $line := .}}{{if $i}}<br>{{end}}{{$line}}{{end}}</p>{{end}}`))
+
+// FeedHandler serves Atom and RSS feeds of the board, the archive and
+// single threads.
+type FeedHandler struct {
+	postService    service.PostService
+	archiveService service.ArchiveReader
+	// baseURL makes feed links absolute. Without one they are built from
+	// the request.
+	baseURL string
+	logger  *slog.Logger
+}
+
+func NewFeedHandler(postService service.PostService, archiveService service.ArchiveReader, baseURL string, logger *slog.Logger) *FeedHandler {
+	return &FeedHandler{postService: postService, archiveService: archiveService, baseURL: strings.TrimSuffix(baseURL, "/"), logger: logger}
+}
+
+// PostsRSS serves the latest threads as RSS.
+func (h *FeedHandler) PostsRSS(w http.ResponseWriter, r *http.Request) {
+	h.servePosts(w, r, "/posts.rss", feed.RSSContentType, feed.WriteRSS)
+}
+
+// PostsAtom serves the latest threads as Atom.
+func (h *FeedHandler) PostsAtom(w http.ResponseWriter, r *http.Request) {
+	h.servePosts(w, r, "/posts.atom", feed.AtomContentType, feed.WriteAtom)
+}
+
+func (h *FeedHandler) servePosts(w http.ResponseWriter, r *http.Request, self, contentType string, write func(io.Writer, *feed.Feed) error) {
+	posts, err := h.postService.ListPosts(r.Context(), "", 0, feedLimit)
+	if err != nil {
+		h.fail(w, r, "Failed to list threads for feed", err)
+		return
+	}
+
+	base := h.base(r)
+	f := &feed.Feed{
+		ID:       "urn:1337b04rd:feed:posts",
+		Title:    "1337b04rd",
+		Subtitle: "Latest threads across every dimension",
+		Link:     base + "/posts",
+		SelfLink: base + self,
+	}
+	for _, post := range posts {
+		updated := post.CreatedAt
+		if post.LastReplyAt != nil && post.LastReplyAt.After(updated) {
+			updated = *post.LastReplyAt
+		}
+		f.Entries = append(f.Entries, h.entry(base, postGUID(post.ID), post.Title, fmt.Sprintf("/posts/%d", post.ID), post.AuthorName, post.Content, post.ImageURL, post.CreatedAt, updated))
+	}
+	h.serve(w, r, f, contentType, write)
+}
+
+// ThreadAtom serves a thread, opening post and replies, as Atom. Archived
+// threads keep their feed, so readers following one are not cut off.
+func (h *FeedHandler) ThreadAtom(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.Atoi(strings.TrimSuffix(r.PathValue("id"), ".atom"))
+	if err != nil {
+		http.Error(w, domain.ErrInvalidIDFormat.Error(), http.StatusBadRequest)
+		return
+	}
+
+	link := fmt.Sprintf("/posts/%d", postID)
+	post, err := h.postService.GetPost(r.Context(), postID)
+	if err != nil && errors.Is(err, domain.ErrPostNotFound) {
+		link = fmt.Sprintf("/archive/%d", postID)
+		post, err = h.archiveService.GetArchivedPostByID(r.Context(), postID)
+	}
+	if err != nil {
+		h.fail(w, r, "Failed to load thread for feed", err)
+		return
+	}
+
+	base := h.base(r)
+	f := &feed.Feed{
+		ID:       fmt.Sprintf("urn:1337b04rd:thread:%d", postID),
+		Title:    post.Title,
+		Subtitle: "Replies to thread #" + strconv.Itoa(postID),
+		Link:     base + link,
+		SelfLink: fmt.Sprintf("%s/posts/%d.atom", base, postID),
+	}
+	f.Entries = append(f.Entries, h.entry(base, postGUID(post.ID), post.Title, link, post.AuthorName, post.Content, post.ImageURL, post.CreatedAt, post.CreatedAt))
+	for _, c := range post.Comments {
+		title := c.Title
+		if title == "" {
+			title = "Re: " + post.Title
+		}
+		f.Entries = append(f.Entries, h.entry(base, commentGUID(c.ID), title, fmt.Sprintf("%s#comment-%d", link, c.ID), c.AuthorName, c.Content, c.ImageURL, c.CreatedAt, c.CreatedAt))
+	}
+	// Newest first, as in the other feeds
+	for i, j := 0, len(f.Entries)-1; i < j; i, j = i+1, j-1 {
+		f.Entries[i], f.Entries[j] = f.Entries[j], f.Entries[i]
+	}
+	h.serve(w, r, f, feed.AtomContentType, feed.WriteAtom)
+}
+
+// ArchiveAtom serves the latest archived threads as Atom.
+func (h *FeedHandler) ArchiveAtom(w http.ResponseWriter, r *http.Request) {
+	posts, err := h.archiveService.ListArchivedPosts(r.Context(), "", 0, feedLimit)
+	if err != nil {
+		h.fail(w, r, "Failed to list archived threads for feed", err)
+		return
+	}
+
+	base := h.base(r)
+	f := &feed.Feed{
+		ID:       "urn:1337b04rd:feed:archive",
+		Title:    "1337b04rd archive",
+		Subtitle: "Threads that have left this dimension",
+		Link:     base + "/archive",
+		SelfLink: base + "/archive.atom",
+	}
+	for _, post := range posts {
+		f.Entries = append(f.Entries, h.entry(base, postGUID(post.ID), post.Title, fmt.Sprintf("/archive/%d", post.ID), post.AuthorName, post.Content, post.ImageURL, post.CreatedAt, post.ArchivedAt))
+	}
+	h.serve(w, r, f, feed.AtomContentType, feed.WriteAtom)
+}
+
+// entry builds a feed entry for a post or reply. Links and images are made
+// absolute against base.
+func (h *FeedHandler) entry(base, id, title, link, author, content, imageURL string, published, updated time.Time) feed.Entry {
+	image := absoluteURL(base, imageURL)
+	var paragraphs [][]string
+	for _, paragraph := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n\n") {
+		if paragraph = strings.Trim(paragraph, "\n"); paragraph != "" {
+			paragraphs = append(paragraphs, strings.Split(paragraph, "\n"))
+		}
+	}
+	var html strings.Builder
+	if err := entryContent.Execute(&html, map[string]interface{}{"Title": title, "Image": image, "Paragraphs": paragraphs}); err != nil {
+		h.logger.Error("Failed to render feed entry", "id", id, "error", err)
+	}
+
+	entry := feed.Entry{
+		ID:        id,
+		Title:     title,
+		Link:      base + link,
+		Author:    author,
+		Content:   html.String(),
+		Published: published,
+		Updated:   updated,
+	}
+	if image != "" {
+		contentType := mime.TypeByExtension(path.Ext(imageURL))
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		entry.Enclosure = &feed.Enclosure{URL: image, Type: contentType}
+	}
+	return entry
+}
+
+// serve writes f with an ETag of its content and a Last-Modified of its
+// newest entry, answering conditional requests with 304 Not Modified.
+func (h *FeedHandler) serve(w http.ResponseWriter, r *http.Request, f *feed.Feed, contentType string, write func(io.Writer, *feed.Feed) error) {
+	for _, e := range f.Entries {
+		if e.Updated.After(f.Updated) {
+			f.Updated = e.Updated
+		}
+	}
+	if f.Updated.IsZero() {
+		f.Updated = time.Unix(0, 0)
+	}
+
+	var buf bytes.Buffer
+	if err := write(&buf, f); err != nil {
+		h.fail(w, r, "Failed to write feed", err)
+		return
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	w.Header().Set("Cache-Control", "public, max-age=60")
+	http.ServeContent(w, r, "", f.Updated, bytes.NewReader(buf.Bytes()))
+}
+
+func (h *FeedHandler) fail(w http.ResponseWriter, r *http.Request, message string, err error) {
+	status, _ := ErrorStatus(err)
+	if status >= http.StatusInternalServerError {
+		requestLog(r, h.logger).Error(message, "error", err)
+	}
+	http.Error(w, err.Error(), status)
+}
+
+// base is the absolute URL of the board.
+func (h *FeedHandler) base(r *http.Request) string {
+	if h.baseURL != "" {
+		return h.baseURL
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// absoluteURL resolves the board's own image paths against base and leaves
+// anything else, such as an empty URL, alone.
+func absoluteURL(base, u string) string {
+	if strings.HasPrefix(u, "/") {
+		return base + u
+	}
+	return u
+}
+
+func postGUID(id int) string    { return fmt.Sprintf("urn:1337b04rd:post:%d", id) }
+func commentGUID(id int) string { return fmt.Sprintf("urn:1337b04rd:comment:%d", id) }
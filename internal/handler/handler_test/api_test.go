@@ -0,0 +1,86 @@
+package handler_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"1337b04rd/internal/domain"
+	"1337b04rd/internal/handler"
+	"1337b04rd/internal/ports"
+	"1337b04rd/internal/service"
+	"1337b04rd/pkg/middleware"
+)
+
+// captchaBoards are boards that all ask for a captcha.
+type captchaBoards struct{}
+
+func (captchaBoards) FindAll(ctx context.Context) ([]*domain.Board, error) { return nil, nil }
+
+func (captchaBoards) FindBySlug(ctx context.Context, slug string) (*domain.Board, error) {
+	return &domain.Board{Slug: slug, CaptchaDifficulty: 4}, nil
+}
+
+// boardThreads finds every thread on /b/.
+type boardThreads struct {
+	ports.PostRepository
+}
+
+func (boardThreads) FindByID(ctx context.Context, id int) (*domain.Post, error) {
+	return &domain.Post{ID: id, Board: "b"}, nil
+}
+
+// usedChallenges remembers the challenges that were used.
+type usedChallenges map[string]bool
+
+func (c usedChallenges) Use(ctx context.Context, id string, expiresAt time.Time) error {
+	if c[id] {
+		return domain.ErrCaptchaUsed
+	}
+	c[id] = true
+	return nil
+}
+
+func (c usedChallenges) DeleteExpired(ctx context.Context, before time.Time) (int, error) {
+	return 0, nil
+}
+
+func TestCreateCommentKeepsCaptchaOnInvalidBody(t *testing.T) {
+	ctx := context.Background()
+	captcha := service.NewCaptchaService([]byte("secret"), captchaBoards{}, boardThreads{}, usedChallenges{})
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	h := handler.NewAPIHandler(nil, nil, nil, nil, nil, captcha, logger)
+
+	challenge, err := captcha.Issue(ctx, "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := challenge.Solve()
+
+	for body, want := range map[string]int{
+		`{"content":`:    http.StatusBadRequest,
+		`{"content":""}`: http.StatusUnprocessableEntity,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/threads/1/comments", strings.NewReader(body))
+		req.SetPathValue("id", "1")
+		req.Header.Set("X-Captcha-Token", challenge.Token)
+		req.Header.Set("X-Captcha-Nonce", nonce)
+		req.AddCookie(&http.Cookie{Name: "session_id", Value: "s1"})
+		req.AddCookie(&http.Cookie{Name: "session_name", Value: "Rick"})
+		rec := httptest.NewRecorder()
+		middleware.LoadSessionMiddleware()(http.HandlerFunc(h.CreateComment)).ServeHTTP(rec, req)
+
+		if rec.Code != want {
+			t.Errorf("%s: got %d, want %d: %s", body, rec.Code, want, rec.Body.String())
+		}
+	}
+
+	if err := captcha.VerifyReply(ctx, 1, challenge.Token, nonce); err != nil {
+		t.Fatalf("solved challenge after invalid bodies: %v", err)
+	}
+}
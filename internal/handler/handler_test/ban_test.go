@@ -0,0 +1,56 @@
+package handler_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"1337b04rd/internal/domain"
+	"1337b04rd/internal/handler"
+	"1337b04rd/internal/ports"
+	"1337b04rd/internal/service"
+	"1337b04rd/pkg/middleware"
+)
+
+// downBans fails every lookup, as with the database gone.
+type downBans struct {
+	ports.BanRepository
+}
+
+func (downBans) FindActive(ctx context.Context, sessionID, ipHash, ip, board string) ([]*domain.Ban, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestRejectBannedFailsClosed(t *testing.T) {
+	bans := service.NewBanService(downBans{}, nil, nil, nil)
+	h := handler.NewBanHandler(bans, nil, slog.Default())
+	posted := false
+	guard := h.RejectBanned(http.NotFoundHandler())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posted = true
+	}))
+
+	for _, path := range []string{"/api/v1/boards/b/threads", "/comments/create"} {
+		t.Run(path, func(t *testing.T) {
+			posted = false
+			req := httptest.NewRequest(http.MethodPost, path, nil)
+			req.SetPathValue("board", "b")
+			req = req.WithContext(middleware.WithClientIP(req.Context(), &middleware.ClientIP{Hash: "hash-1"}))
+			rec := httptest.NewRecorder()
+			guard.ServeHTTP(rec, req)
+
+			if posted {
+				t.Error("post went through without a ban check")
+			}
+			if rec.Code != http.StatusServiceUnavailable {
+				t.Errorf("got %d, want 503", rec.Code)
+			}
+			if strings.HasPrefix(path, "/api/") && !strings.Contains(rec.Body.String(), `"ban_check_failed"`) {
+				t.Errorf("API error = %s", rec.Body.String())
+			}
+		})
+	}
+}
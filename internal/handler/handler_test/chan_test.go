@@ -0,0 +1,59 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"1337b04rd/internal/adapters/chanapi"
+	"1337b04rd/internal/domain"
+	"1337b04rd/internal/handler"
+)
+
+func TestChanThreadArchived(t *testing.T) {
+	created := time.Date(2025, 5, 3, 21, 35, 0, 0, time.UTC)
+	archive := &feedArchive{posts: map[int]*domain.Post{
+		7: {ID: 7, Title: "Portal gun", Content: "wubba", Board: "b", CreatedAt: created, Comments: []domain.Comment{
+			{ID: 12, PostID: 7, Content: "lubba", CreatedAt: created.Add(time.Minute)},
+		}},
+	}}
+	h := handler.NewChanHandler(nil, goneService{}, archive, nil, slog.Default())
+
+	get := func(board, id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/"+board+"/thread/"+id, nil)
+		req.SetPathValue("board", board)
+		req.SetPathValue("id", id)
+		rec := httptest.NewRecorder()
+		h.Thread(rec, req)
+		return rec
+	}
+
+	rec := get("b", "7.json")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got %d: %s", rec.Code, rec.Body.String())
+	}
+	var thread chanapi.Thread
+	if err := json.Unmarshal(rec.Body.Bytes(), &thread); err != nil {
+		t.Fatal(err)
+	}
+	if len(thread.Posts) != 2 {
+		t.Fatalf("got %d posts, want 2", len(thread.Posts))
+	}
+	op, reply := thread.Posts[0], thread.Posts[1]
+	if op.No != 7 || op.Archived != 1 {
+		t.Errorf("opening post no = %d, archived = %d; want the live number 7, archived", op.No, op.Archived)
+	}
+	if reply.No != chanapi.ReplyNoOffset+12 || reply.Resto != 7 {
+		t.Errorf("reply no = %d, resto = %d", reply.No, reply.Resto)
+	}
+
+	if rec := get("g", "7.json"); rec.Code != http.StatusNotFound {
+		t.Errorf("thread on another board = %d, want 404", rec.Code)
+	}
+	if rec := get("b", "8.json"); rec.Code != http.StatusNotFound {
+		t.Errorf("thread neither live nor archived = %d, want 404", rec.Code)
+	}
+}
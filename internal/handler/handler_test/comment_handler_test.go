@@ -17,7 +17,7 @@ import (
 
 type MockCommentService struct{}
 
-func (s *MockCommentService) CreateComment(ctx context.Context, postID int, title, content, imageURL, sessionID, userID string, parentID *int) (*domain.Comment, error) {
+func (s *MockCommentService) CreateComment(ctx context.Context, postID int, title, content, imageURL, imageSourceURL, sessionID, userID string, parentID *int) (*domain.Comment, error) {
 	if postID == 0 {
 		return nil, errors.New("post not found")
 	}
@@ -93,7 +93,7 @@ func NewMockCommentHandler() *handler.CommentHandler {
 	log, _ := logger.SetupLogger() // or discard the log file if not needed
 	service := &MockCommentService{}
 	storage := &storage.MinioClient{}
-	return handler.NewCommentHandler(service, storage, log)
+	return handler.NewCommentHandler(service, nil, storage, log)
 }
 
 // ---- Tests ----
@@ -0,0 +1,66 @@
+package handler_test
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"1337b04rd/internal/handler"
+)
+
+func TestCSRFProtect(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	var token string
+	h := handler.NewCSRFHandler([]byte("secret"), logger).Protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token = handler.CSRFToken(r.Context())
+	}))
+
+	// Rendering a page hands out the key cookie and the token for its forms.
+	rec := httptest.NewRecorder()
+	page := httptest.NewRequest(http.MethodGet, "/posts/create", nil)
+	page.AddCookie(&http.Cookie{Name: "session_id", Value: "alice"})
+	h.ServeHTTP(rec, page)
+	cookies := rec.Result().Cookies()
+	if rec.Code != http.StatusOK || len(cookies) != 1 || token == "" {
+		t.Fatalf("page: status %d, cookies %v, token %q; want 200, the key cookie and a token", rec.Code, cookies, token)
+	}
+	key, formToken := cookies[0], token
+
+	post := func(session, sent string, header http.Header) int {
+		form := url.Values{handler.CSRFField: {sent}}
+		req := httptest.NewRequest(http.MethodPost, "/posts/create", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		for name := range header {
+			req.Header.Set(name, header[name][0])
+		}
+		req.AddCookie(key)
+		req.AddCookie(&http.Cookie{Name: "session_id", Value: session})
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	tests := []struct {
+		name    string
+		session string
+		sent    string
+		header  http.Header
+		want    int
+	}{
+		{"form token", "alice", formToken, nil, http.StatusOK},
+		{"header token", "alice", "", http.Header{handler.CSRFHeader: {formToken}}, http.StatusOK},
+		{"API client", "alice", "", http.Header{handler.APIClientHeader: {"fetch"}}, http.StatusOK},
+		{"no token", "alice", "", nil, http.StatusForbidden},
+		{"forged token", "alice", strings.Repeat("0", 64), nil, http.StatusForbidden},
+		{"token of another session", "mallory", formToken, nil, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		if got := post(tt.session, tt.sent, tt.header); got != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package handler_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"1337b04rd/internal/domain"
+	"1337b04rd/internal/handler"
+)
+
+func TestErrorStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+	}{
+		{"post not found", domain.ErrPostNotFound, http.StatusNotFound, "post_not_found"},
+		{"wrapped post not found", fmt.Errorf("post with id 7: %w", domain.ErrPostNotFound), http.StatusNotFound, "post_not_found"},
+		{"empty comment", domain.ErrEmptyComment, http.StatusUnprocessableEntity, "empty_comment"},
+		{"invalid cursor", domain.ErrInvalidCursor, http.StatusBadRequest, "invalid_cursor"},
+		{"invalid session", domain.ErrInvalidSession, http.StatusUnauthorized, "invalid_session"},
+		{"unknown error", errors.New("boom"), http.StatusInternalServerError, "internal_error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, code := handler.ErrorStatus(tt.err)
+			if status != tt.wantStatus || code != tt.wantCode {
+				t.Errorf("ErrorStatus(%v) = %d %q, want %d %q", tt.err, status, code, tt.wantStatus, tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/xml"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"1337b04rd/internal/domain"
+	"1337b04rd/internal/handler"
+	"1337b04rd/internal/service"
+)
+
+// feedPostService lists a fixed page of threads.
+type feedPostService struct {
+	service.PostService
+	posts []*domain.Post
+}
+
+func (s *feedPostService) ListPosts(ctx context.Context, board string, beforeID, limit int) ([]*domain.Post, error) {
+	return s.posts, nil
+}
+
+func TestPostsAtom(t *testing.T) {
+	created := time.Date(2025, 5, 3, 21, 35, 0, 0, time.UTC)
+	replied := created.Add(time.Hour)
+	posts := &feedPostService{posts: []*domain.Post{
+		{ID: 7, Title: "Portal gun", Content: "<script>alert(1)</script>\nwubba", AuthorName: "Rick", ImageURL: "/images/posts/1-gun.png", CreatedAt: created, LastReplyAt: &replied},
+	}}
+	h := handler.NewFeedHandler(posts, nil, "https://board.example/", slog.Default())
+
+	get := func(header, value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/posts.atom", nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		rec := httptest.NewRecorder()
+		h.PostsAtom(rec, req)
+		return rec
+	}
+
+	rec := get("", "")
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "application/atom+xml") {
+		t.Fatalf("got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if rec.Header().Get("Last-Modified") != replied.Format(http.TimeFormat) {
+		t.Errorf("Last-Modified = %q, want the last reply", rec.Header().Get("Last-Modified"))
+	}
+
+	var doc struct {
+		Entries []struct {
+			ID      string `xml:"id"`
+			Content string `xml:"content"`
+			Links   []struct {
+				Rel  string `xml:"rel,attr"`
+				Type string `xml:"type,attr"`
+				Href string `xml:"href,attr"`
+			} `xml:"link"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid feed: %v", err)
+	}
+	if len(doc.Entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(doc.Entries))
+	}
+	entry := doc.Entries[0]
+	if entry.ID != "urn:1337b04rd:post:7" {
+		t.Errorf("entry ID = %q", entry.ID)
+	}
+	if strings.Contains(entry.Content, "<script>") || !strings.Contains(entry.Content, "&lt;script&gt;") {
+		t.Errorf("post content not escaped: %q", entry.Content)
+	}
+	var enclosure string
+	for _, link := range entry.Links {
+		if link.Rel == "enclosure" && link.Type == "image/png" {
+			enclosure = link.Href
+		}
+	}
+	if enclosure != "https://board.example/images/posts/1-gun.png" {
+		t.Errorf("enclosure = %q, want the absolute image URL", enclosure)
+	}
+
+	etag := rec.Header().Get("ETag")
+	if rec := get("If-None-Match", etag); rec.Code != http.StatusNotModified {
+		t.Errorf("If-None-Match with the ETag = %d, want 304", rec.Code)
+	}
+	if rec := get("If-Modified-Since", replied.Format(http.TimeFormat)); rec.Code != http.StatusNotModified {
+		t.Errorf("If-Modified-Since the last reply = %d, want 304", rec.Code)
+	}
+	if rec := get("If-Modified-Since", created.Format(http.TimeFormat)); rec.Code != http.StatusOK {
+		t.Errorf("If-Modified-Since before the last reply = %d, want 200", rec.Code)
+	}
+}
+
+// feedArchive holds archived threads by the IDs they had while live.
+type feedArchive struct {
+	service.ArchiveReader
+	posts map[int]*domain.Post
+}
+
+func (a *feedArchive) GetArchivedPostByID(ctx context.Context, postID int) (*domain.Post, error) {
+	if post, ok := a.posts[postID]; ok {
+		return post, nil
+	}
+	return nil, domain.ErrPostNotFound
+}
+
+// goneService has no live threads left.
+type goneService struct {
+	service.PostService
+}
+
+func (goneService) GetPost(ctx context.Context, id int) (*domain.Post, error) {
+	return nil, domain.ErrPostNotFound
+}
+
+func TestThreadAtomArchived(t *testing.T) {
+	created := time.Date(2025, 5, 3, 21, 35, 0, 0, time.UTC)
+	archive := &feedArchive{posts: map[int]*domain.Post{
+		7: {ID: 7, Title: "Portal gun", Content: "wubba", AuthorName: "Rick", CreatedAt: created, Comments: []domain.Comment{
+			{ID: 12, PostID: 7, Content: "lubba", AuthorName: "Morty", CreatedAt: created.Add(time.Minute)},
+		}},
+	}}
+	h := handler.NewFeedHandler(goneService{}, archive, "https://board.example", slog.Default())
+
+	req := httptest.NewRequest(http.MethodGet, "/posts/7.atom", nil)
+	req.SetPathValue("id", "7.atom")
+	rec := httptest.NewRecorder()
+	h.ThreadAtom(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var doc struct {
+		Entries []struct {
+			ID    string `xml:"id"`
+			Links []struct {
+				Rel  string `xml:"rel,attr"`
+				Href string `xml:"href,attr"`
+			} `xml:"link"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid feed: %v", err)
+	}
+	want := []struct{ id, link string }{
+		{"urn:1337b04rd:comment:12", "https://board.example/archive/7#comment-12"},
+		{"urn:1337b04rd:post:7", "https://board.example/archive/7"},
+	}
+	if len(doc.Entries) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(doc.Entries), len(want))
+	}
+	for i, w := range want {
+		entry := doc.Entries[i]
+		if entry.ID != w.id {
+			t.Errorf("entry %d ID = %q, want the one it had while live, %q", i, entry.ID, w.id)
+		}
+		var link string
+		for _, l := range entry.Links {
+			if l.Rel == "alternate" || l.Rel == "" {
+				link = l.Href
+			}
+		}
+		if link != w.link {
+			t.Errorf("entry %d link = %q, want %q", i, link, w.link)
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/posts/8.atom", nil)
+	req.SetPathValue("id", "8.atom")
+	rec = httptest.NewRecorder()
+	h.ThreadAtom(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("thread neither live nor archived = %d, want 404", rec.Code)
+	}
+}
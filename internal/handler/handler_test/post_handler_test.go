@@ -0,0 +1,70 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"1337b04rd/internal/domain"
+	"1337b04rd/internal/handler"
+	"1337b04rd/internal/service"
+	"1337b04rd/pkg/middleware"
+)
+
+// openBoards are boards without a captcha.
+type openBoards struct{}
+
+func (openBoards) FindAll(ctx context.Context) ([]*domain.Board, error) { return nil, nil }
+
+func (openBoards) FindBySlug(ctx context.Context, slug string) (*domain.Board, error) {
+	return &domain.Board{Slug: slug}, nil
+}
+
+// refusingPostService refuses every thread with err.
+type refusingPostService struct {
+	service.PostService
+	err error
+}
+
+func (s refusingPostService) CreatePost(ctx context.Context, post *domain.Post) error {
+	return s.err
+}
+
+func TestCreatePostRefused(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"rejected", fmt.Errorf("filter 3: %w", domain.ErrContentRejected), http.StatusUnprocessableEntity},
+		{"banned", domain.ErrBanned, http.StatusForbidden},
+		{"database down", fmt.Errorf("unable to save post"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			captcha := service.NewCaptchaService([]byte("secret"), openBoards{}, nil, nil)
+			h := handler.NewPostHandler(refusingPostService{err: tt.err}, captcha, nil, slog.Default())
+
+			var body bytes.Buffer
+			form := multipart.NewWriter(&body)
+			form.WriteField("title", "Portal gun")
+			form.WriteField("content", "wubba lubba dub dub")
+			form.Close()
+			req := httptest.NewRequest(http.MethodPost, "/submit-post", &body)
+			req.Header.Set("Content-Type", form.FormDataContentType())
+			req.AddCookie(&http.Cookie{Name: "session_id", Value: "s1"})
+			req.AddCookie(&http.Cookie{Name: "session_name", Value: "Rick"})
+			rec := httptest.NewRecorder()
+			middleware.LoadSessionMiddleware()(http.HandlerFunc(h.CreatePost)).ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("got %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"1337b04rd/internal/adapters/ratelimit"
+	"1337b04rd/internal/domain"
+	"1337b04rd/internal/handler"
+	"1337b04rd/pkg/middleware"
+)
+
+func TestRateLimitRetryAfter(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	rules := map[string]domain.RateLimitRule{
+		domain.RateLimitNewThread: {Name: domain.RateLimitNewThread, Burst: 1, Interval: time.Minute},
+	}
+	limits := handler.NewRateLimitHandler(ratelimit.NewMemoryLimiter(), rules, logger)
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusCreated) })
+	h := middleware.ClientIPMiddleware([]byte("salt"), false)(limits.Limit(domain.RateLimitNewThread)(ok))
+
+	post := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/boards/b/threads", nil)
+		req.RemoteAddr = "198.51.100.7:5000"
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := post(); rec.Code != http.StatusCreated {
+		t.Fatalf("first thread: status = %d, want 201", rec.Code)
+	}
+
+	rec := post()
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("second thread: status = %d, want 429", rec.Code)
+	}
+	if retry := rec.Header().Get("Retry-After"); retry != "60" {
+		t.Fatalf("Retry-After = %q, want 60", retry)
+	}
+	var body struct {
+		Error handler.APIError `json:"error"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body.Error.Code != "rate_limited" {
+		t.Fatalf("body = %+v, %v; want a rate_limited envelope", body, err)
+	}
+}
+
+func TestRateLimitUploadsByURL(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	rules := map[string]domain.RateLimitRule{
+		domain.RateLimitUpload: {Name: domain.RateLimitUpload, Burst: 1, Interval: time.Minute},
+	}
+	limits := handler.NewRateLimitHandler(ratelimit.NewMemoryLimiter(), rules, logger)
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusCreated) })
+	h := middleware.ClientIPMiddleware([]byte("salt"), false)(limits.LimitUploads(domain.RateLimitUpload)(ok))
+
+	post := func(fields map[string]string) int {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		for name, value := range fields {
+			form.WriteField(name, value)
+		}
+		form.Close()
+		req := httptest.NewRequest(http.MethodPost, "/comments/create", &body)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		req.RemoteAddr = "198.51.100.7:5000"
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	linked := map[string]string{"content": "look", handler.ImageURLField: "https://example.org/cat.png"}
+	if got := post(linked); got != http.StatusCreated {
+		t.Fatalf("first linked image: status = %d, want 201", got)
+	}
+	if got := post(map[string]string{"content": "no image"}); got != http.StatusCreated {
+		t.Fatalf("text reply: status = %d, want 201", got)
+	}
+	if got := post(linked); got != http.StatusTooManyRequests {
+		t.Fatalf("second linked image: status = %d, want 429", got)
+	}
+}
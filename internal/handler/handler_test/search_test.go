@@ -0,0 +1,104 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"1337b04rd/internal/domain"
+	"1337b04rd/internal/handler"
+	"1337b04rd/internal/service"
+)
+
+// recordingSearchRepo finds nothing and keeps the last query it was asked.
+type recordingSearchRepo struct {
+	last *domain.SearchQuery
+}
+
+func (r *recordingSearchRepo) Search(ctx context.Context, q domain.SearchQuery) ([]domain.SearchResult, int, error) {
+	r.last = &q
+	return nil, 0, nil
+}
+
+func TestSearchParsesQuery(t *testing.T) {
+	day := func(s string) *time.Time {
+		d, _ := time.Parse(time.DateOnly, s)
+		return &d
+	}
+	defaults := func(change func(q *domain.SearchQuery)) *domain.SearchQuery {
+		q, _ := domain.NewSearchQuery("portal")
+		if change != nil {
+			change(q)
+		}
+		return q
+	}
+
+	tests := []struct {
+		query   string
+		want    *domain.SearchQuery
+		wantErr error
+	}{
+		{"q=portal", defaults(nil), nil},
+		{"", nil, domain.ErrEmptySearchQuery},
+		{"q=portal&board=b", defaults(func(q *domain.SearchQuery) { q.Board = "b" }), nil},
+
+		// to is inclusive, so it ends at the start of the next day
+		{"q=portal&from=2025-05-01&to=2025-05-03", defaults(func(q *domain.SearchQuery) { q.From, q.To = day("2025-05-01"), day("2025-05-04") }), nil},
+		{"q=portal&from=05/01/2025", nil, domain.ErrInvalidParam},
+		{"q=portal&to=yesterday", nil, domain.ErrInvalidParam},
+
+		{"q=portal&scope=archive", defaults(func(q *domain.SearchQuery) { q.Scope = domain.ScopeArchive }), nil},
+		{"q=portal&scope=live", defaults(func(q *domain.SearchQuery) { q.Scope = domain.ScopeLive }), nil},
+		{"q=portal&scope=everywhere", nil, domain.ErrInvalidParam},
+
+		{"q=portal&has_image=true", defaults(func(q *domain.SearchQuery) { q.HasImage = true }), nil},
+		{"q=portal&has_image=0", defaults(nil), nil},
+		{"q=portal&has_image=maybe", nil, domain.ErrInvalidParam},
+
+		{"q=portal&sort=date", defaults(func(q *domain.SearchQuery) { q.Sort = domain.SortByDate }), nil},
+		{"q=portal&sort=relevance", defaults(nil), nil},
+		{"q=portal&sort=foo", nil, domain.ErrInvalidSearchSort},
+
+		{"q=portal&page=3&per_page=50", defaults(func(q *domain.SearchQuery) { q.Page, q.PerPage = 3, 50 }), nil},
+		{"q=portal&page=0", nil, domain.ErrInvalidParam},
+		{"q=portal&per_page=many", nil, domain.ErrInvalidParam},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			repo := &recordingSearchRepo{}
+			h := handler.NewSearchHandler(service.NewSearchService(repo), logger)
+			rec := httptest.NewRecorder()
+			h.Search(rec, httptest.NewRequest(http.MethodGet, "/api/v1/search?"+tt.query, nil))
+
+			if tt.wantErr != nil {
+				status, code := handler.ErrorStatus(tt.wantErr)
+				var body struct{ Error handler.APIError }
+				json.NewDecoder(rec.Body).Decode(&body)
+				if rec.Code != status || body.Error.Code != code {
+					t.Fatalf("got %d %q, want %d %q", rec.Code, body.Error.Code, status, code)
+				}
+				return
+			}
+			if rec.Code != http.StatusOK {
+				t.Fatalf("got %d: %s", rec.Code, rec.Body.String())
+			}
+			if repo.last == nil || !sameQuery(repo.last, tt.want) {
+				t.Errorf("searched %+v, want %+v", repo.last, tt.want)
+			}
+		})
+	}
+}
+
+func sameQuery(a, b *domain.SearchQuery) bool {
+	sameTime := func(x, y *time.Time) bool {
+		return (x == nil) == (y == nil) && (x == nil || x.Equal(*y))
+	}
+	return a.Text == b.Text && a.Board == b.Board && sameTime(a.From, b.From) && sameTime(a.To, b.To) &&
+		a.HasImage == b.HasImage && a.Scope == b.Scope && a.Sort == b.Sort && a.Page == b.Page && a.PerPage == b.PerPage
+}
@@ -0,0 +1,164 @@
+package handler_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"1337b04rd/internal/adapters/events"
+	"1337b04rd/internal/domain"
+	"1337b04rd/internal/handler"
+	"1337b04rd/pkg/middleware"
+)
+
+type wsFrame struct {
+	Type   string            `json:"type"`
+	Thread int               `json:"thread,omitempty"`
+	Board  string            `json:"board,omitempty"`
+	Event  *domain.Event     `json:"event,omitempty"`
+	Error  *handler.APIError `json:"error,omitempty"`
+}
+
+func newGateway(t *testing.T, bus *events.Bus, maxPerSession, maxPerIP int) *httptest.Server {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ws := handler.NewWebSocketHandler(bus, []string{"https://client.example"}, maxPerSession, maxPerIP, logger)
+	srv := httptest.NewServer(middleware.ClientIPMiddleware([]byte("salt"), false)(middleware.LoadSessionMiddleware()(ws)))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func dial(srv *httptest.Server, origin string) (*websocket.Conn, error) {
+	return dialAs(srv, origin, "s1")
+}
+
+func dialAs(srv *httptest.Server, origin, sessionID string) (*websocket.Conn, error) {
+	config, err := websocket.NewConfig("ws"+strings.TrimPrefix(srv.URL, "http"), srv.URL)
+	if err != nil {
+		return nil, err
+	}
+	config.Header = http.Header{"Cookie": {"session_id=" + sessionID + "; session_name=Rick"}}
+	if origin != "" {
+		config.Origin, _ = config.Origin.Parse(origin)
+	}
+	return websocket.DialConfig(config)
+}
+
+func receive(t *testing.T, conn *websocket.Conn) wsFrame {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var frame wsFrame
+	if err := websocket.JSON.Receive(conn, &frame); err != nil {
+		t.Fatalf("receive: %v", err)
+	}
+	return frame
+}
+
+func TestWebSocketMultiplexesSubscriptions(t *testing.T) {
+	bus := events.NewBus(events.DefaultHistorySize, events.DefaultBufferSize)
+	conn, err := dial(newGateway(t, bus, handler.DefaultMaxConnsPerSession, handler.DefaultMaxConnsPerIP), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	websocket.JSON.Send(conn, map[string]interface{}{"type": "subscribe", "thread": 7})
+	websocket.JSON.Send(conn, map[string]interface{}{"type": "subscribe", "board": "g"})
+	for i := 0; i < 2; i++ {
+		if frame := receive(t, conn); frame.Type != "subscribed" {
+			t.Fatalf("got %+v, want subscribed", frame)
+		}
+	}
+
+	ctx := context.Background()
+	bus.Publish(ctx, domain.NewEvent(domain.EventReply, 7, "b", &domain.Comment{ID: 1, PostID: 7}))
+	bus.Publish(ctx, domain.NewThreadEvent(&domain.Post{ID: 9, Board: "g"}))
+	bus.Publish(ctx, domain.NewEvent(domain.EventReply, 8, "b", nil))
+
+	// Subscriptions are delivered independently, so their order may vary.
+	got := map[string]wsFrame{}
+	for i := 0; i < 2; i++ {
+		frame := receive(t, conn)
+		if frame.Type != "event" {
+			t.Fatalf("got %+v, want event", frame)
+		}
+		got[string(frame.Event.Type)] = frame
+	}
+	if reply := got["reply"]; reply.Thread != 7 || reply.Event.Comment.ID != 1 {
+		t.Fatalf("got %+v, want reply to thread 7", reply)
+	}
+	if thread := got["thread"]; thread.Board != "g" || thread.Event.Post.ID != 9 {
+		t.Fatalf("got %+v, want new thread on board g", thread)
+	}
+
+	websocket.JSON.Send(conn, map[string]interface{}{"type": "ping"})
+	if frame := receive(t, conn); frame.Type != "pong" {
+		t.Fatalf("got %+v, want pong", frame)
+	}
+
+	websocket.JSON.Send(conn, map[string]interface{}{"type": "subscribe"})
+	if frame := receive(t, conn); frame.Type != "error" || frame.Error.Code != "invalid_topic" {
+		t.Fatalf("got %+v, want invalid_topic error", frame)
+	}
+}
+
+func TestWebSocketRejectsForeignOrigin(t *testing.T) {
+	srv := newGateway(t, events.NewBus(1, 1), handler.DefaultMaxConnsPerSession, handler.DefaultMaxConnsPerIP)
+
+	if _, err := dial(srv, "https://evil.example"); err == nil {
+		t.Fatal("connection from a foreign origin was accepted")
+	}
+	conn, err := dial(srv, "https://client.example")
+	if err != nil {
+		t.Fatalf("allowed origin was rejected: %v", err)
+	}
+	conn.Close()
+}
+
+func TestWebSocketLimitsConnectionsPerSession(t *testing.T) {
+	srv := newGateway(t, events.NewBus(1, 1), 1, handler.DefaultMaxConnsPerIP)
+
+	conn, err := dial(srv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dial(srv, ""); err == nil {
+		t.Fatal("second connection of the session was accepted")
+	}
+
+	conn.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		conn, err := dial(srv, "")
+		if err == nil {
+			conn.Close()
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("session slot was not released: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWebSocketLimitsConnectionsPerIP(t *testing.T) {
+	srv := newGateway(t, events.NewBus(1, 1), handler.DefaultMaxConnsPerSession, 2)
+
+	for _, session := range []string{"s1", "s2"} {
+		conn, err := dialAs(srv, "", session)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+	}
+	if _, err := dialAs(srv, "", "s3"); err == nil {
+		t.Fatal("connection over the address cap was accepted under a fresh session")
+	}
+}
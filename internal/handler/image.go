@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"1337b04rd/internal/adapters/storage"
+	"1337b04rd/internal/domain"
+)
+
+// ImageURLField is the form field posters link an image with instead of
+// uploading one.
+const ImageURLField = "image_url"
+
+// remoteImage is an image posted by URL, fetched into memory.
+type remoteImage struct {
+	data        []byte
+	contentType string
+	sourceURL   string
+}
+
+// filename names the stored copy after its sniffed type, never after the
+// remote path.
+func (img *remoteImage) filename() string {
+	return "remote-image" + storage.ImageExtension(img.contentType)
+}
+
+// fetchFormImage fetches the image the form links to, or returns nil when it
+// links to none. The image is checked by the storage's fetcher, which only
+// accepts public hosts, image types and the upload size limit.
+func fetchFormImage(r *http.Request, store *storage.MinioClient) (*remoteImage, error) {
+	sourceURL := strings.TrimSpace(r.FormValue(ImageURLField))
+	if sourceURL == "" {
+		return nil, nil
+	}
+	if hasUpload(r) {
+		return nil, domain.ErrImageAndImageURL
+	}
+
+	data, contentType, err := store.FetchImage(r.Context(), sourceURL)
+	if err != nil {
+		return nil, err
+	}
+	return &remoteImage{data: data, contentType: contentType, sourceURL: sourceURL}, nil
+}
+
+// hasUpload reports whether the parsed form uploads a non-empty image.
+func hasUpload(r *http.Request) bool {
+	if r.MultipartForm == nil {
+		return false
+	}
+	for _, file := range r.MultipartForm.File["image"] {
+		if file.Size > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// discardImage deletes an image stored for a post that was then refused, so
+// refused posts leave nothing behind in the bucket. It outlives the request,
+// which may already be cancelled.
+func discardImage(r *http.Request, store *storage.MinioClient, logger *slog.Logger, imageURL string) {
+	if err := store.DeleteImage(context.WithoutCancel(r.Context()), imageURL); err != nil {
+		requestLog(r, logger).Error("Failed to delete image of refused post", "image_url", imageURL, "error", err)
+	}
+}
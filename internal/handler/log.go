@@ -0,0 +1,15 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+
+	"1337b04rd/pkg/logger"
+)
+
+// requestLog returns the access log's logger for r, which already carries
+// the request ID, trace, method, route and session, or fallback outside
+// one.
+func requestLog(r *http.Request, fallback *slog.Logger) *slog.Logger {
+	return logger.FromContext(r.Context(), fallback)
+}
@@ -0,0 +1,370 @@
+package handler
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"1337b04rd/internal/domain"
+	"1337b04rd/internal/service"
+	"1337b04rd/pkg/middleware"
+)
+
+// staffCookie holds the staff login. It is separate from the anonymous
+// poster session, so staff post anonymously like everyone else.
+const staffCookie = "staff_session"
+
+type ModHandler struct {
+	staffService      *service.StaffService
+	moderationService *service.ModerationService
+	banService        *service.BanService
+	reportService     *service.ReportService
+	auditService      *service.AuditService
+	filterService     *service.FilterService
+	logger            *slog.Logger
+}
+
+func NewModHandler(staffService *service.StaffService, moderationService *service.ModerationService, banService *service.BanService, reportService *service.ReportService, auditService *service.AuditService, filterService *service.FilterService, logger *slog.Logger) *ModHandler {
+	return &ModHandler{
+		staffService:      staffService,
+		moderationService: moderationService,
+		banService:        banService,
+		reportService:     reportService,
+		auditService:      auditService,
+		filterService:     filterService,
+		logger:            logger,
+	}
+}
+
+// RequireStaff lets through staff members holding perm and stores them in
+// the request context. Visitors of /mod pages without a login are sent to
+// the login page; everything else gets an error envelope.
+func (h *ModHandler) RequireStaff(perm domain.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var token string
+			if cookie, err := r.Cookie(staffCookie); err == nil {
+				token = cookie.Value
+			}
+
+			staff, err := h.staffService.Authenticate(r.Context(), token)
+			if err != nil {
+				if r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/mod") {
+					http.Redirect(w, r, "/mod/login", http.StatusSeeOther)
+					return
+				}
+				requestLog(r, h.logger).Warn("Staff login required", "path", r.URL.Path)
+				writeError(w, err)
+				return
+			}
+
+			if !staff.Can(perm) {
+				requestLog(r, h.logger).Warn("Staff permission denied", "staff", staff.Username, "permission", perm)
+				writeError(w, domain.ErrUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(middleware.WithStaff(r.Context(), staff)))
+		})
+	}
+}
+
+func (h *ModHandler) Login(w http.ResponseWriter, r *http.Request) {
+	token, staff, err := h.staffService.Login(r.Context(), r.FormValue("username"), r.FormValue("password"))
+	if err != nil {
+		requestLog(r, h.logger).Warn("Failed staff login", "username", r.FormValue("username"))
+		http.Redirect(w, r, "/mod/login?failed=1", http.StatusSeeOther)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     staffCookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  time.Now().Add(domain.StaffSessionTTL),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	requestLog(r, h.logger).Info("Staff logged in", "staff", staff.Username)
+	http.Redirect(w, r, "/mod", http.StatusSeeOther)
+}
+
+func (h *ModHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(staffCookie); err == nil {
+		if err := h.staffService.Logout(r.Context(), cookie.Value); err != nil {
+			requestLog(r, h.logger).Error("Failed to end staff session", "error", err)
+		}
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     staffCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+	http.Redirect(w, r, "/mod/login", http.StatusSeeOther)
+}
+
+func (h *ModHandler) DeleteThread(w http.ResponseWriter, r *http.Request) {
+	h.threadAction(w, r, "Deleted thread", func(id int) error {
+		return h.moderationService.DeleteThread(r.Context(), id, strings.TrimSpace(r.FormValue("reason")))
+	})
+}
+
+func (h *ModHandler) StickyThread(w http.ResponseWriter, r *http.Request) {
+	h.threadAction(w, r, "Changed sticky", func(id int) error {
+		return h.moderationService.SetSticky(r.Context(), id, r.FormValue("sticky") == "true")
+	})
+}
+
+func (h *ModHandler) LockThread(w http.ResponseWriter, r *http.Request) {
+	h.threadAction(w, r, "Changed lock", func(id int) error {
+		return h.moderationService.SetLocked(r.Context(), id, r.FormValue("locked") == "true")
+	})
+}
+
+func (h *ModHandler) MoveThread(w http.ResponseWriter, r *http.Request) {
+	h.threadAction(w, r, "Moved thread", func(id int) error {
+		return h.moderationService.MoveThread(r.Context(), id, strings.TrimSpace(r.FormValue("board")))
+	})
+}
+
+func (h *ModHandler) DeleteThreadFile(w http.ResponseWriter, r *http.Request) {
+	h.threadAction(w, r, "Deleted thread file", func(id int) error {
+		return h.moderationService.DeleteThreadFile(r.Context(), id, strings.TrimSpace(r.FormValue("reason")))
+	})
+}
+
+func (h *ModHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	h.threadAction(w, r, "Deleted comment", func(id int) error {
+		return h.moderationService.DeleteComment(r.Context(), id, strings.TrimSpace(r.FormValue("reason")))
+	})
+}
+
+func (h *ModHandler) DeleteCommentFile(w http.ResponseWriter, r *http.Request) {
+	h.threadAction(w, r, "Deleted comment file", func(id int) error {
+		return h.moderationService.DeleteCommentFile(r.Context(), id, strings.TrimSpace(r.FormValue("reason")))
+	})
+}
+
+func (h *ModHandler) BanThreadAuthor(w http.ResponseWriter, r *http.Request) {
+	h.threadAction(w, r, "Banned thread author", func(id int) error {
+		return h.banPoster(r, domain.ReportTargetPost, id)
+	})
+}
+
+func (h *ModHandler) BanCommentAuthor(w http.ResponseWriter, r *http.Request) {
+	h.threadAction(w, r, "Banned comment author", func(id int) error {
+		return h.banPoster(r, domain.ReportTargetComment, id)
+	})
+}
+
+func (h *ModHandler) banPoster(r *http.Request, target domain.ReportTarget, id int) error {
+	duration, err := banDuration(r)
+	if err != nil {
+		return err
+	}
+	_, err = h.banService.BanPoster(r.Context(), target, id, r.FormValue("reason"), duration, r.FormValue("global") == "true", staffID(r))
+	return err
+}
+
+func (h *ModHandler) CreateRangeBan(w http.ResponseWriter, r *http.Request) {
+	duration, err := banDuration(r)
+	if err == nil {
+		_, err = h.banService.BanRange(r.Context(), r.FormValue("cidr"), r.FormValue("board"), r.FormValue("reason"), duration, staffID(r))
+	}
+	if err != nil {
+		requestLog(r, h.logger).Error("Failed to ban range", "error", err)
+		status, _ := ErrorStatus(err)
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	requestLog(r, h.logger).Info("Banned range", "cidr", r.FormValue("cidr"), "staff", staffName(r))
+	http.Redirect(w, r, "/mod/bans", http.StatusSeeOther)
+}
+
+func (h *ModHandler) LiftBan(w http.ResponseWriter, r *http.Request) {
+	h.threadAction(w, r, "Lifted ban", func(id int) error {
+		return h.banService.LiftBan(r.Context(), id)
+	})
+}
+
+func (h *ModHandler) DecideAppeal(w http.ResponseWriter, r *http.Request) {
+	h.threadAction(w, r, "Decided appeal", func(id int) error {
+		return h.banService.DecideAppeal(r.Context(), id, r.FormValue("decision") == "accept", staffID(r))
+	})
+}
+
+// ResolveReport dismisses the reports on a thread or comment, or deletes it
+// or bans its author, and closes every open report on it.
+func (h *ModHandler) ResolveReport(w http.ResponseWriter, r *http.Request) {
+	staff, err := middleware.GetStaff(r.Context())
+	if err != nil {
+		writeError(w, domain.ErrInvalidStaffSession)
+		return
+	}
+
+	targetType := domain.ReportTarget(r.FormValue("target_type"))
+	resolution := domain.ReportResolution(r.FormValue("resolution"))
+	targetID, err := strconv.Atoi(r.FormValue("target_id"))
+	if err != nil {
+		requestLog(r, h.logger).Error("Invalid report target ID")
+		http.Error(w, "Invalid target ID", http.StatusBadRequest)
+		return
+	}
+
+	duration, err := banDuration(r)
+	var closed int
+	if err == nil {
+		closed, err = h.reportService.Resolve(r.Context(), targetType, targetID, resolution, staff, r.FormValue("reason"), duration)
+	}
+	if err != nil {
+		requestLog(r, h.logger).Error("Failed to resolve report", "target_type", targetType, "target_id", targetID, "resolution", resolution, "error", err)
+		status, _ := ErrorStatus(err)
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	requestLog(r, h.logger).Info("Resolved reports", "target_type", targetType, "target_id", targetID, "resolution", resolution, "reports", closed, "staff", staff.Username)
+	http.Redirect(w, r, "/mod/reports", http.StatusSeeOther)
+}
+
+func (h *ModHandler) CreateStaff(w http.ResponseWriter, r *http.Request) {
+	staff, err := h.staffService.CreateStaff(r.Context(), strings.TrimSpace(r.FormValue("username")), r.FormValue("password"), domain.StaffRole(r.FormValue("role")))
+	if err != nil {
+		requestLog(r, h.logger).Error("Failed to create staff account", "error", err)
+		status, _ := ErrorStatus(err)
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	requestLog(r, h.logger).Info("Created staff account", "staff", staff.Username, "role", staff.Role)
+	http.Redirect(w, r, "/mod/staff", http.StatusSeeOther)
+}
+
+func (h *ModHandler) CreateFilter(w http.ResponseWriter, r *http.Request) {
+	threshold, err := formInt(r, "threshold")
+	var banHours int
+	if err == nil {
+		banHours, err = formInt(r, "hours")
+	}
+	var filter *domain.Filter
+	if err == nil {
+		filter, err = h.filterService.CreateFilter(r.Context(), r.FormValue("board"), domain.FilterKind(r.FormValue("kind")),
+			r.FormValue("pattern"), r.FormValue("replacement"), threshold, domain.FilterAction(r.FormValue("action")),
+			banHours, r.FormValue("reason"), r.FormValue("dry_run") == "true")
+	}
+	if err != nil {
+		requestLog(r, h.logger).Error("Failed to create filter", "error", err)
+		status, _ := ErrorStatus(err)
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	requestLog(r, h.logger).Info("Created filter", "id", filter.ID, "kind", filter.Kind, "action", filter.Action, "staff", staffName(r))
+	http.Redirect(w, r, "/mod/filters", http.StatusSeeOther)
+}
+
+// SetFilter turns a filter on or off and in or out of dry run, from the
+// "enabled" and "dry_run" form fields.
+func (h *ModHandler) SetFilter(w http.ResponseWriter, r *http.Request) {
+	h.threadAction(w, r, "Updated filter", func(id int) error {
+		return h.filterService.SetFilter(r.Context(), id, r.FormValue("enabled") == "true", r.FormValue("dry_run") == "true")
+	})
+}
+
+func (h *ModHandler) DeleteFilter(w http.ResponseWriter, r *http.Request) {
+	h.threadAction(w, r, "Deleted filter", func(id int) error {
+		return h.filterService.DeleteFilter(r.Context(), id)
+	})
+}
+
+// threadAction runs a moderation action on the thread, comment, ban, appeal
+// or filter named by the {id} path value and sends the moderator back to where
+// they came from.
+func (h *ModHandler) threadAction(w http.ResponseWriter, r *http.Request, msg string, action func(id int) error) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		requestLog(r, h.logger).Error("Invalid ID")
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := action(id); err != nil {
+		requestLog(r, h.logger).Error("Moderation action failed", "action", msg, "id", id, "error", err)
+		status, _ := ErrorStatus(err)
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	requestLog(r, h.logger).Info(msg, "id", id, "staff", staffName(r))
+	http.Redirect(w, r, modRedirect(r), http.StatusSeeOther)
+}
+
+// modRedirect returns the form's "next" field when it points back into the
+// panel, and the dashboard otherwise.
+func modRedirect(r *http.Request) string {
+	next := r.FormValue("next")
+	if next == "/mod" || strings.HasPrefix(next, "/mod/") {
+		return next
+	}
+	return "/mod"
+}
+
+// logFilter reads the /mod/log filters from the query string.
+func logFilter(r *http.Request) domain.ModActionFilter {
+	query := r.URL.Query()
+	before, _ := strconv.ParseInt(query.Get("before"), 10, 64)
+	return domain.ModActionFilter{
+		Actor:      strings.TrimSpace(query.Get("actor")),
+		Action:     domain.ModActionType(query.Get("action")),
+		TargetType: query.Get("target_type"),
+		TargetID:   strings.TrimSpace(query.Get("target_id")),
+		Board:      strings.TrimSpace(query.Get("board")),
+		BeforeID:   before,
+	}
+}
+
+// banDuration reads the "hours" form field; zero or empty bans permanently.
+func banDuration(r *http.Request) (time.Duration, error) {
+	hours, err := formInt(r, "hours")
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(hours) * time.Hour, nil
+}
+
+// formInt reads a non-negative number from a form field, zero when empty.
+func formInt(r *http.Request, field string) (int, error) {
+	value := strings.TrimSpace(r.FormValue(field))
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s %q: %w", field, value, domain.ErrInvalidParam)
+	}
+	return n, nil
+}
+
+func staffID(r *http.Request) int {
+	if staff, err := middleware.GetStaff(r.Context()); err == nil {
+		return staff.ID
+	}
+	return 0
+}
+
+func staffName(r *http.Request) string {
+	if staff, err := middleware.GetStaff(r.Context()); err == nil {
+		return staff.Username
+	}
+	return ""
+}
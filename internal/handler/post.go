@@ -16,13 +16,14 @@ import (
 )
 
 type PostHandler struct {
-	postService service.PostService
-	storage     *storage.MinioClient
-	logger      *slog.Logger
+	postService    service.PostService
+	captchaService *service.CaptchaService
+	storage        *storage.MinioClient
+	logger         *slog.Logger
 }
 
-func NewPostHandler(postService service.PostService, storage *storage.MinioClient, logger *slog.Logger) *PostHandler {
-	return &PostHandler{postService: postService, storage: storage, logger: logger}
+func NewPostHandler(postService service.PostService, captchaService *service.CaptchaService, storage *storage.MinioClient, logger *slog.Logger) *PostHandler {
+	return &PostHandler{postService: postService, captchaService: captchaService, storage: storage, logger: logger}
 }
 
 func (h *PostHandler) CreatePost(w http.ResponseWriter, r *http.Request) {
@@ -31,6 +32,14 @@ func (h *PostHandler) CreatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Threads from the form land on the default board.
+	if err := h.captchaService.VerifyThread(r.Context(), domain.DefaultBoard, r.FormValue("captcha_token"), r.FormValue("captcha_nonce")); err != nil {
+		requestLog(r, h.logger).Warn("Captcha check failed", "error", err)
+		status, _ := ErrorStatus(err)
+		http.Error(w, err.Error(), status)
+		return
+	}
+
 	title := r.FormValue("title")
 	content := r.FormValue("content")
 
@@ -39,68 +48,78 @@ func (h *PostHandler) CreatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if an image file is provided in the form
-	var imageURL string
-	file, _, err := r.FormFile("image")
-	if err == nil {
-		// If image is provided, upload it
-		defer file.Close()
+	session, err := middleware.GetSession(r.Context())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error retrieving session: %s", err), http.StatusUnauthorized)
+		return
+	}
 
-		session, err := middleware.GetSession(r.Context())
-		if err != nil {
-			http.Error(w, fmt.Sprintf("Error retrieving session: %s", err), http.StatusUnauthorized)
-			return
+	post, err := domain.NewPost(title, content, session.ID, nil)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error creating post: %s", err), http.StatusBadRequest)
+		return
+	}
+	post.AuthorName = session.Name
+
+	// An image may also be linked; it is fetched and stored like an upload.
+	remote, err := fetchFormImage(r, h.storage)
+	if err != nil {
+		requestLog(r, h.logger).Warn("Failed to fetch image by URL", "error", err)
+		status, _ := ErrorStatus(err)
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	// The image is stored before the post, which may still be refused; it
+	// is deleted again unless the post is saved.
+	saved := false
+	defer func() {
+		if post.ImageURL != "" && !saved {
+			discardImage(r, h.storage, h.logger, post.ImageURL)
 		}
+	}()
 
-		post, err := domain.NewPost(title, content, session.ID, nil)
+	// Check if an image file is provided in the form
+	if remote != nil {
+		post.ImageURL, err = h.storage.UploadPostImage(r.Context(), remote.data, remote.filename(), remote.contentType)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("Error creating post: %s", err), http.StatusBadRequest)
+			http.Error(w, fmt.Sprintf("Error uploading image: %s", err), http.StatusInternalServerError)
 			return
 		}
+		post.ImageSourceURL = remote.sourceURL
+	}
+	file, _, err := r.FormFile("image")
+	if err == nil {
+		// If image is provided, upload it
+		defer file.Close()
 
-		post.AuthorName = session.Name
-
-		imageURL, err = h.storage.UploadImage(r.Context(), file, "post-image.jpg", "image/jpeg")
+		post.ImageURL, err = h.storage.UploadImage(r.Context(), file, "post-image.jpg", "image/jpeg")
 		if err != nil {
 			http.Error(w, fmt.Sprintf("Error uploading image: %s", err), http.StatusInternalServerError)
 			return
 		}
-		post.ImageURL = imageURL
 	} else if err != http.ErrMissingFile {
 		// If error is something other than missing file, handle it
 		http.Error(w, fmt.Sprintf("Error extracting image: %s", err), http.StatusBadRequest)
 		return
 	}
 
-	session, err := middleware.GetSession(r.Context())
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Error retrieving session: %s", err), http.StatusUnauthorized)
-		return
-	}
-
-	// Create the post with or without the image URL
-	post, err := domain.NewPost(title, content, session.ID, nil)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Error creating post: %s", err), http.StatusBadRequest)
-		return
-	}
-
-	post.AuthorName = session.Name
-
-	// Only set ImageURL if the image was provided
-	if imageURL != "" {
-		post.ImageURL = imageURL
-	}
-
 	if err := h.postService.CreatePost(r.Context(), post); err != nil {
-		http.Error(w, fmt.Sprintf("Error saving post: %s", err), http.StatusInternalServerError)
+		status, _ := ErrorStatus(err)
+		if status >= http.StatusInternalServerError {
+			requestLog(r, h.logger).Error("Failed to create post", "error", err)
+		} else {
+			requestLog(r, h.logger).Warn("Failed to create post", "error", err)
+		}
+		http.Error(w, err.Error(), status)
 		return
 	}
+	saved = true
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	if err := json.NewEncoder(w).Encode(post); err != nil {
-		h.logger.Error("Error encoding response", "method", r.Method)
+		requestLog(r, h.logger).Error("Error encoding response")
 		http.Error(w, fmt.Sprintf("Error encoding response: %s", err), http.StatusInternalServerError)
 		return
 	}
@@ -109,39 +128,39 @@ func (h *PostHandler) CreatePost(w http.ResponseWriter, r *http.Request) {
 func (h *PostHandler) GetPost(w http.ResponseWriter, r *http.Request) {
 	parts := strings.Split(r.URL.Path, "/")
 	if len(parts) < 2 {
-		h.logger.Error("Missing id", "method", r.Method)
+		requestLog(r, h.logger).Error("Missing id")
 		http.Error(w, "Missing id", http.StatusBadRequest)
 		return
 	}
 
 	idStr := parts[len(parts)-1]
 	if idStr == "" {
-		h.logger.Error("Missing id", "method", r.Method)
+		requestLog(r, h.logger).Error("Missing id")
 		http.Error(w, "Missing id", http.StatusBadRequest)
 		return
 	}
 
 	intID, err := strconv.Atoi(idStr)
 	if err != nil {
-		h.logger.Error("Invalid id format", "error", err, "method", r.Method)
+		requestLog(r, h.logger).Error("Invalid id format", "error", err)
 		http.Error(w, "Invalid id format", http.StatusBadRequest)
 		return
 	}
 
 	post, err := h.postService.GetPost(r.Context(), intID)
 	if err != nil {
-		h.logger.Error("Post not found:", "error", err, "method", r.Method)
+		requestLog(r, h.logger).Error("Post not found:", "error", err)
 		http.Error(w, fmt.Sprintf("Post not found: %v", err), http.StatusNotFound)
 		return
 	}
 
 	if post == nil {
-		h.logger.Error("Post is empty:", "method", r.Method)
+		requestLog(r, h.logger).Error("Post is empty:")
 		http.Error(w, "Post is empty", http.StatusInternalServerError)
 		return
 	}
 
-	h.logger.Info("Successfully got post", "method", r.Method)
+	requestLog(r, h.logger).Info("Successfully got post")
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -153,23 +172,21 @@ func (h *PostHandler) GetPost(w http.ResponseWriter, r *http.Request) {
 func (h *PostHandler) GetAllPosts(w http.ResponseWriter, r *http.Request) {
 	posts, err := h.postService.GetAllPosts(r.Context())
 	if err != nil {
-		h.logger.Error("Error retrieving posts", "error", err, "method", r.Method)
+		requestLog(r, h.logger).Error("Error retrieving posts", "error", err)
 		http.Error(w, fmt.Sprintf("Error retrieving posts: %s", err), http.StatusInternalServerError)
 		return
 	}
 
-	if len(posts) == 0 {
-		h.logger.Error("No posts found", "method", r.Method)
-		http.Error(w, "No posts found", http.StatusNotFound)
-		return
+	if posts == nil {
+		posts = []*domain.Post{}
 	}
 
-	h.logger.Info("Successfully got all posts", "method", r.Method)
+	requestLog(r, h.logger).Info("Successfully got all posts")
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(posts); err != nil {
-		h.logger.Error("Error encoding posts", "error", err, "method", r.Method)
+		requestLog(r, h.logger).Error("Error encoding posts", "error", err)
 		http.Error(w, fmt.Sprintf("Error encoding posts: %s", err), http.StatusInternalServerError)
 	}
 }
@@ -178,16 +195,16 @@ func (h *PostHandler) UpdateAuthorNameForAllPosts(w http.ResponseWriter, r *http
 	// Get all posts
 	posts, err := h.postService.GetAllPosts(r.Context())
 	if err != nil {
-		h.logger.Error("Error retrieving posts", "error", err, "method", r.Method)
+		requestLog(r, h.logger).Error("Error retrieving posts", "error", err)
 		http.Error(w, fmt.Sprintf("Error retrieving posts: %s", err), http.StatusInternalServerError)
 		return
 	}
-	h.logger.Info("Successfully updated author name of all posts", "method", r.Method)
+	requestLog(r, h.logger).Info("Successfully updated author name of all posts")
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(posts); err != nil {
-		h.logger.Error("Error encoding posts", "error", err, "method", r.Method)
+		requestLog(r, h.logger).Error("Error encoding posts", "error", err)
 		http.Error(w, fmt.Sprintf("Error encoding posts: %s", err), http.StatusInternalServerError)
 	}
 }
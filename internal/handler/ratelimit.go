@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"1337b04rd/internal/domain"
+	"1337b04rd/internal/ports"
+	"1337b04rd/pkg/middleware"
+)
+
+// maxUploadFormMemory matches what the post and comment handlers parse.
+const maxUploadFormMemory = 10 << 20
+
+// RateLimitHandler throttles posting, session creation and uploads with a
+// token bucket per session and another per IP hash, so neither a fresh
+// session nor a new address gets around a rule on its own.
+type RateLimitHandler struct {
+	limiter ports.RateLimiter
+	rules   map[string]domain.RateLimitRule
+	logger  *slog.Logger
+}
+
+func NewRateLimitHandler(limiter ports.RateLimiter, rules map[string]domain.RateLimitRule, logger *slog.Logger) *RateLimitHandler {
+	return &RateLimitHandler{limiter: limiter, rules: rules, logger: logger}
+}
+
+// Limit applies the named rule to every request.
+func (h *RateLimitHandler) Limit(name string) func(http.Handler) http.Handler {
+	return h.limitIf(name, func(r *http.Request) bool { return true })
+}
+
+// LimitNewSessions applies the named rule to requests without a session
+// cookie, which are the ones that get a new session and avatar.
+func (h *RateLimitHandler) LimitNewSessions(name string) func(http.Handler) http.Handler {
+	return h.limitIf(name, func(r *http.Request) bool {
+		cookie, err := r.Cookie("session_id")
+		return err != nil || cookie.Value == ""
+	})
+}
+
+// LimitUploads applies the named rule to form posts carrying an image, as a
+// file or by URL.
+func (h *RateLimitHandler) LimitUploads(name string) func(http.Handler) http.Handler {
+	return h.limitIf(name, func(r *http.Request) bool {
+		if err := r.ParseMultipartForm(maxUploadFormMemory); err != nil {
+			return false
+		}
+		return hasUpload(r) || strings.TrimSpace(r.FormValue(ImageURLField)) != ""
+	})
+}
+
+func (h *RateLimitHandler) limitIf(name string, applies func(r *http.Request) bool) func(http.Handler) http.Handler {
+	rule := h.rules[name]
+	return func(next http.Handler) http.Handler {
+		if !rule.Enabled() {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !applies(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			wait, err := h.wait(r, rule)
+			if err != nil {
+				// A failing limiter must not take posting down with it.
+				requestLog(r, h.logger).Error("Failed to check rate limit", "rule", rule.Name, "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+			if wait == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			requestLog(r, h.logger).Warn("Rate limited", "rule", rule.Name, "retry_after", wait, "path", r.URL.Path)
+			seconds := int(math.Ceil(wait.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(seconds))
+			err = fmt.Errorf("%w: %s, try again in %ds", domain.ErrRateLimited, strings.ReplaceAll(rule.Name, "_", " "), seconds)
+			if strings.HasPrefix(r.URL.Path, "/api/") {
+				writeError(w, err)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+		})
+	}
+}
+
+// wait takes a token from the session's and the address's bucket and
+// returns how long the request has to wait, zero if it may go ahead.
+func (h *RateLimitHandler) wait(r *http.Request, rule domain.RateLimitRule) (time.Duration, error) {
+	var keys []string
+	if session, err := middleware.GetSession(r.Context()); err == nil {
+		keys = append(keys, rule.Name+":session:"+session.ID)
+	}
+	if client := middleware.GetClientIP(r.Context()); client.Hash != "" {
+		keys = append(keys, rule.Name+":ip:"+client.Hash)
+	}
+
+	var longest time.Duration
+	for _, key := range keys {
+		ok, wait, err := h.limiter.Allow(r.Context(), key, rule)
+		if err != nil {
+			return 0, err
+		}
+		if !ok && wait > longest {
+			longest = wait
+		}
+	}
+	return longest, nil
+}
@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"1337b04rd/internal/domain"
+	"1337b04rd/internal/service"
+	"1337b04rd/pkg/middleware"
+)
+
+type ReportHandler struct {
+	reportService *service.ReportService
+	logger        *slog.Logger
+}
+
+func NewReportHandler(reportService *service.ReportService, logger *slog.Logger) *ReportHandler {
+	return &ReportHandler{reportService: reportService, logger: logger}
+}
+
+// CreateReport files the report form of a thread or comment and sends the
+// reader back to the thread.
+func (h *ReportHandler) CreateReport(w http.ResponseWriter, r *http.Request) {
+	session, err := middleware.GetSession(r.Context())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error retrieving session: %s", err), http.StatusUnauthorized)
+		return
+	}
+
+	targetID, err := strconv.Atoi(r.FormValue("target_id"))
+	if err != nil {
+		requestLog(r, h.logger).Error("Invalid report target ID")
+		http.Error(w, "Invalid target ID", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.reportService.CreateReport(r.Context(),
+		domain.ReportTarget(r.FormValue("target_type")),
+		targetID,
+		domain.ReportCategory(r.FormValue("category")),
+		r.FormValue("note"),
+		session.ID,
+	)
+	if err != nil {
+		requestLog(r, h.logger).Warn("Failed to file report", "target_type", r.FormValue("target_type"), "target_id", targetID, "error", err)
+		status, _ := ErrorStatus(err)
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	requestLog(r, h.logger).Info("Filed report", "report", report.ID, "target_type", report.TargetType, "target_id", report.TargetID)
+	http.Redirect(w, r, fmt.Sprintf("/posts/%d?reported=1", report.PostID), http.StatusSeeOther)
+}
@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"1337b04rd/internal/domain"
+	"1337b04rd/internal/service"
+)
+
+type SearchHandler struct {
+	service *service.SearchService
+	logger  *slog.Logger
+}
+
+func NewSearchHandler(service *service.SearchService, logger *slog.Logger) *SearchHandler {
+	return &SearchHandler{service: service, logger: logger}
+}
+
+func (h *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
+	q, err := parseSearchQuery(r.URL.Query())
+	if err != nil {
+		requestLog(r, h.logger).Error("Invalid search query", "error", err)
+		writeError(w, err)
+		return
+	}
+
+	page, err := h.service.Search(r.Context(), *q)
+	if err != nil {
+		requestLog(r, h.logger).Error("Failed to search", "error", err)
+		writeError(w, err)
+		return
+	}
+
+	requestLog(r, h.logger).Info("Successfully searched")
+	writeJSON(w, http.StatusOK, page)
+}
+
+// parseSearchQuery reads q, board, from, to (YYYY-MM-DD, to is inclusive),
+// has_image, scope, sort, page and per_page from the query string.
+func parseSearchQuery(values url.Values) (*domain.SearchQuery, error) {
+	q, err := domain.NewSearchQuery(values.Get("q"))
+	if err != nil {
+		return nil, err
+	}
+
+	q.Board = values.Get("board")
+
+	if v := values.Get("from"); v != "" {
+		from, err := time.Parse(time.DateOnly, v)
+		if err != nil {
+			return nil, fmt.Errorf("%w: from date %q", domain.ErrInvalidParam, v)
+		}
+		q.From = &from
+	}
+	if v := values.Get("to"); v != "" {
+		to, err := time.Parse(time.DateOnly, v)
+		if err != nil {
+			return nil, fmt.Errorf("%w: to date %q", domain.ErrInvalidParam, v)
+		}
+		to = to.AddDate(0, 0, 1)
+		q.To = &to
+	}
+
+	if v := values.Get("has_image"); v != "" {
+		hasImage, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("%w: has_image %q", domain.ErrInvalidParam, v)
+		}
+		q.HasImage = hasImage
+	}
+
+	switch scope := domain.SearchScope(values.Get("scope")); scope {
+	case "":
+	case domain.ScopeAll, domain.ScopeLive, domain.ScopeArchive:
+		q.Scope = scope
+	default:
+		return nil, fmt.Errorf("%w: scope %q", domain.ErrInvalidParam, scope)
+	}
+
+	switch sort := domain.SearchSort(values.Get("sort")); sort {
+	case "":
+	case domain.SortByRelevance, domain.SortByDate:
+		q.Sort = sort
+	default:
+		return nil, fmt.Errorf("%w: %q", domain.ErrInvalidSearchSort, sort)
+	}
+
+	if v := values.Get("page"); v != "" {
+		page, err := strconv.Atoi(v)
+		if err != nil || page < 1 {
+			return nil, fmt.Errorf("%w: page %q", domain.ErrInvalidParam, v)
+		}
+		q.Page = page
+	}
+	if v := values.Get("per_page"); v != "" {
+		perPage, err := strconv.Atoi(v)
+		if err != nil || perPage < 1 {
+			return nil, fmt.Errorf("%w: per_page %q", domain.ErrInvalidParam, v)
+		}
+		q.PerPage = perPage
+	}
+
+	return q, nil
+}
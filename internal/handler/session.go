@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"net/http"
 
+	"1337b04rd/internal/domain"
 	"1337b04rd/internal/service"
 )
 
@@ -23,7 +24,7 @@ func (h *SessionHandler) CreateSession(w http.ResponseWriter, r *http.Request) {
 		Name string `json:"name"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Error("Invalid request body", "method", r.Method)
+		requestLog(r, h.logger).Error("Invalid request body")
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
@@ -31,11 +32,22 @@ func (h *SessionHandler) CreateSession(w http.ResponseWriter, r *http.Request) {
 
 	session, err := h.sessionService.CreateSession(r.Context(), req.Name)
 	if err != nil {
-		h.logger.Error("Failed to create session", "error", err, "method", r.Method)
+		requestLog(r, h.logger).Error("Failed to create session", "error", err)
 		http.Error(w, fmt.Sprintf("Failed to create session: %v", err), http.StatusInternalServerError)
 		return
 	}
 
+	setSessionCookies(w, session)
+
+	requestLog(r, h.logger).Info("Successfully created session")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(session)
+}
+
+// setSessionCookies stores the session ID and display name in the cookies
+// read by middleware.InjectSessionMiddleware.
+func setSessionCookies(w http.ResponseWriter, session *domain.Session) {
 	http.SetCookie(w, &http.Cookie{
 		Name:     "session_id",
 		Value:    session.ID,
@@ -54,39 +66,34 @@ func (h *SessionHandler) CreateSession(w http.ResponseWriter, r *http.Request) {
 		SameSite: http.SameSiteStrictMode,
 		Expires:  session.ExpiresAt,
 	})
-
-	h.logger.Info("Successfully created session", "method", r.Method)
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(session)
 }
 
 func (h *SessionHandler) DeleteSession(w http.ResponseWriter, r *http.Request) {
 	err := h.sessionService.DeleteSession(w, r)
 	if err != nil {
-		h.logger.Error("Failed to delete session", "error", err, "method", r.Method)
+		requestLog(r, h.logger).Error("Failed to delete session", "error", err)
 		http.Error(w, fmt.Sprintf("Failed to delete session: %v", err), http.StatusInternalServerError)
 		return
 	}
-	h.logger.Info("Successfully deleted session", "method", r.Method)
+	requestLog(r, h.logger).Info("Successfully deleted session")
 	w.WriteHeader(http.StatusNoContent)
 }
 
 func (h *SessionHandler) GetSession(w http.ResponseWriter, r *http.Request) {
 	cookie, err := r.Cookie("session_id")
 	if err != nil {
-		h.logger.Error("No session cookie found", "error", err, "method", r.Method)
+		requestLog(r, h.logger).Error("No session cookie found", "error", err)
 		http.Error(w, "No session cookie found", http.StatusUnauthorized)
 		return
 	}
 
 	session, err := h.sessionService.GetSession(r.Context(), cookie.Value)
 	if err != nil {
-		h.logger.Error("Failed to get session", "error", err, "method", r.Method)
+		requestLog(r, h.logger).Error("Failed to get session", "error", err)
 		http.Error(w, fmt.Sprintf("Failed to get session: %v", err), http.StatusInternalServerError)
 		return
 	}
-	h.logger.Info("Successfully got session", "method", r.Method)
+	requestLog(r, h.logger).Info("Successfully got session")
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(session)
 }
@@ -94,11 +101,11 @@ func (h *SessionHandler) GetSession(w http.ResponseWriter, r *http.Request) {
 func (h *SessionHandler) GetAllSession(w http.ResponseWriter, r *http.Request) {
 	sessions, err := h.sessionService.GetAllSessions(r.Context())
 	if err != nil {
-		h.logger.Error("Failed to get sessions", "error", err, "method", r.Method)
+		requestLog(r, h.logger).Error("Failed to get sessions", "error", err)
 		http.Error(w, fmt.Sprintf("Failed to get sessions: %v", err), http.StatusInternalServerError)
 		return
 	}
-	h.logger.Info("Successfully got all sessions", "method", r.Method)
+	requestLog(r, h.logger).Info("Successfully got all sessions")
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(sessions)
 }
@@ -106,7 +113,7 @@ func (h *SessionHandler) GetAllSession(w http.ResponseWriter, r *http.Request) {
 func (h *SessionHandler) UpdateSession(w http.ResponseWriter, r *http.Request) {
 	cookie, err := r.Cookie("session_id")
 	if err != nil {
-		h.logger.Error("No session cookie found", "error", err, "method", r.Method)
+		requestLog(r, h.logger).Error("No session cookie found", "error", err)
 		http.Error(w, "No session cookie found", http.StatusUnauthorized)
 		return
 	}
@@ -116,26 +123,26 @@ func (h *SessionHandler) UpdateSession(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Error("No session cookie found", "error", err, "method", r.Method)
+		requestLog(r, h.logger).Error("No session cookie found", "error", err)
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 	defer r.Body.Close()
 
 	if req.Name == "" {
-		h.logger.Error("Name cannot be empty", "method", r.Method)
+		requestLog(r, h.logger).Error("Name cannot be empty")
 		http.Error(w, "Name cannot be empty", http.StatusBadRequest)
 		return
 	}
 
 	session, err := h.sessionService.UpdateSession(cookie.Value, req.Name)
 	if err != nil {
-		h.logger.Error("Failed to update session", "error", err, "method", r.Method)
+		requestLog(r, h.logger).Error("Failed to update session", "error", err)
 		http.Error(w, fmt.Sprintf("Failed to update session: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	h.logger.Info("Successfully updated session", "method", r.Method)
+	requestLog(r, h.logger).Info("Successfully updated session")
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -1,15 +1,23 @@
 package handler
 
 import (
+	"fmt"
 	"html/template"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 
+	"go.opentelemetry.io/otel"
+
+	"1337b04rd/internal/domain"
 	"1337b04rd/pkg/middleware"
+	"1337b04rd/pkg/tracing"
 )
 
+var tracer = otel.Tracer("1337b04rd/internal/handler")
+
 type TemplateHandler struct {
 	templates *template.Template
 	logger    *slog.Logger
@@ -20,42 +28,37 @@ func NewTemplateHandler(logger *slog.Logger) *TemplateHandler {
 	return &TemplateHandler{templates: tmpl, logger: logger}
 }
 
+// execute renders the named template, adding what every page needs to
+// data: the CSRF token its forms send back and the nonce its inline scripts
+// run with. Rendering gets its own span, apart from the queries before it.
+func (t *TemplateHandler) execute(w http.ResponseWriter, r *http.Request, name string, data map[string]interface{}) error {
+	data["CSRFToken"] = CSRFToken(r.Context())
+	data["CSPNonce"] = middleware.GetCSPNonce(r.Context())
+	_, span := tracer.Start(r.Context(), "render "+name)
+	err := t.templates.ExecuteTemplate(w, name, data)
+	tracing.End(span, err)
+	return err
+}
+
 func (t *TemplateHandler) RenderHomePage(sessionHandler *SessionHandler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		_, err := r.Cookie("session_id")
 		if err != nil {
 			session, err := sessionHandler.sessionService.CreateSession(r.Context(), "")
 			if err != nil {
-				t.logger.Error("Failed to create session", "error", err, "method", r.Method)
+				requestLog(r, t.logger).Error("Failed to create session", "error", err)
 				handler := t.RenderErrorPage(http.StatusInternalServerError, "Failed to create session.")
 				handler.ServeHTTP(w, r)
 				return
 			}
 
-			http.SetCookie(w, &http.Cookie{
-				Name:     "session_id",
-				Value:    session.ID,
-				Path:     "/",
-				HttpOnly: true,
-				Secure:   false,
-				SameSite: http.SameSiteStrictMode,
-				Expires:  session.ExpiresAt,
-			})
-			http.SetCookie(w, &http.Cookie{
-				Name:     "session_name",
-				Value:    session.Name,
-				Path:     "/",
-				HttpOnly: true,
-				Secure:   false,
-				SameSite: http.SameSiteStrictMode,
-				Expires:  session.ExpiresAt,
-			})
+			setSessionCookies(w, session)
 		}
 
 		// client := externalapi.NewRickAndMortyClient()
 		// characters, err := client.FetchAllCharacters()
 		// if err != nil {
-		// 	t.logger.Error("Failed to fetch characters from the API.", "error", err, "method", r.Method)
+		// 	requestLog(r, t.logger).Error("Failed to fetch characters from the API.", "error", err)
 		// 	handler := t.RenderErrorPage(http.StatusInternalServerError, "Failed to fetch characters from the API.")
 		// 	handler.ServeHTTP(w, r)
 		// 	return
@@ -69,14 +72,14 @@ func (t *TemplateHandler) RenderHomePage(sessionHandler *SessionHandler) http.Ha
 			"Title": "1337b04rd",
 		}
 
-		err = t.templates.ExecuteTemplate(w, "home-content.html", data)
+		err = t.execute(w, r, "home-content.html", data)
 		if err != nil {
-			t.logger.Error("Failed to render the home page.", "error", err, "method", r.Method)
+			requestLog(r, t.logger).Error("Failed to render the home page.", "error", err)
 			handler := t.RenderErrorPage(http.StatusInternalServerError, "Failed to render the home page.")
 			handler.ServeHTTP(w, r)
 			return
 		}
-		t.logger.Info("Successfully rendered home page", "method", r.Method)
+		requestLog(r, t.logger).Info("Successfully rendered home page")
 	})
 }
 
@@ -84,16 +87,17 @@ func (t *TemplateHandler) RenderCreatePostPage() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		data := map[string]interface{}{
 			"Title": "Create Post",
+			"Board": domain.DefaultBoard,
 		}
 
-		err := t.templates.ExecuteTemplate(w, "create-post.html", data)
+		err := t.execute(w, r, "create-post.html", data)
 		if err != nil {
-			t.logger.Error("Failed to render created post page.", "error", err, "method", r.Method)
+			requestLog(r, t.logger).Error("Failed to render created post page.", "error", err)
 			handler := t.RenderErrorPage(http.StatusInternalServerError, "Failed to render created post page")
 			handler.ServeHTTP(w, r)
 			return
 		}
-		t.logger.Info("Successfully rendered created post page", "method", r.Method)
+		requestLog(r, t.logger).Info("Successfully rendered created post page")
 	})
 }
 
@@ -101,7 +105,7 @@ func (t *TemplateHandler) RenderCatalogPage(PostHandler *PostHandler) http.Handl
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		posts, err := PostHandler.postService.GetAllPosts(r.Context())
 		if err != nil {
-			t.logger.Error("Failed to fetch posts", "error", err, "method", r.Method)
+			requestLog(r, t.logger).Error("Failed to fetch posts", "error", err)
 			handler := t.RenderErrorPage(http.StatusInternalServerError, "Failed to fetch posts")
 			handler.ServeHTTP(w, r)
 			return
@@ -109,7 +113,7 @@ func (t *TemplateHandler) RenderCatalogPage(PostHandler *PostHandler) http.Handl
 
 		session, err := middleware.GetSession(r.Context())
 		if err != nil {
-			t.logger.Error("Error retrieving session", "error", err, "method", r.Method)
+			requestLog(r, t.logger).Error("Error retrieving session", "error", err)
 			handler := t.RenderErrorPage(http.StatusUnauthorized, "Error retrieving session.")
 			handler.ServeHTTP(w, r)
 			return
@@ -121,14 +125,14 @@ func (t *TemplateHandler) RenderCatalogPage(PostHandler *PostHandler) http.Handl
 			"Posts": posts,
 		}
 
-		err = t.templates.ExecuteTemplate(w, "catalog.html", data)
+		err = t.execute(w, r, "catalog.html", data)
 		if err != nil {
-			t.logger.Error("Failed to render catalog page", "error", err, "method", r.Method)
+			requestLog(r, t.logger).Error("Failed to render catalog page", "error", err)
 			handler := t.RenderErrorPage(http.StatusInternalServerError, "Failed to render catalog page")
 			handler.ServeHTTP(w, r)
 			return
 		}
-		t.logger.Info("Successfully rendered catalog page", "method", r.Method)
+		requestLog(r, t.logger).Info("Successfully rendered catalog page")
 	})
 }
 
@@ -136,7 +140,7 @@ func (t *TemplateHandler) RenderArchivePage(ArchiveHandler *ArchiveHandler) http
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		posts, err := ArchiveHandler.service.GetAllArchivedPosts(r.Context())
 		if err != nil {
-			t.logger.Error("Failed to fetch archived posts", "error", err, "method", r.Method)
+			requestLog(r, t.logger).Error("Failed to fetch archived posts", "error", err)
 			handler := t.RenderErrorPage(http.StatusInternalServerError, "Failed to fetch archived posts")
 			handler.ServeHTTP(w, r)
 			return
@@ -144,7 +148,7 @@ func (t *TemplateHandler) RenderArchivePage(ArchiveHandler *ArchiveHandler) http
 
 		session, err := middleware.GetSession(r.Context())
 		if err != nil {
-			t.logger.Error("Failed to fetch characters from the API", "error", err, "method", r.Method)
+			requestLog(r, t.logger).Error("Failed to fetch characters from the API", "error", err)
 			handler := t.RenderErrorPage(http.StatusInternalServerError, "Failed to fetch characters from the API.")
 			handler.ServeHTTP(w, r)
 			return
@@ -155,14 +159,14 @@ func (t *TemplateHandler) RenderArchivePage(ArchiveHandler *ArchiveHandler) http
 			"Name":  session.Name,
 			"Posts": posts,
 		}
-		err = t.templates.ExecuteTemplate(w, "archive.html", data)
+		err = t.execute(w, r, "archive.html", data)
 		if err != nil {
-			t.logger.Error("Failed to render archive page", "error", err, "method", r.Method)
+			requestLog(r, t.logger).Error("Failed to render archive page", "error", err)
 			handler := t.RenderErrorPage(http.StatusInternalServerError, "Failed to render archive page")
 			handler.ServeHTTP(w, r)
 			return
 		}
-		t.logger.Info("Successfully rendered archive page", "method", r.Method)
+		requestLog(r, t.logger).Info("Successfully rendered archive page")
 	})
 }
 
@@ -171,7 +175,7 @@ func (t *TemplateHandler) RenderPostPage(PostHandler *PostHandler, CommentHandle
 		parts := strings.Split(r.URL.Path, "/")
 		postID, err := strconv.Atoi(parts[len(parts)-1])
 		if err != nil {
-			t.logger.Error("Invalid Post ID", "error", err, "method", r.Method)
+			requestLog(r, t.logger).Error("Invalid Post ID", "error", err)
 			handler := t.RenderErrorPage(http.StatusBadRequest, "Invalid Post ID")
 			handler.ServeHTTP(w, r)
 			return
@@ -179,7 +183,7 @@ func (t *TemplateHandler) RenderPostPage(PostHandler *PostHandler, CommentHandle
 
 		post, err := PostHandler.postService.GetPost(r.Context(), postID)
 		if err != nil {
-			t.logger.Error("Post not found", "error", err, "method", r.Method)
+			requestLog(r, t.logger).Error("Post not found", "error", err)
 			handler := t.RenderErrorPage(http.StatusNotFound, "Post not found")
 			handler.ServeHTTP(w, r)
 			return
@@ -187,26 +191,29 @@ func (t *TemplateHandler) RenderPostPage(PostHandler *PostHandler, CommentHandle
 
 		comments, err := CommentHandler.commentService.GetAllCommentsOfPost(r.Context(), postID)
 		if err != nil {
-			t.logger.Error("Failed to fetch comments", "error", err, "method", r.Method)
+			requestLog(r, t.logger).Error("Failed to fetch comments", "error", err)
 			handler := t.RenderErrorPage(http.StatusInternalServerError, "Failed to fetch comments")
 			handler.ServeHTTP(w, r)
 			return
 		}
 
 		data := map[string]interface{}{
-			"Title":    "Post - " + post.Title,
-			"Post":     post,
-			"Comments": comments,
+			"Title":      "Post - " + post.Title,
+			"Post":       post,
+			"Comments":   comments,
+			"Categories": domain.ReportCategories,
+			"Reported":   r.URL.Query().Get("reported") != "",
+			"NoteMaxLen": domain.MaxReportNoteLength,
 		}
 
-		err = t.templates.ExecuteTemplate(w, "post.html", data)
+		err = t.execute(w, r, "post.html", data)
 		if err != nil {
-			t.logger.Error("Failed to render page", "error", err, "method", r.Method)
+			requestLog(r, t.logger).Error("Failed to render page", "error", err)
 			handler := t.RenderErrorPage(http.StatusInternalServerError, "Failed to render page")
 			handler.ServeHTTP(w, r)
 			return
 		}
-		t.logger.Info("Successfully rendered post page", "method", r.Method)
+		requestLog(r, t.logger).Info("Successfully rendered post page")
 	})
 }
 
@@ -214,22 +221,22 @@ func (t *TemplateHandler) RenderArchivedPostPage(ArchiveHandler *ArchiveHandler)
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
 		if len(parts) < 2 {
-			t.logger.Error("Invalid URL format", "method", r.Method)
+			requestLog(r, t.logger).Error("Invalid URL format")
 			handler := t.RenderErrorPage(http.StatusBadRequest, "Invalid URL format")
 			handler.ServeHTTP(w, r)
 			return
 		}
 		postID, err := strconv.Atoi(parts[len(parts)-1])
 		if err != nil {
-			t.logger.Error("Invalid post ID", "method", r.Method)
+			requestLog(r, t.logger).Error("Invalid post ID")
 			handler := t.RenderErrorPage(http.StatusBadRequest, "Invalid post ID")
 			handler.ServeHTTP(w, r)
 			return
 		}
 
-		post := ArchiveHandler.service.GetArchivedPostByID(r.Context(), postID)
+		post, err := ArchiveHandler.service.GetArchivedPostByID(r.Context(), postID)
 		if err != nil {
-			t.logger.Error("Post not found", "error", err, "method", r.Method)
+			requestLog(r, t.logger).Error("Post not found", "error", err)
 			handler := t.RenderErrorPage(http.StatusNotFound, "Post not found")
 			handler.ServeHTTP(w, r)
 			return
@@ -241,14 +248,438 @@ func (t *TemplateHandler) RenderArchivedPostPage(ArchiveHandler *ArchiveHandler)
 			"Comments": post.Comments,
 		}
 
-		err = t.templates.ExecuteTemplate(w, "archive-post.html", data)
+		err = t.execute(w, r, "archive-post.html", data)
 		if err != nil {
-			t.logger.Error("Failed to render page", "error", err, "method", r.Method)
+			requestLog(r, t.logger).Error("Failed to render page", "error", err)
 			handler := t.RenderErrorPage(http.StatusInternalServerError, "Failed to render page")
 			handler.ServeHTTP(w, r)
 			return
 		}
-		t.logger.Info("Successfully rendered archived post page", "method", r.Method)
+		requestLog(r, t.logger).Info("Successfully rendered archived post page")
+	})
+}
+
+type searchResultView struct {
+	domain.SearchResult
+	Link        string
+	SnippetHTML template.HTML
+}
+
+func (t *TemplateHandler) RenderSearchPage(SearchHandler *SearchHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		values := r.URL.Query()
+		data := map[string]interface{}{
+			"Title": "Search",
+			"Query": values,
+		}
+
+		if values.Get("q") != "" {
+			q, err := parseSearchQuery(values)
+			if err != nil {
+				requestLog(r, t.logger).Warn("Invalid search query", "error", err)
+				status, _ := ErrorStatus(err)
+				handler := t.RenderErrorPage(status, err.Error())
+				handler.ServeHTTP(w, r)
+				return
+			}
+
+			page, err := SearchHandler.service.Search(r.Context(), *q)
+			if err != nil {
+				status, _ := ErrorStatus(err)
+				message := err.Error()
+				if status >= http.StatusInternalServerError {
+					requestLog(r, t.logger).Error("Failed to search", "error", err)
+					message = "Failed to search"
+				}
+				handler := t.RenderErrorPage(status, message)
+				handler.ServeHTTP(w, r)
+				return
+			}
+
+			results := make([]searchResultView, 0, len(page.Results))
+			for _, res := range page.Results {
+				link := fmt.Sprintf("/posts/%d", res.PostID)
+				if res.Archived {
+					link = fmt.Sprintf("/archive/%d", res.PostID)
+				}
+				results = append(results, searchResultView{
+					SearchResult: res,
+					Link:         link,
+					// Snippets are escaped by the search service.
+					SnippetHTML: template.HTML(res.Snippet),
+				})
+			}
+
+			data["Results"] = results
+			data["Total"] = page.Total
+			if page.Page > 1 {
+				data["PrevURL"] = searchPageURL(values, page.Page-1)
+			}
+			if page.Page*page.PerPage < page.Total {
+				data["NextURL"] = searchPageURL(values, page.Page+1)
+			}
+		}
+
+		err := t.execute(w, r, "search.html", data)
+		if err != nil {
+			requestLog(r, t.logger).Error("Failed to render search page", "error", err)
+			handler := t.RenderErrorPage(http.StatusInternalServerError, "Failed to render search page")
+			handler.ServeHTTP(w, r)
+			return
+		}
+		requestLog(r, t.logger).Info("Successfully rendered search page")
+	})
+}
+
+func searchPageURL(values url.Values, page int) string {
+	next := url.Values{}
+	for k, v := range values {
+		next[k] = v
+	}
+	next.Set("page", strconv.Itoa(page))
+	return "/search?" + next.Encode()
+}
+
+func (t *TemplateHandler) RenderModLoginPage() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data := map[string]interface{}{
+			"Title":  "Staff Login",
+			"Failed": r.URL.Query().Get("failed") != "",
+		}
+
+		err := t.execute(w, r, "mod-login.html", data)
+		if err != nil {
+			requestLog(r, t.logger).Error("Failed to render staff login page", "error", err)
+			handler := t.RenderErrorPage(http.StatusInternalServerError, "Failed to render staff login page")
+			handler.ServeHTTP(w, r)
+			return
+		}
+		requestLog(r, t.logger).Info("Successfully rendered staff login page")
+	})
+}
+
+func (t *TemplateHandler) RenderModPage(ModHandler *ModHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posts, err := ModHandler.moderationService.ListThreads(r.Context())
+		if err != nil {
+			requestLog(r, t.logger).Error("Failed to fetch posts", "error", err)
+			handler := t.RenderErrorPage(http.StatusInternalServerError, "Failed to fetch posts")
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		staff, _ := middleware.GetStaff(r.Context())
+		data := map[string]interface{}{
+			"Title": "Moderation",
+			"Staff": staff,
+			"Posts": posts,
+		}
+
+		err = t.execute(w, r, "mod.html", data)
+		if err != nil {
+			requestLog(r, t.logger).Error("Failed to render moderation page", "error", err)
+			handler := t.RenderErrorPage(http.StatusInternalServerError, "Failed to render moderation page")
+			handler.ServeHTTP(w, r)
+			return
+		}
+		requestLog(r, t.logger).Info("Successfully rendered moderation page")
+	})
+}
+
+func (t *TemplateHandler) RenderModThreadPage(ModHandler *ModHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		postID, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			requestLog(r, t.logger).Error("Invalid Post ID", "error", err)
+			handler := t.RenderErrorPage(http.StatusBadRequest, "Invalid Post ID")
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		post, err := ModHandler.moderationService.GetThread(r.Context(), postID)
+		if err != nil {
+			requestLog(r, t.logger).Error("Failed to fetch post", "error", err)
+			status, _ := ErrorStatus(err)
+			handler := t.RenderErrorPage(status, "Failed to fetch post")
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		staff, _ := middleware.GetStaff(r.Context())
+		data := map[string]interface{}{
+			"Title": "Moderate: " + post.Title,
+			"Staff": staff,
+			"Post":  post,
+		}
+
+		err = t.execute(w, r, "mod-thread.html", data)
+		if err != nil {
+			requestLog(r, t.logger).Error("Failed to render moderation thread page", "error", err)
+			handler := t.RenderErrorPage(http.StatusInternalServerError, "Failed to render moderation thread page")
+			handler.ServeHTTP(w, r)
+			return
+		}
+		requestLog(r, t.logger).Info("Successfully rendered moderation thread page")
+	})
+}
+
+func (t *TemplateHandler) RenderModReportsPage(ModHandler *ModHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		groups, err := ModHandler.reportService.ListQueue(r.Context())
+		if err != nil {
+			requestLog(r, t.logger).Error("Failed to fetch reports", "error", err)
+			handler := t.RenderErrorPage(http.StatusInternalServerError, "Failed to fetch reports")
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		resolved, err := ModHandler.reportService.ListResolved(r.Context())
+		if err != nil {
+			requestLog(r, t.logger).Error("Failed to fetch resolved reports", "error", err)
+			handler := t.RenderErrorPage(http.StatusInternalServerError, "Failed to fetch resolved reports")
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		staff, _ := middleware.GetStaff(r.Context())
+		data := map[string]interface{}{
+			"Title":    "Reports",
+			"Staff":    staff,
+			"Groups":   groups,
+			"Resolved": resolved,
+		}
+
+		err = t.execute(w, r, "mod-reports.html", data)
+		if err != nil {
+			requestLog(r, t.logger).Error("Failed to render reports page", "error", err)
+			handler := t.RenderErrorPage(http.StatusInternalServerError, "Failed to render reports page")
+			handler.ServeHTTP(w, r)
+			return
+		}
+		requestLog(r, t.logger).Info("Successfully rendered reports page")
+	})
+}
+
+func (t *TemplateHandler) RenderModStaffPage(ModHandler *ModHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		members, err := ModHandler.staffService.ListStaff(r.Context())
+		if err != nil {
+			requestLog(r, t.logger).Error("Failed to fetch staff", "error", err)
+			handler := t.RenderErrorPage(http.StatusInternalServerError, "Failed to fetch staff")
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		staff, _ := middleware.GetStaff(r.Context())
+		data := map[string]interface{}{
+			"Title":   "Staff",
+			"Staff":   staff,
+			"Members": members,
+			"Roles":   []domain.StaffRole{domain.RoleJanitor, domain.RoleMod, domain.RoleAdmin},
+		}
+
+		err = t.execute(w, r, "mod-staff.html", data)
+		if err != nil {
+			requestLog(r, t.logger).Error("Failed to render staff page", "error", err)
+			handler := t.RenderErrorPage(http.StatusInternalServerError, "Failed to render staff page")
+			handler.ServeHTTP(w, r)
+			return
+		}
+		requestLog(r, t.logger).Info("Successfully rendered staff page")
+	})
+}
+
+func (t *TemplateHandler) RenderModBansPage(ModHandler *ModHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bans, err := ModHandler.banService.ListBans(r.Context())
+		if err != nil {
+			requestLog(r, t.logger).Error("Failed to fetch bans", "error", err)
+			handler := t.RenderErrorPage(http.StatusInternalServerError, "Failed to fetch bans")
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		staff, _ := middleware.GetStaff(r.Context())
+		data := map[string]interface{}{
+			"Title": "Bans",
+			"Staff": staff,
+			"Bans":  bans,
+		}
+
+		err = t.execute(w, r, "mod-bans.html", data)
+		if err != nil {
+			requestLog(r, t.logger).Error("Failed to render bans page", "error", err)
+			handler := t.RenderErrorPage(http.StatusInternalServerError, "Failed to render bans page")
+			handler.ServeHTTP(w, r)
+			return
+		}
+		requestLog(r, t.logger).Info("Successfully rendered bans page")
+	})
+}
+
+func (t *TemplateHandler) RenderModFiltersPage(ModHandler *ModHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		filters, err := ModHandler.filterService.ListFilters(r.Context())
+		if err != nil {
+			requestLog(r, t.logger).Error("Failed to fetch filters", "error", err)
+			handler := t.RenderErrorPage(http.StatusInternalServerError, "Failed to fetch filters")
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		staff, _ := middleware.GetStaff(r.Context())
+		data := map[string]interface{}{
+			"Title":   "Filters",
+			"Staff":   staff,
+			"Filters": filters,
+			"Kinds":   domain.FilterKinds,
+			"Actions": domain.FilterActions,
+		}
+
+		err = t.execute(w, r, "mod-filters.html", data)
+		if err != nil {
+			requestLog(r, t.logger).Error("Failed to render filters page", "error", err)
+			handler := t.RenderErrorPage(http.StatusInternalServerError, "Failed to render filters page")
+			handler.ServeHTTP(w, r)
+			return
+		}
+		requestLog(r, t.logger).Info("Successfully rendered filters page")
+	})
+}
+
+func (t *TemplateHandler) RenderModAppealsPage(ModHandler *ModHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		appeals, err := ModHandler.banService.ListPendingAppeals(r.Context())
+		if err != nil {
+			requestLog(r, t.logger).Error("Failed to fetch appeals", "error", err)
+			handler := t.RenderErrorPage(http.StatusInternalServerError, "Failed to fetch appeals")
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		staff, _ := middleware.GetStaff(r.Context())
+		data := map[string]interface{}{
+			"Title":   "Appeals",
+			"Staff":   staff,
+			"Appeals": appeals,
+		}
+
+		err = t.execute(w, r, "mod-appeals.html", data)
+		if err != nil {
+			requestLog(r, t.logger).Error("Failed to render appeals page", "error", err)
+			handler := t.RenderErrorPage(http.StatusInternalServerError, "Failed to render appeals page")
+			handler.ServeHTTP(w, r)
+			return
+		}
+		requestLog(r, t.logger).Info("Successfully rendered appeals page")
+	})
+}
+
+// RenderModLogPage shows the moderation log with its filters, newest first.
+func (t *TemplateHandler) RenderModLogPage(ModHandler *ModHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		filter := logFilter(r)
+		actions, err := ModHandler.auditService.List(r.Context(), filter)
+		if err != nil {
+			requestLog(r, t.logger).Error("Failed to fetch moderation log", "error", err)
+			handler := t.RenderErrorPage(http.StatusInternalServerError, "Failed to fetch moderation log")
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		staff, _ := middleware.GetStaff(r.Context())
+		data := map[string]interface{}{
+			"Title":   "Moderation log",
+			"Staff":   staff,
+			"Actions": actions,
+			"Filter":  filter,
+			"Older":   olderLogURL(r.URL.Path, r.URL.Query(), actions),
+		}
+
+		err = t.execute(w, r, "mod-log.html", data)
+		if err != nil {
+			requestLog(r, t.logger).Error("Failed to render moderation log", "error", err)
+			handler := t.RenderErrorPage(http.StatusInternalServerError, "Failed to render moderation log")
+			handler.ServeHTTP(w, r)
+			return
+		}
+		requestLog(r, t.logger).Info("Successfully rendered moderation log")
+	})
+}
+
+// RenderPublicLogPage shows the public actions of the moderation log without
+// naming staff or posters.
+func (t *TemplateHandler) RenderPublicLogPage(ModHandler *ModHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		before, _ := strconv.ParseInt(r.URL.Query().Get("before"), 10, 64)
+		actions, err := ModHandler.auditService.ListPublic(r.Context(), before)
+		if err != nil {
+			requestLog(r, t.logger).Error("Failed to fetch public log", "error", err)
+			handler := t.RenderErrorPage(http.StatusInternalServerError, "Failed to fetch public log")
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		data := map[string]interface{}{
+			"Title":   "Moderation log",
+			"Actions": actions,
+			"Older":   olderLogURL(r.URL.Path, url.Values{}, actions),
+		}
+
+		err = t.execute(w, r, "public-log.html", data)
+		if err != nil {
+			requestLog(r, t.logger).Error("Failed to render public log", "error", err)
+			handler := t.RenderErrorPage(http.StatusInternalServerError, "Failed to render public log")
+			handler.ServeHTTP(w, r)
+			return
+		}
+		requestLog(r, t.logger).Info("Successfully rendered public log")
+	})
+}
+
+// olderLogURL links to the page after actions with the same filters, or is
+// empty when actions did not fill a page.
+func olderLogURL(path string, values url.Values, actions []*domain.ModAction) string {
+	if len(actions) < domain.ModActionPageSize {
+		return ""
+	}
+	values.Set("before", strconv.FormatInt(actions[len(actions)-1].ID, 10))
+	return path + "?" + values.Encode()
+}
+
+// RenderBannedPage lists the visitor's bans with their reason and expiry and
+// offers one appeal per ban. RejectBanned serves it in place of a post.
+func (t *TemplateHandler) RenderBannedPage(BanHandler *BanHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var sessionID string
+		if session, err := middleware.GetSession(r.Context()); err == nil {
+			sessionID = session.ID
+		}
+		client := middleware.GetClientIP(r.Context())
+
+		bans, err := BanHandler.banService.ActiveBans(r.Context(), sessionID, client.Hash, ipString(client))
+		if err != nil {
+			requestLog(r, t.logger).Error("Failed to fetch bans", "error", err)
+			handler := t.RenderErrorPage(http.StatusInternalServerError, "Failed to fetch bans")
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		data := map[string]interface{}{
+			"Title":    "Banned",
+			"Bans":     bans,
+			"Appealed": r.URL.Query().Get("appealed") != "",
+			"MaxLen":   domain.MaxAppealLength,
+		}
+
+		err = t.execute(w, r, "banned.html", data)
+		if err != nil {
+			requestLog(r, t.logger).Error("Failed to render banned page", "error", err)
+			handler := t.RenderErrorPage(http.StatusInternalServerError, "Failed to render banned page")
+			handler.ServeHTTP(w, r)
+			return
+		}
+		requestLog(r, t.logger).Info("Successfully rendered banned page")
 	})
 }
 
@@ -259,12 +690,12 @@ func (t *TemplateHandler) RenderErrorPage(code int, message string) http.Handler
 			"Message": message,
 		}
 
-		err := t.templates.ExecuteTemplate(w, "error.html", data)
+		err := t.execute(w, r, "error.html", data)
 		if err != nil {
-			t.logger.Error("Failed to render error page", "error", err, "method", r.Method)
+			requestLog(r, t.logger).Error("Failed to render error page", "error", err)
 			http.Error(w, "Failed to render error page", http.StatusInternalServerError)
 			return
 		}
-		t.logger.Info("Successfully rendered error page", "method", r.Method)
+		requestLog(r, t.logger).Info("Successfully rendered error page")
 	})
 }
@@ -0,0 +1,352 @@
+package handler
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"1337b04rd/internal/adapters/events"
+	"1337b04rd/internal/domain"
+	"1337b04rd/pkg/middleware"
+)
+
+const (
+	// DefaultMaxConnsPerSession caps the gateway connections one session may
+	// hold open at a time.
+	DefaultMaxConnsPerSession = 4
+	// DefaultMaxConnsPerIP caps the gateway connections one client address
+	// may hold open, however many sessions it rotates through. It is higher
+	// than the session cap since one address may front several clients.
+	DefaultMaxConnsPerIP = 16
+	// maxTopicsPerConn caps the subscriptions of a single connection.
+	maxTopicsPerConn = 100
+
+	wsPingInterval   = 30 * time.Second
+	wsPongWait       = 2 * wsPingInterval
+	wsWriteTimeout   = 10 * time.Second
+	wsOutboundBuffer = 64
+	wsMaxFrameBytes  = 4 << 10
+)
+
+// wsMessage is every frame exchanged over the gateway, in both directions.
+//
+// Clients send subscribe, unsubscribe, ping and pong. A subscription names
+// either a thread or a board and may carry last_event_id to replay what was
+// missed. The server sends subscribed, unsubscribed, event, error, ping and
+// pong; event frames repeat the thread or board they were delivered for.
+// A connection that sends nothing, not even a pong to the server's ping, for
+// wsPongWait is closed.
+type wsMessage struct {
+	Type        string        `json:"type"`
+	Thread      int           `json:"thread,omitempty"`
+	Board       string        `json:"board,omitempty"`
+	LastEventID int64         `json:"last_event_id,omitempty"`
+	Event       *domain.Event `json:"event,omitempty"`
+	Error       *APIError     `json:"error,omitempty"`
+}
+
+// topic identifies a subscription of one connection.
+func (m wsMessage) topic() (string, error) {
+	switch {
+	case m.Thread > 0 && m.Board == "":
+		return fmt.Sprintf("thread:%d", m.Thread), nil
+	case m.Board != "" && m.Thread == 0:
+		return "board:" + m.Board, nil
+	}
+	return "", domain.ErrInvalidTopic
+}
+
+// WebSocketHandler multiplexes thread and board catalog subscriptions over a
+// single connection.
+type WebSocketHandler struct {
+	bus            *events.Bus
+	allowedOrigins map[string]bool
+	maxPerSession  int
+	maxPerIP       int
+	logger         *slog.Logger
+
+	mu       sync.Mutex
+	sessions map[string]int
+	ips      map[string]int
+}
+
+// NewWebSocketHandler accepts connections from pages served by this host and
+// from allowedOrigins, given as scheme://host[:port]. Open connections are
+// capped per session and per client IP hash.
+func NewWebSocketHandler(bus *events.Bus, allowedOrigins []string, maxPerSession, maxPerIP int, logger *slog.Logger) *WebSocketHandler {
+	origins := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		origins[strings.TrimSuffix(origin, "/")] = true
+	}
+	return &WebSocketHandler{
+		bus:            bus,
+		allowedOrigins: origins,
+		maxPerSession:  maxPerSession,
+		maxPerIP:       maxPerIP,
+		logger:         logger,
+		sessions:       make(map[string]int),
+		ips:            make(map[string]int),
+	}
+}
+
+func (h *WebSocketHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	session, err := apiSession(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if !h.originAllowed(r) {
+		requestLog(r, h.logger).Warn("Rejected WebSocket origin", "origin", r.Header.Get("Origin"))
+		writeError(w, domain.ErrOriginNotAllowed)
+		return
+	}
+
+	ipHash := middleware.GetClientIP(r.Context()).Hash
+	if !h.acquire(session.ID, ipHash) {
+		writeError(w, domain.ErrTooManyConnections)
+		return
+	}
+	defer h.release(session.ID, ipHash)
+
+	server := websocket.Server{
+		// The origin was checked above; the default check would reject
+		// clients that send none.
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler: func(ws *websocket.Conn) {
+			ws.MaxPayloadBytes = wsMaxFrameBytes
+			newWSConn(h, ws).serve()
+		},
+	}
+	server.ServeHTTP(w, r)
+}
+
+// originAllowed accepts requests without an Origin, which browsers always
+// send, so only cross-site pages are refused.
+func (h *WebSocketHandler) originAllowed(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	return h.allowedOrigins[u.Scheme+"://"+u.Host]
+}
+
+// acquire takes a connection slot of the session and of the client IP
+// hash, if the request has one, or neither when either is full.
+func (h *WebSocketHandler) acquire(sessionID, ipHash string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.sessions[sessionID] >= h.maxPerSession {
+		return false
+	}
+	if ipHash != "" && h.ips[ipHash] >= h.maxPerIP {
+		return false
+	}
+	h.sessions[sessionID]++
+	if ipHash != "" {
+		h.ips[ipHash]++
+	}
+	return true
+}
+
+func (h *WebSocketHandler) release(sessionID, ipHash string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	releaseSlot(h.sessions, sessionID)
+	if ipHash != "" {
+		releaseSlot(h.ips, ipHash)
+	}
+}
+
+func releaseSlot(conns map[string]int, key string) {
+	conns[key]--
+	if conns[key] <= 0 {
+		delete(conns, key)
+	}
+}
+
+// wsConn is one gateway connection. The read loop handles client frames,
+// a single writer owns the socket for output, and every subscription
+// forwards bus events to the writer.
+type wsConn struct {
+	h    *WebSocketHandler
+	ws   *websocket.Conn
+	out  chan wsMessage
+	done chan struct{}
+
+	mu     sync.Mutex
+	topics map[string]*events.Subscription
+}
+
+func newWSConn(h *WebSocketHandler, ws *websocket.Conn) *wsConn {
+	return &wsConn{
+		h:      h,
+		ws:     ws,
+		out:    make(chan wsMessage, wsOutboundBuffer),
+		done:   make(chan struct{}),
+		topics: make(map[string]*events.Subscription),
+	}
+}
+
+func (c *wsConn) serve() {
+	defer c.ws.Close()
+	go c.writeLoop()
+	c.readLoop()
+
+	close(c.done)
+	c.mu.Lock()
+	for topic, sub := range c.topics {
+		sub.Close()
+		delete(c.topics, topic)
+	}
+	c.mu.Unlock()
+}
+
+func (c *wsConn) readLoop() {
+	for {
+		c.ws.SetReadDeadline(time.Now().Add(wsPongWait))
+
+		var msg wsMessage
+		if err := websocket.JSON.Receive(c.ws, &msg); err != nil {
+			return
+		}
+
+		switch msg.Type {
+		case "subscribe":
+			c.subscribe(msg)
+		case "unsubscribe":
+			c.unsubscribe(msg)
+		case "ping":
+			c.send(wsMessage{Type: "pong"})
+		case "pong":
+			// the read deadline was already extended
+		default:
+			c.sendError(msg, fmt.Errorf("unknown message type %q: %w", msg.Type, domain.ErrInvalidBody))
+		}
+	}
+}
+
+func (c *wsConn) writeLoop() {
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+
+	for {
+		var msg wsMessage
+		select {
+		case <-c.done:
+			return
+		case <-ping.C:
+			msg = wsMessage{Type: "ping"}
+		case msg = <-c.out:
+		}
+
+		c.ws.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+		if err := websocket.JSON.Send(c.ws, msg); err != nil {
+			// Closing the socket ends the read loop and with it the
+			// connection.
+			c.ws.Close()
+			return
+		}
+	}
+}
+
+// send queues msg for the writer. It blocks while the writer is behind, which
+// in turn makes slow subscriptions overflow on the bus.
+func (c *wsConn) send(msg wsMessage) {
+	select {
+	case c.out <- msg:
+	case <-c.done:
+	}
+}
+
+func (c *wsConn) sendError(req wsMessage, err error) {
+	_, code := ErrorStatus(err)
+	c.send(wsMessage{Type: "error", Thread: req.Thread, Board: req.Board, Error: &APIError{Code: code, Message: err.Error()}})
+}
+
+func (c *wsConn) subscribe(msg wsMessage) {
+	topic, err := msg.topic()
+	if err != nil {
+		c.sendError(msg, err)
+		return
+	}
+
+	filter := events.ForPost(msg.Thread)
+	if msg.Board != "" {
+		filter = events.ForBoard(msg.Board)
+	}
+
+	c.mu.Lock()
+	if _, ok := c.topics[topic]; ok {
+		c.mu.Unlock()
+		c.send(wsMessage{Type: "subscribed", Thread: msg.Thread, Board: msg.Board})
+		return
+	}
+	if len(c.topics) >= maxTopicsPerConn {
+		c.mu.Unlock()
+		c.sendError(msg, domain.ErrTooManySubscriptions)
+		return
+	}
+	sub, replay := c.h.bus.Subscribe(filter, msg.LastEventID)
+	c.topics[topic] = sub
+	c.mu.Unlock()
+
+	c.send(wsMessage{Type: "subscribed", Thread: msg.Thread, Board: msg.Board})
+	go c.forward(topic, msg, sub, replay)
+}
+
+func (c *wsConn) unsubscribe(msg wsMessage) {
+	topic, err := msg.topic()
+	if err != nil {
+		c.sendError(msg, err)
+		return
+	}
+
+	c.mu.Lock()
+	if sub, ok := c.topics[topic]; ok {
+		sub.Close()
+		delete(c.topics, topic)
+	}
+	c.mu.Unlock()
+
+	c.send(wsMessage{Type: "unsubscribed", Thread: msg.Thread, Board: msg.Board})
+}
+
+// forward relays one subscription to the client until it is closed. A
+// subscription dropped for falling behind is reported so the client can
+// resubscribe with the last event ID it saw.
+func (c *wsConn) forward(topic string, req wsMessage, sub *events.Subscription, replay []domain.Event) {
+	frame := func(event domain.Event) wsMessage {
+		return wsMessage{Type: "event", Thread: req.Thread, Board: req.Board, Event: &event}
+	}
+
+	for _, event := range replay {
+		c.send(frame(event))
+	}
+	for event := range sub.C {
+		c.send(frame(event))
+	}
+
+	if sub.Dropped() {
+		c.mu.Lock()
+		if c.topics[topic] == sub {
+			delete(c.topics, topic)
+		}
+		c.mu.Unlock()
+		c.h.logger.Warn("Dropped slow WebSocket subscription", "topic", topic)
+		c.sendError(req, domain.ErrSubscriptionOverflown)
+	}
+}
@@ -0,0 +1,20 @@
+package ports
+
+import (
+	"context"
+	"time"
+
+	"1337b04rd/internal/domain"
+)
+
+type BanRepository interface {
+	Save(ctx context.Context, ban *domain.Ban) error
+	FindByID(ctx context.Context, id int) (*domain.Ban, error)
+	FindActive(ctx context.Context, sessionID, ipHash, ip, board string) ([]*domain.Ban, error)
+	FindAllActive(ctx context.Context) ([]*domain.Ban, error)
+	Lift(ctx context.Context, id int) error
+	SaveAppeal(ctx context.Context, appeal *domain.BanAppeal) error
+	FindAppealByBan(ctx context.Context, banID int) (*domain.BanAppeal, error)
+	FindPendingAppeals(ctx context.Context) ([]*domain.BanAppeal, error)
+	DecideAppeal(ctx context.Context, appealID int, status domain.AppealStatus, staffID int, decidedAt time.Time) error
+}
@@ -0,0 +1,12 @@
+package ports
+
+import (
+	"context"
+
+	"1337b04rd/internal/domain"
+)
+
+type BoardRepository interface {
+	FindAll(ctx context.Context) ([]*domain.Board, error)
+	FindBySlug(ctx context.Context, slug string) (*domain.Board, error)
+}
@@ -0,0 +1,14 @@
+package ports
+
+import (
+	"context"
+	"time"
+)
+
+// ChallengeRepository remembers the captcha challenges already used.
+type ChallengeRepository interface {
+	// Use marks a challenge as used, or fails with ErrCaptchaUsed if it
+	// already was.
+	Use(ctx context.Context, id string, expiresAt time.Time) error
+	DeleteExpired(ctx context.Context, before time.Time) (int, error)
+}
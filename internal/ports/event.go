@@ -0,0 +1,11 @@
+package ports
+
+import (
+	"context"
+
+	"1337b04rd/internal/domain"
+)
+
+type EventPublisher interface {
+	Publish(ctx context.Context, event domain.Event) error
+}
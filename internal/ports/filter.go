@@ -0,0 +1,19 @@
+package ports
+
+import (
+	"context"
+	"time"
+
+	"1337b04rd/internal/domain"
+)
+
+type FilterRepository interface {
+	FindAll(ctx context.Context) ([]*domain.Filter, error)
+	FindByID(ctx context.Context, id int) (*domain.Filter, error)
+	Save(ctx context.Context, filter *domain.Filter) error
+	Update(ctx context.Context, filter *domain.Filter) error
+	Delete(ctx context.Context, id int) error
+	// CountDuplicates counts the threads and comments on board created
+	// after since whose normalized text is text.
+	CountDuplicates(ctx context.Context, board, text string, since time.Time) (int, error)
+}
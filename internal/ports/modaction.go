@@ -0,0 +1,12 @@
+package ports
+
+import (
+	"context"
+
+	"1337b04rd/internal/domain"
+)
+
+type ModActionRepository interface {
+	Append(ctx context.Context, action *domain.ModAction) error
+	Find(ctx context.Context, filter domain.ModActionFilter) ([]*domain.ModAction, error)
+}
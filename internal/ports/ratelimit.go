@@ -0,0 +1,14 @@
+package ports
+
+import (
+	"context"
+	"time"
+
+	"1337b04rd/internal/domain"
+)
+
+// RateLimiter keeps a token bucket per key. Allow spends a token under rule
+// or reports how long to wait for one.
+type RateLimiter interface {
+	Allow(ctx context.Context, key string, rule domain.RateLimitRule) (bool, time.Duration, error)
+}
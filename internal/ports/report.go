@@ -0,0 +1,16 @@
+package ports
+
+import (
+	"context"
+	"time"
+
+	"1337b04rd/internal/domain"
+)
+
+type ReportRepository interface {
+	Save(ctx context.Context, report *domain.Report) error
+	CountSince(ctx context.Context, sessionID string, since time.Time) (int, error)
+	FindOpenGrouped(ctx context.Context) ([]*domain.ReportGroup, error)
+	FindResolved(ctx context.Context, limit int) ([]*domain.Report, error)
+	Resolve(ctx context.Context, targetType domain.ReportTarget, targetID int, resolution domain.ReportResolution, staffID int, resolvedAt time.Time) (int, error)
+}
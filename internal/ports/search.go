@@ -0,0 +1,14 @@
+package ports
+
+import (
+	"context"
+
+	"1337b04rd/internal/domain"
+)
+
+type SearchRepository interface {
+	// Search returns one page of hits for q and the total number of
+	// matches. Snippets wrap highlighted terms in domain.HighlightStart and
+	// domain.HighlightStop.
+	Search(ctx context.Context, q domain.SearchQuery) ([]domain.SearchResult, int, error)
+}
@@ -0,0 +1,18 @@
+package ports
+
+import (
+	"context"
+	"time"
+
+	"1337b04rd/internal/domain"
+)
+
+type StaffRepository interface {
+	Save(ctx context.Context, staff *domain.Staff) error
+	FindByUsername(ctx context.Context, username string) (*domain.Staff, error)
+	FindAll(ctx context.Context) ([]*domain.Staff, error)
+	Count(ctx context.Context) (int, error)
+	CreateSession(ctx context.Context, tokenHash string, staffID int, expiresAt time.Time) error
+	FindBySession(ctx context.Context, tokenHash string) (*domain.Staff, error)
+	DeleteSession(ctx context.Context, tokenHash string) error
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"sort"
+	"time"
 
 	"1337b04rd/internal/domain"
 )
@@ -16,55 +18,198 @@ func NewArchiveRepository(db *sql.DB) *ArchiveRepository {
 	return &ArchiveRepository{db: db}
 }
 
+// Save moves a thread and its comments into the archive in one
+// transaction. They keep their IDs, so links and feed entries point at the
+// same thread before and after it is archived.
 func (r *ArchiveRepository) Save(ctx context.Context, post *domain.Post) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("unable to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	// 1. Insert post into archived_posts
 	insertQuery := `
         INSERT INTO archived_posts 
-        (title, content, author_id, author_name, image_url, created_at, expires_at, archived_at)
-        VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
-        RETURNING id
+        (id, title, content, author_id, author_name, image_url, image_source_url, board, created_at, expires_at, archived_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW())
     `
-	var archiveID int
-	err := r.db.QueryRowContext(ctx, insertQuery,
-		post.Title, post.Content, post.AuthorID, post.AuthorName, post.ImageURL,
+	board := post.Board
+	if board == "" {
+		board = domain.DefaultBoard
+	}
+	_, err = tx.ExecContext(ctx, insertQuery,
+		post.ID, post.Title, post.Content, post.AuthorID, post.AuthorName, post.ImageURL, post.ImageSourceURL, board,
 		post.CreatedAt, post.ExpiresAt,
-	).Scan(&archiveID)
+	)
 	if err != nil {
 		return fmt.Errorf("unable to archive post: %w", err)
 	}
 
-	// 2. Archive comments (assign new post_id pointing to archived_posts)
-	for _, c := range post.Comments {
-		_, err := r.db.ExecContext(ctx, `
-			INSERT INTO archived_comments (
-				post_id, title, content, author_id, author_name, image_url, reply_to_comment_id, created_at
-			)
-			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-		`, archiveID, c.Title, c.Content, c.AuthorID, c.AuthorName, c.ImageURL, c.ReplyToCommentID, c.CreatedAt)
-		if err != nil {
-			return fmt.Errorf("unable to archive comment: %w", err)
-		}
+	// 2. Archive comments, oldest first so replies follow what they reply to
+	comments := append([]domain.Comment(nil), post.Comments...)
+	sort.Slice(comments, func(i, j int) bool { return comments[i].ID < comments[j].ID })
+	if err := copyComments(ctx, tx, "archived_comments", post.ID, comments, true); err != nil {
+		return fmt.Errorf("unable to archive comment: %w", err)
 	}
 
 	// 3. Delete original comments
-	_, err = r.db.ExecContext(ctx, "DELETE FROM comments WHERE post_id = $1", post.ID)
+	_, err = tx.ExecContext(ctx, "DELETE FROM comments WHERE post_id = $1", post.ID)
 	if err != nil {
 		return fmt.Errorf("unable to delete original comments: %w", err)
 	}
 
 	// 4. Delete original post
-	_, err = r.db.ExecContext(ctx, "DELETE FROM posts WHERE id = $1", post.ID)
+	_, err = tx.ExecContext(ctx, "DELETE FROM posts WHERE id = $1", post.ID)
 	if err != nil {
 		return fmt.Errorf("unable to delete original post: %w", err)
 	}
 
+	return tx.Commit()
+}
+
+// Restore moves an archived thread and its comments back onto the board in
+// one transaction, expiring at expiresAt. Their dates move forward so the
+// latest of them falls at restoredAt, keeping the gaps between them, which
+// gives the thread a fresh lease on the expiry job. The thread and its
+// comments come back under the IDs they had before archiving. Threads archived before
+// archives kept IDs have negative ones; they get new IDs, which the
+// returned post carries, and replies are remapped to them. The archive
+// keeps no IP hashes, so restored posts carry none.
+func (r *ArchiveRepository) Restore(ctx context.Context, archivedID int, restoredAt, expiresAt time.Time) (*domain.Post, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// 1. Lock the archived post, so it is restored at most once
+	var post domain.Post
+	var imageURL sql.NullString
+	err = tx.QueryRowContext(ctx, `
+        SELECT title, content, author_id, author_name, image_url, image_source_url, board, created_at
+        FROM archived_posts
+        WHERE id = $1
+        FOR UPDATE
+    `, archivedID).Scan(&post.Title, &post.Content, &post.AuthorID, &post.AuthorName, &imageURL, &post.ImageSourceURL, &post.Board, &post.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("archived post with id %d: %w", archivedID, domain.ErrPostNotFound)
+		}
+		return nil, fmt.Errorf("unable to load archived post: %w", err)
+	}
+	post.ImageURL = imageURL.String
+	post.ExpiresAt = expiresAt
+
+	// 2. Load its comments, oldest first so replies follow what they reply to
+	rows, err := tx.QueryContext(ctx, `
+        SELECT id, title, content, author_id, author_name, image_url, image_source_url, reply_to_comment_id, created_at
+        FROM archived_comments
+        WHERE post_id = $1
+        ORDER BY id
+    `, archivedID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch archived comments: %w", err)
+	}
+	for rows.Next() {
+		var c domain.Comment
+		var commentImage sql.NullString
+		if err := rows.Scan(&c.ID, &c.Title, &c.Content, &c.AuthorID, &c.AuthorName, &commentImage, &c.ImageSourceURL, &c.ReplyToCommentID, &c.CreatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("unable to scan archived comment: %w", err)
+		}
+		c.ImageURL = commentImage.String
+		post.Comments = append(post.Comments, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error while iterating over archived comments: %w", err)
+	}
+
+	// 3. Date the thread as if its latest post were made now
+	latest := post.CreatedAt
+	for _, c := range post.Comments {
+		if c.CreatedAt.After(latest) {
+			latest = c.CreatedAt
+		}
+	}
+	if shift := restoredAt.Sub(latest); shift > 0 {
+		post.CreatedAt = post.CreatedAt.Add(shift)
+		for i := range post.Comments {
+			post.Comments[i].CreatedAt = post.Comments[i].CreatedAt.Add(shift)
+		}
+	}
+
+	// 4. Insert the post and its comments
+	var keep *int
+	keepIDs := archivedID > 0
+	if keepIDs {
+		keep = &archivedID
+	}
+	err = tx.QueryRowContext(ctx, `
+        INSERT INTO posts (id, title, content, author_id, author_name, image_url, image_source_url, board, created_at, expires_at)
+        VALUES (COALESCE($1, nextval(pg_get_serial_sequence('posts', 'id'))), $2, $3, $4, $5, $6, $7, $8, $9, $10)
+        RETURNING id
+    `, keep, post.Title, post.Content, post.AuthorID, post.AuthorName, post.ImageURL, post.ImageSourceURL, post.Board, post.CreatedAt, post.ExpiresAt).Scan(&post.ID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to restore post: %w", err)
+	}
+	if err := copyComments(ctx, tx, "comments", post.ID, post.Comments, keepIDs); err != nil {
+		return nil, fmt.Errorf("unable to restore comment: %w", err)
+	}
+
+	// 5. Delete the archived post; its comments cascade
+	if _, err := tx.ExecContext(ctx, "DELETE FROM archived_posts WHERE id = $1", archivedID); err != nil {
+		return nil, fmt.Errorf("unable to delete archived post: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("unable to commit restore: %w", err)
+	}
+	return &post, nil
+}
+
+// copyComments inserts comments, oldest first, into table under postID.
+// With keepIDs they keep their IDs; otherwise every comment gets a new ID,
+// set on it, and replies are remapped to the new IDs. Replies to comments
+// outside the thread are cut loose.
+func copyComments(ctx context.Context, tx *sql.Tx, table string, postID int, comments []domain.Comment, keepIDs bool) error {
+	query := `
+        INSERT INTO ` + table + ` (id, post_id, title, content, author_id, author_name, image_url, image_source_url, reply_to_comment_id, created_at)
+        VALUES (COALESCE($1, nextval(pg_get_serial_sequence('` + table + `', 'id'))), $2, $3, $4, $5, $6, $7, $8, $9, $10)
+        RETURNING id
+    `
+	newIDs := make(map[int]int, len(comments))
+	for i := range comments {
+		c := &comments[i]
+		var replyTo *int
+		if c.ReplyToCommentID != nil {
+			if id, ok := newIDs[*c.ReplyToCommentID]; ok {
+				replyTo = &id
+			}
+		}
+		var keep *int
+		if keepIDs {
+			keep = &c.ID
+		}
+		var id int
+		if err := tx.QueryRowContext(ctx, query, keep, postID, c.Title, c.Content, c.AuthorID, c.AuthorName, c.ImageURL, c.ImageSourceURL, replyTo, c.CreatedAt).Scan(&id); err != nil {
+			return err
+		}
+		newIDs[c.ID] = id
+		c.ID, c.PostID, c.ReplyToCommentID = id, postID, replyTo
+	}
 	return nil
 }
 
 func (r *ArchiveRepository) FindAll(ctx context.Context) ([]*domain.Archive, error) {
 	rows, err := r.db.QueryContext(ctx, `
-        SELECT id, title, content, author_id, author_name, image_url, created_at, expires_at, archived_at
-        FROM archived_posts
+        SELECT p.id, p.title, p.content, p.author_id, p.author_name, p.image_url, p.image_source_url, p.board, p.created_at, p.expires_at, p.archived_at,
+            COUNT(c.id), MAX(c.created_at)
+        FROM archived_posts p
+        LEFT JOIN archived_comments c ON c.post_id = p.id
+        GROUP BY p.id
+        ORDER BY p.archived_at DESC
     `)
 	if err != nil {
 		return nil, fmt.Errorf("unable to fetch archived posts: %w", err)
@@ -72,8 +217,10 @@ func (r *ArchiveRepository) FindAll(ctx context.Context) ([]*domain.Archive, err
 	defer rows.Close()
 
 	var posts []*domain.Archive
+	byID := make(map[int]*domain.Archive)
 	for rows.Next() {
 		var post domain.Archive
+		var lastReply sql.NullTime
 		if err := rows.Scan(
 			&post.ID,
 			&post.Title,
@@ -81,72 +228,152 @@ func (r *ArchiveRepository) FindAll(ctx context.Context) ([]*domain.Archive, err
 			&post.AuthorID,
 			&post.AuthorName,
 			&post.ImageURL,
+			&post.ImageSourceURL,
+			&post.Board,
 			&post.CreatedAt,
 			&post.ExpiredAt,
 			&post.ArchivedAt,
+			&post.ReplyCount,
+			&lastReply,
 		); err != nil {
 			return nil, fmt.Errorf("unable to scan archived post: %w", err)
 		}
-
-		// Fetch archived comments for each post
-		commentRows, err := r.db.QueryContext(ctx, `
-            SELECT id, post_id, title, content, author_id, author_name, image_url, reply_to_comment_id, created_at
-            FROM archived_comments
-            WHERE post_id = $1
-        `, post.ID)
-		if err != nil {
-			return nil, fmt.Errorf("unable to fetch comments for post %d: %w", post.ID, err)
+		if lastReply.Valid {
+			post.LastReplyAt = &lastReply.Time
 		}
+		posts = append(posts, &post)
+		byID[post.ID] = &post
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error while iterating over archived posts: %w", err)
+	}
 
-		var comments []domain.Comment
-		for commentRows.Next() {
-			var comment domain.Comment
-			var replyTo *int
-			if err := commentRows.Scan(
-				&comment.ID,
-				&comment.PostID,
-				&comment.Title,
-				&comment.Content,
-				&comment.AuthorID,
-				&comment.AuthorName,
-				&comment.ImageURL,
-				&replyTo,
-				&comment.CreatedAt,
-			); err != nil {
-				commentRows.Close()
-				return nil, fmt.Errorf("unable to scan archived comment: %w", err)
-			}
-			comment.ReplyToCommentID = replyTo
-			comments = append(comments, comment)
+	if len(posts) == 0 {
+		return posts, nil
+	}
+
+	// Load only the latest archived replies of every thread in a single query.
+	commentRows, err := r.db.QueryContext(ctx, `
+        SELECT id, post_id, title, content, author_id, author_name, image_url, image_source_url, reply_to_comment_id, created_at
+        FROM (
+            SELECT c.*, ROW_NUMBER() OVER (PARTITION BY c.post_id ORDER BY c.created_at DESC) AS rn
+            FROM archived_comments c
+        ) latest
+        WHERE rn <= $1
+        ORDER BY post_id, created_at ASC
+    `, CatalogPreviewReplies)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch latest archived comments: %w", err)
+	}
+	defer commentRows.Close()
+
+	for commentRows.Next() {
+		var comment domain.Comment
+		if err := commentRows.Scan(
+			&comment.ID,
+			&comment.PostID,
+			&comment.Title,
+			&comment.Content,
+			&comment.AuthorID,
+			&comment.AuthorName,
+			&comment.ImageURL,
+			&comment.ImageSourceURL,
+			&comment.ReplyToCommentID,
+			&comment.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("unable to scan archived comment: %w", err)
+		}
+		if post, ok := byID[comment.PostID]; ok {
+			post.Comments = append(post.Comments, comment)
 		}
-		commentRows.Close()
+	}
+	if err := commentRows.Err(); err != nil {
+		return nil, fmt.Errorf("error while iterating over archived comments: %w", err)
+	}
+
+	return posts, nil
+}
+
+// Count returns how many threads are archived.
+func (r *ArchiveRepository) Count(ctx context.Context) (int, error) {
+	var n int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM archived_posts").Scan(&n); err != nil {
+		return 0, fmt.Errorf("unable to count archived posts: %w", err)
+	}
+	return n, nil
+}
 
-		post.Comments = comments
+// FindPage returns up to limit archived threads of board (all boards when
+// empty), most recently archived first, starting after the archived thread
+// with ID beforeID (0 for the first page).
+func (r *ArchiveRepository) FindPage(ctx context.Context, board string, beforeID, limit int) ([]*domain.Archive, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT p.id, p.title, p.content, p.author_id, p.author_name, p.image_url, p.image_source_url, p.board, p.created_at, p.expires_at, p.archived_at,
+            COUNT(c.id), MAX(c.created_at)
+        FROM archived_posts p
+        LEFT JOIN archived_comments c ON c.post_id = p.id
+        WHERE ($1 = '' OR p.board = $1) AND ($2 = 0 OR p.id < $2)
+        GROUP BY p.id
+        ORDER BY p.id DESC
+        LIMIT $3
+    `, board, beforeID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch archived posts: %w", err)
+	}
+	defer rows.Close()
+
+	var posts []*domain.Archive
+	for rows.Next() {
+		var post domain.Archive
+		var lastReply sql.NullTime
+		if err := rows.Scan(
+			&post.ID,
+			&post.Title,
+			&post.Content,
+			&post.AuthorID,
+			&post.AuthorName,
+			&post.ImageURL,
+			&post.ImageSourceURL,
+			&post.Board,
+			&post.CreatedAt,
+			&post.ExpiredAt,
+			&post.ArchivedAt,
+			&post.ReplyCount,
+			&lastReply,
+		); err != nil {
+			return nil, fmt.Errorf("unable to scan archived post: %w", err)
+		}
+		if lastReply.Valid {
+			post.LastReplyAt = &lastReply.Time
+		}
 		posts = append(posts, &post)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error while iterating over archived posts: %w", err)
+	}
 
 	return posts, nil
 }
 
 func (r *ArchiveRepository) FindByID(ctx context.Context, id int) (*domain.Post, error) {
 	query := `
-        SELECT id, title, content, author_id, author_name, image_url, created_at, expires_at
+        SELECT id, title, content, author_id, author_name, image_url, image_source_url, board, created_at, expires_at
         FROM archived_posts
         WHERE id = $1
     `
 	row := r.db.QueryRowContext(ctx, query, id)
 
 	var post domain.Post
-	if err := row.Scan(&post.ID, &post.Title, &post.Content, &post.AuthorID, &post.AuthorName, &post.ImageURL, &post.CreatedAt, &post.ExpiresAt); err != nil {
+	if err := row.Scan(&post.ID, &post.Title, &post.Content, &post.AuthorID, &post.AuthorName, &post.ImageURL, &post.ImageSourceURL, &post.Board, &post.CreatedAt, &post.ExpiresAt); err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("archived post with id %d not found", id)
+			return nil, fmt.Errorf("archived post with id %d: %w", id, domain.ErrPostNotFound)
 		}
 		return nil, fmt.Errorf("error scanning archived post: %w", err)
 	}
 
 	// Fetch archived comments
 	commentQuery := `
-        SELECT id, post_id, title, content, author_id, author_name, image_url, reply_to_comment_id, created_at
+        SELECT id, post_id, title, content, author_id, author_name, image_url, image_source_url, reply_to_comment_id, created_at
         FROM archived_comments
         WHERE post_id = $1
     `
@@ -169,6 +396,7 @@ func (r *ArchiveRepository) FindByID(ctx context.Context, id int) (*domain.Post,
 			&c.AuthorID,
 			&c.AuthorName,
 			&c.ImageURL,
+			&c.ImageSourceURL,
 			&replyTo,
 			&c.CreatedAt,
 		); err != nil {
@@ -0,0 +1,215 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"1337b04rd/internal/domain"
+)
+
+type BanRepository struct {
+	db *sql.DB
+}
+
+func NewBanRepository(db *sql.DB) *BanRepository {
+	return &BanRepository{db: db}
+}
+
+const banColumns = `id, COALESCE(session_id, ''), COALESCE(ip_hash, ''), COALESCE(cidr::text, ''), COALESCE(board, ''), reason, COALESCE(staff_id, 0), created_at, expires_at`
+
+func (r *BanRepository) Save(ctx context.Context, ban *domain.Ban) error {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO bans (session_id, ip_hash, cidr, board, reason, staff_id, created_at, expires_at)
+		VALUES (NULLIF($1, ''), NULLIF($2, ''), NULLIF($3, '')::cidr, NULLIF($4, ''), $5, NULLIF($6, 0), $7, $8)
+		RETURNING id
+	`, ban.SessionID, ban.IPHash, ban.CIDR, ban.Board, ban.Reason, ban.StaffID, ban.CreatedAt, ban.ExpiresAt).Scan(&ban.ID)
+	if err != nil {
+		return fmt.Errorf("unable to insert ban: %w", err)
+	}
+	return nil
+}
+
+func (r *BanRepository) FindByID(ctx context.Context, id int) (*domain.Ban, error) {
+	row := r.db.QueryRowContext(ctx, "SELECT "+banColumns+" FROM bans WHERE id = $1", id)
+	ban, err := scanBan(row)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("ban with id %d: %w", id, domain.ErrBanNotFound)
+	}
+	return ban, err
+}
+
+// FindActive returns the unexpired bans matching the session, the IP hash or
+// a range containing ip, permanent and longest first. With a board only bans
+// covering that board are returned; without one, bans from every board.
+func (r *BanRepository) FindActive(ctx context.Context, sessionID, ipHash, ip, board string) ([]*domain.Ban, error) {
+	return r.findBans(ctx, `
+		SELECT `+banColumns+`
+		FROM bans
+		WHERE (expires_at IS NULL OR expires_at > NOW())
+			AND ($4 = '' OR board IS NULL OR board = $4)
+			AND (session_id = NULLIF($1, '') OR ip_hash = NULLIF($2, '') OR cidr >>= NULLIF($3, '')::inet)
+		ORDER BY expires_at DESC NULLS FIRST, id DESC
+	`, sessionID, ipHash, ip, board)
+}
+
+// FindAllActive returns every unexpired ban, newest first.
+func (r *BanRepository) FindAllActive(ctx context.Context) ([]*domain.Ban, error) {
+	return r.findBans(ctx, `
+		SELECT `+banColumns+`
+		FROM bans
+		WHERE expires_at IS NULL OR expires_at > NOW()
+		ORDER BY created_at DESC
+	`)
+}
+
+// Lift ends a ban now. The row stays for the record.
+func (r *BanRepository) Lift(ctx context.Context, id int) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE bans SET expires_at = NOW()
+		WHERE id = $1 AND (expires_at IS NULL OR expires_at > NOW())
+	`, id)
+	if err != nil {
+		return fmt.Errorf("unable to lift ban: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("ban with id %d: %w", id, domain.ErrBanNotFound)
+	}
+	return nil
+}
+
+func (r *BanRepository) findBans(ctx context.Context, query string, args ...interface{}) ([]*domain.Ban, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch bans: %w", err)
+	}
+	defer rows.Close()
+
+	var bans []*domain.Ban
+	for rows.Next() {
+		ban, err := scanBan(rows)
+		if err != nil {
+			return nil, err
+		}
+		bans = append(bans, ban)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error while iterating over rows: %w", err)
+	}
+	return bans, nil
+}
+
+func scanBan(row rowScanner) (*domain.Ban, error) {
+	var b domain.Ban
+	if err := row.Scan(&b.ID, &b.SessionID, &b.IPHash, &b.CIDR, &b.Board, &b.Reason, &b.StaffID, &b.CreatedAt, &b.ExpiresAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("unable to scan ban: %w", err)
+	}
+	return &b, nil
+}
+
+// SaveAppeal stores the appeal of a ban, which may be appealed only once.
+func (r *BanRepository) SaveAppeal(ctx context.Context, appeal *domain.BanAppeal) error {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO ban_appeals (ban_id, message, status, created_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`, appeal.BanID, appeal.Message, appeal.Status, appeal.CreatedAt).Scan(&appeal.ID)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return fmt.Errorf("ban %d: %w", appeal.BanID, domain.ErrAppealExists)
+		}
+		return fmt.Errorf("unable to insert appeal: %w", err)
+	}
+	return nil
+}
+
+func (r *BanRepository) FindAppealByBan(ctx context.Context, banID int) (*domain.BanAppeal, error) {
+	var a domain.BanAppeal
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, ban_id, message, status, created_at, decided_at, decided_by
+		FROM ban_appeals
+		WHERE ban_id = $1
+	`, banID).Scan(&a.ID, &a.BanID, &a.Message, &a.Status, &a.CreatedAt, &a.DecidedAt, &a.DecidedBy)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("appeal of ban %d: %w", banID, domain.ErrAppealNotFound)
+		}
+		return nil, fmt.Errorf("unable to fetch appeal: %w", err)
+	}
+	return &a, nil
+}
+
+// FindPendingAppeals returns the undecided appeals with their bans, oldest
+// first.
+func (r *BanRepository) FindPendingAppeals(ctx context.Context) ([]*domain.BanAppeal, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT a.id, a.ban_id, a.message, a.status, a.created_at,
+			b.id, COALESCE(b.session_id, ''), COALESCE(b.ip_hash, ''), COALESCE(b.cidr::text, ''), COALESCE(b.board, ''), b.reason, COALESCE(b.staff_id, 0), b.created_at, b.expires_at
+		FROM ban_appeals a
+		JOIN bans b ON b.id = a.ban_id
+		WHERE a.status = 'pending'
+		ORDER BY a.created_at ASC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch appeals: %w", err)
+	}
+	defer rows.Close()
+
+	var appeals []*domain.BanAppeal
+	for rows.Next() {
+		var a domain.BanAppeal
+		var b domain.Ban
+		if err := rows.Scan(&a.ID, &a.BanID, &a.Message, &a.Status, &a.CreatedAt,
+			&b.ID, &b.SessionID, &b.IPHash, &b.CIDR, &b.Board, &b.Reason, &b.StaffID, &b.CreatedAt, &b.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("unable to scan appeal: %w", err)
+		}
+		a.Ban = &b
+		appeals = append(appeals, &a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error while iterating over rows: %w", err)
+	}
+	return appeals, nil
+}
+
+// DecideAppeal records the decision on a pending appeal and, when it is
+// accepted, lifts the ban in the same transaction.
+func (r *BanRepository) DecideAppeal(ctx context.Context, appealID int, status domain.AppealStatus, staffID int, decidedAt time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("unable to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var banID int
+	err = tx.QueryRowContext(ctx, `
+		UPDATE ban_appeals SET status = $2, decided_at = $3, decided_by = NULLIF($4, 0)
+		WHERE id = $1 AND status = 'pending'
+		RETURNING ban_id
+	`, appealID, status, decidedAt, staffID).Scan(&banID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("pending appeal with id %d: %w", appealID, domain.ErrAppealNotFound)
+		}
+		return fmt.Errorf("unable to decide appeal: %w", err)
+	}
+
+	if status == domain.AppealAccepted {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE bans SET expires_at = $2
+			WHERE id = $1 AND (expires_at IS NULL OR expires_at > $2)
+		`, banID, decidedAt); err != nil {
+			return fmt.Errorf("unable to lift ban: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"1337b04rd/internal/domain"
+)
+
+type BoardRepository struct {
+	db *sql.DB
+}
+
+func NewBoardRepository(db *sql.DB) *BoardRepository {
+	return &BoardRepository{db: db}
+}
+
+func (r *BoardRepository) FindAll(ctx context.Context) ([]*domain.Board, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT slug, title, description, captcha_difficulty, created_at
+		FROM boards
+		ORDER BY slug ASC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch boards: %w", err)
+	}
+	defer rows.Close()
+
+	var boards []*domain.Board
+	for rows.Next() {
+		var b domain.Board
+		if err := rows.Scan(&b.Slug, &b.Title, &b.Description, &b.CaptchaDifficulty, &b.CreatedAt); err != nil {
+			return nil, fmt.Errorf("unable to scan board: %w", err)
+		}
+		boards = append(boards, &b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error while iterating over rows: %w", err)
+	}
+
+	return boards, nil
+}
+
+func (r *BoardRepository) FindBySlug(ctx context.Context, slug string) (*domain.Board, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT slug, title, description, captcha_difficulty, created_at
+		FROM boards
+		WHERE slug = $1
+	`, slug)
+
+	var b domain.Board
+	if err := row.Scan(&b.Slug, &b.Title, &b.Description, &b.CaptchaDifficulty, &b.CreatedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("board %q: %w", slug, domain.ErrBoardNotFound)
+		}
+		return nil, fmt.Errorf("error scanning board: %w", err)
+	}
+	return &b, nil
+}
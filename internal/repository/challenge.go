@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"1337b04rd/internal/domain"
+)
+
+type ChallengeRepository struct {
+	db *sql.DB
+}
+
+func NewChallengeRepository(db *sql.DB) *ChallengeRepository {
+	return &ChallengeRepository{db: db}
+}
+
+func (r *ChallengeRepository) Use(ctx context.Context, id string, expiresAt time.Time) error {
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO used_challenges (id, expires_at) VALUES ($1, $2)
+		ON CONFLICT (id) DO NOTHING
+	`, id, expiresAt)
+	if err != nil {
+		return fmt.Errorf("unable to record challenge: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("unable to record challenge: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("challenge %s: %w", id, domain.ErrCaptchaUsed)
+	}
+	return nil
+}
+
+// DeleteExpired forgets the challenges that expired before before; they
+// are refused as expired from then on.
+func (r *ChallengeRepository) DeleteExpired(ctx context.Context, before time.Time) (int, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM used_challenges WHERE expires_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("unable to delete expired challenges: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("unable to delete expired challenges: %w", err)
+	}
+	return int(n), nil
+}
@@ -19,9 +19,9 @@ func NewCommentRepository(db *sql.DB) *CommentRepository {
 func (r *CommentRepository) Save(ctx context.Context, comment *domain.Comment) error {
 	query := `
 		INSERT INTO comments 
-			(post_id, title, content, author_id, author_name, image_url, reply_to_comment_id, created_at)
+			(post_id, title, content, author_id, author_name, image_url, image_source_url, reply_to_comment_id, created_at, ip_hash)
 		VALUES 
-			($1, $2, $3, $4, $5, $6, $7, $8)
+			($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 		RETURNING id
 	`
 	return r.db.QueryRowContext(ctx, query,
@@ -31,24 +31,26 @@ func (r *CommentRepository) Save(ctx context.Context, comment *domain.Comment) e
 		comment.AuthorID,
 		comment.AuthorName,
 		comment.ImageURL,
+		comment.ImageSourceURL,
 		comment.ReplyToCommentID,
 		comment.CreatedAt,
+		comment.IPHash,
 	).Scan(&comment.ID)
 }
 
 func (r *CommentRepository) FindByID(ctx context.Context, id int) (*domain.Comment, error) {
 	query := `
-		SELECT id, post_id, title, content, author_id, author_name, image_url, reply_to_comment_id, created_at 
+		SELECT id, post_id, title, content, author_id, author_name, image_url, image_source_url, reply_to_comment_id, created_at, ip_hash
 		FROM comments 
 		WHERE id = $1
 	`
 	row := r.db.QueryRowContext(ctx, query, id)
 
 	var c domain.Comment
-	err := row.Scan(&c.ID, &c.PostID, &c.Title, &c.Content, &c.AuthorID, &c.AuthorName, &c.ImageURL, &c.ReplyToCommentID, &c.CreatedAt)
+	err := row.Scan(&c.ID, &c.PostID, &c.Title, &c.Content, &c.AuthorID, &c.AuthorName, &c.ImageURL, &c.ImageSourceURL, &c.ReplyToCommentID, &c.CreatedAt, &c.IPHash)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("comment not found")
+			return nil, fmt.Errorf("comment with id %d: %w", id, domain.ErrCommentNptFound)
 		}
 		return nil, err
 	}
@@ -57,7 +59,7 @@ func (r *CommentRepository) FindByID(ctx context.Context, id int) (*domain.Comme
 
 func (r *CommentRepository) FindCommentOfPost(ctx context.Context, postID int) ([]*domain.Comment, error) {
 	query := `
-		SELECT id, post_id, title, content, author_id, author_name, image_url, reply_to_comment_id, created_at 
+		SELECT id, post_id, title, content, author_id, author_name, image_url, image_source_url, reply_to_comment_id, created_at 
 		FROM comments 
 		WHERE post_id = $1
 		ORDER BY created_at ASC
@@ -71,7 +73,7 @@ func (r *CommentRepository) FindCommentOfPost(ctx context.Context, postID int) (
 	var comments []*domain.Comment
 	for rows.Next() {
 		var c domain.Comment
-		err := rows.Scan(&c.ID, &c.PostID, &c.Title, &c.Content, &c.AuthorID, &c.AuthorName, &c.ImageURL, &c.ReplyToCommentID, &c.CreatedAt)
+		err := rows.Scan(&c.ID, &c.PostID, &c.Title, &c.Content, &c.AuthorID, &c.AuthorName, &c.ImageURL, &c.ImageSourceURL, &c.ReplyToCommentID, &c.CreatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -80,6 +82,34 @@ func (r *CommentRepository) FindCommentOfPost(ctx context.Context, postID int) (
 	return comments, nil
 }
 
+// FindPageOfPost returns up to limit comments of a post in posting order,
+// starting after the comment with ID afterID (0 for the first page).
+func (r *CommentRepository) FindPageOfPost(ctx context.Context, postID, afterID, limit int) ([]*domain.Comment, error) {
+	query := `
+		SELECT id, post_id, title, content, author_id, author_name, image_url, reply_to_comment_id, created_at 
+		FROM comments 
+		WHERE post_id = $1 AND id > $2
+		ORDER BY id ASC
+		LIMIT $3
+	`
+	rows, err := r.db.QueryContext(ctx, query, postID, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var comments []*domain.Comment
+	for rows.Next() {
+		var c domain.Comment
+		err := rows.Scan(&c.ID, &c.PostID, &c.Title, &c.Content, &c.AuthorID, &c.AuthorName, &c.ImageURL, &c.ReplyToCommentID, &c.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, &c)
+	}
+	return comments, rows.Err()
+}
+
 func (r *CommentRepository) FindByAuthorID(ctx context.Context, authorID string) ([]*domain.Comment, error) {
 	query := `
 		SELECT id, post_id, title, content, author_id, author_name, image_url, reply_to_comment_id, created_at 
@@ -136,3 +166,25 @@ func (r *CommentRepository) FindRepliesToComment(ctx context.Context, parentComm
 
 	return replies, nil
 }
+
+func (r *CommentRepository) Delete(ctx context.Context, id int) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM comments WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("unable to delete comment: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("comment with id %d: %w", id, domain.ErrCommentNptFound)
+	}
+	return nil
+}
+
+func (r *CommentRepository) ClearImage(ctx context.Context, id int) error {
+	result, err := r.db.ExecContext(ctx, "UPDATE comments SET image_url = '' WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("unable to clear comment image: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("comment with id %d: %w", id, domain.ErrCommentNptFound)
+	}
+	return nil
+}
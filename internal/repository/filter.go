@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"1337b04rd/internal/domain"
+)
+
+type FilterRepository struct {
+	db *sql.DB
+}
+
+func NewFilterRepository(db *sql.DB) *FilterRepository {
+	return &FilterRepository{db: db}
+}
+
+const filterColumns = `id, COALESCE(board, ''), kind, pattern, replacement, threshold, action, ban_hours, reason, enabled, dry_run, created_at, updated_at`
+
+// FindAll returns every filter, oldest first, which is the order they run.
+func (r *FilterRepository) FindAll(ctx context.Context) ([]*domain.Filter, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+filterColumns+" FROM filters ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch filters: %w", err)
+	}
+	defer rows.Close()
+
+	var filters []*domain.Filter
+	for rows.Next() {
+		filter, err := scanFilter(rows)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, filter)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error while iterating over rows: %w", err)
+	}
+	return filters, nil
+}
+
+func (r *FilterRepository) FindByID(ctx context.Context, id int) (*domain.Filter, error) {
+	row := r.db.QueryRowContext(ctx, "SELECT "+filterColumns+" FROM filters WHERE id = $1", id)
+	filter, err := scanFilter(row)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("filter with id %d: %w", id, domain.ErrFilterNotFound)
+	}
+	return filter, err
+}
+
+func (r *FilterRepository) Save(ctx context.Context, f *domain.Filter) error {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO filters (board, kind, pattern, replacement, threshold, action, ban_hours, reason, enabled, dry_run, created_at, updated_at)
+		VALUES (NULLIF($1, ''), $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id
+	`, f.Board, f.Kind, f.Pattern, f.Replacement, f.Threshold, f.Action, f.BanHours, f.Reason, f.Enabled, f.DryRun, f.CreatedAt, f.UpdatedAt).Scan(&f.ID)
+	if err != nil {
+		return fmt.Errorf("unable to insert filter: %w", err)
+	}
+	return nil
+}
+
+// Update stores whether a filter is enabled and in dry run; the rule itself
+// does not change once saved.
+func (r *FilterRepository) Update(ctx context.Context, f *domain.Filter) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE filters SET enabled = $2, dry_run = $3, updated_at = $4 WHERE id = $1
+	`, f.ID, f.Enabled, f.DryRun, f.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("unable to update filter: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("filter with id %d: %w", f.ID, domain.ErrFilterNotFound)
+	}
+	return nil
+}
+
+func (r *FilterRepository) Delete(ctx context.Context, id int) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM filters WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("unable to delete filter: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("filter with id %d: %w", id, domain.ErrFilterNotFound)
+	}
+	return nil
+}
+
+// CountDuplicates counts the threads and comments on board created after
+// since whose content, with case and whitespace folded as in
+// domain.NormalizeForDuplicates, is text.
+func (r *FilterRepository) CountDuplicates(ctx context.Context, board, text string, since time.Time) (int, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM posts
+				WHERE board = $1 AND created_at > $3
+					AND lower(regexp_replace(btrim(content), '\s+', ' ', 'g')) = $2)
+			+ (SELECT COUNT(*) FROM comments c JOIN posts p ON p.id = c.post_id
+				WHERE p.board = $1 AND c.created_at > $3
+					AND lower(regexp_replace(btrim(c.content), '\s+', ' ', 'g')) = $2)
+	`, board, text, since).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("unable to count duplicates: %w", err)
+	}
+	return n, nil
+}
+
+func scanFilter(row rowScanner) (*domain.Filter, error) {
+	var f domain.Filter
+	if err := row.Scan(&f.ID, &f.Board, &f.Kind, &f.Pattern, &f.Replacement, &f.Threshold, &f.Action, &f.BanHours, &f.Reason, &f.Enabled, &f.DryRun, &f.CreatedAt, &f.UpdatedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("unable to scan filter: %w", err)
+	}
+	return &f, nil
+}
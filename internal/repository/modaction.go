@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"1337b04rd/internal/domain"
+)
+
+// ModActionRepository writes and reads the moderation log. It never updates
+// or deletes; the table refuses both.
+type ModActionRepository struct {
+	db *sql.DB
+}
+
+func NewModActionRepository(db *sql.DB) *ModActionRepository {
+	return &ModActionRepository{db: db}
+}
+
+func (r *ModActionRepository) Append(ctx context.Context, action *domain.ModAction) error {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO mod_actions (actor_type, actor_id, actor_name, action, target_type, target_id, board, reason, before, after, created_at)
+		VALUES ($1, NULLIF($2, 0), $3, $4, $5, $6, NULLIF($7, ''), $8, $9, $10, $11)
+		RETURNING id
+	`, action.ActorType, action.ActorID, action.ActorName, action.Action, action.TargetType, action.TargetID, action.Board, action.Reason,
+		nullJSON(action.Before), nullJSON(action.After), action.CreatedAt).Scan(&action.ID)
+	if err != nil {
+		return fmt.Errorf("unable to append mod action: %w", err)
+	}
+	return nil
+}
+
+// Find returns the log entries matching filter, newest first.
+func (r *ModActionRepository) Find(ctx context.Context, filter domain.ModActionFilter) ([]*domain.ModAction, error) {
+	var conds []string
+	var args []interface{}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+
+	if filter.Actor != "" {
+		add("actor_name = $%d", filter.Actor)
+	}
+	if filter.Action != "" {
+		add("action = $%d", filter.Action)
+	}
+	if filter.TargetType != "" {
+		add("target_type = $%d", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		add("target_id = $%d", filter.TargetID)
+	}
+	if filter.Board != "" {
+		add("board = $%d", filter.Board)
+	}
+	if filter.PublicOnly {
+		var public []string
+		for _, action := range domain.PublicActionTypes() {
+			public = append(public, string(action))
+		}
+		add("action = ANY($%d)", pq.Array(public))
+		conds = append(conds, "target_type IN ('post', 'comment')")
+	}
+	if filter.BeforeID > 0 {
+		add("id < $%d", filter.BeforeID)
+	}
+
+	limit := filter.Limit
+	if limit <= 0 || limit > domain.ModActionPageSize {
+		limit = domain.ModActionPageSize
+	}
+
+	query := `
+		SELECT id, actor_type, COALESCE(actor_id, 0), actor_name, action, target_type, target_id, COALESCE(board, ''), reason,
+			COALESCE(before::text, ''), COALESCE(after::text, ''), created_at
+		FROM mod_actions`
+	if len(conds) > 0 {
+		query += "\n\t\tWHERE " + strings.Join(conds, " AND ")
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf("\n\t\tORDER BY id DESC\n\t\tLIMIT $%d", len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch mod actions: %w", err)
+	}
+	defer rows.Close()
+
+	var actions []*domain.ModAction
+	for rows.Next() {
+		var a domain.ModAction
+		var before, after string
+		if err := rows.Scan(&a.ID, &a.ActorType, &a.ActorID, &a.ActorName, &a.Action, &a.TargetType, &a.TargetID, &a.Board, &a.Reason, &before, &after, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("unable to scan mod action: %w", err)
+		}
+		if before != "" {
+			a.Before = []byte(before)
+		}
+		if after != "" {
+			a.After = []byte(after)
+		}
+		actions = append(actions, &a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error while iterating over rows: %w", err)
+	}
+	return actions, nil
+}
+
+// nullJSON stores an empty snapshot as NULL.
+func nullJSON(data []byte) interface{} {
+	if len(data) == 0 {
+		return nil
+	}
+	return string(data)
+}
@@ -9,6 +9,10 @@ import (
 	"1337b04rd/internal/domain"
 )
 
+// CatalogPreviewReplies is the number of most recent replies FindAll loads
+// for every thread.
+const CatalogPreviewReplies = 3
+
 type PostRepository struct {
 	db *sql.DB
 }
@@ -21,11 +25,14 @@ func (r *PostRepository) Save(ctx context.Context, post *domain.Post) error {
 	// If the post ID is zero, this is a new post, so we insert it
 	if post.ID == 0 {
 		insertPostQuery := `
-            INSERT INTO posts (title, content, author_id, author_name, image_url, created_at, expires_at)
-            VALUES ($1, $2, $3, $4, $5, $6, $7)
+            INSERT INTO posts (title, content, author_id, author_name, image_url, image_source_url, board, created_at, expires_at, ip_hash)
+            VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
             RETURNING id
         `
-		err := r.db.QueryRowContext(ctx, insertPostQuery, post.Title, post.Content, post.AuthorID, post.AuthorName, post.ImageURL, post.CreatedAt, post.ExpiresAt).Scan(&post.ID)
+		if post.Board == "" {
+			post.Board = domain.DefaultBoard
+		}
+		err := r.db.QueryRowContext(ctx, insertPostQuery, post.Title, post.Content, post.AuthorID, post.AuthorName, post.ImageURL, post.ImageSourceURL, post.Board, post.CreatedAt, post.ExpiresAt, post.IPHash).Scan(&post.ID)
 		if err != nil {
 			return fmt.Errorf("unable to insert post: %w", err)
 		}
@@ -41,18 +48,23 @@ func (r *PostRepository) Save(ctx context.Context, post *domain.Post) error {
 }
 
 func (r *PostRepository) FindByID(ctx context.Context, id int) (*domain.Post, error) {
-	query := "SELECT id, title, content, author_id, author_name, image_url, created_at, expires_at FROM posts WHERE id = $1"
+	query := "SELECT id, title, content, author_id, author_name, image_url, image_source_url, board, sticky, locked, created_at, expires_at, ip_hash FROM posts WHERE id = $1"
 	row := r.db.QueryRowContext(ctx, query, id)
 
 	var post domain.Post
-	if err := row.Scan(&post.ID, &post.Title, &post.Content, &post.AuthorID, &post.AuthorName, &post.ImageURL, &post.CreatedAt, &post.ExpiresAt); err != nil {
+	if err := row.Scan(&post.ID, &post.Title, &post.Content, &post.AuthorID, &post.AuthorName, &post.ImageURL, &post.ImageSourceURL, &post.Board, &post.Sticky, &post.Locked, &post.CreatedAt, &post.ExpiresAt, &post.IPHash); err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("post with id %d not found", id)
+			return nil, fmt.Errorf("post with id %d: %w", id, domain.ErrPostNotFound)
 		}
 		return nil, fmt.Errorf("error scanning post: %w", err)
 	}
 
-	commentQuery := "SELECT id, content, author_id, image_url FROM comments WHERE post_id = $1"
+	commentQuery := `
+		SELECT id, post_id, title, content, author_id, author_name, image_url, image_source_url, reply_to_comment_id, created_at
+		FROM comments
+		WHERE post_id = $1
+		ORDER BY created_at ASC
+	`
 	rows, err := r.db.QueryContext(ctx, commentQuery, id)
 	if err != nil {
 		return nil, fmt.Errorf("unable to fetch comments: %w", err)
@@ -62,7 +74,7 @@ func (r *PostRepository) FindByID(ctx context.Context, id int) (*domain.Post, er
 	var comments []domain.Comment
 	for rows.Next() {
 		var comment domain.Comment
-		if err := rows.Scan(&comment.ID, &comment.Content, &comment.AuthorID, &comment.ImageURL); err != nil {
+		if err := rows.Scan(&comment.ID, &comment.PostID, &comment.Title, &comment.Content, &comment.AuthorID, &comment.AuthorName, &comment.ImageURL, &comment.ImageSourceURL, &comment.ReplyToCommentID, &comment.CreatedAt); err != nil {
 			return nil, fmt.Errorf("unable to scan comment: %w", err)
 		}
 		comments = append(comments, comment)
@@ -115,7 +127,12 @@ func (r *PostRepository) FindExpired(ctx context.Context) ([]*domain.Post, error
 
 func (r *PostRepository) FindAll(ctx context.Context) ([]*domain.Post, error) {
 	rows, err := r.db.QueryContext(ctx, `
-		SELECT id, title, content, author_id, author_name, image_url, created_at, expires_at FROM posts
+		SELECT p.id, p.title, p.content, p.author_id, p.author_name, p.image_url, p.board, p.sticky, p.locked, p.created_at, p.expires_at,
+			COUNT(c.id), COUNT(NULLIF(c.image_url, '')), MAX(c.created_at)
+		FROM posts p
+		LEFT JOIN comments c ON c.post_id = p.id
+		GROUP BY p.id
+		ORDER BY p.sticky DESC, COALESCE(MAX(c.created_at), p.created_at) DESC
 	`)
 	if err != nil {
 		return nil, fmt.Errorf("unable to fetch posts: %w", err)
@@ -123,44 +140,102 @@ func (r *PostRepository) FindAll(ctx context.Context) ([]*domain.Post, error) {
 	defer rows.Close()
 
 	var posts []*domain.Post
+	byID := make(map[int]*domain.Post)
 	for rows.Next() {
 		var post domain.Post
-		if err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.AuthorID, &post.AuthorName, &post.ImageURL, &post.CreatedAt, &post.ExpiresAt); err != nil {
+		var lastReply sql.NullTime
+		if err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.AuthorID, &post.AuthorName, &post.ImageURL, &post.Board, &post.Sticky, &post.Locked, &post.CreatedAt, &post.ExpiresAt, &post.ReplyCount, &post.ImageCount, &lastReply); err != nil {
 			return nil, fmt.Errorf("unable to scan post: %w", err)
 		}
-
-		commentRows, err := r.db.QueryContext(ctx, `
-			SELECT id, post_id, title, content, author_id, author_name, image_url, reply_to_comment_id, created_at
-			FROM comments WHERE post_id = $1
-		`, post.ID)
-		if err != nil {
-			return nil, fmt.Errorf("unable to fetch comments for post %d: %w", post.ID, err)
+		if lastReply.Valid {
+			post.LastReplyAt = &lastReply.Time
 		}
+		posts = append(posts, &post)
+		byID[post.ID] = &post
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error while iterating over rows: %w", err)
+	}
+
+	if len(posts) == 0 {
+		return posts, nil
+	}
 
-		var comments []domain.Comment
-		for commentRows.Next() {
-			var comment domain.Comment
-			if err := commentRows.Scan(
-				&comment.ID,
-				&comment.PostID,
-				&comment.Title,
-				&comment.Content,
-				&comment.AuthorID,
-				&comment.AuthorName,
-				&comment.ImageURL,
-				&comment.ReplyToCommentID,
-				&comment.CreatedAt,
-			); err != nil {
-				commentRows.Close()
-				return nil, fmt.Errorf("unable to scan comment: %w", err)
-			}
-			comments = append(comments, comment)
+	// Load only the latest replies of every thread in a single query.
+	commentRows, err := r.db.QueryContext(ctx, `
+		SELECT id, post_id, title, content, author_id, author_name, image_url, reply_to_comment_id, created_at
+		FROM (
+			SELECT c.*, ROW_NUMBER() OVER (PARTITION BY c.post_id ORDER BY c.created_at DESC) AS rn
+			FROM comments c
+		) latest
+		WHERE rn <= $1
+		ORDER BY post_id, created_at ASC
+	`, CatalogPreviewReplies)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch latest comments: %w", err)
+	}
+	defer commentRows.Close()
+
+	for commentRows.Next() {
+		var comment domain.Comment
+		if err := commentRows.Scan(
+			&comment.ID,
+			&comment.PostID,
+			&comment.Title,
+			&comment.Content,
+			&comment.AuthorID,
+			&comment.AuthorName,
+			&comment.ImageURL,
+			&comment.ReplyToCommentID,
+			&comment.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("unable to scan comment: %w", err)
+		}
+		if post, ok := byID[comment.PostID]; ok {
+			post.Comments = append(post.Comments, comment)
 		}
-		commentRows.Close()
+	}
+	if err := commentRows.Err(); err != nil {
+		return nil, fmt.Errorf("error while iterating over comments: %w", err)
+	}
+
+	return posts, nil
+}
+
+// FindPage returns up to limit threads of board (all boards when empty),
+// newest first, starting after the thread with ID beforeID (0 for the first
+// page). Comments are not loaded, only the reply aggregates.
+func (r *PostRepository) FindPage(ctx context.Context, board string, beforeID, limit int) ([]*domain.Post, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT p.id, p.title, p.content, p.author_id, p.author_name, p.image_url, p.board, p.sticky, p.locked, p.created_at, p.expires_at,
+			COUNT(c.id), MAX(c.created_at)
+		FROM posts p
+		LEFT JOIN comments c ON c.post_id = p.id
+		WHERE ($1 = '' OR p.board = $1) AND ($2 = 0 OR p.id < $2)
+		GROUP BY p.id
+		ORDER BY p.id DESC
+		LIMIT $3
+	`, board, beforeID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch posts: %w", err)
+	}
+	defer rows.Close()
 
-		post.Comments = comments
+	var posts []*domain.Post
+	for rows.Next() {
+		var post domain.Post
+		var lastReply sql.NullTime
+		if err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.AuthorID, &post.AuthorName, &post.ImageURL, &post.Board, &post.Sticky, &post.Locked, &post.CreatedAt, &post.ExpiresAt, &post.ReplyCount, &lastReply); err != nil {
+			return nil, fmt.Errorf("unable to scan post: %w", err)
+		}
+		if lastReply.Valid {
+			post.LastReplyAt = &lastReply.Time
+		}
 		posts = append(posts, &post)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error while iterating over rows: %w", err)
+	}
 
 	return posts, nil
 }
@@ -195,22 +270,6 @@ func (r *PostRepository) FindByAuthorID(ctx context.Context, authorID string) ([
 	return posts, nil
 }
 
-func (r *PostRepository) UpdateAuthorNameForPostAndComments(ctx context.Context, postID int, newAuthorName string) error {
-	updatePostQuery := "UPDATE posts SET author_name = $1 WHERE id = $2"
-	_, err := r.db.ExecContext(ctx, updatePostQuery, newAuthorName, postID)
-	if err != nil {
-		return fmt.Errorf("unable to update post author name: %w", err)
-	}
-
-	updateCommentsQuery := "UPDATE comments SET author_name = $1 WHERE post_id = $2"
-	_, err = r.db.ExecContext(ctx, updateCommentsQuery, newAuthorName, postID)
-	if err != nil {
-		return fmt.Errorf("unable to update comments author name: %w", err)
-	}
-
-	return nil
-}
-
 func (r *PostRepository) UpdatePostAuthorName(ctx context.Context, postID int, authorName string) error {
 	// Prepare the SQL query
 	query := `UPDATE posts SET author_name = $1 WHERE id = $2`
@@ -250,3 +309,58 @@ func (r *PostRepository) UpdateExpiration(ctx context.Context, postID int, newEx
 	_, err := r.db.ExecContext(ctx, query, newExpiration, postID)
 	return err
 }
+
+// SetSticky pins a thread to the top of the catalog and keeps it from
+// expiring.
+func (r *PostRepository) SetSticky(ctx context.Context, postID int, sticky bool) error {
+	return r.setFlag(ctx, "sticky", postID, sticky)
+}
+
+// SetLocked closes a thread to new replies.
+func (r *PostRepository) SetLocked(ctx context.Context, postID int, locked bool) error {
+	return r.setFlag(ctx, "locked", postID, locked)
+}
+
+func (r *PostRepository) setFlag(ctx context.Context, column string, postID int, value bool) error {
+	result, err := r.db.ExecContext(ctx, "UPDATE posts SET "+column+" = $1 WHERE id = $2", value, postID)
+	if err != nil {
+		return fmt.Errorf("unable to update %s of post: %w", column, err)
+	}
+	return expectOneRow(result, postID)
+}
+
+func (r *PostRepository) UpdateBoard(ctx context.Context, postID int, board string) error {
+	result, err := r.db.ExecContext(ctx, "UPDATE posts SET board = $1 WHERE id = $2", board, postID)
+	if err != nil {
+		return fmt.Errorf("unable to move post: %w", err)
+	}
+	return expectOneRow(result, postID)
+}
+
+func (r *PostRepository) ClearImage(ctx context.Context, postID int) error {
+	result, err := r.db.ExecContext(ctx, "UPDATE posts SET image_url = '' WHERE id = $1", postID)
+	if err != nil {
+		return fmt.Errorf("unable to clear post image: %w", err)
+	}
+	return expectOneRow(result, postID)
+}
+
+// Count returns how many threads are live.
+func (r *PostRepository) Count(ctx context.Context) (int, error) {
+	var n int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM posts").Scan(&n); err != nil {
+		return 0, fmt.Errorf("unable to count posts: %w", err)
+	}
+	return n, nil
+}
+
+func expectOneRow(result sql.Result, postID int) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("unable to read affected rows: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("post with id %d: %w", postID, domain.ErrPostNotFound)
+	}
+	return nil
+}
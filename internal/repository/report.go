@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"1337b04rd/internal/domain"
+)
+
+type ReportRepository struct {
+	db *sql.DB
+}
+
+func NewReportRepository(db *sql.DB) *ReportRepository {
+	return &ReportRepository{db: db}
+}
+
+// Save files a report. A session that already has an open report on the
+// same target gets ErrAlreadyReported.
+func (r *ReportRepository) Save(ctx context.Context, report *domain.Report) error {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO reports (target_type, target_id, post_id, board, category, note, session_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id
+	`, report.TargetType, report.TargetID, report.PostID, report.Board, report.Category, report.Note, report.SessionID, report.CreatedAt).Scan(&report.ID)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return fmt.Errorf("%s %d: %w", report.TargetType, report.TargetID, domain.ErrAlreadyReported)
+		}
+		return fmt.Errorf("unable to insert report: %w", err)
+	}
+	return nil
+}
+
+// CountSince counts the reports a session filed after since.
+func (r *ReportRepository) CountSince(ctx context.Context, sessionID string, since time.Time) (int, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM reports WHERE session_id = $1 AND created_at > $2
+	`, sessionID, since).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("unable to count reports: %w", err)
+	}
+	return n, nil
+}
+
+// FindOpenGrouped returns the unresolved reports grouped by target, most
+// reported first and oldest first among equals.
+func (r *ReportRepository) FindOpenGrouped(ctx context.Context) ([]*domain.ReportGroup, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT target_type, target_id, MIN(post_id), MIN(board), COUNT(*),
+			array_agg(DISTINCT category),
+			COALESCE(array_agg(note ORDER BY created_at) FILTER (WHERE note <> ''), '{}'),
+			MIN(created_at), MAX(created_at)
+		FROM reports
+		WHERE resolved_at IS NULL
+		GROUP BY target_type, target_id
+		ORDER BY COUNT(*) DESC, MIN(created_at) ASC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch reports: %w", err)
+	}
+	defer rows.Close()
+
+	var groups []*domain.ReportGroup
+	for rows.Next() {
+		var g domain.ReportGroup
+		var categories []string
+		if err := rows.Scan(&g.TargetType, &g.TargetID, &g.PostID, &g.Board, &g.Count, pq.Array(&categories), pq.Array(&g.Notes), &g.FirstReportedAt, &g.LastReportedAt); err != nil {
+			return nil, fmt.Errorf("unable to scan report group: %w", err)
+		}
+		for _, category := range categories {
+			g.Categories = append(g.Categories, domain.ReportCategory(category))
+		}
+		groups = append(groups, &g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error while iterating over rows: %w", err)
+	}
+	return groups, nil
+}
+
+// FindResolved returns the most recently resolved reports with the staff
+// member who resolved each.
+func (r *ReportRepository) FindResolved(ctx context.Context, limit int) ([]*domain.Report, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT r.id, r.target_type, r.target_id, r.post_id, r.board, r.category, r.note, r.session_id, r.created_at,
+			r.resolved_at, COALESCE(r.resolution, ''), COALESCE(s.username, '')
+		FROM reports r
+		LEFT JOIN staff s ON s.id = r.resolved_by
+		WHERE r.resolved_at IS NOT NULL
+		ORDER BY r.resolved_at DESC, r.id DESC
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch reports: %w", err)
+	}
+	defer rows.Close()
+
+	var reports []*domain.Report
+	for rows.Next() {
+		var report domain.Report
+		if err := rows.Scan(&report.ID, &report.TargetType, &report.TargetID, &report.PostID, &report.Board, &report.Category, &report.Note, &report.SessionID, &report.CreatedAt,
+			&report.ResolvedAt, &report.Resolution, &report.ResolvedBy); err != nil {
+			return nil, fmt.Errorf("unable to scan report: %w", err)
+		}
+		reports = append(reports, &report)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error while iterating over rows: %w", err)
+	}
+	return reports, nil
+}
+
+// Resolve closes every open report on a target. Deleting a thread also
+// closes the reports on its comments, which went with it.
+func (r *ReportRepository) Resolve(ctx context.Context, targetType domain.ReportTarget, targetID int, resolution domain.ReportResolution, staffID int, resolvedAt time.Time) (int, error) {
+	wholeThread := targetType == domain.ReportTargetPost && resolution == domain.ResolutionDelete
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE reports SET resolved_at = $4, resolution = $3, resolved_by = NULLIF($5, 0)
+		WHERE resolved_at IS NULL
+			AND ((target_type = $1 AND target_id = $2) OR ($6 AND post_id = $2))
+	`, targetType, targetID, resolution, resolvedAt, staffID, wholeThread)
+	if err != nil {
+		return 0, fmt.Errorf("unable to resolve reports: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("unable to resolve reports: %w", err)
+	}
+	return int(n), nil
+}
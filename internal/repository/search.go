@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"1337b04rd/internal/domain"
+)
+
+type SearchRepository struct {
+	db *sql.DB
+}
+
+func NewSearchRepository(db *sql.DB) *SearchRepository {
+	return &SearchRepository{db: db}
+}
+
+var searchOrder = map[domain.SearchSort]string{
+	domain.SortByRelevance: "rank DESC, created_at DESC",
+	domain.SortByDate:      "created_at DESC, rank DESC",
+}
+
+// Search runs q against posts, comments and their archived counterparts and
+// returns one page of hits together with the total number of matches.
+func (r *SearchRepository) Search(ctx context.Context, q domain.SearchQuery) ([]domain.SearchResult, int, error) {
+	order, ok := searchOrder[q.Sort]
+	if !ok {
+		return nil, 0, domain.ErrInvalidSearchSort
+	}
+
+	query := `
+		WITH q AS (
+			SELECT websearch_to_tsquery('english', $1) AS query
+		),
+		hits AS (
+			SELECT 'post' AS kind, FALSE AS archived, p.id AS post_id, NULL::INT AS comment_id, p.board,
+				p.title, p.content, COALESCE(p.image_url, '') AS image_url, p.created_at,
+				ts_rank(p.search_vector, q.query) AS rank
+			FROM posts p, q
+			WHERE p.search_vector @@ q.query
+			UNION ALL
+			SELECT 'comment', FALSE, c.post_id, c.id, p.board,
+				c.title, c.content, COALESCE(c.image_url, ''), c.created_at,
+				ts_rank(c.search_vector, q.query)
+			FROM comments c JOIN posts p ON p.id = c.post_id, q
+			WHERE c.search_vector @@ q.query
+			UNION ALL
+			SELECT 'post', TRUE, p.id, NULL::INT, p.board,
+				p.title, p.content, COALESCE(p.image_url, ''), p.created_at,
+				ts_rank(p.search_vector, q.query)
+			FROM archived_posts p, q
+			WHERE p.search_vector @@ q.query
+			UNION ALL
+			SELECT 'comment', TRUE, c.post_id, c.id, p.board,
+				c.title, c.content, COALESCE(c.image_url, ''), c.created_at,
+				ts_rank(c.search_vector, q.query)
+			FROM archived_comments c JOIN archived_posts p ON p.id = c.post_id, q
+			WHERE c.search_vector @@ q.query
+		),
+		page AS (
+			SELECT *, COUNT(*) OVER () AS total
+			FROM hits
+			WHERE ($2 = '' OR board = $2)
+				AND ($3::TIMESTAMP IS NULL OR created_at >= $3)
+				AND ($4::TIMESTAMP IS NULL OR created_at < $4)
+				AND (NOT $5 OR image_url <> '')
+				AND ($6 = 'all' OR archived = ($6 = 'archive'))
+			ORDER BY ` + order + `
+			LIMIT $7 OFFSET $8
+		)
+		SELECT kind, archived, post_id, comment_id, board, title,
+			ts_headline('english', translate(content, $9 || $10, ''), q.query,
+				'StartSel=' || $9 || ', StopSel=' || $10 || ', MaxFragments=2, MaxWords=30, MinWords=10'),
+			image_url, created_at, rank, total
+		FROM page, q
+		ORDER BY ` + order
+
+	var from, to sql.NullTime
+	if q.From != nil {
+		from = sql.NullTime{Time: *q.From, Valid: true}
+	}
+	if q.To != nil {
+		to = sql.NullTime{Time: *q.To, Valid: true}
+	}
+
+	rows, err := r.db.QueryContext(ctx, query,
+		q.Text, q.Board, from, to, q.HasImage, string(q.Scope),
+		q.PerPage, (q.Page-1)*q.PerPage,
+		domain.HighlightStart, domain.HighlightStop,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("unable to search: %w", err)
+	}
+	defer rows.Close()
+
+	var results []domain.SearchResult
+	total := 0
+	for rows.Next() {
+		var res domain.SearchResult
+		if err := rows.Scan(
+			&res.Kind,
+			&res.Archived,
+			&res.PostID,
+			&res.CommentID,
+			&res.Board,
+			&res.Title,
+			&res.Snippet,
+			&res.ImageURL,
+			&res.CreatedAt,
+			&res.Rank,
+			&total,
+		); err != nil {
+			return nil, 0, fmt.Errorf("unable to scan search result: %w", err)
+		}
+		results = append(results, res)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error while iterating over search results: %w", err)
+	}
+
+	return results, total, nil
+}
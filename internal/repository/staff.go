@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"1337b04rd/internal/domain"
+)
+
+type StaffRepository struct {
+	db *sql.DB
+}
+
+func NewStaffRepository(db *sql.DB) *StaffRepository {
+	return &StaffRepository{db: db}
+}
+
+func (r *StaffRepository) Save(ctx context.Context, staff *domain.Staff) error {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO staff (username, password_hash, role, created_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`, staff.Username, staff.PasswordHash, staff.Role, staff.CreatedAt).Scan(&staff.ID)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return fmt.Errorf("staff %q: %w", staff.Username, domain.ErrStaffExists)
+		}
+		return fmt.Errorf("unable to insert staff: %w", err)
+	}
+	return nil
+}
+
+func (r *StaffRepository) FindByUsername(ctx context.Context, username string) (*domain.Staff, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT id, username, password_hash, role, created_at
+		FROM staff
+		WHERE username = $1
+	`, username)
+	return scanStaff(row)
+}
+
+func (r *StaffRepository) FindAll(ctx context.Context) ([]*domain.Staff, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, username, password_hash, role, created_at
+		FROM staff
+		ORDER BY username ASC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch staff: %w", err)
+	}
+	defer rows.Close()
+
+	var staff []*domain.Staff
+	for rows.Next() {
+		s, err := scanStaff(rows)
+		if err != nil {
+			return nil, err
+		}
+		staff = append(staff, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error while iterating over rows: %w", err)
+	}
+	return staff, nil
+}
+
+func (r *StaffRepository) Count(ctx context.Context) (int, error) {
+	var n int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM staff").Scan(&n); err != nil {
+		return 0, fmt.Errorf("unable to count staff: %w", err)
+	}
+	return n, nil
+}
+
+func (r *StaffRepository) CreateSession(ctx context.Context, tokenHash string, staffID int, expiresAt time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO staff_sessions (token_hash, staff_id, expires_at)
+		VALUES ($1, $2, $3)
+	`, tokenHash, staffID, expiresAt)
+	if err != nil {
+		return fmt.Errorf("unable to create staff session: %w", err)
+	}
+	return nil
+}
+
+// FindBySession returns the staff member logged in with the token hash, as
+// long as the login has not expired.
+func (r *StaffRepository) FindBySession(ctx context.Context, tokenHash string) (*domain.Staff, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT s.id, s.username, s.password_hash, s.role, s.created_at
+		FROM staff_sessions ss
+		JOIN staff s ON s.id = ss.staff_id
+		WHERE ss.token_hash = $1 AND ss.expires_at > NOW()
+	`, tokenHash)
+	return scanStaff(row)
+}
+
+func (r *StaffRepository) DeleteSession(ctx context.Context, tokenHash string) error {
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM staff_sessions WHERE token_hash = $1 OR expires_at <= NOW()", tokenHash); err != nil {
+		return fmt.Errorf("unable to delete staff session: %w", err)
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanStaff(row rowScanner) (*domain.Staff, error) {
+	var s domain.Staff
+	if err := row.Scan(&s.ID, &s.Username, &s.PasswordHash, &s.Role, &s.CreatedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain.ErrStaffNotFound
+		}
+		return nil, fmt.Errorf("unable to scan staff: %w", err)
+	}
+	return &s, nil
+}
@@ -5,16 +5,26 @@ import (
 	"time"
 
 	"1337b04rd/internal/domain"
+	"1337b04rd/internal/ports"
 	"1337b04rd/internal/repository"
 )
 
+// ArchiveReader reads archived threads. Archived threads keep the IDs they
+// had while live.
+type ArchiveReader interface {
+	ListArchivedPosts(ctx context.Context, board string, beforeID, limit int) ([]*domain.Archive, error)
+	GetArchivedPostByID(ctx context.Context, postID int) (*domain.Post, error)
+}
+
 type ArchiveService struct {
 	archiveRepo *repository.ArchiveRepository
 	postRepo    *repository.PostRepository
+	events      ports.EventPublisher
+	audit       ports.ModActionRepository
 }
 
-func NewArchiveService(archiveRepo *repository.ArchiveRepository, postRepo *repository.PostRepository) *ArchiveService {
-	return &ArchiveService{archiveRepo: archiveRepo, postRepo: postRepo}
+func NewArchiveService(archiveRepo *repository.ArchiveRepository, postRepo *repository.PostRepository, events ports.EventPublisher, audit ports.ModActionRepository) *ArchiveService {
+	return &ArchiveService{archiveRepo: archiveRepo, postRepo: postRepo, events: events, audit: audit}
 }
 
 func (s *ArchiveService) ArchivePostByID(ctx context.Context, postID int) error {
@@ -22,29 +32,66 @@ func (s *ArchiveService) ArchivePostByID(ctx context.Context, postID int) error
 	if err != nil {
 		return err
 	}
-	return s.archiveRepo.Save(ctx, post)
+	if err := s.archiveRepo.Save(ctx, post); err != nil {
+		return err
+	}
+	publish(ctx, s.events, domain.NewEvent(domain.EventArchived, post.ID, post.Board, nil))
+	record(ctx, s.audit, domain.NewModAction(domain.ActionArchive, string(domain.ReportTargetPost), post.ID, post.Board, "", nil, nil))
+	return nil
 }
 
 func (s *ArchiveService) ArchiveExpiredPosts(ctx context.Context) error {
+	archived := 0
+	defer func(start time.Time) { observeRun(jobArchive, start, archived) }(time.Now())
+
 	posts, err := s.postRepo.FindAll(ctx)
 	if err != nil {
 		return err
 	}
 	for _, post := range posts {
-		if post.ExpiresAt.Before(time.Now()) {
-			if err := s.archiveRepo.Save(ctx, post); err != nil {
+		if !post.Sticky && post.ExpiresAt.Before(time.Now()) {
+			// FindAll only carries the latest replies, load the whole thread.
+			full, err := s.postRepo.FindByID(ctx, post.ID)
+			if err != nil {
+				return err
+			}
+			if err := s.archiveRepo.Save(ctx, full); err != nil {
 				return err
 			}
+			archived++
+			publish(ctx, s.events, domain.NewEvent(domain.EventArchived, full.ID, full.Board, nil))
+			record(ctx, s.audit, domain.NewModAction(domain.ActionArchive, string(domain.ReportTargetPost), full.ID, full.Board, "", nil, nil))
 		}
 	}
 	return nil
 }
 
+// RestorePost moves an archived thread back onto its board with a fresh
+// TTL, under the ID it had before archiving. Its dates move up to now, so
+// the expiry job treats it as freshly active. Threads archived before the
+// archive kept IDs come back under a new one; the log records both.
+// Archiving leaves images where they were uploaded, so they come back with
+// their posts.
+func (s *ArchiveService) RestorePost(ctx context.Context, archivedID int) (*domain.Post, error) {
+	now := time.Now()
+	post, err := s.archiveRepo.Restore(ctx, archivedID, now, now.Add(domain.DefaultPostTTL))
+	if err != nil {
+		return nil, err
+	}
+	publish(ctx, s.events, domain.NewThreadEvent(post))
+	record(ctx, s.audit, domain.NewModAction(domain.ActionRestore, string(domain.ReportTargetPost), post.ID, post.Board, "",
+		map[string]interface{}{"archived_id": archivedID}, domain.PostSnapshot(post)))
+	return post, nil
+}
+
 func (s *ArchiveService) GetAllArchivedPosts(ctx context.Context) ([]*domain.Archive, error) {
 	return s.archiveRepo.FindAll(ctx)
 }
 
-func (s *ArchiveService) GetArchivedPostByID(ctx context.Context, postID int) *domain.Post {
-	post, _ := s.archiveRepo.FindByID(ctx, postID)
-	return post
+func (s *ArchiveService) ListArchivedPosts(ctx context.Context, board string, beforeID, limit int) ([]*domain.Archive, error) {
+	return s.archiveRepo.FindPage(ctx, board, beforeID, limit)
+}
+
+func (s *ArchiveService) GetArchivedPostByID(ctx context.Context, postID int) (*domain.Post, error) {
+	return s.archiveRepo.FindByID(ctx, postID)
 }
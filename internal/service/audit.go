@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+
+	"1337b04rd/internal/domain"
+	"1337b04rd/internal/ports"
+	"1337b04rd/pkg/middleware"
+)
+
+// record appends action to the moderation log, attributed to the staff
+// member in ctx or, without one, to the system. Like publish it never fails
+// an action that has already been carried out.
+func record(ctx context.Context, log ports.ModActionRepository, action *domain.ModAction) {
+	if log == nil {
+		return
+	}
+	if staff, err := middleware.GetStaff(ctx); err == nil {
+		action.ActorType, action.ActorID, action.ActorName = domain.ActorStaff, staff.ID, staff.Username
+	} else {
+		action.ActorType, action.ActorName = domain.ActorSystem, domain.SystemActorName
+	}
+	_ = log.Append(ctx, action)
+}
+
+// AuditService reads the moderation log.
+type AuditService struct {
+	log ports.ModActionRepository
+}
+
+func NewAuditService(log ports.ModActionRepository) *AuditService {
+	return &AuditService{log: log}
+}
+
+// Record appends an action taken outside the services, such as a scheduled
+// job run.
+func (s *AuditService) Record(ctx context.Context, action *domain.ModAction) {
+	record(ctx, s.log, action)
+}
+
+func (s *AuditService) List(ctx context.Context, filter domain.ModActionFilter) ([]*domain.ModAction, error) {
+	return s.log.Find(ctx, filter)
+}
+
+// ListPublic returns the public actions only. Callers show them through
+// PublicSummary, which names neither staff nor posters.
+func (s *AuditService) ListPublic(ctx context.Context, beforeID int64) ([]*domain.ModAction, error) {
+	return s.log.Find(ctx, domain.ModActionFilter{PublicOnly: true, BeforeID: beforeID})
+}
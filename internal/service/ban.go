@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"1337b04rd/internal/domain"
+	"1337b04rd/internal/ports"
+)
+
+// BanService issues and checks bans and handles their appeals. Permissions
+// are checked by the caller.
+type BanService struct {
+	banRepo     ports.BanRepository
+	postRepo    ports.PostRepository
+	commentRepo ports.CommentRepository
+	audit       ports.ModActionRepository
+}
+
+func NewBanService(banRepo ports.BanRepository, postRepo ports.PostRepository, commentRepo ports.CommentRepository, audit ports.ModActionRepository) *BanService {
+	return &BanService{banRepo: banRepo, postRepo: postRepo, commentRepo: commentRepo, audit: audit}
+}
+
+// CheckBan returns the ban keeping the poster from posting on board, or nil
+// when there is none. ip is the raw client address, only used to match
+// ranges.
+func (s *BanService) CheckBan(ctx context.Context, sessionID, ipHash, ip, board string) (*domain.Ban, error) {
+	if sessionID == "" && ipHash == "" && ip == "" {
+		return nil, nil
+	}
+	bans, err := s.banRepo.FindActive(ctx, sessionID, ipHash, ip, board)
+	if err != nil {
+		return nil, err
+	}
+	if len(bans) == 0 {
+		return nil, nil
+	}
+	return bans[0], nil
+}
+
+// ActiveBans returns every ban of the poster on any board, each with its
+// appeal if one was made.
+func (s *BanService) ActiveBans(ctx context.Context, sessionID, ipHash, ip string) ([]*domain.Ban, error) {
+	if sessionID == "" && ipHash == "" && ip == "" {
+		return nil, nil
+	}
+	bans, err := s.banRepo.FindActive(ctx, sessionID, ipHash, ip, "")
+	if err != nil {
+		return nil, err
+	}
+	for _, ban := range bans {
+		appeal, err := s.banRepo.FindAppealByBan(ctx, ban.ID)
+		if err != nil && !errors.Is(err, domain.ErrAppealNotFound) {
+			return nil, err
+		}
+		ban.Appeal = appeal
+	}
+	return bans, nil
+}
+
+// BanPoster bans the author of a thread or comment by session and IP hash,
+// on the board it was posted to or, with global, everywhere.
+func (s *BanService) BanPoster(ctx context.Context, target domain.ReportTarget, targetID int, reason string, duration time.Duration, global bool, staffID int) (*domain.Ban, error) {
+	var sessionID, ipHash, board, postBoard string
+	switch target {
+	case domain.ReportTargetPost:
+		post, err := s.postRepo.FindByID(ctx, targetID)
+		if err != nil {
+			return nil, err
+		}
+		sessionID, ipHash, board, postBoard = post.AuthorID, post.IPHash, post.Board, post.Board
+	case domain.ReportTargetComment:
+		comment, err := s.commentRepo.FindByID(ctx, targetID)
+		if err != nil {
+			return nil, err
+		}
+		post, err := s.postRepo.FindByID(ctx, comment.PostID)
+		if err != nil {
+			return nil, err
+		}
+		sessionID, ipHash, board, postBoard = comment.AuthorID, comment.IPHash, post.Board, post.Board
+	default:
+		return nil, domain.ErrInvalidBanTarget
+	}
+
+	if global {
+		board = ""
+	}
+	ban, err := s.save(ctx, sessionID, ipHash, "", board, reason, staffID, duration)
+	if err != nil {
+		return nil, err
+	}
+	record(ctx, s.audit, domain.NewModAction(domain.ActionBan, string(target), targetID, postBoard, ban.Reason, nil, ban))
+	return ban, nil
+}
+
+// BanRange bans an address range given in CIDR notation. A bare address is
+// banned on its own.
+func (s *BanService) BanRange(ctx context.Context, cidr, board, reason string, duration time.Duration, staffID int) (*domain.Ban, error) {
+	cidr = strings.TrimSpace(cidr)
+	if !strings.Contains(cidr, "/") {
+		ip := net.ParseIP(cidr)
+		if ip == nil {
+			return nil, fmt.Errorf("address %q: %w", cidr, domain.ErrInvalidBanTarget)
+		}
+		bits := 128
+		if ip.To4() != nil {
+			bits = 32
+		}
+		cidr = fmt.Sprintf("%s/%d", ip, bits)
+	}
+
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, fmt.Errorf("range %q: %w", cidr, domain.ErrInvalidBanTarget)
+	}
+	ban, err := s.save(ctx, "", "", network.String(), strings.TrimSpace(board), reason, staffID, duration)
+	if err != nil {
+		return nil, err
+	}
+	record(ctx, s.audit, domain.NewModAction(domain.ActionBan, domain.TargetRange, ban.CIDR, ban.Board, ban.Reason, nil, ban))
+	return ban, nil
+}
+
+// BanSubmitter bans whoever is posting by session and IP hash. It is how
+// content filters ban, so the ban has no staff member.
+func (s *BanService) BanSubmitter(ctx context.Context, sessionID, ipHash, board, reason string, duration time.Duration) (*domain.Ban, error) {
+	ban, err := s.save(ctx, sessionID, ipHash, "", board, reason, 0, duration)
+	if err != nil {
+		return nil, err
+	}
+	record(ctx, s.audit, domain.NewModAction(domain.ActionBan, domain.TargetBan, ban.ID, board, ban.Reason, nil, ban))
+	return ban, nil
+}
+
+func (s *BanService) save(ctx context.Context, sessionID, ipHash, cidr, board, reason string, staffID int, duration time.Duration) (*domain.Ban, error) {
+	ban, err := domain.NewBan(sessionID, ipHash, cidr, board, reason, staffID, duration)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.banRepo.Save(ctx, ban); err != nil {
+		return nil, err
+	}
+	return ban, nil
+}
+
+func (s *BanService) ListBans(ctx context.Context) ([]*domain.Ban, error) {
+	return s.banRepo.FindAllActive(ctx)
+}
+
+func (s *BanService) LiftBan(ctx context.Context, banID int) error {
+	ban, err := s.banRepo.FindByID(ctx, banID)
+	if err != nil {
+		return err
+	}
+	if err := s.banRepo.Lift(ctx, banID); err != nil {
+		return err
+	}
+	record(ctx, s.audit, domain.NewModAction(domain.ActionLiftBan, domain.TargetBan, banID, ban.Board, "", ban, nil))
+	return nil
+}
+
+// Appeal files the one appeal allowed for a ban. Posters may only appeal
+// bans that apply to them.
+func (s *BanService) Appeal(ctx context.Context, banID int, sessionID, ipHash, ip, message string) (*domain.BanAppeal, error) {
+	bans, err := s.ActiveBans(ctx, sessionID, ipHash, ip)
+	if err != nil {
+		return nil, err
+	}
+
+	var ban *domain.Ban
+	for _, b := range bans {
+		if b.ID == banID {
+			ban = b
+		}
+	}
+	if ban == nil {
+		return nil, fmt.Errorf("ban with id %d: %w", banID, domain.ErrBanNotFound)
+	}
+	if ban.Appeal != nil {
+		return nil, fmt.Errorf("ban %d: %w", banID, domain.ErrAppealExists)
+	}
+
+	appeal, err := domain.NewBanAppeal(banID, message)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.banRepo.SaveAppeal(ctx, appeal); err != nil {
+		return nil, err
+	}
+	return appeal, nil
+}
+
+func (s *BanService) ListPendingAppeals(ctx context.Context) ([]*domain.BanAppeal, error) {
+	return s.banRepo.FindPendingAppeals(ctx)
+}
+
+// DecideAppeal accepts an appeal, which lifts its ban, or denies it.
+func (s *BanService) DecideAppeal(ctx context.Context, appealID int, accept bool, staffID int) error {
+	status := domain.AppealDenied
+	if accept {
+		status = domain.AppealAccepted
+	}
+	if err := s.banRepo.DecideAppeal(ctx, appealID, status, staffID, time.Now()); err != nil {
+		return err
+	}
+	record(ctx, s.audit, domain.NewModAction(domain.ActionDecideAppeal, domain.TargetAppeal, appealID, "", "",
+		map[string]domain.AppealStatus{"status": domain.AppealPending}, map[string]domain.AppealStatus{"status": status}))
+	return nil
+}
@@ -0,0 +1,24 @@
+package service
+
+import (
+	"context"
+
+	"1337b04rd/internal/domain"
+	"1337b04rd/internal/repository"
+)
+
+type BoardService struct {
+	boardRepo *repository.BoardRepository
+}
+
+func NewBoardService(boardRepo *repository.BoardRepository) *BoardService {
+	return &BoardService{boardRepo: boardRepo}
+}
+
+func (s *BoardService) GetAllBoards(ctx context.Context) ([]*domain.Board, error) {
+	return s.boardRepo.FindAll(ctx)
+}
+
+func (s *BoardService) GetBoard(ctx context.Context, slug string) (*domain.Board, error) {
+	return s.boardRepo.FindBySlug(ctx, slug)
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"1337b04rd/internal/domain"
+	"1337b04rd/internal/ports"
+)
+
+// CaptchaService issues and checks the proof-of-work challenges asked for
+// before posting. Challenges are signed rather than stored; only used ones
+// are remembered, until they expire.
+type CaptchaService struct {
+	secret     []byte
+	boards     ports.BoardRepository
+	posts      ports.PostRepository
+	challenges ports.ChallengeRepository
+
+	mu sync.Mutex
+	// recent holds the times of the latest posts per board, within
+	// CaptchaSurgeWindow, to tell a surge.
+	recent map[string][]time.Time
+}
+
+func NewCaptchaService(secret []byte, boards ports.BoardRepository, posts ports.PostRepository, challenges ports.ChallengeRepository) *CaptchaService {
+	return &CaptchaService{
+		secret:     secret,
+		boards:     boards,
+		posts:      posts,
+		challenges: challenges,
+		recent:     make(map[string][]time.Time),
+	}
+}
+
+// Issue returns a fresh challenge for posting on board. Its difficulty is
+// the board's, raised while the board is getting more posts than usual.
+func (s *CaptchaService) Issue(ctx context.Context, board string) (*domain.Challenge, error) {
+	b, err := s.boards.FindBySlug(ctx, board)
+	if err != nil {
+		return nil, err
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("unable to generate challenge: %w", err)
+	}
+
+	challenge := &domain.Challenge{
+		ID:         hex.EncodeToString(id),
+		Board:      b.Slug,
+		Difficulty: s.difficulty(b, time.Now()),
+		ExpiresAt:  time.Now().Add(domain.ChallengeTTL).Truncate(time.Second),
+	}
+	challenge.Token = s.sign(challenge)
+	return challenge, nil
+}
+
+// VerifyThread checks the solution sent with a new thread on board.
+func (s *CaptchaService) VerifyThread(ctx context.Context, board, token, nonce string) error {
+	b, err := s.boards.FindBySlug(ctx, board)
+	if err != nil {
+		return err
+	}
+	return s.verify(ctx, b, token, nonce)
+}
+
+// VerifyReply checks the solution sent with a reply to a thread, against
+// the board of the thread.
+func (s *CaptchaService) VerifyReply(ctx context.Context, postID int, token, nonce string) error {
+	post, err := s.posts.FindByID(ctx, postID)
+	if err != nil {
+		return err
+	}
+	b, err := s.boards.FindBySlug(ctx, post.Board)
+	if err != nil {
+		return err
+	}
+	return s.verify(ctx, b, token, nonce)
+}
+
+func (s *CaptchaService) verify(ctx context.Context, board *domain.Board, token, nonce string) error {
+	if board.CaptchaDifficulty == 0 {
+		return nil
+	}
+	if token == "" || nonce == "" {
+		return domain.ErrCaptchaRequired
+	}
+
+	challenge, err := s.parse(token)
+	if err != nil {
+		return err
+	}
+	if challenge.Board != board.Slug {
+		return fmt.Errorf("%w: challenge is for /%s/", domain.ErrCaptchaInvalid, challenge.Board)
+	}
+	now := time.Now()
+	if now.After(challenge.ExpiresAt) {
+		return domain.ErrCaptchaExpired
+	}
+	if !challenge.Solved(nonce) {
+		return domain.ErrCaptchaInvalid
+	}
+	if err := s.challenges.Use(ctx, challenge.ID, challenge.ExpiresAt); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.recent[board.Slug] = append(s.recent[board.Slug], now)
+	s.mu.Unlock()
+	return nil
+}
+
+// Prune forgets used challenges once they have expired.
+func (s *CaptchaService) Prune(ctx context.Context) (int, error) {
+	return s.challenges.DeleteExpired(ctx, time.Now())
+}
+
+// difficulty adds a bit to the board's difficulty for every doubling of its
+// recent posts past CaptchaSurgeThreshold.
+func (s *CaptchaService) difficulty(board *domain.Board, now time.Time) int {
+	if board.CaptchaDifficulty == 0 {
+		return 0
+	}
+
+	s.mu.Lock()
+	recent := s.recent[board.Slug]
+	for len(recent) > 0 && now.Sub(recent[0]) > domain.CaptchaSurgeWindow {
+		recent = recent[1:]
+	}
+	s.recent[board.Slug] = recent
+	s.mu.Unlock()
+
+	difficulty := board.CaptchaDifficulty
+	if n := len(recent); n >= domain.CaptchaSurgeThreshold {
+		difficulty += bits.Len(uint(n / domain.CaptchaSurgeThreshold))
+	}
+	return min(difficulty, domain.MaxCaptchaDifficulty)
+}
+
+// A token reads id.board.difficulty.expiry.signature.
+func (s *CaptchaService) sign(c *domain.Challenge) string {
+	payload := strings.Join([]string{c.ID, c.Board, strconv.Itoa(c.Difficulty), strconv.FormatInt(c.ExpiresAt.Unix(), 10)}, ".")
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(payload))
+	return payload + "." + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *CaptchaService) parse(token string) (*domain.Challenge, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 5 {
+		return nil, domain.ErrCaptchaInvalid
+	}
+	difficulty, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return nil, domain.ErrCaptchaInvalid
+	}
+	expires, err := strconv.ParseInt(parts[3], 10, 64)
+	if err != nil {
+		return nil, domain.ErrCaptchaInvalid
+	}
+
+	challenge := &domain.Challenge{ID: parts[0], Board: parts[1], Difficulty: difficulty, ExpiresAt: time.Unix(expires, 0)}
+	challenge.Token = s.sign(challenge)
+	if !hmac.Equal([]byte(challenge.Token), []byte(token)) {
+		return nil, domain.ErrCaptchaInvalid
+	}
+	return challenge, nil
+}
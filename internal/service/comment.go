@@ -7,37 +7,53 @@ import (
 
 	"1337b04rd/internal/domain"
 	"1337b04rd/internal/ports"
+	"1337b04rd/pkg/middleware"
 )
 
 type CommentService struct {
 	commentRepo ports.CommentRepository
 	postRepo    ports.PostRepository // <-- Added
+	events      ports.EventPublisher
+	filters     *FilterService
 }
 
-func NewCommentService(commentRepo ports.CommentRepository, postRepo ports.PostRepository) *CommentService {
+func NewCommentService(commentRepo ports.CommentRepository, postRepo ports.PostRepository, events ports.EventPublisher, filters *FilterService) *CommentService {
 	return &CommentService{
 		commentRepo: commentRepo,
 		postRepo:    postRepo,
+		events:      events,
+		filters:     filters,
 	}
 }
 
-func (s *CommentService) CreateComment(ctx context.Context, postID int, authorID, authorName, title, content, imageURL string, replyToID *int) (*domain.Comment, error) {
+func (s *CommentService) CreateComment(ctx context.Context, postID int, authorID, authorName, title, content, imageURL, imageSourceURL string, replyToID *int) (*domain.Comment, error) {
 	post, err := s.postRepo.FindByID(ctx, postID)
 	if err != nil || post == nil {
-		return nil, fmt.Errorf("post with ID %d not found", postID)
+		return nil, fmt.Errorf("post with ID %d: %w", postID, domain.ErrPostNotFound)
+	}
+	if post.Locked {
+		return nil, fmt.Errorf("post with ID %d: %w", postID, domain.ErrThreadLocked)
 	}
 
 	if replyToID != nil {
 		reply, err := s.commentRepo.FindByID(ctx, *replyToID)
-		if err != nil || reply == nil {
-			return nil, fmt.Errorf("reply-to comment with ID %d not found", *replyToID)
+		if err != nil || reply == nil || reply.PostID != postID {
+			return nil, fmt.Errorf("reply-to comment with ID %d: %w", *replyToID, domain.ErrCommentNptFound)
 		}
 	}
 
-	comment, err := domain.NewComment(postID, authorID, authorName, title, content, imageURL, replyToID)
+	ipHash := middleware.GetClientIP(ctx).Hash
+	sub := &domain.Submission{Board: post.Board, SessionID: authorID, IPHash: ipHash, Title: title, Content: content}
+	if err := s.filters.Apply(ctx, sub); err != nil {
+		return nil, err
+	}
+
+	comment, err := domain.NewComment(postID, authorID, authorName, sub.Title, sub.Content, imageURL, replyToID)
 	if err != nil {
 		return nil, fmt.Errorf("create comment failed: %w", err)
 	}
+	comment.ImageSourceURL = imageSourceURL
+	comment.IPHash = ipHash
 
 	if err := s.commentRepo.Save(ctx, comment); err != nil {
 		return nil, err
@@ -49,6 +65,8 @@ func (s *CommentService) CreateComment(ctx context.Context, postID int, authorID
 		_ = s.postRepo.UpdateExpiration(ctx, postID, post.ExpiresAt)
 	}
 
+	publish(ctx, s.events, domain.NewEvent(domain.EventReply, postID, post.Board, comment))
+	publish(ctx, s.events, domain.NewEvent(domain.EventBump, postID, post.Board, nil))
 	return comment, nil
 }
 
@@ -60,6 +78,13 @@ func (s *CommentService) GetAllCommentsOfPost(ctx context.Context, postID int) (
 	return s.commentRepo.FindCommentOfPost(ctx, postID)
 }
 
+func (s *CommentService) ListCommentsOfPost(ctx context.Context, postID, afterID, limit int) ([]*domain.Comment, error) {
+	if _, err := s.postRepo.FindByID(ctx, postID); err != nil {
+		return nil, err
+	}
+	return s.commentRepo.FindPageOfPost(ctx, postID, afterID, limit)
+}
+
 func (s *CommentService) GetRepliesToComment(ctx context.Context, commentID int) ([]*domain.Comment, error) {
 	return s.commentRepo.FindRepliesToComment(ctx, commentID)
 }
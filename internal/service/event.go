@@ -0,0 +1,17 @@
+package service
+
+import (
+	"context"
+
+	"1337b04rd/internal/domain"
+	"1337b04rd/internal/ports"
+)
+
+// publish notifies live readers. Delivery is best effort: the change is
+// already stored, so a failed publish must not fail the request.
+func publish(ctx context.Context, events ports.EventPublisher, event domain.Event) {
+	if events == nil {
+		return
+	}
+	_ = events.Publish(ctx, event)
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"1337b04rd/internal/domain"
+	"1337b04rd/internal/ports"
+)
+
+// filterExcerpt is how much of a matched text the log keeps.
+const filterExcerpt = 200
+
+// compiledFilter is a filter with its expression, compiled once per load.
+type compiledFilter struct {
+	*domain.Filter
+	re *regexp.Regexp
+}
+
+// FilterService runs the content filters over new threads and comments and
+// lets staff manage them. Filters are cached and read again every
+// FilterReloadInterval, or at once after an edit on this replica.
+type FilterService struct {
+	filters ports.FilterRepository
+	bans    *BanService
+	audit   ports.ModActionRepository
+
+	mu       sync.Mutex
+	cached   []compiledFilter
+	loadedAt time.Time
+}
+
+func NewFilterService(filters ports.FilterRepository, bans *BanService, audit ports.ModActionRepository) *FilterService {
+	return &FilterService{filters: filters, bans: bans, audit: audit}
+}
+
+// Apply runs every enabled filter covering the submission's board, in the
+// order they were added. Replacements rewrite the submission in place; a
+// reject or a ban stops it with ErrContentRejected or ErrBanned. Filters in
+// dry run only log their matches. A nil FilterService lets everything
+// through.
+func (s *FilterService) Apply(ctx context.Context, sub *domain.Submission) error {
+	if s == nil {
+		return nil
+	}
+	filters, err := s.load(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range filters {
+		if !f.Enabled || !f.AppliesTo(sub.Board) {
+			continue
+		}
+		matched, err := s.matches(ctx, f, sub)
+		if err != nil {
+			return err
+		}
+		if !matched {
+			continue
+		}
+
+		record(ctx, s.audit, domain.NewModAction(domain.ActionFilterMatch, domain.TargetFilter, f.ID, sub.Board, f.Reason, nil, map[string]interface{}{
+			"action":  f.Action,
+			"dry_run": f.DryRun,
+			"excerpt": excerpt(sub.Title + "\n" + sub.Content),
+		}))
+		if f.DryRun {
+			continue
+		}
+
+		switch f.Action {
+		case domain.FilterReplace:
+			sub.Title = f.re.ReplaceAllString(sub.Title, f.Replacement)
+			sub.Content = f.re.ReplaceAllString(sub.Content, f.Replacement)
+		case domain.FilterReject:
+			return fmt.Errorf("%w: %s", domain.ErrContentRejected, f.Reason)
+		case domain.FilterBan:
+			if _, err := s.bans.BanSubmitter(ctx, sub.SessionID, sub.IPHash, sub.Board, f.Reason, time.Duration(f.BanHours)*time.Hour); err != nil {
+				return err
+			}
+			return fmt.Errorf("%w: %s", domain.ErrBanned, f.Reason)
+		}
+	}
+	return nil
+}
+
+func (s *FilterService) matches(ctx context.Context, f compiledFilter, sub *domain.Submission) (bool, error) {
+	switch f.Kind {
+	case domain.FilterPattern, domain.FilterLiteral:
+		return f.re.MatchString(sub.Title) || f.re.MatchString(sub.Content), nil
+	case domain.FilterLinks:
+		return domain.CountLinks(sub.Title)+domain.CountLinks(sub.Content) > f.Threshold, nil
+	case domain.FilterCaps:
+		return domain.CapsPercent(sub.Title+" "+sub.Content) > f.Threshold, nil
+	case domain.FilterFlood:
+		return domain.LongestRun(sub.Content) > f.Threshold, nil
+	case domain.FilterDuplicate:
+		text := domain.NormalizeForDuplicates(sub.Content)
+		if len(text) < domain.MinDuplicateLength {
+			return false, nil
+		}
+		n, err := s.filters.CountDuplicates(ctx, sub.Board, text, time.Now().Add(-domain.DuplicateWindow))
+		if err != nil {
+			return false, err
+		}
+		return n >= max(f.Threshold, 1), nil
+	}
+	return false, nil
+}
+
+// load returns the cached filters, reading them again once they are older
+// than FilterReloadInterval.
+func (s *FilterService) load(ctx context.Context) ([]compiledFilter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.loadedAt.IsZero() && time.Since(s.loadedAt) < domain.FilterReloadInterval {
+		return s.cached, nil
+	}
+
+	filters, err := s.filters.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	compiled := make([]compiledFilter, 0, len(filters))
+	for _, f := range filters {
+		re, err := f.Compile()
+		if err != nil {
+			// Saved filters were valid when added; skip rather than block
+			// posting should one stop compiling.
+			continue
+		}
+		compiled = append(compiled, compiledFilter{Filter: f, re: re})
+	}
+	s.cached, s.loadedAt = compiled, time.Now()
+	return compiled, nil
+}
+
+// invalidate makes the next Apply read the filters again.
+func (s *FilterService) invalidate() {
+	s.mu.Lock()
+	s.loadedAt = time.Time{}
+	s.mu.Unlock()
+}
+
+func (s *FilterService) ListFilters(ctx context.Context) ([]*domain.Filter, error) {
+	return s.filters.FindAll(ctx)
+}
+
+func (s *FilterService) CreateFilter(ctx context.Context, board string, kind domain.FilterKind, pattern, replacement string, threshold int, action domain.FilterAction, banHours int, reason string, dryRun bool) (*domain.Filter, error) {
+	filter, err := domain.NewFilter(board, kind, pattern, replacement, threshold, action, banHours, reason, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.filters.Save(ctx, filter); err != nil {
+		return nil, err
+	}
+	s.invalidate()
+	record(ctx, s.audit, domain.NewModAction(domain.ActionCreateFilter, domain.TargetFilter, filter.ID, filter.Board, filter.Reason, nil, filter))
+	return filter, nil
+}
+
+// SetFilter turns a filter on or off and in or out of dry run.
+func (s *FilterService) SetFilter(ctx context.Context, id int, enabled, dryRun bool) error {
+	filter, err := s.filters.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	before := *filter
+	filter.Enabled, filter.DryRun, filter.UpdatedAt = enabled, dryRun, time.Now()
+	if err := s.filters.Update(ctx, filter); err != nil {
+		return err
+	}
+	s.invalidate()
+	record(ctx, s.audit, domain.NewModAction(domain.ActionEditFilter, domain.TargetFilter, id, filter.Board, "", &before, filter))
+	return nil
+}
+
+func (s *FilterService) DeleteFilter(ctx context.Context, id int) error {
+	filter, err := s.filters.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.filters.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.invalidate()
+	record(ctx, s.audit, domain.NewModAction(domain.ActionDeleteFilter, domain.TargetFilter, id, filter.Board, "", filter, nil))
+	return nil
+}
+
+func excerpt(text string) string {
+	runes := []rune(text)
+	if len(runes) > filterExcerpt {
+		return string(runes[:filterExcerpt]) + "…"
+	}
+	return string(runes)
+}
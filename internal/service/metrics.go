@@ -0,0 +1,29 @@
+package service
+
+import (
+	"time"
+
+	"1337b04rd/pkg/metrics"
+)
+
+// Lifecycle jobs, as labelled in the metrics.
+const (
+	jobExpire  = "expire"
+	jobArchive = "archive"
+)
+
+var (
+	activeSessions = metrics.NewGaugeVec("board_active_sessions", "Poster sessions held by this replica.")
+
+	lifecycleDuration = metrics.NewHistogramVec("board_lifecycle_job_duration_seconds", "Duration of lifecycle job runs by job.", nil, "job")
+	lifecycleThreads  = metrics.NewGaugeVec("board_lifecycle_last_run_threads", "Threads the last run of each lifecycle job deleted or archived.", "job")
+	lifecycleTotal    = metrics.NewCounterVec("board_lifecycle_threads_total", "Threads deleted or archived by lifecycle jobs.", "job")
+)
+
+// observeRun records one run of a lifecycle job that started at start and
+// removed threads threads, whether it finished or failed part way.
+func observeRun(job string, start time.Time, threads int) {
+	lifecycleDuration.With(job).Observe(time.Since(start).Seconds())
+	lifecycleThreads.With(job).Set(float64(threads))
+	lifecycleTotal.With(job).Add(float64(threads))
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+
+	"1337b04rd/internal/adapters/storage"
+	"1337b04rd/internal/domain"
+	"1337b04rd/internal/ports"
+	"1337b04rd/internal/repository"
+)
+
+// ModerationService carries out staff actions on threads, comments and
+// their files. Permissions are checked by the caller.
+type ModerationService struct {
+	postRepo    *repository.PostRepository
+	commentRepo *repository.CommentRepository
+	boardRepo   *repository.BoardRepository
+	storage     *storage.MinioClient
+	events      ports.EventPublisher
+	audit       ports.ModActionRepository
+}
+
+func NewModerationService(
+	postRepo *repository.PostRepository,
+	commentRepo *repository.CommentRepository,
+	boardRepo *repository.BoardRepository,
+	storage *storage.MinioClient,
+	events ports.EventPublisher,
+	audit ports.ModActionRepository,
+) *ModerationService {
+	return &ModerationService{
+		postRepo:    postRepo,
+		commentRepo: commentRepo,
+		boardRepo:   boardRepo,
+		storage:     storage,
+		events:      events,
+		audit:       audit,
+	}
+}
+
+func (s *ModerationService) ListThreads(ctx context.Context) ([]*domain.Post, error) {
+	return s.postRepo.FindAll(ctx)
+}
+
+func (s *ModerationService) GetThread(ctx context.Context, postID int) (*domain.Post, error) {
+	return s.postRepo.FindByID(ctx, postID)
+}
+
+// DeleteThread removes a thread, its replies and every file attached to them.
+func (s *ModerationService) DeleteThread(ctx context.Context, postID int, reason string) error {
+	post, err := s.postRepo.FindByID(ctx, postID)
+	if err != nil {
+		return err
+	}
+
+	s.removeFile(ctx, post.ImageURL)
+	for _, comment := range post.Comments {
+		s.removeFile(ctx, comment.ImageURL)
+	}
+
+	if err := s.postRepo.Delete(ctx, postID); err != nil {
+		return err
+	}
+	publish(ctx, s.events, domain.NewEvent(domain.EventDeleted, post.ID, post.Board, nil))
+	record(ctx, s.audit, domain.NewModAction(domain.ActionDeleteThread, string(domain.ReportTargetPost), post.ID, post.Board, reason, domain.PostSnapshot(post), nil))
+	return nil
+}
+
+func (s *ModerationService) DeleteComment(ctx context.Context, commentID int, reason string) error {
+	comment, err := s.commentRepo.FindByID(ctx, commentID)
+	if err != nil {
+		return err
+	}
+	s.removeFile(ctx, comment.ImageURL)
+	if err := s.commentRepo.Delete(ctx, commentID); err != nil {
+		return err
+	}
+	record(ctx, s.audit, domain.NewModAction(domain.ActionDeleteComment, string(domain.ReportTargetComment), comment.ID, s.boardOf(ctx, comment.PostID), reason, domain.CommentSnapshot(comment), nil))
+	return nil
+}
+
+func (s *ModerationService) SetSticky(ctx context.Context, postID int, sticky bool) error {
+	post, err := s.postRepo.FindByID(ctx, postID)
+	if err != nil {
+		return err
+	}
+	if err := s.postRepo.SetSticky(ctx, postID, sticky); err != nil {
+		return err
+	}
+	record(ctx, s.audit, domain.NewModAction(domain.ActionSticky, string(domain.ReportTargetPost), postID, post.Board, "",
+		map[string]bool{"sticky": post.Sticky}, map[string]bool{"sticky": sticky}))
+	return nil
+}
+
+func (s *ModerationService) SetLocked(ctx context.Context, postID int, locked bool) error {
+	post, err := s.postRepo.FindByID(ctx, postID)
+	if err != nil {
+		return err
+	}
+	if err := s.postRepo.SetLocked(ctx, postID, locked); err != nil {
+		return err
+	}
+	record(ctx, s.audit, domain.NewModAction(domain.ActionLock, string(domain.ReportTargetPost), postID, post.Board, "",
+		map[string]bool{"locked": post.Locked}, map[string]bool{"locked": locked}))
+	return nil
+}
+
+func (s *ModerationService) MoveThread(ctx context.Context, postID int, board string) error {
+	if _, err := s.boardRepo.FindBySlug(ctx, board); err != nil {
+		return err
+	}
+	post, err := s.postRepo.FindByID(ctx, postID)
+	if err != nil {
+		return err
+	}
+	if err := s.postRepo.UpdateBoard(ctx, postID, board); err != nil {
+		return err
+	}
+	record(ctx, s.audit, domain.NewModAction(domain.ActionMove, string(domain.ReportTargetPost), postID, board, "",
+		map[string]string{"board": post.Board}, map[string]string{"board": board}))
+	return nil
+}
+
+// DeleteThreadFile removes the image of the opening post but keeps the text.
+func (s *ModerationService) DeleteThreadFile(ctx context.Context, postID int, reason string) error {
+	post, err := s.postRepo.FindByID(ctx, postID)
+	if err != nil {
+		return err
+	}
+	if post.ImageURL == "" {
+		return nil
+	}
+	if err := s.postRepo.ClearImage(ctx, postID); err != nil {
+		return err
+	}
+	s.removeFile(ctx, post.ImageURL)
+	record(ctx, s.audit, domain.NewModAction(domain.ActionDeleteFile, string(domain.ReportTargetPost), postID, post.Board, reason,
+		map[string]string{"image_url": post.ImageURL}, nil))
+	return nil
+}
+
+func (s *ModerationService) DeleteCommentFile(ctx context.Context, commentID int, reason string) error {
+	comment, err := s.commentRepo.FindByID(ctx, commentID)
+	if err != nil {
+		return err
+	}
+	if comment.ImageURL == "" {
+		return nil
+	}
+	if err := s.commentRepo.ClearImage(ctx, commentID); err != nil {
+		return err
+	}
+	s.removeFile(ctx, comment.ImageURL)
+	record(ctx, s.audit, domain.NewModAction(domain.ActionDeleteFile, string(domain.ReportTargetComment), commentID, s.boardOf(ctx, comment.PostID), reason,
+		map[string]string{"image_url": comment.ImageURL}, nil))
+	return nil
+}
+
+// boardOf returns the board of a thread for the log, or "" if it is gone.
+func (s *ModerationService) boardOf(ctx context.Context, postID int) string {
+	if post, err := s.postRepo.FindByID(ctx, postID); err == nil {
+		return post.Board
+	}
+	return ""
+}
+
+// removeFile deletes a stored image. The reference is already gone from the
+// database, so an orphaned object is harmless and errors are ignored.
+func (s *ModerationService) removeFile(ctx context.Context, imageURL string) {
+	if s.storage == nil || imageURL == "" {
+		return
+	}
+	_ = s.storage.DeleteImage(ctx, imageURL)
+}
@@ -6,7 +6,9 @@ import (
 	"time"
 
 	"1337b04rd/internal/domain"
+	"1337b04rd/internal/ports"
 	"1337b04rd/internal/repository"
+	"1337b04rd/pkg/middleware"
 )
 
 type PostService interface {
@@ -15,21 +17,44 @@ type PostService interface {
 	DeletePost(ctx context.Context, id int) error
 	DeleteExpiredPost(ctx context.Context) error
 	GetAllPosts(ctx context.Context) ([]*domain.Post, error)
+	ListPosts(ctx context.Context, board string, beforeID, limit int) ([]*domain.Post, error)
 	ArchiveExpiredPosts(ctx context.Context) error
 }
 
 type postService struct {
 	postRepo *repository.PostRepository
+	events   ports.EventPublisher
+	audit    ports.ModActionRepository
+	filters  *FilterService
 }
 
-func NewPostService(postRepo *repository.PostRepository) PostService {
-	return &postService{postRepo: postRepo}
+func NewPostService(postRepo *repository.PostRepository, events ports.EventPublisher, audit ports.ModActionRepository, filters *FilterService) PostService {
+	return &postService{postRepo: postRepo, events: events, audit: audit, filters: filters}
 }
 
 func (s *postService) CreatePost(ctx context.Context, post *domain.Post) error {
 	// Validate the post data
-	if len(post.Title) == 0 || len(post.Content) == 0 {
-		return fmt.Errorf("title and content cannot be empty")
+	if len(post.Title) == 0 {
+		return domain.ErrTitleEmpty
+	}
+	if len(post.Content) == 0 {
+		return domain.ErrEmptyContent
+	}
+
+	if post.IPHash == "" {
+		post.IPHash = middleware.GetClientIP(ctx).Hash
+	}
+
+	if post.Board == "" {
+		post.Board = domain.DefaultBoard
+	}
+	sub := &domain.Submission{Board: post.Board, SessionID: post.AuthorID, IPHash: post.IPHash, Title: post.Title, Content: post.Content}
+	if err := s.filters.Apply(ctx, sub); err != nil {
+		return err
+	}
+	post.Title, post.Content = sub.Title, sub.Content
+	if len(post.Content) == 0 {
+		return domain.ErrEmptyContent
 	}
 
 	// Use repository to save post
@@ -37,6 +62,7 @@ func (s *postService) CreatePost(ctx context.Context, post *domain.Post) error {
 		return fmt.Errorf("unable to save post: %w", err)
 	}
 
+	publish(ctx, s.events, domain.NewThreadEvent(post))
 	return nil
 }
 
@@ -45,7 +71,15 @@ func (s *postService) GetPost(ctx context.Context, id int) (*domain.Post, error)
 }
 
 func (s *postService) DeletePost(ctx context.Context, id int) error {
-	return s.postRepo.Delete(ctx, id)
+	board := ""
+	if post, err := s.postRepo.FindByID(ctx, id); err == nil {
+		board = post.Board
+	}
+	if err := s.postRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+	publish(ctx, s.events, domain.NewEvent(domain.EventDeleted, id, board, nil))
+	return nil
 }
 
 func (s *postService) GetAllPosts(ctx context.Context) ([]*domain.Post, error) {
@@ -54,12 +88,13 @@ func (s *postService) GetAllPosts(ctx context.Context) ([]*domain.Post, error) {
 	if err != nil {
 		return nil, fmt.Errorf("unable to retrieve posts: %w", err)
 	}
+	return posts, nil
+}
 
-	for _, post := range posts {
-		err := s.postRepo.UpdateAuthorNameForPostAndComments(ctx, post.ID, post.AuthorName)
-		if err != nil {
-			return nil, fmt.Errorf("unable to update author name for post %d: %w", post.ID, err)
-		}
+func (s *postService) ListPosts(ctx context.Context, board string, beforeID, limit int) ([]*domain.Post, error) {
+	posts, err := s.postRepo.FindPage(ctx, board, beforeID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve posts: %w", err)
 	}
 	return posts, nil
 }
@@ -69,6 +104,9 @@ func (s *postService) ArchiveExpiredPosts(ctx context.Context) error {
 }
 
 func (s *postService) DeleteExpiredPost(ctx context.Context) error {
+	deleted := 0
+	defer func(start time.Time) { observeRun(jobExpire, start, deleted) }(time.Now())
+
 	posts, err := s.postRepo.FindAll(ctx)
 	if err != nil {
 		return fmt.Errorf("unable to fetch posts: %w", err)
@@ -77,21 +115,30 @@ func (s *postService) DeleteExpiredPost(ctx context.Context) error {
 	currentTime := time.Now()
 
 	for _, post := range posts {
-		if len(post.Comments) == 0 && currentTime.Sub(post.CreatedAt) > 10*time.Minute {
+		if post.Sticky {
+			continue
+		}
+
+		if post.ReplyCount == 0 && currentTime.Sub(post.CreatedAt) > 10*time.Minute {
 			err := s.postRepo.Delete(ctx, post.ID)
 			if err != nil {
 				return fmt.Errorf("unable to delete post without comments: %w", err)
 			}
+			deleted++
+			publish(ctx, s.events, domain.NewEvent(domain.EventDeleted, post.ID, post.Board, nil))
+			record(ctx, s.audit, domain.NewModAction(domain.ActionExpire, string(domain.ReportTargetPost), post.ID, post.Board, "no replies", domain.PostSnapshot(post), nil))
 			continue
 		}
 
-		if len(post.Comments) > 0 {
-			lastComment := post.Comments[len(post.Comments)-1]
-			if currentTime.Sub(lastComment.CreatedAt) > 15*time.Minute {
+		if post.LastReplyAt != nil {
+			if currentTime.Sub(*post.LastReplyAt) > 15*time.Minute {
 				err := s.postRepo.Delete(ctx, post.ID)
 				if err != nil {
 					return fmt.Errorf("unable to delete post with comments: %w", err)
 				}
+				deleted++
+				publish(ctx, s.events, domain.NewEvent(domain.EventDeleted, post.ID, post.Board, nil))
+				record(ctx, s.audit, domain.NewModAction(domain.ActionExpire, string(domain.ReportTargetPost), post.ID, post.Board, "inactive", domain.PostSnapshot(post), nil))
 			}
 		}
 	}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"1337b04rd/internal/domain"
+	"1337b04rd/internal/ports"
+)
+
+// resolvedReportsShown is how many resolved reports the queue lists.
+const resolvedReportsShown = 50
+
+// ReportService files reader reports and lets staff resolve them.
+type ReportService struct {
+	reportRepo  ports.ReportRepository
+	postRepo    ports.PostRepository
+	commentRepo ports.CommentRepository
+	moderation  *ModerationService
+	bans        *BanService
+	audit       ports.ModActionRepository
+}
+
+func NewReportService(reportRepo ports.ReportRepository, postRepo ports.PostRepository, commentRepo ports.CommentRepository, moderation *ModerationService, bans *BanService, audit ports.ModActionRepository) *ReportService {
+	return &ReportService{
+		reportRepo:  reportRepo,
+		postRepo:    postRepo,
+		commentRepo: commentRepo,
+		moderation:  moderation,
+		bans:        bans,
+		audit:       audit,
+	}
+}
+
+// CreateReport files a report on a thread or comment. Each session may report
+// a target once while the report is open, and only ReportRateLimit times per
+// ReportRateWindow.
+func (s *ReportService) CreateReport(ctx context.Context, targetType domain.ReportTarget, targetID int, category domain.ReportCategory, note, sessionID string) (*domain.Report, error) {
+	report, err := domain.NewReport(targetType, targetID, category, note, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	postID := targetID
+	if targetType == domain.ReportTargetComment {
+		comment, err := s.commentRepo.FindByID(ctx, targetID)
+		if err != nil {
+			return nil, err
+		}
+		postID = comment.PostID
+	}
+	post, err := s.postRepo.FindByID(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	report.PostID = post.ID
+	report.Board = post.Board
+
+	n, err := s.reportRepo.CountSince(ctx, sessionID, time.Now().Add(-domain.ReportRateWindow))
+	if err != nil {
+		return nil, err
+	}
+	if n >= domain.ReportRateLimit {
+		return nil, domain.ErrReportRateLimited
+	}
+
+	if err := s.reportRepo.Save(ctx, report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func (s *ReportService) ListQueue(ctx context.Context) ([]*domain.ReportGroup, error) {
+	return s.reportRepo.FindOpenGrouped(ctx)
+}
+
+func (s *ReportService) ListResolved(ctx context.Context) ([]*domain.Report, error) {
+	return s.reportRepo.FindResolved(ctx, resolvedReportsShown)
+}
+
+// Resolve carries out the resolution on the reported target and records it,
+// with the staff member, on every open report of that target. It returns how
+// many reports were closed. reason goes to the log and, for bans, to the
+// poster; banDuration zero bans permanently.
+func (s *ReportService) Resolve(ctx context.Context, targetType domain.ReportTarget, targetID int, resolution domain.ReportResolution, staff *domain.Staff, reason string, banDuration time.Duration) (int, error) {
+	if !targetType.Valid() {
+		return 0, domain.ErrInvalidReportTarget
+	}
+
+	switch resolution {
+	case domain.ResolutionDismiss:
+	case domain.ResolutionDelete:
+		if !staff.Can(domain.PermDeletePost) {
+			return 0, domain.ErrUnauthorized
+		}
+		var err error
+		if targetType == domain.ReportTargetPost {
+			err = s.moderation.DeleteThread(ctx, targetID, reason)
+		} else {
+			err = s.moderation.DeleteComment(ctx, targetID, reason)
+		}
+		if err != nil {
+			return 0, err
+		}
+	case domain.ResolutionBan:
+		if !staff.Can(domain.PermBan) {
+			return 0, domain.ErrUnauthorized
+		}
+		if _, err := s.bans.BanPoster(ctx, targetType, targetID, reason, banDuration, false, staff.ID); err != nil {
+			return 0, err
+		}
+	default:
+		return 0, domain.ErrInvalidResolution
+	}
+
+	n, err := s.reportRepo.Resolve(ctx, targetType, targetID, resolution, staff.ID, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	if resolution == domain.ResolutionDismiss {
+		record(ctx, s.audit, domain.NewModAction(domain.ActionDismissReports, string(targetType), targetID, "", reason, nil, map[string]int{"reports": n}))
+	}
+	return n, nil
+}
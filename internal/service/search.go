@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+
+	"1337b04rd/internal/domain"
+	"1337b04rd/internal/ports"
+)
+
+type SearchService struct {
+	searchRepo ports.SearchRepository
+}
+
+func NewSearchService(searchRepo ports.SearchRepository) *SearchService {
+	return &SearchService{searchRepo: searchRepo}
+}
+
+func (s *SearchService) Search(ctx context.Context, q domain.SearchQuery) (*domain.SearchPage, error) {
+	q.Text = strings.TrimSpace(q.Text)
+	if len(q.Text) == 0 {
+		return nil, domain.ErrEmptySearchQuery
+	}
+
+	if q.Sort == "" {
+		q.Sort = domain.SortByRelevance
+	}
+	if q.Sort != domain.SortByRelevance && q.Sort != domain.SortByDate {
+		return nil, domain.ErrInvalidSearchSort
+	}
+	if q.Scope == "" {
+		q.Scope = domain.ScopeAll
+	}
+	if q.From != nil && q.To != nil && !q.From.Before(*q.To) {
+		return nil, domain.ErrInvalidDateRange
+	}
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.PerPage < 1 {
+		q.PerPage = domain.DefaultSearchPerPage
+	}
+	if q.PerPage > domain.MaxSearchPerPage {
+		q.PerPage = domain.MaxSearchPerPage
+	}
+
+	results, total, err := s.searchRepo.Search(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("unable to search: %w", err)
+	}
+
+	for i := range results {
+		results[i].Snippet = highlightSnippet(results[i].Snippet)
+	}
+
+	return &domain.SearchPage{
+		Results: results,
+		Total:   total,
+		Page:    q.Page,
+		PerPage: q.PerPage,
+	}, nil
+}
+
+// highlightSnippet escapes a raw ts_headline fragment and turns the
+// highlight markers into <mark> tags, so the result is safe to embed as HTML.
+// The repository strips the markers from posts before highlighting; should a
+// stray one get through anyway, it is dropped so every tag is closed.
+func highlightSnippet(raw string) string {
+	escaped := html.EscapeString(raw)
+	var b strings.Builder
+	open := false
+	for {
+		i := strings.IndexAny(escaped, domain.HighlightStart+domain.HighlightStop)
+		if i < 0 {
+			break
+		}
+		b.WriteString(escaped[:i])
+		if start := escaped[i:i+1] == domain.HighlightStart; start != open {
+			if start {
+				b.WriteString("<mark>")
+			} else {
+				b.WriteString("</mark>")
+			}
+			open = start
+		}
+		escaped = escaped[i+1:]
+	}
+	b.WriteString(escaped)
+	if open {
+		b.WriteString("</mark>")
+	}
+	return b.String()
+}
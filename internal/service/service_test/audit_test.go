@@ -0,0 +1,97 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"1337b04rd/internal/domain"
+	"1337b04rd/internal/service"
+	"1337b04rd/pkg/middleware"
+)
+
+type mockModActionRepo struct {
+	actions []*domain.ModAction
+}
+
+func (m *mockModActionRepo) Append(ctx context.Context, action *domain.ModAction) error {
+	action.ID = int64(len(m.actions) + 1)
+	m.actions = append(m.actions, action)
+	return nil
+}
+
+func (m *mockModActionRepo) Find(ctx context.Context, filter domain.ModActionFilter) ([]*domain.ModAction, error) {
+	var found []*domain.ModAction
+	for i := len(m.actions) - 1; i >= 0; i-- {
+		action := m.actions[i]
+		if filter.PublicOnly && !action.Public() {
+			continue
+		}
+		found = append(found, action)
+	}
+	return found, nil
+}
+
+func TestAuditAttribution(t *testing.T) {
+	posts := &mockPostRepo{posts: map[int]*domain.Post{
+		123: {ID: 123, AuthorID: "spammer", Board: "b"},
+	}}
+	log := &mockModActionRepo{}
+	svc := service.NewBanService(newMockBanRepo(), posts, &mockCommentRepo{}, log)
+
+	mod := &domain.Staff{ID: 4, Username: "mod", Role: domain.RoleMod}
+	ban, err := svc.BanPoster(middleware.WithStaff(context.Background(), mod), domain.ReportTargetPost, 123, "spam", time.Hour, false, mod.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.BanRange(context.Background(), "203.0.113.0/24", "", "open proxy", 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.LiftBan(context.Background(), ban.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(log.actions) != 3 {
+		t.Fatalf("logged %d actions, want 3", len(log.actions))
+	}
+	banned := log.actions[0]
+	if banned.ActorType != domain.ActorStaff || banned.ActorID != 4 || banned.ActorName != "mod" {
+		t.Fatalf("ban actor = %s %d %q; want staff 4 mod", banned.ActorType, banned.ActorID, banned.ActorName)
+	}
+	if banned.TargetID != "123" || banned.Reason != "spam" || len(banned.After) == 0 {
+		t.Fatalf("ban entry = %+v; want target 123, the reason and a snapshot", banned)
+	}
+	if lifted := log.actions[2]; lifted.ActorType != domain.ActorSystem || lifted.Action != domain.ActionLiftBan || len(lifted.Before) == 0 {
+		t.Fatalf("lift entry = %+v; want a system lift_ban with the ban before", lifted)
+	}
+
+	public, err := service.NewAuditService(log).ListPublic(context.Background(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(public) != 1 || public[0] != banned {
+		t.Fatalf("public log = %v; want only the poster ban, range bans and lifts stay private", public)
+	}
+}
+
+func TestPublicSummary(t *testing.T) {
+	tests := []struct {
+		action *domain.ModAction
+		want   string
+	}{
+		{domain.NewModAction(domain.ActionDeleteThread, "post", 123, "b", "spam", nil, nil), "a moderator deleted >>123 for spam"},
+		{domain.NewModAction(domain.ActionLock, "post", 5, "b", "", nil, map[string]bool{"locked": true}), "a moderator locked >>5"},
+		{domain.NewModAction(domain.ActionSticky, "post", 5, "b", "", nil, map[string]bool{"sticky": false}), "a moderator unstickied >>5"},
+		{domain.NewModAction(domain.ActionMove, "post", 8, "g", "", nil, nil), "a moderator moved >>8 to /g/"},
+		{&domain.ModAction{ActorType: domain.ActorSystem, Action: domain.ActionArchive, TargetType: "post", TargetID: "9"}, "the system archived >>9"},
+		{domain.NewModAction(domain.ActionRestore, "post", 12, "b", "archived by mistake", nil, nil), "a moderator restored >>12 from the archive for archived by mistake"},
+	}
+	for _, tt := range tests {
+		if tt.action.ActorType == "" {
+			tt.action.ActorType, tt.action.ActorName = domain.ActorStaff, "secret-mod-name"
+		}
+		if got := tt.action.PublicSummary(); got != tt.want {
+			t.Errorf("PublicSummary() = %q, want %q", got, tt.want)
+		}
+	}
+}
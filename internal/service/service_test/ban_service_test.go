@@ -0,0 +1,216 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"1337b04rd/internal/domain"
+	"1337b04rd/internal/service"
+)
+
+type mockBanRepo struct {
+	bans    []*domain.Ban
+	appeals map[int]*domain.BanAppeal
+}
+
+func newMockBanRepo() *mockBanRepo {
+	return &mockBanRepo{appeals: make(map[int]*domain.BanAppeal)}
+}
+
+func (m *mockBanRepo) Save(ctx context.Context, ban *domain.Ban) error {
+	ban.ID = len(m.bans) + 1
+	m.bans = append(m.bans, ban)
+	return nil
+}
+
+func (m *mockBanRepo) FindByID(ctx context.Context, id int) (*domain.Ban, error) {
+	for _, ban := range m.bans {
+		if ban.ID == id {
+			return ban, nil
+		}
+	}
+	return nil, domain.ErrBanNotFound
+}
+
+func (m *mockBanRepo) FindActive(ctx context.Context, sessionID, ipHash, ip, board string) ([]*domain.Ban, error) {
+	var found []*domain.Ban
+	for _, ban := range m.bans {
+		if ban.ExpiresAt != nil && !ban.ExpiresAt.After(time.Now()) {
+			continue
+		}
+		if board != "" && ban.Board != "" && ban.Board != board {
+			continue
+		}
+		inRange := false
+		if _, network, err := net.ParseCIDR(ban.CIDR); err == nil {
+			inRange = network.Contains(net.ParseIP(ip))
+		}
+		if (sessionID != "" && ban.SessionID == sessionID) || (ipHash != "" && ban.IPHash == ipHash) || inRange {
+			found = append(found, ban)
+		}
+	}
+	return found, nil
+}
+
+func (m *mockBanRepo) FindAllActive(ctx context.Context) ([]*domain.Ban, error) {
+	return m.FindActive(ctx, "", "", "", "")
+}
+
+func (m *mockBanRepo) Lift(ctx context.Context, id int) error {
+	ban, err := m.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	ban.ExpiresAt = &now
+	return nil
+}
+
+func (m *mockBanRepo) SaveAppeal(ctx context.Context, appeal *domain.BanAppeal) error {
+	if _, ok := m.appeals[appeal.BanID]; ok {
+		return domain.ErrAppealExists
+	}
+	appeal.ID = len(m.appeals) + 1
+	m.appeals[appeal.BanID] = appeal
+	return nil
+}
+
+func (m *mockBanRepo) FindAppealByBan(ctx context.Context, banID int) (*domain.BanAppeal, error) {
+	if appeal, ok := m.appeals[banID]; ok {
+		return appeal, nil
+	}
+	return nil, domain.ErrAppealNotFound
+}
+
+func (m *mockBanRepo) FindPendingAppeals(ctx context.Context) ([]*domain.BanAppeal, error) {
+	var pending []*domain.BanAppeal
+	for _, appeal := range m.appeals {
+		if appeal.Status == domain.AppealPending {
+			pending = append(pending, appeal)
+		}
+	}
+	return pending, nil
+}
+
+func (m *mockBanRepo) DecideAppeal(ctx context.Context, appealID int, status domain.AppealStatus, staffID int, decidedAt time.Time) error {
+	for _, appeal := range m.appeals {
+		if appeal.ID == appealID && appeal.Status == domain.AppealPending {
+			appeal.Status = status
+			if status == domain.AppealAccepted {
+				return m.Lift(ctx, appeal.BanID)
+			}
+			return nil
+		}
+	}
+	return domain.ErrAppealNotFound
+}
+
+func newBanFixture() (*service.BanService, *mockBanRepo) {
+	posts := &mockPostRepo{posts: map[int]*domain.Post{
+		1: {ID: 1, AuthorID: "spammer", IPHash: "hash-1", Board: "b"},
+	}}
+	comments := &mockCommentRepo{comments: map[int]*domain.Comment{
+		7: {ID: 7, PostID: 1, AuthorID: "replier", IPHash: "hash-7"},
+	}}
+	repo := newMockBanRepo()
+	return service.NewBanService(repo, posts, comments, nil), repo
+}
+
+func TestBanPosterScope(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newBanFixture()
+
+	if _, err := svc.BanPoster(ctx, domain.ReportTargetPost, 1, "spam", time.Hour, false, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	ban, err := svc.CheckBan(ctx, "spammer", "", "", "b")
+	if err != nil || ban == nil || ban.Reason != "spam" {
+		t.Fatalf("CheckBan by session on /b/ = %v, %v; want the ban", ban, err)
+	}
+	if ban, _ := svc.CheckBan(ctx, "new-session", "hash-1", "", "b"); ban == nil {
+		t.Fatal("a new session from the same IP was not banned")
+	}
+	if ban, _ := svc.CheckBan(ctx, "spammer", "", "", "g"); ban != nil {
+		t.Fatal("a board ban applied to another board")
+	}
+
+	if _, err := svc.BanPoster(ctx, domain.ReportTargetComment, 7, "flood", 0, true, 1); err != nil {
+		t.Fatal(err)
+	}
+	ban, _ = svc.CheckBan(ctx, "replier", "", "", "g")
+	if ban == nil || !ban.Permanent() || !ban.Global() {
+		t.Fatalf("comment author ban = %+v; want a permanent global ban", ban)
+	}
+}
+
+func TestBanRange(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newBanFixture()
+
+	ban, err := svc.BanRange(ctx, "203.0.113.77/24", "", "open proxy", 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ban.CIDR != "203.0.113.0/24" {
+		t.Fatalf("CIDR = %q, want it normalised to 203.0.113.0/24", ban.CIDR)
+	}
+	if ban, _ := svc.CheckBan(ctx, "", "", "203.0.113.5", "b"); ban == nil {
+		t.Fatal("address inside the range was not banned")
+	}
+	if ban, _ := svc.CheckBan(ctx, "", "", "198.51.100.5", "b"); ban != nil {
+		t.Fatal("address outside the range was banned")
+	}
+
+	single, err := svc.BanRange(ctx, "198.51.100.9", "", "abuse", 0, 1)
+	if err != nil || single.CIDR != "198.51.100.9/32" {
+		t.Fatalf("bare address = %v, %v; want a /32", single, err)
+	}
+
+	if _, err := svc.BanRange(ctx, "not-an-ip", "", "abuse", 0, 1); !errors.Is(err, domain.ErrInvalidBanTarget) {
+		t.Fatalf("invalid range: err = %v, want ErrInvalidBanTarget", err)
+	}
+	if _, err := svc.BanRange(ctx, "10.0.0.0/8", "", " ", 0, 1); !errors.Is(err, domain.ErrEmptyBanReason) {
+		t.Fatalf("empty reason: err = %v, want ErrEmptyBanReason", err)
+	}
+}
+
+func TestAppealOncePerBan(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newBanFixture()
+
+	ban, err := svc.BanPoster(ctx, domain.ReportTargetPost, 1, "spam", 0, false, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := svc.Appeal(ctx, ban.ID, "someone-else", "other-hash", "", "let me in"); !errors.Is(err, domain.ErrBanNotFound) {
+		t.Fatalf("appeal of someone else's ban: err = %v, want ErrBanNotFound", err)
+	}
+	if _, err := svc.Appeal(ctx, ban.ID, "spammer", "", "", "  "); !errors.Is(err, domain.ErrEmptyAppeal) {
+		t.Fatalf("empty appeal: err = %v, want ErrEmptyAppeal", err)
+	}
+
+	appeal, err := svc.Appeal(ctx, ban.ID, "spammer", "", "", "it was a joke")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Appeal(ctx, ban.ID, "spammer", "", "", "please"); !errors.Is(err, domain.ErrAppealExists) {
+		t.Fatalf("second appeal: err = %v, want ErrAppealExists", err)
+	}
+
+	bans, err := svc.ActiveBans(ctx, "spammer", "", "")
+	if err != nil || len(bans) != 1 || bans[0].Appeal == nil {
+		t.Fatalf("ActiveBans = %v, %v; want the ban with its appeal", bans, err)
+	}
+
+	if err := svc.DecideAppeal(ctx, appeal.ID, true, 1); err != nil {
+		t.Fatal(err)
+	}
+	if ban, _ := svc.CheckBan(ctx, "spammer", "", "", "b"); ban != nil {
+		t.Fatal("accepted appeal did not lift the ban")
+	}
+}
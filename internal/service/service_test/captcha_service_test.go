@@ -0,0 +1,145 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"1337b04rd/internal/domain"
+	"1337b04rd/internal/service"
+)
+
+type mockBoardRepo struct {
+	boards map[string]*domain.Board
+}
+
+func (m *mockBoardRepo) FindAll(ctx context.Context) ([]*domain.Board, error) {
+	var boards []*domain.Board
+	for _, b := range m.boards {
+		boards = append(boards, b)
+	}
+	return boards, nil
+}
+
+func (m *mockBoardRepo) FindBySlug(ctx context.Context, slug string) (*domain.Board, error) {
+	if b, ok := m.boards[slug]; ok {
+		return b, nil
+	}
+	return nil, domain.ErrBoardNotFound
+}
+
+type mockChallengeRepo struct {
+	used map[string]time.Time
+}
+
+func (m *mockChallengeRepo) Use(ctx context.Context, id string, expiresAt time.Time) error {
+	if _, ok := m.used[id]; ok {
+		return domain.ErrCaptchaUsed
+	}
+	m.used[id] = expiresAt
+	return nil
+}
+
+func (m *mockChallengeRepo) DeleteExpired(ctx context.Context, before time.Time) (int, error) {
+	n := 0
+	for id, expiresAt := range m.used {
+		if expiresAt.Before(before) {
+			delete(m.used, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func newCaptchaFixture() *service.CaptchaService {
+	boards := &mockBoardRepo{boards: map[string]*domain.Board{
+		"b":    {Slug: "b", CaptchaDifficulty: 8},
+		"g":    {Slug: "g", CaptchaDifficulty: 8},
+		"meta": {Slug: "meta"},
+	}}
+	posts := &mockPostRepo{posts: map[int]*domain.Post{
+		1: {ID: 1, Board: "b"},
+	}}
+	return service.NewCaptchaService([]byte("secret"), boards, posts, &mockChallengeRepo{used: map[string]time.Time{}})
+}
+
+func TestCaptchaSolveOnce(t *testing.T) {
+	ctx := context.Background()
+	svc := newCaptchaFixture()
+
+	challenge, err := svc.Issue(ctx, "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if challenge.Difficulty != 8 {
+		t.Fatalf("difficulty = %d, want the board's 8", challenge.Difficulty)
+	}
+	nonce := challenge.Solve()
+
+	if err := svc.VerifyThread(ctx, "b", challenge.Token, nonce); err != nil {
+		t.Fatalf("valid solution: %v", err)
+	}
+	if err := svc.VerifyThread(ctx, "b", challenge.Token, nonce); !errors.Is(err, domain.ErrCaptchaUsed) {
+		t.Fatalf("replayed solution: err = %v, want ErrCaptchaUsed", err)
+	}
+
+	reply, _ := svc.Issue(ctx, "b")
+	if err := svc.VerifyReply(ctx, 1, reply.Token, reply.Solve()); err != nil {
+		t.Fatalf("reply on /b/: %v", err)
+	}
+}
+
+func TestCaptchaRejects(t *testing.T) {
+	ctx := context.Background()
+	svc := newCaptchaFixture()
+	challenge, _ := svc.Issue(ctx, "b")
+	nonce := challenge.Solve()
+
+	if err := svc.VerifyThread(ctx, "b", "", ""); !errors.Is(err, domain.ErrCaptchaRequired) {
+		t.Fatalf("missing solution: err = %v, want ErrCaptchaRequired", err)
+	}
+	if err := svc.VerifyThread(ctx, "g", challenge.Token, nonce); !errors.Is(err, domain.ErrCaptchaInvalid) {
+		t.Fatalf("challenge of another board: err = %v, want ErrCaptchaInvalid", err)
+	}
+	easier := strings.Replace(challenge.Token, ".8.", ".0.", 1)
+	if err := svc.VerifyThread(ctx, "b", easier, "0"); !errors.Is(err, domain.ErrCaptchaInvalid) {
+		t.Fatalf("tampered difficulty: err = %v, want ErrCaptchaInvalid", err)
+	}
+	for n := 1; ; n++ {
+		if wrong := strings.Repeat("x", n); !challenge.Solved(wrong) {
+			if err := svc.VerifyThread(ctx, "b", challenge.Token, wrong); !errors.Is(err, domain.ErrCaptchaInvalid) {
+				t.Fatalf("wrong nonce: err = %v, want ErrCaptchaInvalid", err)
+			}
+			break
+		}
+	}
+
+	if err := svc.VerifyThread(ctx, "meta", "", ""); err != nil {
+		t.Fatalf("board without captcha: %v", err)
+	}
+}
+
+func TestCaptchaDifficultyRisesWithSurge(t *testing.T) {
+	ctx := context.Background()
+	svc := newCaptchaFixture()
+
+	for i := 0; i < 2*domain.CaptchaSurgeThreshold; i++ {
+		challenge, err := svc.Issue(ctx, "b")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := svc.VerifyThread(ctx, "b", challenge.Token, challenge.Solve()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	challenge, _ := svc.Issue(ctx, "b")
+	if challenge.Difficulty != 10 {
+		t.Fatalf("difficulty after %d posts = %d, want 10", 2*domain.CaptchaSurgeThreshold, challenge.Difficulty)
+	}
+	if quiet, _ := svc.Issue(ctx, "g"); quiet.Difficulty != 8 {
+		t.Fatalf("difficulty on a quiet board = %d, want 8", quiet.Difficulty)
+	}
+}
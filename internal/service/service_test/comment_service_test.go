@@ -35,6 +35,10 @@ func (m *mockCommentRepo) FindCommentOfPost(ctx context.Context, postID int) ([]
 	return nil, nil
 }
 
+func (m *mockCommentRepo) FindPageOfPost(ctx context.Context, postID, afterID, limit int) ([]*domain.Comment, error) {
+	return nil, nil
+}
+
 func (m *mockCommentRepo) FindRepliesToComment(ctx context.Context, commentID int) ([]*domain.Comment, error) {
 	return nil, nil
 }
@@ -86,9 +90,9 @@ func TestCommentService_CreateComment_Success(t *testing.T) {
 	post := &domain.Post{ID: 1, CreatedAt: time.Now(), ExpiresAt: time.Now().Add(10 * time.Minute)}
 	postRepo := &mockPostRepo{posts: map[int]*domain.Post{1: post}}
 	commentRepo := &mockCommentRepo{comments: make(map[int]*domain.Comment)}
-	svc := service.NewCommentService(commentRepo, postRepo)
+	svc := service.NewCommentService(commentRepo, postRepo, nil, nil)
 
-	comment, err := svc.CreateComment(context.Background(), 1, "user1", "Author", "Title", "Content", "", nil)
+	comment, err := svc.CreateComment(context.Background(), 1, "user1", "Author", "Title", "Content", "", "", nil)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -105,9 +109,9 @@ func TestCommentService_CreateComment_Success(t *testing.T) {
 func TestCommentService_CreateComment_PostNotFound(t *testing.T) {
 	postRepo := &mockPostRepo{posts: make(map[int]*domain.Post)}
 	commentRepo := &mockCommentRepo{comments: make(map[int]*domain.Comment)}
-	svc := service.NewCommentService(commentRepo, postRepo)
+	svc := service.NewCommentService(commentRepo, postRepo, nil, nil)
 
-	_, err := svc.CreateComment(context.Background(), 999, "user1", "Author", "Title", "Content", "", nil)
+	_, err := svc.CreateComment(context.Background(), 999, "user1", "Author", "Title", "Content", "", "", nil)
 	if err == nil {
 		t.Fatal("expected error when post does not exist")
 	}
@@ -117,10 +121,10 @@ func TestCommentService_CreateComment_ReplyNotFound(t *testing.T) {
 	post := &domain.Post{ID: 1, CreatedAt: time.Now(), ExpiresAt: time.Now().Add(10 * time.Minute)}
 	postRepo := &mockPostRepo{posts: map[int]*domain.Post{1: post}}
 	commentRepo := &mockCommentRepo{comments: make(map[int]*domain.Comment)}
-	svc := service.NewCommentService(commentRepo, postRepo)
+	svc := service.NewCommentService(commentRepo, postRepo, nil, nil)
 
 	replyID := 999
-	_, err := svc.CreateComment(context.Background(), 1, "user1", "Author", "Title", "Content", "", &replyID)
+	_, err := svc.CreateComment(context.Background(), 1, "user1", "Author", "Title", "Content", "", "", &replyID)
 	if err == nil {
 		t.Fatal("expected error when reply-to comment does not exist")
 	}
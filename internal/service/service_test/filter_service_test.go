@@ -0,0 +1,181 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"1337b04rd/internal/domain"
+	"1337b04rd/internal/service"
+)
+
+type mockFilterRepo struct {
+	filters    []*domain.Filter
+	duplicates int
+}
+
+func (m *mockFilterRepo) FindAll(ctx context.Context) ([]*domain.Filter, error) {
+	return m.filters, nil
+}
+
+func (m *mockFilterRepo) FindByID(ctx context.Context, id int) (*domain.Filter, error) {
+	for _, f := range m.filters {
+		if f.ID == id {
+			return f, nil
+		}
+	}
+	return nil, domain.ErrFilterNotFound
+}
+
+func (m *mockFilterRepo) Save(ctx context.Context, filter *domain.Filter) error {
+	filter.ID = len(m.filters) + 1
+	m.filters = append(m.filters, filter)
+	return nil
+}
+
+func (m *mockFilterRepo) Update(ctx context.Context, filter *domain.Filter) error {
+	return nil
+}
+
+func (m *mockFilterRepo) Delete(ctx context.Context, id int) error {
+	for i, f := range m.filters {
+		if f.ID == id {
+			m.filters = append(m.filters[:i], m.filters[i+1:]...)
+			return nil
+		}
+	}
+	return domain.ErrFilterNotFound
+}
+
+func (m *mockFilterRepo) CountDuplicates(ctx context.Context, board, text string, since time.Time) (int, error) {
+	return m.duplicates, nil
+}
+
+func newFilterFixture(t *testing.T, filters ...*domain.Filter) (*service.FilterService, *mockFilterRepo, *mockBanRepo, *mockModActionRepo) {
+	t.Helper()
+	repo, bans, log := &mockFilterRepo{}, newMockBanRepo(), &mockModActionRepo{}
+	svc := service.NewFilterService(repo, service.NewBanService(bans, &mockPostRepo{}, &mockCommentRepo{}, log), log)
+	for _, f := range filters {
+		if _, err := svc.CreateFilter(context.Background(), f.Board, f.Kind, f.Pattern, f.Replacement, f.Threshold, f.Action, f.BanHours, f.Reason, f.DryRun); err != nil {
+			t.Fatal(err)
+		}
+	}
+	log.actions = nil
+	return svc, repo, bans, log
+}
+
+func TestFilterActions(t *testing.T) {
+	ctx := context.Background()
+	svc, _, bans, _ := newFilterFixture(t,
+		&domain.Filter{Kind: domain.FilterLiteral, Pattern: "darn", Replacement: "d*rn", Action: domain.FilterReplace, Reason: "language"},
+		&domain.Filter{Kind: domain.FilterLinks, Threshold: 2, Action: domain.FilterReject, Reason: "too many links"},
+		&domain.Filter{Kind: domain.FilterPattern, Pattern: `buy\s+cheap`, Action: domain.FilterBan, BanHours: 24, Reason: "spam", Board: "b"},
+	)
+
+	sub := &domain.Submission{Board: "g", Title: "Darn", Content: "darn it, darnation"}
+	if err := svc.Apply(ctx, sub); err != nil {
+		t.Fatal(err)
+	}
+	if sub.Title != "d*rn" || sub.Content != "d*rn it, darnation" {
+		t.Fatalf("replaced = %q / %q; want whole words only, in any case", sub.Title, sub.Content)
+	}
+
+	links := &domain.Submission{Board: "g", Content: "http://a.example www.b.example https://c.example"}
+	if err := svc.Apply(ctx, links); !errors.Is(err, domain.ErrContentRejected) {
+		t.Fatalf("three links: err = %v, want ErrContentRejected", err)
+	}
+
+	if err := svc.Apply(ctx, &domain.Submission{Board: "g", Content: "buy cheap watches"}); err != nil {
+		t.Fatalf("ban filter of /b/ on /g/: %v", err)
+	}
+	spam := &domain.Submission{Board: "b", SessionID: "spammer", IPHash: "hash", Content: "buy  cheap watches"}
+	if err := svc.Apply(ctx, spam); !errors.Is(err, domain.ErrBanned) {
+		t.Fatalf("spam on /b/: err = %v, want ErrBanned", err)
+	}
+	if len(bans.bans) != 1 || bans.bans[0].SessionID != "spammer" || bans.bans[0].Board != "b" || bans.bans[0].Permanent() {
+		t.Fatalf("bans = %+v; want a 24h ban of the spammer on /b/", bans.bans)
+	}
+}
+
+func TestFilterHeuristics(t *testing.T) {
+	ctx := context.Background()
+	svc, repo, _, _ := newFilterFixture(t,
+		&domain.Filter{Kind: domain.FilterCaps, Threshold: 70, Action: domain.FilterReject, Reason: "caps"},
+		&domain.Filter{Kind: domain.FilterFlood, Threshold: 10, Action: domain.FilterReject, Reason: "flood"},
+		&domain.Filter{Kind: domain.FilterDuplicate, Threshold: 1, Action: domain.FilterReject, Reason: "repost"},
+	)
+
+	tests := []struct {
+		name    string
+		content string
+		dupes   int
+		reject  bool
+	}{
+		{"short shouting", "WHY", 0, false},
+		{"shouting", "WHY IS NOBODY TALKING ABOUT THIS", 0, true},
+		{"long word", "aaaaaaaaaa", 0, false},
+		{"character flood", "a" + strings.Repeat("!", 11), 0, true},
+		{"line flood", strings.Repeat("spam\n", 11), 0, true},
+		{"first post", "the quick brown fox jumps over", 0, false},
+		{"repost", "the quick brown fox jumps over", 1, true},
+	}
+	for _, tt := range tests {
+		repo.duplicates = tt.dupes
+		err := svc.Apply(ctx, &domain.Submission{Board: "b", Content: tt.content})
+		if rejected := errors.Is(err, domain.ErrContentRejected); rejected != tt.reject {
+			t.Errorf("%s: err = %v, want rejected %v", tt.name, err, tt.reject)
+		}
+	}
+}
+
+func TestFilterDryRunAndEdits(t *testing.T) {
+	ctx := context.Background()
+	svc, _, _, log := newFilterFixture(t,
+		&domain.Filter{Kind: domain.FilterLiteral, Pattern: "spam", Action: domain.FilterReject, Reason: "spam", DryRun: true},
+	)
+
+	if err := svc.Apply(ctx, &domain.Submission{Board: "b", Content: "spam"}); err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if len(log.actions) != 1 || log.actions[0].Action != domain.ActionFilterMatch || log.actions[0].ActorType != domain.ActorSystem {
+		t.Fatalf("log = %+v; want one filter_match by the system", log.actions)
+	}
+
+	// Edits take effect at once, without waiting for the reload.
+	if err := svc.SetFilter(ctx, 1, true, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.Apply(ctx, &domain.Submission{Board: "b", Content: "spam"}); !errors.Is(err, domain.ErrContentRejected) {
+		t.Fatalf("enforced: err = %v, want ErrContentRejected", err)
+	}
+	if err := svc.SetFilter(ctx, 1, false, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.Apply(ctx, &domain.Submission{Board: "b", Content: "spam"}); err != nil {
+		t.Fatalf("disabled: %v", err)
+	}
+}
+
+func TestNewFilterValidates(t *testing.T) {
+	tests := []struct {
+		name      string
+		kind      domain.FilterKind
+		pattern   string
+		threshold int
+		action    domain.FilterAction
+		want      error
+	}{
+		{"bad regexp", domain.FilterPattern, "(", 0, domain.FilterReject, domain.ErrInvalidFilterPattern},
+		{"no pattern", domain.FilterLiteral, "", 0, domain.FilterReject, domain.ErrInvalidFilter},
+		{"replace by heuristic", domain.FilterCaps, "", 50, domain.FilterReplace, domain.ErrInvalidFilter},
+		{"caps over 100", domain.FilterCaps, "", 101, domain.FilterReject, domain.ErrInvalidFilter},
+		{"unknown kind", "bayes", "", 0, domain.FilterReject, domain.ErrInvalidFilter},
+	}
+	for _, tt := range tests {
+		if _, err := domain.NewFilter("", tt.kind, tt.pattern, "", tt.threshold, tt.action, 0, "reason", false); !errors.Is(err, tt.want) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
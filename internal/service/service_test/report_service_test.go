@@ -0,0 +1,136 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"1337b04rd/internal/domain"
+	"1337b04rd/internal/service"
+)
+
+type mockReportRepo struct {
+	reports []*domain.Report
+}
+
+func (m *mockReportRepo) Save(ctx context.Context, report *domain.Report) error {
+	for _, r := range m.reports {
+		if r.ResolvedAt == nil && r.SessionID == report.SessionID && r.TargetType == report.TargetType && r.TargetID == report.TargetID {
+			return domain.ErrAlreadyReported
+		}
+	}
+	report.ID = len(m.reports) + 1
+	m.reports = append(m.reports, report)
+	return nil
+}
+
+func (m *mockReportRepo) CountSince(ctx context.Context, sessionID string, since time.Time) (int, error) {
+	n := 0
+	for _, r := range m.reports {
+		if r.SessionID == sessionID && r.CreatedAt.After(since) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *mockReportRepo) FindOpenGrouped(ctx context.Context) ([]*domain.ReportGroup, error) {
+	return nil, nil
+}
+
+func (m *mockReportRepo) FindResolved(ctx context.Context, limit int) ([]*domain.Report, error) {
+	return nil, nil
+}
+
+func (m *mockReportRepo) Resolve(ctx context.Context, targetType domain.ReportTarget, targetID int, resolution domain.ReportResolution, staffID int, resolvedAt time.Time) (int, error) {
+	n := 0
+	for _, r := range m.reports {
+		if r.ResolvedAt == nil && r.TargetType == targetType && r.TargetID == targetID {
+			r.ResolvedAt = &resolvedAt
+			r.Resolution = resolution
+			n++
+		}
+	}
+	return n, nil
+}
+
+func newReportFixture() (*service.ReportService, *mockReportRepo) {
+	posts := &mockPostRepo{posts: map[int]*domain.Post{
+		1: {ID: 1, Board: "b"},
+		2: {ID: 2, Board: "b"},
+	}}
+	comments := &mockCommentRepo{comments: map[int]*domain.Comment{
+		7: {ID: 7, PostID: 1},
+	}}
+	repo := &mockReportRepo{}
+	return service.NewReportService(repo, posts, comments, nil, nil, nil), repo
+}
+
+func TestCreateReport(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newReportFixture()
+
+	report, err := svc.CreateReport(ctx, domain.ReportTargetComment, 7, domain.ReportSpam, " buy pills ", "reader")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.PostID != 1 || report.Board != "b" || report.Note != "buy pills" {
+		t.Fatalf("report = %+v; want post 1 on /b/ with a trimmed note", report)
+	}
+
+	if _, err := svc.CreateReport(ctx, domain.ReportTargetComment, 7, domain.ReportIllegal, "", "reader"); !errors.Is(err, domain.ErrAlreadyReported) {
+		t.Fatalf("second report of the same comment: err = %v, want ErrAlreadyReported", err)
+	}
+	if _, err := svc.CreateReport(ctx, domain.ReportTargetComment, 7, domain.ReportSpam, "", "another-reader"); err != nil {
+		t.Fatalf("report by another session: %v", err)
+	}
+
+	if _, err := svc.CreateReport(ctx, domain.ReportTargetPost, 1, "boring", "", "reader"); !errors.Is(err, domain.ErrInvalidReportCategory) {
+		t.Fatalf("unknown category: err = %v, want ErrInvalidReportCategory", err)
+	}
+	if _, err := svc.CreateReport(ctx, "board", 1, domain.ReportSpam, "", "reader"); !errors.Is(err, domain.ErrInvalidReportTarget) {
+		t.Fatalf("unknown target: err = %v, want ErrInvalidReportTarget", err)
+	}
+}
+
+func TestCreateReportRateLimit(t *testing.T) {
+	ctx := context.Background()
+	svc, repo := newReportFixture()
+
+	for i := 0; i < domain.ReportRateLimit; i++ {
+		repo.reports = append(repo.reports, &domain.Report{SessionID: "flooder", TargetID: 100 + i, CreatedAt: time.Now()})
+	}
+	if _, err := svc.CreateReport(ctx, domain.ReportTargetPost, 1, domain.ReportSpam, "", "flooder"); !errors.Is(err, domain.ErrReportRateLimited) {
+		t.Fatalf("report over the limit: err = %v, want ErrReportRateLimited", err)
+	}
+}
+
+func TestResolveReport(t *testing.T) {
+	ctx := context.Background()
+	svc, repo := newReportFixture()
+
+	for _, session := range []string{"a", "b", "c"} {
+		if _, err := svc.CreateReport(ctx, domain.ReportTargetPost, 2, domain.ReportOffTopic, "", session); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	janitor := &domain.Staff{ID: 3, Username: "jan", Role: domain.RoleJanitor}
+	if _, err := svc.Resolve(ctx, domain.ReportTargetPost, 2, domain.ResolutionBan, janitor, "spam", 0); !errors.Is(err, domain.ErrUnauthorized) {
+		t.Fatalf("janitor ban: err = %v, want ErrUnauthorized", err)
+	}
+	if _, err := svc.Resolve(ctx, domain.ReportTargetPost, 2, "ignore", janitor, "", 0); !errors.Is(err, domain.ErrInvalidResolution) {
+		t.Fatalf("unknown resolution: err = %v, want ErrInvalidResolution", err)
+	}
+
+	n, err := svc.Resolve(ctx, domain.ReportTargetPost, 2, domain.ResolutionDismiss, janitor, "", 0)
+	if err != nil || n != 3 {
+		t.Fatalf("dismiss = %d, %v; want 3 reports closed", n, err)
+	}
+	for _, r := range repo.reports {
+		if r.Resolution != domain.ResolutionDismiss {
+			t.Fatalf("report %d resolution = %q, want dismiss", r.ID, r.Resolution)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"1337b04rd/internal/domain"
+	"1337b04rd/internal/service"
+)
+
+// mockSearchRepo finds one post for every query, with snippet as its raw
+// snippet.
+type mockSearchRepo struct {
+	snippet string
+}
+
+func (m *mockSearchRepo) Search(ctx context.Context, q domain.SearchQuery) ([]domain.SearchResult, int, error) {
+	return []domain.SearchResult{{Kind: "post", PostID: 1, Snippet: m.snippet}}, 1, nil
+}
+
+func TestSearchHighlightsSnippets(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{"plain", "wubba lubba", "wubba lubba"},
+		{"match", "the \x01portal\x02 gun", "the <mark>portal</mark> gun"},
+		{"matches", "\x01portal\x02 and \x01gun\x02", "<mark>portal</mark> and <mark>gun</mark>"},
+		{"escaped", `<b>"rick" & 'morty'</b>`, "&lt;b&gt;&#34;rick&#34; &amp; &#39;morty&#39;&lt;/b&gt;"},
+		{"escaped match", "\x01<script>\x02", "<mark>&lt;script&gt;</mark>"},
+		{"stray start", "a\x01b\x01c\x02d", "a<mark>bc</mark>d"},
+		{"stray stop", "a\x02b\x01c\x02\x02d", "ab<mark>c</mark>d"},
+		{"left open", "\x01portal", "<mark>portal</mark>"},
+		{"empty mark", "a\x01\x02b", "a<mark></mark>b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := service.NewSearchService(&mockSearchRepo{snippet: tt.raw})
+			page, err := svc.Search(context.Background(), domain.SearchQuery{Text: "portal"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := page.Results[0].Snippet; got != tt.want {
+				t.Errorf("snippet of %q = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
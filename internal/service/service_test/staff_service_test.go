@@ -0,0 +1,164 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"1337b04rd/internal/domain"
+	"1337b04rd/internal/service"
+)
+
+type mockStaffRepo struct {
+	staff    map[string]*domain.Staff
+	sessions map[string]staffSession
+}
+
+type staffSession struct {
+	staffID   int
+	expiresAt time.Time
+}
+
+func newMockStaffRepo() *mockStaffRepo {
+	return &mockStaffRepo{staff: map[string]*domain.Staff{}, sessions: map[string]staffSession{}}
+}
+
+func (m *mockStaffRepo) Save(ctx context.Context, s *domain.Staff) error {
+	if _, ok := m.staff[s.Username]; ok {
+		return domain.ErrStaffExists
+	}
+	s.ID = len(m.staff) + 1
+	m.staff[s.Username] = s
+	return nil
+}
+
+func (m *mockStaffRepo) FindByUsername(ctx context.Context, username string) (*domain.Staff, error) {
+	if s, ok := m.staff[username]; ok {
+		return s, nil
+	}
+	return nil, domain.ErrStaffNotFound
+}
+
+func (m *mockStaffRepo) FindAll(ctx context.Context) ([]*domain.Staff, error) {
+	var all []*domain.Staff
+	for _, s := range m.staff {
+		all = append(all, s)
+	}
+	return all, nil
+}
+
+func (m *mockStaffRepo) Count(ctx context.Context) (int, error) {
+	return len(m.staff), nil
+}
+
+func (m *mockStaffRepo) CreateSession(ctx context.Context, tokenHash string, staffID int, expiresAt time.Time) error {
+	m.sessions[tokenHash] = staffSession{staffID: staffID, expiresAt: expiresAt}
+	return nil
+}
+
+func (m *mockStaffRepo) FindBySession(ctx context.Context, tokenHash string) (*domain.Staff, error) {
+	session, ok := m.sessions[tokenHash]
+	if !ok || session.expiresAt.Before(time.Now()) {
+		return nil, domain.ErrStaffNotFound
+	}
+	for _, s := range m.staff {
+		if s.ID == session.staffID {
+			return s, nil
+		}
+	}
+	return nil, domain.ErrStaffNotFound
+}
+
+func (m *mockStaffRepo) DeleteSession(ctx context.Context, tokenHash string) error {
+	delete(m.sessions, tokenHash)
+	return nil
+}
+
+func TestStaffLogin(t *testing.T) {
+	ctx := context.Background()
+	repo := newMockStaffRepo()
+	svc := service.NewStaffService(repo, nil)
+
+	staff, err := svc.CreateStaff(ctx, "rick", "wubba-lubba-dub", domain.RoleMod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if staff.PasswordHash == "wubba-lubba-dub" {
+		t.Fatal("password stored in plain text")
+	}
+
+	if _, _, err := svc.Login(ctx, "rick", "wrong-password"); !errors.Is(err, domain.ErrInvalidCredentials) {
+		t.Fatalf("wrong password: err = %v, want ErrInvalidCredentials", err)
+	}
+	if _, _, err := svc.Login(ctx, "morty", "wubba-lubba-dub"); !errors.Is(err, domain.ErrInvalidCredentials) {
+		t.Fatalf("unknown user: err = %v, want ErrInvalidCredentials", err)
+	}
+
+	token, _, err := svc.Login(ctx, "rick", "wubba-lubba-dub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := repo.sessions[token]; ok {
+		t.Fatal("session token stored unhashed")
+	}
+
+	got, err := svc.Authenticate(ctx, token)
+	if err != nil || got.Username != "rick" {
+		t.Fatalf("Authenticate = %v, %v; want rick", got, err)
+	}
+
+	if err := svc.Logout(ctx, token); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Authenticate(ctx, token); !errors.Is(err, domain.ErrInvalidStaffSession) {
+		t.Fatalf("after logout: err = %v, want ErrInvalidStaffSession", err)
+	}
+}
+
+func TestCreateStaffValidation(t *testing.T) {
+	ctx := context.Background()
+	svc := service.NewStaffService(newMockStaffRepo(), nil)
+
+	if _, err := svc.CreateStaff(ctx, "rick", "short", domain.RoleMod); !errors.Is(err, domain.ErrWeakPassword) {
+		t.Errorf("short password: err = %v, want ErrWeakPassword", err)
+	}
+	if _, err := svc.CreateStaff(ctx, "rick", "long-enough-password", "overlord"); !errors.Is(err, domain.ErrInvalidStaffRole) {
+		t.Errorf("unknown role: err = %v, want ErrInvalidStaffRole", err)
+	}
+}
+
+func TestEnsureAdminOnlyBootstraps(t *testing.T) {
+	ctx := context.Background()
+	repo := newMockStaffRepo()
+	svc := service.NewStaffService(repo, nil)
+
+	if err := svc.EnsureAdmin(ctx, "admin", "first-password"); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.EnsureAdmin(ctx, "other", "second-password"); err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.staff) != 1 || repo.staff["admin"].Role != domain.RoleAdmin {
+		t.Fatalf("staff = %v, want only the first admin", repo.staff)
+	}
+}
+
+func TestRolePermissions(t *testing.T) {
+	tests := []struct {
+		role domain.StaffRole
+		perm domain.Permission
+		want bool
+	}{
+		{domain.RoleJanitor, domain.PermDeleteFile, true},
+		{domain.RoleJanitor, domain.PermSticky, false},
+		{domain.RoleMod, domain.PermMove, true},
+		{domain.RoleMod, domain.PermManageStaff, false},
+		{domain.RoleAdmin, domain.PermManageStaff, true},
+	}
+	for _, tt := range tests {
+		if got := tt.role.Can(tt.perm); got != tt.want {
+			t.Errorf("%s.Can(%s) = %v, want %v", tt.role, tt.perm, got, tt.want)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"math/rand"
 	"net/http"
 	"time"
@@ -11,6 +12,7 @@ import (
 	"1337b04rd/internal/adapters/storage"
 	"1337b04rd/internal/domain"
 	"1337b04rd/internal/ports"
+	"1337b04rd/pkg/logger"
 )
 
 type SessionService struct {
@@ -20,9 +22,10 @@ type SessionService struct {
 	allCharacters   []domain.Character
 	postRepo        ports.PostRepository
 	commentRepo     ports.CommentRepository
+	logger          *slog.Logger
 }
 
-func NewSessionService(minioClient *storage.MinioClient, postRepo ports.PostRepository, commentRepo ports.CommentRepository) *SessionService {
+func NewSessionService(minioClient *storage.MinioClient, postRepo ports.PostRepository, commentRepo ports.CommentRepository, logger *slog.Logger) *SessionService {
 	svc := &SessionService{
 		minioClient:     minioClient,
 		sessions:        make(map[string]*domain.Session),
@@ -30,6 +33,7 @@ func NewSessionService(minioClient *storage.MinioClient, postRepo ports.PostRepo
 		allCharacters:   []domain.Character{},
 		postRepo:        postRepo,
 		commentRepo:     commentRepo,
+		logger:          logger,
 	}
 	svc.loadAllCharacters()
 	return svc
@@ -38,7 +42,7 @@ func NewSessionService(minioClient *storage.MinioClient, postRepo ports.PostRepo
 func (s *SessionService) loadAllCharacters() {
 	client := externalapi.NewRickAndMortyClient()
 
-	characters, err := client.FetchAllCharacters()
+	characters, err := client.FetchAllCharacters(context.Background())
 	if err != nil {
 		panic(fmt.Sprintf("Failed to load Rick & Morty characters: %v", err))
 	}
@@ -49,7 +53,7 @@ func (s *SessionService) loadAllCharacters() {
 		}
 	}
 
-	fmt.Printf("Loaded %d valid characters\n", len(s.allCharacters))
+	s.logger.Info("Loaded characters", "count", len(s.allCharacters))
 }
 
 func (s *SessionService) pickRandomCharacter() domain.Character {
@@ -60,7 +64,7 @@ func (s *SessionService) pickRandomCharacter() domain.Character {
 
 func (s *SessionService) resetAssignedAvatarsIfNeeded() {
 	if len(s.assignedAvatars) >= 826 {
-		fmt.Println("All avatars assigned. Resetting assigned avatars.")
+		s.logger.Info("All avatars assigned, resetting assigned avatars")
 		s.assignedAvatars = make(map[string]bool)
 	}
 }
@@ -82,7 +86,7 @@ func (s *SessionService) CreateSession(ctx context.Context, name string) (*domai
 			break
 		}
 
-		fmt.Println("Avatar already assigned, retrying...")
+		logger.FromContext(ctx, s.logger).Debug("Avatar already assigned, retrying", "attempt", attempt)
 	}
 
 	avatarURL, err := s.minioClient.UploadAvatarFromURL(ctx, char.Image)
@@ -102,9 +106,10 @@ func (s *SessionService) CreateSession(ctx context.Context, name string) (*domai
 	}
 
 	s.sessions[session.ID] = session
+	activeSessions.With().Inc()
 	s.assignedAvatars[char.Image] = true
 
-	fmt.Printf("Session created: ID=%s, Name=%s, AvatarURL=%s\n", session.ID, session.Name, session.AvatarURL)
+	logger.FromContext(ctx, s.logger).Info("Session created", "name", session.Name, "avatar_url", session.AvatarURL)
 
 	return session, nil
 }
@@ -112,11 +117,11 @@ func (s *SessionService) CreateSession(ctx context.Context, name string) (*domai
 func (s *SessionService) UpdateSession(sessionID, newName string) (*domain.Session, error) {
 	session, ok := s.sessions[sessionID]
 	if !ok {
-		return nil, fmt.Errorf("session not found")
+		return nil, fmt.Errorf("session %s: %w", sessionID, domain.ErrInvalidSession)
 	}
 
 	if newName == "" {
-		return nil, fmt.Errorf("name cannot be empty")
+		return nil, domain.ErrEmptyUserName
 	}
 
 	// Update the session name
@@ -167,7 +172,10 @@ func (s *SessionService) DeleteSession(w http.ResponseWriter, r *http.Request) e
 		return fmt.Errorf("failed to get session cookie: %w", err)
 	}
 
-	delete(s.sessions, cookie.Value)
+	if _, ok := s.sessions[cookie.Value]; ok {
+		delete(s.sessions, cookie.Value)
+		activeSessions.With().Dec()
+	}
 
 	http.SetCookie(w, &http.Cookie{
 		Name:    "session_id",
@@ -175,6 +183,12 @@ func (s *SessionService) DeleteSession(w http.ResponseWriter, r *http.Request) e
 		Expires: time.Unix(0, 0),
 		Path:    "/",
 	})
+	http.SetCookie(w, &http.Cookie{
+		Name:    "session_name",
+		Value:   "",
+		Expires: time.Unix(0, 0),
+		Path:    "/",
+	})
 
 	return nil
 }
@@ -188,9 +202,13 @@ func (s *SessionService) GetSession(ctx context.Context, sessionID string) (*dom
 	}
 
 	// If not, retrieve the session ID from the cookie and name from the cookie (if present)
-	cookie, err := ctx.Value("request").(*http.Request).Cookie("session_id")
+	req, ok := ctx.Value("request").(*http.Request)
+	if !ok {
+		return nil, fmt.Errorf("session %s: %w", sessionID, domain.ErrInvalidSession)
+	}
+	cookie, err := req.Cookie("session_id")
 	if err != nil || cookie == nil {
-		return nil, fmt.Errorf("session not found")
+		return nil, fmt.Errorf("session %s: %w", sessionID, domain.ErrInvalidSession)
 	}
 
 	// Use the cookie value as the session ID and create a new session using cookie name
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"1337b04rd/internal/domain"
+	"1337b04rd/internal/ports"
+)
+
+// dummyPasswordHash is compared against when the username does not exist, so
+// failed logins take as long for unknown users as for wrong passwords.
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("not-a-real-password"), bcrypt.DefaultCost)
+
+type StaffService struct {
+	staffRepo ports.StaffRepository
+	audit     ports.ModActionRepository
+}
+
+func NewStaffService(staffRepo ports.StaffRepository, audit ports.ModActionRepository) *StaffService {
+	return &StaffService{staffRepo: staffRepo, audit: audit}
+}
+
+func (s *StaffService) CreateStaff(ctx context.Context, username, password string, role domain.StaffRole) (*domain.Staff, error) {
+	if len(password) < domain.MinStaffPasswordLength {
+		return nil, domain.ErrWeakPassword
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("unable to hash password: %w", err)
+	}
+
+	staff, err := domain.NewStaff(username, string(hash), role)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.staffRepo.Save(ctx, staff); err != nil {
+		return nil, err
+	}
+	record(ctx, s.audit, domain.NewModAction(domain.ActionCreateStaff, domain.TargetStaff, staff.Username, "", "",
+		nil, map[string]interface{}{"username": staff.Username, "role": staff.Role}))
+	return staff, nil
+}
+
+// EnsureAdmin creates the first admin account. It does nothing once any
+// staff account exists, so the bootstrap credentials can stay configured.
+func (s *StaffService) EnsureAdmin(ctx context.Context, username, password string) error {
+	n, err := s.staffRepo.Count(ctx)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+	_, err = s.CreateStaff(ctx, username, password, domain.RoleAdmin)
+	return err
+}
+
+func (s *StaffService) ListStaff(ctx context.Context) ([]*domain.Staff, error) {
+	return s.staffRepo.FindAll(ctx)
+}
+
+// Login checks the credentials and starts a staff session. The returned
+// token goes into the staff cookie; only its hash is stored.
+func (s *StaffService) Login(ctx context.Context, username, password string) (string, *domain.Staff, error) {
+	staff, err := s.staffRepo.FindByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, domain.ErrStaffNotFound) {
+			bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+			return "", nil, domain.ErrInvalidCredentials
+		}
+		return "", nil, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(staff.PasswordHash), []byte(password)); err != nil {
+		return "", nil, domain.ErrInvalidCredentials
+	}
+
+	token, err := generateStaffToken()
+	if err != nil {
+		return "", nil, err
+	}
+	if err := s.staffRepo.CreateSession(ctx, hashStaffToken(token), staff.ID, time.Now().Add(domain.StaffSessionTTL)); err != nil {
+		return "", nil, err
+	}
+	return token, staff, nil
+}
+
+// Authenticate returns the staff member logged in with token.
+func (s *StaffService) Authenticate(ctx context.Context, token string) (*domain.Staff, error) {
+	if token == "" {
+		return nil, domain.ErrInvalidStaffSession
+	}
+	staff, err := s.staffRepo.FindBySession(ctx, hashStaffToken(token))
+	if err != nil {
+		if errors.Is(err, domain.ErrStaffNotFound) {
+			return nil, domain.ErrInvalidStaffSession
+		}
+		return nil, err
+	}
+	return staff, nil
+}
+
+func (s *StaffService) Logout(ctx context.Context, token string) error {
+	return s.staffRepo.DeleteSession(ctx, hashStaffToken(token))
+}
+
+func generateStaffToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("unable to generate staff token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func hashStaffToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,182 @@
+// Package health serves the liveness and readiness probes of the board.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Defaults for how long results are reused and how long a check may take.
+const (
+	DefaultCacheTTL = 5 * time.Second
+	DefaultTimeout  = 2 * time.Second
+)
+
+// Statuses reported for the board and each dependency.
+const (
+	StatusOK           = "ok"
+	StatusDegraded     = "degraded"
+	StatusUnavailable  = "unavailable"
+	StatusShuttingDown = "shutting_down"
+)
+
+// Check reports whether a dependency is usable.
+type Check func(ctx context.Context) error
+
+type check struct {
+	name     string
+	fn       Check
+	optional bool
+}
+
+// Result is the outcome of one check.
+type Result struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+	Optional  bool    `json:"optional,omitempty"`
+}
+
+// Report is the body of /readyz.
+type Report struct {
+	Status    string            `json:"status"`
+	CheckedAt time.Time         `json:"checked_at"`
+	Checks    map[string]Result `json:"checks"`
+}
+
+// Checker runs the readiness checks. Results are cached for the TTL so
+// probes from several orchestrators do not each hit the dependencies.
+type Checker struct {
+	ttl     time.Duration
+	timeout time.Duration
+
+	mu     sync.Mutex
+	checks []check
+	last   Report
+
+	shuttingDown atomic.Bool
+}
+
+func NewChecker(ttl, timeout time.Duration) *Checker {
+	return &Checker{ttl: ttl, timeout: timeout}
+}
+
+// Register adds a dependency the board cannot serve without.
+func (c *Checker) Register(name string, fn Check) {
+	c.add(check{name: name, fn: fn})
+}
+
+// RegisterOptional adds a dependency the board can limp along without. A
+// failing optional check degrades the report but keeps the board ready.
+func (c *Checker) RegisterOptional(name string, fn Check) {
+	c.add(check{name: name, fn: fn, optional: true})
+}
+
+func (c *Checker) add(ch check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks = append(c.checks, ch)
+	c.last = Report{}
+}
+
+// Shutdown marks the board unready so load balancers stop sending it
+// traffic while in-flight requests drain.
+func (c *Checker) Shutdown() {
+	c.shuttingDown.Store(true)
+}
+
+// Check returns the latest report, running the checks again once the
+// cached one is older than the TTL. Concurrent callers wait for a single
+// run instead of starting their own.
+func (c *Checker) Check(ctx context.Context) Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.last.Checks == nil || time.Since(c.last.CheckedAt) >= c.ttl {
+		c.last = c.run(ctx)
+	}
+
+	report := c.last
+	if c.shuttingDown.Load() {
+		report.Status = StatusShuttingDown
+	}
+	return report
+}
+
+// run checks every dependency concurrently.
+func (c *Checker) run(ctx context.Context) Report {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.timeout)
+	defer cancel()
+
+	results := make([]Result, len(c.checks))
+	var wg sync.WaitGroup
+	for i, ch := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			err := ch.fn(ctx)
+			results[i] = Result{
+				Status:    StatusOK,
+				LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+				Optional:  ch.optional,
+			}
+			if err != nil {
+				results[i].Status, results[i].Error = StatusUnavailable, err.Error()
+			}
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, CheckedAt: time.Now(), Checks: make(map[string]Result, len(c.checks))}
+	for i, ch := range c.checks {
+		report.Checks[ch.name] = results[i]
+		switch {
+		case results[i].Status == StatusOK:
+		case ch.optional:
+			if report.Status == StatusOK {
+				report.Status = StatusDegraded
+			}
+		default:
+			report.Status = StatusUnavailable
+		}
+	}
+	return report
+}
+
+// Ready reports whether the board should receive traffic.
+func (r Report) Ready() bool {
+	return r.Status == StatusOK || r.Status == StatusDegraded
+}
+
+// LiveHandler answers as long as the process can serve HTTP at all. It
+// checks no dependencies, so an outage of one does not get the board
+// restarted.
+func LiveHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": StatusOK})
+	})
+}
+
+// ReadyHandler answers 200 with the report while the board is ready and
+// 503 otherwise.
+func (c *Checker) ReadyHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := c.Check(r.Context())
+		status := http.StatusOK
+		if !report.Ready() {
+			status = http.StatusServiceUnavailable
+		}
+		writeJSON(w, status, report)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,96 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"1337b04rd/pkg/health"
+)
+
+func ready(t *testing.T, c *health.Checker) (int, health.Report) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	c.ReadyHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	var report health.Report
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+	return rec.Code, report
+}
+
+func TestReadiness(t *testing.T) {
+	down := errors.New("connection refused")
+	tests := []struct {
+		name       string
+		database   error
+		avatars    error
+		wantCode   int
+		wantStatus string
+	}{
+		{"all up", nil, nil, http.StatusOK, health.StatusOK},
+		{"optional down", nil, down, http.StatusOK, health.StatusDegraded},
+		{"required down", down, nil, http.StatusServiceUnavailable, health.StatusUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := health.NewChecker(time.Minute, time.Second)
+			c.Register("database", func(context.Context) error { return tt.database })
+			c.RegisterOptional("avatars", func(context.Context) error { return tt.avatars })
+
+			code, report := ready(t, c)
+			if code != tt.wantCode || report.Status != tt.wantStatus {
+				t.Fatalf("got %d %q, want %d %q", code, report.Status, tt.wantCode, tt.wantStatus)
+			}
+			if len(report.Checks) != 2 || report.Checks["avatars"].Optional != true {
+				t.Errorf("checks = %+v", report.Checks)
+			}
+			if tt.database != nil && report.Checks["database"].Error != tt.database.Error() {
+				t.Errorf("database error = %q", report.Checks["database"].Error)
+			}
+		})
+	}
+}
+
+func TestReadinessCache(t *testing.T) {
+	var calls atomic.Int32
+	c := health.NewChecker(time.Minute, time.Second)
+	c.Register("database", func(context.Context) error { calls.Add(1); return nil })
+
+	for range 3 {
+		ready(t, c)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("checked %d times within the TTL, want once", calls.Load())
+	}
+}
+
+func TestReadinessTimeout(t *testing.T) {
+	c := health.NewChecker(0, 10*time.Millisecond)
+	c.Register("storage", func(ctx context.Context) error { <-ctx.Done(); return ctx.Err() })
+
+	if code, report := ready(t, c); code != http.StatusServiceUnavailable || report.Checks["storage"].Error == "" {
+		t.Errorf("got %d %+v, want a timed out check", code, report)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	c := health.NewChecker(time.Minute, time.Second)
+	c.Register("database", func(context.Context) error { return nil })
+	c.Shutdown()
+
+	if code, report := ready(t, c); code != http.StatusServiceUnavailable || report.Status != health.StatusShuttingDown {
+		t.Errorf("got %d %q, want unready while shutting down", code, report.Status)
+	}
+
+	rec := httptest.NewRecorder()
+	health.LiveHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("/healthz = %d while shutting down, want 200", rec.Code)
+	}
+}
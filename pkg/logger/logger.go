@@ -1,12 +1,14 @@
 package logger
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"log"
 	"log/slog"
 	"os"
-	"time"
+	"strconv"
+	"strings"
 )
 
 var (
@@ -37,21 +39,122 @@ func Error(v ...any) {
 	errorLogger.Output(2, coloredMessage)
 }
 
-func SetupLogger() (*slog.Logger, *os.File) {
-	if _, err := os.Stat("logs"); os.IsNotExist(err) {
-		os.Mkdir("logs", 0o755)
+// Config chooses how the board logs.
+type Config struct {
+	// Format is "text" or "json".
+	Format string
+	Level  slog.Level
+	// File, when set, receives the log as well as stdout and is rotated
+	// once it grows past MaxSize bytes, keeping MaxBackups old files.
+	File       string
+	MaxSize    int64
+	MaxBackups int
+}
+
+// Defaults for the rotated log file.
+const (
+	DefaultMaxSize    = 100 << 20
+	DefaultMaxBackups = 5
+)
+
+// ConfigFromEnv reads LOG_FORMAT (text or json), LOG_LEVEL (debug, info,
+// warn or error), LOG_FILE, LOG_FILE_MAX_MB and LOG_FILE_MAX_BACKUPS.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		Format:     strings.ToLower(os.Getenv("LOG_FORMAT")),
+		File:       os.Getenv("LOG_FILE"),
+		MaxSize:    DefaultMaxSize,
+		MaxBackups: DefaultMaxBackups,
+	}
+	if cfg.Format == "" {
+		cfg.Format = "text"
+	}
+	if cfg.Format != "text" && cfg.Format != "json" {
+		return cfg, fmt.Errorf("LOG_FORMAT %q is neither text nor json", cfg.Format)
+	}
+	if level := os.Getenv("LOG_LEVEL"); level != "" {
+		if err := cfg.Level.UnmarshalText([]byte(level)); err != nil {
+			return cfg, fmt.Errorf("LOG_LEVEL: %w", err)
+		}
+	}
+	if mb := os.Getenv("LOG_FILE_MAX_MB"); mb != "" {
+		n, err := strconv.Atoi(mb)
+		if err != nil || n <= 0 {
+			return cfg, fmt.Errorf("LOG_FILE_MAX_MB %q is not a positive number", mb)
+		}
+		cfg.MaxSize = int64(n) << 20
+	}
+	if backups := os.Getenv("LOG_FILE_MAX_BACKUPS"); backups != "" {
+		n, err := strconv.Atoi(backups)
+		if err != nil || n < 0 {
+			return cfg, fmt.Errorf("LOG_FILE_MAX_BACKUPS %q is not a number", backups)
+		}
+		cfg.MaxBackups = n
+	}
+	return cfg, nil
+}
+
+// New builds a logger writing to stdout and, when configured, to a
+// rotating file. The returned closer closes that file.
+func New(cfg Config) (*slog.Logger, io.Closer, error) {
+	var out io.Writer = os.Stdout
+	var closer io.Closer = io.NopCloser(nil)
+	if cfg.File != "" {
+		file, err := OpenRotatingFile(cfg.File, cfg.MaxSize, cfg.MaxBackups)
+		if err != nil {
+			return nil, nil, err
+		}
+		out, closer = io.MultiWriter(os.Stdout, file), file
+	}
+
+	opts := &slog.HandlerOptions{Level: cfg.Level}
+	var handler slog.Handler
+	if cfg.Format == "json" {
+		handler = slog.NewJSONHandler(out, opts)
+	} else {
+		handler = slog.NewTextHandler(out, opts)
 	}
+	return slog.New(handler), closer, nil
+}
 
-	logFileName := fmt.Sprintf("logs/log_%s.log", time.Now().Format("20060102_150405"))
-	logFile, err := os.OpenFile(logFileName, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+// SetupLogger builds the logger configured in the environment. A bad
+// configuration is reported and the defaults are used instead, so logging
+// never keeps the board from starting.
+func SetupLogger() (*slog.Logger, io.Closer) {
+	cfg, cfgErr := ConfigFromEnv()
+	if cfgErr != nil {
+		cfg = Config{Format: "text"}
+	}
+	logger, closer, err := New(cfg)
 	if err != nil {
-		fmt.Printf("Error creating the logs file: %v\n", err)
-		os.Exit(1)
+		cfgErr = err
+		logger, closer, _ = New(Config{Format: cfg.Format, Level: cfg.Level})
+	}
+	if cfgErr != nil {
+		logger.Error("Invalid logging configuration, using defaults", "error", cfgErr)
 	}
 
-	multiWriter := io.MultiWriter(os.Stdout, logFile)
-	logger := slog.New(slog.NewTextHandler(multiWriter, nil))
+	logger.Info("Server starting...", "format", cfg.Format, "level", cfg.Level.String(), "logFile", cfg.File)
+	return logger, closer
+}
+
+type contextKey struct{}
+
+// WithLogger returns a context carrying l, for code further down the
+// request to log with.
+func WithLogger(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
 
-	logger.Info("Server starting...", "logFile", logFileName)
-	return logger, logFile
+// FromContext returns the logger the context carries, such as the request
+// logger of the access log, or fallback when it carries none. Without
+// either it returns slog.Default().
+func FromContext(ctx context.Context, fallback *slog.Logger) *slog.Logger {
+	if l, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return l
+	}
+	if fallback != nil {
+		return fallback
+	}
+	return slog.Default()
 }
@@ -0,0 +1,51 @@
+package logger_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"1337b04rd/pkg/logger"
+)
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "board.log")
+	f, err := logger.OpenRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := map[string]string{path: "fourth\n", path + ".1": "third\n", path + ".2": "second\n"}
+	for name, content := range want {
+		got, err := os.ReadFile(name)
+		if err != nil || string(got) != content {
+			t.Errorf("%s = %q, %v; want %q", filepath.Base(name), got, err, content)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Error("kept more backups than configured")
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("LOG_FORMAT", "json")
+	t.Setenv("LOG_LEVEL", "warn")
+	t.Setenv("LOG_FILE_MAX_MB", "2")
+	cfg, err := logger.ConfigFromEnv()
+	if err != nil || cfg.Format != "json" || cfg.Level.String() != "WARN" || cfg.MaxSize != 2<<20 {
+		t.Fatalf("config = %+v, %v", cfg, err)
+	}
+
+	t.Setenv("LOG_FORMAT", "xml")
+	if _, err := logger.ConfigFromEnv(); err == nil || !strings.Contains(err.Error(), "LOG_FORMAT") {
+		t.Fatalf("error = %v, want a LOG_FORMAT error", err)
+	}
+}
@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// HTTPMetrics counts and times requests per route.
+type HTTPMetrics struct {
+	requests *CounterVec
+	duration *HistogramVec
+}
+
+func NewHTTPMetrics(r *Registry) *HTTPMetrics {
+	return &HTTPMetrics{
+		requests: r.NewCounterVec("http_requests_total", "HTTP requests by route, method and status code.", "route", "method", "code"),
+		duration: r.NewHistogramVec("http_request_duration_seconds", "HTTP request latencies by route and method.", nil, "route", "method"),
+	}
+}
+
+// Instrument records every request under the route it resolves to. route
+// should return the pattern the request matched, never the raw path, so
+// the number of series stays bounded; it falls back to "unmatched".
+func (m *HTTPMetrics) Instrument(route func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			name := route(r)
+			if name == "" {
+				name = "unmatched"
+			}
+			start := time.Now()
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+
+			m.requests.With(name, r.Method, strconv.Itoa(rec.status)).Inc()
+			m.duration.With(name, r.Method).Observe(time.Since(start).Seconds())
+		})
+	}
+}
+
+// statusRecorder remembers the status code written. It keeps flushing and
+// hijacking available for event streams and WebSockets.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (rec *statusRecorder) WriteHeader(code int) {
+	if !rec.wroteHeader {
+		rec.status, rec.wroteHeader = code, true
+	}
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	return rec.ResponseWriter.Write(b)
+}
+
+func (rec *statusRecorder) Flush() {
+	http.NewResponseController(rec.ResponseWriter).Flush()
+}
+
+func (rec *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if !rec.wroteHeader {
+		rec.status, rec.wroteHeader = http.StatusSwitchingProtocols, true
+	}
+	return http.NewResponseController(rec.ResponseWriter).Hijack()
+}
+
+func (rec *statusRecorder) Unwrap() http.ResponseWriter { return rec.ResponseWriter }
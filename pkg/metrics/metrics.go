@@ -0,0 +1,365 @@
+// Package metrics is a small registry of counters, gauges and histograms
+// that writes the Prometheus text exposition format, so the board can be
+// scraped without pulling in the Prometheus client.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets suit request and query latencies, in seconds.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Default is the registry the package-level constructors register with and
+// the one the board serves on /metrics.
+var Default = NewRegistry()
+
+// collector is one metric family.
+type collector interface {
+	name() string
+	write(w *bufio.Writer)
+}
+
+// Registry holds metric families by name.
+type Registry struct {
+	mu         sync.Mutex
+	collectors map[string]collector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{collectors: make(map[string]collector)}
+}
+
+// register adds c, panicking on a duplicate name as that is a programming
+// error caught at start-up.
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.collectors[c.name()]; ok {
+		panic(fmt.Sprintf("metrics: %s registered twice", c.name()))
+	}
+	r.collectors[c.name()] = c
+}
+
+// WriteText writes every family, sorted by name, in the text format.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	collectors := make([]collector, 0, len(r.collectors))
+	for _, c := range r.collectors {
+		collectors = append(collectors, c)
+	}
+	r.mu.Unlock()
+	sort.Slice(collectors, func(i, j int) bool { return collectors[i].name() < collectors[j].name() })
+
+	buf := bufio.NewWriter(w)
+	for _, c := range collectors {
+		c.write(buf)
+	}
+	return buf.Flush()
+}
+
+// Handler serves the registry to Prometheus.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.WriteText(w)
+	})
+}
+
+// desc is what every family shares: its name, help and label names.
+type desc struct {
+	fqName string
+	help   string
+	labels []string
+}
+
+func (d *desc) name() string { return d.fqName }
+
+func (d *desc) header(w *bufio.Writer, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", d.fqName, escapeHelp(d.help), d.fqName, kind)
+}
+
+// series keeps one value per label combination of a family.
+type series[T any] struct {
+	mu     sync.Mutex
+	byKey  map[string]*T
+	values map[string][]string
+	create func() *T
+}
+
+func newSeries[T any](create func() *T) *series[T] {
+	return &series[T]{byKey: make(map[string]*T), values: make(map[string][]string), create: create}
+}
+
+func (s *series[T]) with(d *desc, values []string) *T {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", d.fqName, len(d.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.byKey[key]
+	if !ok {
+		v = s.create()
+		s.byKey[key] = v
+		s.values[key] = append([]string(nil), values...)
+	}
+	return v
+}
+
+// each calls fn for every series sorted by label values.
+func (s *series[T]) each(fn func(values []string, v *T)) {
+	s.mu.Lock()
+	keys := make([]string, 0, len(s.byKey))
+	for key := range s.byKey {
+		keys = append(keys, key)
+	}
+	s.mu.Unlock()
+	sort.Strings(keys)
+	for _, key := range keys {
+		s.mu.Lock()
+		v, values := s.byKey[key], s.values[key]
+		s.mu.Unlock()
+		fn(values, v)
+	}
+}
+
+// value is a float updated under a lock, enough for the board's rates.
+type value struct {
+	mu sync.Mutex
+	v  float64
+}
+
+func (v *value) add(delta float64) {
+	v.mu.Lock()
+	v.v += delta
+	v.mu.Unlock()
+}
+
+func (v *value) set(x float64) {
+	v.mu.Lock()
+	v.v = x
+	v.mu.Unlock()
+}
+
+func (v *value) get() float64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.v
+}
+
+// Counter only goes up.
+type Counter struct{ v value }
+
+func (c *Counter) Inc() { c.v.add(1) }
+
+// Add adds delta, which must not be negative.
+func (c *Counter) Add(delta float64) {
+	if delta < 0 {
+		panic("metrics: counter cannot decrease")
+	}
+	c.v.add(delta)
+}
+
+// CounterVec is a counter family partitioned by labels.
+type CounterVec struct {
+	desc
+	series *series[Counter]
+}
+
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{desc: desc{name, help, labels}, series: newSeries(func() *Counter { return &Counter{} })}
+	r.register(c)
+	return c
+}
+
+// With returns the counter for the label values, in the order the labels
+// were declared.
+func (c *CounterVec) With(values ...string) *Counter { return c.series.with(&c.desc, values) }
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.header(w, "counter")
+	c.series.each(func(values []string, counter *Counter) {
+		writeSample(w, c.fqName, c.labels, values, "", "", counter.v.get())
+	})
+}
+
+// Gauge goes up and down.
+type Gauge struct{ v value }
+
+func (g *Gauge) Set(x float64)     { g.v.set(x) }
+func (g *Gauge) Add(delta float64) { g.v.add(delta) }
+func (g *Gauge) Inc()              { g.v.add(1) }
+func (g *Gauge) Dec()              { g.v.add(-1) }
+
+// GaugeVec is a gauge family partitioned by labels.
+type GaugeVec struct {
+	desc
+	series *series[Gauge]
+}
+
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{desc: desc{name, help, labels}, series: newSeries(func() *Gauge { return &Gauge{} })}
+	r.register(g)
+	return g
+}
+
+func (g *GaugeVec) With(values ...string) *Gauge { return g.series.with(&g.desc, values) }
+
+func (g *GaugeVec) write(w *bufio.Writer) {
+	g.header(w, "gauge")
+	g.series.each(func(values []string, gauge *Gauge) {
+		writeSample(w, g.fqName, g.labels, values, "", "", gauge.v.get())
+	})
+}
+
+// GaugeFunc is a gauge read when scraped, for values the board already
+// keeps such as counts in the database.
+type GaugeFunc struct {
+	desc
+	fn func() float64
+}
+
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) *GaugeFunc {
+	g := &GaugeFunc{desc: desc{fqName: name, help: help}, fn: fn}
+	r.register(g)
+	return g
+}
+
+func (g *GaugeFunc) write(w *bufio.Writer) {
+	g.header(w, "gauge")
+	writeSample(w, g.fqName, nil, nil, "", "", g.fn())
+}
+
+// Histogram counts observations into cumulative buckets.
+type Histogram struct {
+	mu      sync.Mutex
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+func (h *Histogram) Observe(x float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, upper := range h.buckets {
+		if x <= upper {
+			h.counts[i]++
+		}
+	}
+	h.sum += x
+	h.count++
+}
+
+// HistogramVec is a histogram family partitioned by labels.
+type HistogramVec struct {
+	desc
+	series *series[Histogram]
+}
+
+// NewHistogramVec makes a histogram family with the given upper bounds,
+// which must be sorted; nil means DefaultBuckets.
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	if !sort.Float64sAreSorted(buckets) {
+		panic(fmt.Sprintf("metrics: %s buckets are not sorted", name))
+	}
+	h := &HistogramVec{desc: desc{name, help, labels}}
+	h.series = newSeries(func() *Histogram {
+		return &Histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+	})
+	r.register(h)
+	return h
+}
+
+func (h *HistogramVec) With(values ...string) *Histogram { return h.series.with(&h.desc, values) }
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.header(w, "histogram")
+	h.series.each(func(values []string, hist *Histogram) {
+		hist.mu.Lock()
+		defer hist.mu.Unlock()
+		for i, upper := range hist.buckets {
+			writeSample(w, h.fqName+"_bucket", h.labels, values, "le", formatFloat(upper), float64(hist.counts[i]))
+		}
+		writeSample(w, h.fqName+"_bucket", h.labels, values, "le", "+Inf", float64(hist.count))
+		writeSample(w, h.fqName+"_sum", h.labels, values, "", "", hist.sum)
+		writeSample(w, h.fqName+"_count", h.labels, values, "", "", float64(hist.count))
+	})
+}
+
+// NewCounterVec registers a counter family with Default.
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return Default.NewCounterVec(name, help, labels...)
+}
+
+// NewGaugeVec registers a gauge family with Default.
+func NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	return Default.NewGaugeVec(name, help, labels...)
+}
+
+// NewGaugeFunc registers a scrape-time gauge with Default.
+func NewGaugeFunc(name, help string, fn func() float64) *GaugeFunc {
+	return Default.NewGaugeFunc(name, help, fn)
+}
+
+// NewHistogramVec registers a histogram family with Default.
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	return Default.NewHistogramVec(name, help, buckets, labels...)
+}
+
+// writeSample writes one line; extraName and extraValue add the le label of
+// histogram buckets.
+func writeSample(w *bufio.Writer, name string, labels, values []string, extraName, extraValue string, v float64) {
+	w.WriteString(name)
+	if len(labels) > 0 || extraName != "" {
+		w.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, "%s=\"%s\"", label, escapeLabel(values[i]))
+		}
+		if extraName != "" {
+			if len(labels) > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, "%s=\"%s\"", extraName, extraValue)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(v))
+	w.WriteByte('\n')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
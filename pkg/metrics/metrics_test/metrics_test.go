@@ -0,0 +1,80 @@
+package metrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"1337b04rd/pkg/metrics"
+)
+
+func TestWriteText(t *testing.T) {
+	reg := metrics.NewRegistry()
+	uploads := reg.NewCounterVec("uploads_total", "Uploads by bucket.", "bucket")
+	latency := reg.NewHistogramVec("latency_seconds", "Latency.", []float64{0.1, 1}, "route")
+	reg.NewGaugeFunc("threads", "Live threads.", func() float64 { return 7 })
+
+	uploads.With("posts").Add(3)
+	uploads.With(`a "quoted"\bucket`).Inc()
+	latency.With("/").Observe(0.05)
+	latency.With("/").Observe(0.5)
+	latency.With("/").Observe(5)
+
+	var out strings.Builder
+	if err := reg.WriteText(&out); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{route="/",le="0.1"} 1
+latency_seconds_bucket{route="/",le="1"} 2
+latency_seconds_bucket{route="/",le="+Inf"} 3
+latency_seconds_sum{route="/"} 5.55
+latency_seconds_count{route="/"} 3
+# HELP threads Live threads.
+# TYPE threads gauge
+threads 7
+# HELP uploads_total Uploads by bucket.
+# TYPE uploads_total counter
+uploads_total{bucket="a \"quoted\"\\bucket"} 1
+uploads_total{bucket="posts"} 3
+`
+	if out.String() != want {
+		t.Fatalf("exposition:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestHTTPMetrics(t *testing.T) {
+	reg := metrics.NewRegistry()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /posts/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := w.(http.Flusher); !ok {
+			t.Error("instrumented writer cannot flush")
+		}
+		if _, ok := w.(http.Hijacker); !ok {
+			t.Error("instrumented writer cannot hijack")
+		}
+		w.WriteHeader(http.StatusTeapot)
+	})
+	h := metrics.NewHTTPMetrics(reg).Instrument(func(r *http.Request) string {
+		_, pattern := mux.Handler(r)
+		return pattern
+	})(mux)
+
+	for _, path := range []string{"/posts/1", "/posts/2", "/nowhere"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	var out strings.Builder
+	reg.WriteText(&out)
+	for _, line := range []string{
+		`http_requests_total{route="GET /posts/{id}",method="GET",code="418"} 2`,
+		`http_requests_total{route="unmatched",method="GET",code="404"} 1`,
+		`http_request_duration_seconds_count{route="GET /posts/{id}",method="GET"} 2`,
+	} {
+		if !strings.Contains(out.String(), line+"\n") {
+			t.Errorf("missing %s in:\n%s", line, out.String())
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"1337b04rd/pkg/logger"
+)
+
+// RequestIDHeader carries the request ID in from a proxy and back out to
+// the client.
+const RequestIDHeader = "X-Request-ID"
+
+const requestIDKey contextKey = "request_id"
+
+// maxRequestIDLength bounds propagated request IDs.
+const maxRequestIDLength = 64
+
+// AccessLog gives every request an ID, taking a well-formed X-Request-ID
+// from upstream, and a logger carrying it with the route, method, trace
+// and a hash of the poster's session, for handlers to get with
+// logger.FromContext. When the request is done it logs one line with the
+// status, duration and bytes written. route should return the pattern the
+// request matched, never the raw path.
+func AccessLog(base *slog.Logger, route func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			id := r.Header.Get(RequestIDHeader)
+			if !validRequestID(id) {
+				id = newRequestID()
+			}
+			w.Header().Set(RequestIDHeader, id)
+
+			pattern := route(r)
+			if pattern == "" {
+				pattern = "unmatched"
+			}
+			attrs := []any{"request_id", id, "method", r.Method, "route", pattern}
+			if cookie, err := r.Cookie("session_id"); err == nil && cookie.Value != "" {
+				attrs = append(attrs, "session", sessionHash(cookie.Value))
+			}
+			if span := trace.SpanContextFromContext(r.Context()); span.IsValid() {
+				attrs = append(attrs, "trace_id", span.TraceID().String(), "span_id", span.SpanID().String())
+			}
+			reqLogger := base.With(attrs...)
+
+			ctx := context.WithValue(r.Context(), requestIDKey, id)
+			ctx = logger.WithLogger(ctx, reqLogger)
+			rec := &accessRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r.WithContext(ctx))
+
+			level := slog.LevelInfo
+			switch {
+			case rec.status >= 500:
+				level = slog.LevelError
+			case rec.status >= 400:
+				level = slog.LevelWarn
+			}
+			reqLogger.Log(ctx, level, "Request",
+				"path", r.URL.Path,
+				"status", rec.status,
+				"duration", time.Since(start),
+				"bytes", rec.bytes,
+			)
+		})
+	}
+}
+
+// GetRequestID returns the ID AccessLog gave the request, empty outside
+// one.
+func GetRequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// sessionHash identifies a session in logs without writing down the
+// cookie, which is all it takes to post as someone.
+func sessionHash(sessionID string) string {
+	sum := sha256.Sum256([]byte(sessionID))
+	return hex.EncodeToString(sum[:6])
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// accessRecorder remembers the status and size of the response. It keeps
+// flushing and hijacking available for event streams and WebSockets.
+type accessRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+func (rec *accessRecorder) WriteHeader(code int) {
+	if !rec.wroteHeader {
+		rec.status, rec.wroteHeader = code, true
+	}
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+func (rec *accessRecorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += int64(n)
+	return n, err
+}
+
+func (rec *accessRecorder) Flush() {
+	http.NewResponseController(rec.ResponseWriter).Flush()
+}
+
+func (rec *accessRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if !rec.wroteHeader {
+		rec.status, rec.wroteHeader = http.StatusSwitchingProtocols, true
+	}
+	return http.NewResponseController(rec.ResponseWriter).Hijack()
+}
+
+func (rec *accessRecorder) Unwrap() http.ResponseWriter { return rec.ResponseWriter }
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strings"
+)
+
+const clientIPKey contextKey = "client_ip"
+
+// ClientIP is the address a request came from. Only Hash is ever stored;
+// the raw IP lives for the request so it can be matched against ranges.
+type ClientIP struct {
+	IP   net.IP
+	Hash string
+}
+
+// HashIP returns the salted hash of ip that stands in for it in the
+// database.
+func HashIP(salt []byte, ip net.IP) string {
+	if ip == nil {
+		return ""
+	}
+	mac := hmac.New(sha256.New, salt)
+	mac.Write([]byte(ip.String()))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ClientIPMiddleware stores the client address and its salted hash in the
+// request context. With trustProxy the first X-Forwarded-For entry wins over
+// the connection's address.
+func ClientIPMiddleware(salt []byte, trustProxy bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := remoteIP(r, trustProxy)
+			client := &ClientIP{IP: ip, Hash: HashIP(salt, ip)}
+			next.ServeHTTP(w, r.WithContext(WithClientIP(r.Context(), client)))
+		})
+	}
+}
+
+func remoteIP(r *http.Request, trustProxy bool) net.IP {
+	if trustProxy {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			first, _, _ := strings.Cut(forwarded, ",")
+			if ip := net.ParseIP(strings.TrimSpace(first)); ip != nil {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return net.ParseIP(host)
+}
+
+// WithClientIP stores the client address in the context.
+func WithClientIP(ctx context.Context, client *ClientIP) context.Context {
+	return context.WithValue(ctx, clientIPKey, client)
+}
+
+// GetClientIP returns the client address, or an empty one for requests that
+// did not pass through ClientIPMiddleware.
+func GetClientIP(ctx context.Context) *ClientIP {
+	if client, ok := ctx.Value(clientIPKey).(*ClientIP); ok && client != nil {
+		return client
+	}
+	return &ClientIP{}
+}
@@ -10,7 +10,10 @@ import (
 
 type contextKey string
 
-const sessionKey contextKey = "session"
+const (
+	sessionKey contextKey = "session"
+	staffKey   contextKey = "staff"
+)
 
 // InjectSessionMiddleware injects the session into the request context
 func InjectSessionMiddleware() func(http.Handler) http.Handler {
@@ -43,6 +46,29 @@ func InjectSessionMiddleware() func(http.Handler) http.Handler {
 	}
 }
 
+// LoadSessionMiddleware injects the session into the request context when
+// the session cookies are present, and lets the request through otherwise.
+// Handlers that need a session report the missing one themselves.
+func LoadSessionMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cookie, err := r.Cookie("session_id")
+			if err != nil || cookie.Value == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			session := &domain.Session{ID: cookie.Value}
+			if nameCookie, err := r.Cookie("session_name"); err == nil {
+				session.Name = nameCookie.Value
+			}
+
+			ctx := context.WithValue(r.Context(), sessionKey, session)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
 func GetSession(ctx context.Context) (*domain.Session, error) {
 	session, ok := ctx.Value(sessionKey).(*domain.Session)
 	if !ok || session == nil {
@@ -50,3 +76,16 @@ func GetSession(ctx context.Context) (*domain.Session, error) {
 	}
 	return session, nil
 }
+
+// WithStaff stores the logged-in staff member in the context.
+func WithStaff(ctx context.Context, staff *domain.Staff) context.Context {
+	return context.WithValue(ctx, staffKey, staff)
+}
+
+func GetStaff(ctx context.Context) (*domain.Staff, error) {
+	staff, ok := ctx.Value(staffKey).(*domain.Staff)
+	if !ok || staff == nil {
+		return nil, fmt.Errorf("no staff login found")
+	}
+	return staff, nil
+}
@@ -0,0 +1,72 @@
+package middleware_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"1337b04rd/pkg/logger"
+	"1337b04rd/pkg/middleware"
+)
+
+func TestAccessLog(t *testing.T) {
+	var out bytes.Buffer
+	base := slog.New(slog.NewJSONHandler(&out, nil))
+	var requestID string
+	h := middleware.AccessLog(base, func(*http.Request) string { return "GET /posts/{id}" })(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestID = middleware.GetRequestID(r.Context())
+			logger.FromContext(r.Context(), nil).Info("Handled")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("missing"))
+		}))
+
+	serve := func(id string) (*httptest.ResponseRecorder, []map[string]any) {
+		out.Reset()
+		req := httptest.NewRequest(http.MethodGet, "/posts/7", nil)
+		req.AddCookie(&http.Cookie{Name: "session_id", Value: "secret-session"})
+		if id != "" {
+			req.Header.Set(middleware.RequestIDHeader, id)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		var lines []map[string]any
+		for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+			var entry map[string]any
+			if err := json.Unmarshal([]byte(line), &entry); err != nil {
+				t.Fatalf("log line %q: %v", line, err)
+			}
+			lines = append(lines, entry)
+		}
+		return rec, lines
+	}
+
+	rec, lines := serve("upstream-id.42")
+	if got := rec.Header().Get(middleware.RequestIDHeader); got != "upstream-id.42" || requestID != got {
+		t.Fatalf("request ID = %q in the response and %q in the context, want the upstream one", got, requestID)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("logged %d lines, want the handler's and the access line", len(lines))
+	}
+	for _, entry := range lines {
+		if entry["request_id"] != "upstream-id.42" || entry["route"] != "GET /posts/{id}" || entry["session"] == nil {
+			t.Errorf("line %v lacks the request attributes", entry)
+		}
+		if strings.Contains(out.String(), "secret-session") {
+			t.Error("session cookie written to the log")
+		}
+	}
+	access := lines[1]
+	if access["status"] != float64(http.StatusNotFound) || access["bytes"] != float64(len("missing")) || access["level"] != "WARN" {
+		t.Errorf("access line = %v, want status 404, 7 bytes at WARN", access)
+	}
+
+	if rec, _ := serve("bad id\n"); rec.Header().Get(middleware.RequestIDHeader) == "bad id\n" || len(requestID) != 32 {
+		t.Errorf("malformed request ID propagated, got %q", requestID)
+	}
+}
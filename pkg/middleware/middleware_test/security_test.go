@@ -0,0 +1,63 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"1337b04rd/pkg/middleware"
+)
+
+func TestSecurityHeadersNonce(t *testing.T) {
+	var nonce string
+	h := middleware.SecurityHeaders(middleware.SecurityConfig{ScriptSources: []string{"https://cdn.example"}})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			nonce = middleware.GetCSPNonce(r.Context())
+		}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/posts", nil))
+	csp := rec.Header().Get("Content-Security-Policy")
+	if nonce == "" || !strings.Contains(csp, "script-src 'self' 'nonce-"+nonce+"' https://cdn.example;") {
+		t.Fatalf("policy %q does not allow scripts with the request's nonce %q", csp, nonce)
+	}
+	if got := rec.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+		t.Fatalf("X-Content-Type-Options = %q, want nosniff", got)
+	}
+	if rec.Header().Get("Strict-Transport-Security") != "" {
+		t.Fatal("HSTS sent without being configured")
+	}
+
+	first := nonce
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/posts", nil))
+	if nonce == first {
+		t.Fatal("nonce reused across requests")
+	}
+}
+
+func TestMediaOrigin(t *testing.T) {
+	origin, _ := url.Parse("https://media.example")
+	h := middleware.MediaOrigin(origin, "/images/")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		host, path string
+		want       int
+		location   string
+	}{
+		{"board.example", "/posts", http.StatusOK, ""},
+		{"board.example", "/images/posts/1-a.jpg", http.StatusFound, "https://media.example/images/posts/1-a.jpg"},
+		{"media.example", "/images/posts/1-a.jpg", http.StatusOK, ""},
+		{"media.example", "/posts", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		req.Host = tt.host
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tt.want || rec.Header().Get("Location") != tt.location {
+			t.Errorf("%s%s: %d %q, want %d %q", tt.host, tt.path, rec.Code, rec.Header().Get("Location"), tt.want, tt.location)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package tracing
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "1337b04rd/pkg/tracing"
+
+// Middleware starts a server span for every request, continuing the trace
+// of a traceparent header. Spans are named after the route, which should
+// return the pattern the request matched, never the raw path.
+func Middleware(route func(*http.Request) string) func(http.Handler) http.Handler {
+	tracer := otel.Tracer(instrumentationName)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			pattern := route(r)
+			if pattern == "" {
+				pattern = "unmatched"
+			}
+			path := strings.TrimPrefix(pattern, r.Method+" ")
+
+			ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			ctx, span := tracer.Start(ctx, r.Method+" "+path,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPRequestMethodKey.String(r.Method),
+					semconv.HTTPRoute(path),
+					semconv.URLPath(r.URL.Path),
+				),
+			)
+			defer span.End()
+
+			rec := &spanRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r.WithContext(ctx))
+
+			span.SetAttributes(semconv.HTTPResponseStatusCode(rec.status))
+			if rec.status >= 500 {
+				span.SetStatus(codes.Error, http.StatusText(rec.status))
+			}
+		})
+	}
+}
+
+// spanRecorder remembers the status of the response. It keeps flushing
+// and hijacking available for event streams and WebSockets.
+type spanRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (rec *spanRecorder) WriteHeader(code int) {
+	if !rec.wroteHeader {
+		rec.status, rec.wroteHeader = code, true
+	}
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+func (rec *spanRecorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	return rec.ResponseWriter.Write(b)
+}
+
+func (rec *spanRecorder) Flush() {
+	http.NewResponseController(rec.ResponseWriter).Flush()
+}
+
+func (rec *spanRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if !rec.wroteHeader {
+		rec.status, rec.wroteHeader = http.StatusSwitchingProtocols, true
+	}
+	return http.NewResponseController(rec.ResponseWriter).Hijack()
+}
+
+func (rec *spanRecorder) Unwrap() http.ResponseWriter { return rec.ResponseWriter }
@@ -0,0 +1,148 @@
+// Package tracing sets up OpenTelemetry tracing for the board. Spans are
+// only exported when an exporter is configured; otherwise starting one
+// costs next to nothing.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"1337b04rd/pkg/logger"
+)
+
+// Exporters TRACE_EXPORTER can choose.
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterFile   = "file"
+	ExporterOTLP   = "otlp"
+)
+
+// DefaultFile is where the file exporter writes without TRACE_FILE.
+const DefaultFile = "logs/traces.jsonl"
+
+const defaultServiceName = "1337b04rd"
+
+// Config chooses where spans go.
+type Config struct {
+	Exporter string
+	// File receives one JSON span per line with the file exporter and is
+	// rotated like the log file.
+	File        string
+	ServiceName string
+}
+
+// ConfigFromEnv reads TRACE_EXPORTER (none, stdout, file or otlp),
+// TRACE_FILE and OTEL_SERVICE_NAME. The OTLP exporter reads its endpoint
+// and headers from the standard OTEL_EXPORTER_OTLP_* variables.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		Exporter:    strings.ToLower(os.Getenv("TRACE_EXPORTER")),
+		File:        os.Getenv("TRACE_FILE"),
+		ServiceName: os.Getenv("OTEL_SERVICE_NAME"),
+	}
+	if cfg.Exporter == "" {
+		cfg.Exporter = ExporterNone
+	}
+	if cfg.File == "" {
+		cfg.File = DefaultFile
+	}
+	if cfg.ServiceName == "" {
+		cfg.ServiceName = defaultServiceName
+	}
+	switch cfg.Exporter {
+	case ExporterNone, ExporterStdout, ExporterFile, ExporterOTLP:
+		return cfg, nil
+	}
+	return cfg, fmt.Errorf("TRACE_EXPORTER %q is not one of none, stdout, file or otlp", cfg.Exporter)
+}
+
+// Setup installs the global tracer provider and W3C trace context
+// propagation. The returned function flushes buffered spans and must run
+// before the process exits.
+func Setup(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if cfg.Exporter == ExporterNone {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	var exporter sdktrace.SpanExporter
+	var file io.Closer
+	var err error
+	switch cfg.Exporter {
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case ExporterFile:
+		var rotating *logger.RotatingFile
+		rotating, err = logger.OpenRotatingFile(cfg.File, logger.DefaultMaxSize, logger.DefaultMaxBackups)
+		if err == nil {
+			file = rotating
+			exporter, err = stdouttrace.New(stdouttrace.WithWriter(rotating))
+		}
+	case ExporterOTLP:
+		exporter, err = otlptracehttp.New(ctx)
+	default:
+		err = fmt.Errorf("unknown trace exporter %q", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s trace exporter: %w", cfg.Exporter, err)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithAttributes(semconv.ServiceName(cfg.ServiceName)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if file != nil {
+			file.Close()
+		}
+		return err
+	}, nil
+}
+
+// SetupTracing sets up the tracing configured in the environment. A bad
+// configuration is reported and tracing turned off, so it never keeps the
+// board from starting.
+func SetupTracing(log *slog.Logger) func(context.Context) error {
+	cfg, err := ConfigFromEnv()
+	if err == nil {
+		var shutdown func(context.Context) error
+		if shutdown, err = Setup(context.Background(), cfg); err == nil {
+			log.Info("Tracing configured", "exporter", cfg.Exporter, "service", cfg.ServiceName)
+			return shutdown
+		}
+	}
+	log.Error("Invalid tracing configuration, tracing disabled", "error", err)
+	return func(context.Context) error { return nil }
+}
+
+// End records err, if any, on the span and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,64 @@
+package tracing_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"1337b04rd/pkg/middleware"
+	"1337b04rd/pkg/tracing"
+)
+
+func TestMiddleware(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	var out bytes.Buffer
+	route := func(*http.Request) string { return "GET /posts/{id}" }
+	h := tracing.Middleware(route)(middleware.AccessLog(slog.New(slog.NewJSONHandler(&out, nil)), route)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "boom", http.StatusInternalServerError)
+		})))
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/posts/7", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	ended := spans.Ended()
+	if len(ended) != 1 {
+		t.Fatalf("ended %d spans, want 1", len(ended))
+	}
+	span := ended[0]
+	if span.Name() != "GET /posts/{id}" || span.SpanContext().TraceID().String() != traceID {
+		t.Errorf("span %q in trace %s, want GET /posts/{id} continuing %s", span.Name(), span.SpanContext().TraceID(), traceID)
+	}
+	if span.Status().Code != codes.Error {
+		t.Errorf("span status = %v, want an error for a 500", span.Status())
+	}
+	attrs := map[string]any{}
+	for _, kv := range span.Attributes() {
+		attrs[string(kv.Key)] = kv.Value.AsInterface()
+	}
+	if attrs["http.route"] != "/posts/{id}" || attrs["http.response.status_code"] != int64(500) {
+		t.Errorf("span attributes = %v", attrs)
+	}
+
+	var line map[string]any
+	if err := json.Unmarshal(out.Bytes(), &line); err != nil {
+		t.Fatal(err)
+	}
+	if line["trace_id"] != traceID || line["span_id"] != span.SpanContext().SpanID().String() {
+		t.Errorf("access line = %v, want the span's trace and span IDs", line)
+	}
+}